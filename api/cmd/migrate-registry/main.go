@@ -0,0 +1,63 @@
+// Command migrate-registry copies the registry mappings and rules stored in
+// the helm-version-manager-registry-mappings ConfigMap into ReleaseRegistry
+// and RegistryRule resources. It is safe to run more than once; existing
+// resources are overwritten with the ConfigMap's mappings and rules.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+
+	"github.com/helm-version-manager/api/internal/storage"
+)
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "list the mappings that would be migrated without writing them")
+	flag.Parse()
+
+	ctx := context.Background()
+
+	from, err := storage.NewRegistryStore()
+	if err != nil {
+		log.Fatalf("Failed to create ConfigMap registry store: %v", err)
+	}
+
+	if *dryRun {
+		mappings, err := from.ListMappings(ctx)
+		if err != nil {
+			log.Fatalf("Failed to list mappings: %v", err)
+		}
+		for _, m := range mappings {
+			log.Printf("Would migrate %s/%s: %s", m.Namespace, m.ReleaseName, m.Registry)
+		}
+		rules, err := from.ListRules(ctx)
+		if err != nil {
+			log.Fatalf("Failed to list rules: %v", err)
+		}
+		for _, r := range rules {
+			log.Printf("Would migrate rule %s: %s", r.ID, r.Registry)
+		}
+		log.Printf("%d mappings and %d rules would be migrated", len(mappings), len(rules))
+		return
+	}
+
+	to, err := storage.NewReleaseRegistryStore()
+	if err != nil {
+		log.Fatalf("Failed to create ReleaseRegistry store: %v", err)
+	}
+
+	migrated, mappingsErr := storage.MigrateMappings(ctx, from, to)
+	for _, m := range migrated {
+		log.Printf("Migrated %s/%s: %s", m.Namespace, m.ReleaseName, m.Registry)
+	}
+	migratedRules, rulesErr := storage.MigrateRules(ctx, from, to)
+	for _, r := range migratedRules {
+		log.Printf("Migrated rule %s: %s", r.ID, r.Registry)
+	}
+	if err := errors.Join(mappingsErr, rulesErr); err != nil {
+		log.Fatalf("Migrated %d mappings and %d rules with errors: %v", len(migrated), len(migratedRules), err)
+	}
+	log.Printf("Migrated %d mappings and %d rules", len(migrated), len(migratedRules))
+}
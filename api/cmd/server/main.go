@@ -8,33 +8,73 @@ import (
 
 	"github.com/helm-version-manager/api/internal/handler"
 	"github.com/helm-version-manager/api/internal/helm"
+	"github.com/helm-version-manager/api/internal/lock"
 	mcpserver "github.com/helm-version-manager/api/internal/mcp"
+	"github.com/helm-version-manager/api/internal/operation"
 	"github.com/helm-version-manager/api/internal/storage"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 )
 
 func main() {
-	registryStore, err := storage.NewRegistryStore()
+	mappingStore, err := storage.NewMappingStore()
 	if err != nil {
 		log.Fatalf("Failed to create registry store: %v", err)
 	}
 
-	helmClient, err := helm.NewClient(registryStore)
+	// Releases without an explicit mapping fall back to registries named in
+	// chart, release or namespace metadata
+	registryStore, err := storage.NewDiscoveringStore(mappingStore)
+	if err != nil {
+		log.Fatalf("Failed to create registry discovery: %v", err)
+	}
+
+	auditStore, err := storage.NewAuditStore()
+	if err != nil {
+		log.Fatalf("Failed to create audit store: %v", err)
+	}
+
+	credentialStore, err := storage.NewCredentialStore()
+	if err != nil {
+		log.Fatalf("Failed to create credential store: %v", err)
+	}
+
+	helmClient, err := helm.NewClient(registryStore, auditStore, credentialStore)
 	if err != nil {
 		log.Fatalf("Failed to create Helm client: %v", err)
 	}
 
-	releaseHandler := handler.NewReleaseHandler(helmClient, registryStore)
+	releaseLocker, err := lock.NewLeaseLocker()
+	if err != nil {
+		log.Fatalf("Failed to create release locker: %v", err)
+	}
+
+	operationStore, err := storage.NewOperationStore()
+	if err != nil {
+		log.Fatalf("Failed to create operation store: %v", err)
+	}
+
+	// Upgrades, values updates and rollbacks run as background operations
+	// shared by the REST API and the MCP server, one at a time per release.
+	// They are stored so that any replica can report on them.
+	operations := operation.NewManager(operation.DefaultRetention, releaseLocker, operationStore)
+
+	releaseHandler := handler.NewReleaseHandler(helmClient, registryStore, operations)
+	operationHandler := handler.NewOperationHandler(operations)
+	registryHandler := handler.NewRegistryHandler(registryStore)
+	credentialHandler := handler.NewCredentialHandler(credentialStore)
 
 	// Create MCP server
-	mcpServer := mcpserver.NewServer(helmClient, registryStore)
+	mcpServer := mcpserver.NewServer(helmClient, registryStore, operations)
 
 	e := echo.New()
 
 	e.Use(middleware.Logger())
 	e.Use(middleware.Recover())
-	e.Use(middleware.CORS())
+	e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
+		// Let browser clients read the revision ETag and operation location
+		ExposeHeaders: []string{"ETag", echo.HeaderLocation},
+	}))
 
 	e.GET("/health", func(c echo.Context) error {
 		return c.JSON(http.StatusOK, map[string]string{"status": "ok"})
@@ -44,20 +84,55 @@ func main() {
 
 	// Release endpoints
 	api.GET("/releases", releaseHandler.List)
+	api.POST("/releases/:namespace", releaseHandler.Install)
 	api.GET("/releases/:namespace/:name", releaseHandler.Get)
 	api.GET("/releases/:namespace/:name/versions", releaseHandler.GetVersions)
 	api.PUT("/releases/:namespace/:name", releaseHandler.Upgrade)
+	api.DELETE("/releases/:namespace/:name", releaseHandler.Uninstall)
+	api.POST("/releases/:namespace/:name/upgrade/preview", releaseHandler.PreviewUpgrade)
 	api.GET("/releases/:namespace/:name/history", releaseHandler.GetHistory)
+	api.GET("/releases/:namespace/:name/history/:revision/values", releaseHandler.GetRevisionValues)
+	api.GET("/releases/:namespace/:name/diff", releaseHandler.Diff)
 	api.POST("/releases/:namespace/:name/rollback", releaseHandler.Rollback)
+	api.GET("/releases/:namespace/:name/manifest", releaseHandler.GetManifest)
+	api.GET("/releases/:namespace/:name/notes", releaseHandler.GetNotes)
+	api.GET("/releases/:namespace/:name/hooks", releaseHandler.GetHooks)
+	api.GET("/releases/:namespace/:name/resources", releaseHandler.GetResources)
+	api.GET("/releases/:namespace/:name/audit", releaseHandler.GetAudit)
+	api.POST("/releases/:namespace/:name/test", releaseHandler.RunTests)
 
 	// Registry mapping endpoints
 	api.GET("/releases/:namespace/:name/registry", releaseHandler.GetRegistry)
 	api.PUT("/releases/:namespace/:name/registry", releaseHandler.SetRegistry)
 	api.DELETE("/releases/:namespace/:name/registry", releaseHandler.DeleteRegistry)
+	api.GET("/releases/:namespace/:name/registry/resolve", registryHandler.Resolve)
+	api.GET("/registry-mappings/export", releaseHandler.ExportRegistryMappings)
+	api.POST("/registry-mappings/import", releaseHandler.ImportRegistryMappings)
+
+	// Registry rule endpoints
+	api.GET("/registry-rules", registryHandler.ListRules)
+	api.POST("/registry-rules", registryHandler.CreateRule)
+	api.GET("/registry-rules/:id", registryHandler.GetRule)
+	api.PUT("/registry-rules/:id", registryHandler.UpdateRule)
+	api.DELETE("/registry-rules/:id", registryHandler.DeleteRule)
+
+	// Registry credential endpoints
+	api.GET("/registry-credentials", credentialHandler.List)
+	api.POST("/registry-credentials", credentialHandler.Create)
+	api.GET("/registry-credentials/:id", credentialHandler.Get)
+	api.PUT("/registry-credentials/:id", credentialHandler.Update)
+	api.DELETE("/registry-credentials/:id", credentialHandler.Delete)
 
 	// Values endpoints
 	api.GET("/releases/:namespace/:name/values", releaseHandler.GetValues)
 	api.PUT("/releases/:namespace/:name/values", releaseHandler.UpdateValues)
+	api.GET("/releases/:namespace/:name/values/defaults", releaseHandler.GetChartValues)
+	api.GET("/releases/:namespace/:name/values/computed", releaseHandler.GetComputedValues)
+	api.GET("/releases/:namespace/:name/values/schema", releaseHandler.GetValuesSchema)
+
+	// Operation endpoints
+	api.GET("/operations/:id", operationHandler.Get)
+	api.GET("/operations/:id/events", operationHandler.Events)
 
 	// MCP server endpoint (Streamable HTTP)
 	mcpHandler := echo.WrapHandler(mcpServer.NewHTTPHandler())
@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/helm-version-manager/api/internal/model"
+	"github.com/labstack/echo/v4"
+)
+
+// RegistryCredentialStore stores the references from registries to the
+// Secrets holding their credentials
+type RegistryCredentialStore interface {
+	GetCredential(ctx context.Context, id string) (*model.RegistryCredential, error)
+	SetCredential(ctx context.Context, credential model.RegistryCredential) error
+	DeleteCredential(ctx context.Context, id string) error
+	ListCredentials(ctx context.Context) ([]model.RegistryCredential, error)
+	ValidateCredential(ctx context.Context, credential model.RegistryCredential) error
+}
+
+// CredentialHandler manages registry credentials. Responses only name the
+// Secret, never its contents.
+type CredentialHandler struct {
+	store RegistryCredentialStore
+}
+
+func NewCredentialHandler(store RegistryCredentialStore) *CredentialHandler {
+	return &CredentialHandler{
+		store: store,
+	}
+}
+
+func (h *CredentialHandler) List(c echo.Context) error {
+	credentials, err := h.store.ListCredentials(c.Request().Context())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, credentials)
+}
+
+func (h *CredentialHandler) Get(c echo.Context) error {
+	id := c.Param("id")
+
+	credential, err := h.store.GetCredential(c.Request().Context(), id)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	if credential == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "registry credential not found")
+	}
+
+	return c.JSON(http.StatusOK, credential)
+}
+
+// Create adds a credential, failing with 400 if its Secret does not hold
+// credentials for its registry and with 409 if its ID is taken
+func (h *CredentialHandler) Create(c echo.Context) error {
+	var credential model.RegistryCredential
+	if err := c.Bind(&credential); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
+	}
+
+	ctx := c.Request().Context()
+	if err := h.store.ValidateCredential(ctx, credential); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	existing, err := h.store.GetCredential(ctx, credential.ID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	if existing != nil {
+		return echo.NewHTTPError(http.StatusConflict, "registry credential "+credential.ID+" already exists")
+	}
+
+	if err := h.store.SetCredential(ctx, credential); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	c.Response().Header().Set(echo.HeaderLocation, "/api/registry-credentials/"+credential.ID)
+	return c.JSON(http.StatusCreated, credential)
+}
+
+// Update creates or replaces the credential named in the path
+func (h *CredentialHandler) Update(c echo.Context) error {
+	id := c.Param("id")
+
+	var credential model.RegistryCredential
+	if err := c.Bind(&credential); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
+	}
+	if credential.ID != "" && credential.ID != id {
+		return echo.NewHTTPError(http.StatusBadRequest, "credential id does not match the path")
+	}
+	credential.ID = id
+
+	ctx := c.Request().Context()
+	if err := h.store.ValidateCredential(ctx, credential); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if err := h.store.SetCredential(ctx, credential); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, credential)
+}
+
+func (h *CredentialHandler) Delete(c echo.Context) error {
+	id := c.Param("id")
+
+	if err := h.store.DeleteCredential(c.Request().Context(), id); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,122 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/helm-version-manager/api/internal/model"
+	"github.com/labstack/echo/v4"
+)
+
+type fakeCredentialStore struct {
+	credentials map[string]model.RegistryCredential
+	// secrets are the Secrets that hold credentials
+	secrets map[string]bool
+}
+
+func (s *fakeCredentialStore) GetCredential(ctx context.Context, id string) (*model.RegistryCredential, error) {
+	if credential, ok := s.credentials[id]; ok {
+		return &credential, nil
+	}
+	return nil, nil
+}
+
+func (s *fakeCredentialStore) SetCredential(ctx context.Context, credential model.RegistryCredential) error {
+	s.credentials[credential.ID] = credential
+	return nil
+}
+
+func (s *fakeCredentialStore) DeleteCredential(ctx context.Context, id string) error {
+	delete(s.credentials, id)
+	return nil
+}
+
+func (s *fakeCredentialStore) ListCredentials(ctx context.Context) ([]model.RegistryCredential, error) {
+	credentials := make([]model.RegistryCredential, 0, len(s.credentials))
+	for _, credential := range s.credentials {
+		credentials = append(credentials, credential)
+	}
+	return credentials, nil
+}
+
+func (s *fakeCredentialStore) ValidateCredential(ctx context.Context, credential model.RegistryCredential) error {
+	if !s.secrets[credential.SecretName] {
+		return errors.New("secret " + credential.SecretName + " not found")
+	}
+	return nil
+}
+
+func TestCredentialHandlerCreate(t *testing.T) {
+	store := &fakeCredentialStore{
+		credentials: map[string]model.RegistryCredential{
+			"existing": {ID: "existing", Registry: "oci://ghcr.io/ourorg", SecretName: "pull-secret"},
+		},
+		secrets: map[string]bool{"pull-secret": true},
+	}
+	h := NewCredentialHandler(store)
+
+	testCases := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{name: "created", body: `{"id":"harbor","registry":"oci://harbor.example.com","secretName":"pull-secret"}`, wantStatus: http.StatusCreated},
+		{name: "exists", body: `{"id":"existing","registry":"oci://ghcr.io/other","secretName":"pull-secret"}`, wantStatus: http.StatusConflict},
+		{name: "missing secret", body: `{"id":"quay","registry":"oci://quay.io","secretName":"missing"}`, wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, rec := newTestContext(http.MethodPost, "/api/registry-credentials", tc.body, map[string]string{echo.HeaderContentType: echo.MIMEApplicationJSON})
+			err := h.Create(c)
+			if tc.wantStatus != http.StatusCreated {
+				if got := httpStatus(err); got != tc.wantStatus {
+					t.Errorf("expected status %d, got %v", tc.wantStatus, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if rec.Code != http.StatusCreated || rec.Header().Get(echo.HeaderLocation) != "/api/registry-credentials/harbor" {
+				t.Errorf("expected 201 with location, got %d %q", rec.Code, rec.Header().Get(echo.HeaderLocation))
+			}
+		})
+	}
+
+	if store.credentials["existing"].Registry != "oci://ghcr.io/ourorg" {
+		t.Errorf("expected the existing credential to be kept, got %+v", store.credentials["existing"])
+	}
+	if _, ok := store.credentials["quay"]; ok {
+		t.Error("expected the invalid credential not to be stored")
+	}
+}
+
+func TestCredentialHandlerUpdate(t *testing.T) {
+	store := &fakeCredentialStore{
+		credentials: map[string]model.RegistryCredential{},
+		secrets:     map[string]bool{"pull-secret": true},
+	}
+	h := NewCredentialHandler(store)
+
+	update := func(id, body string) error {
+		c, _ := newTestContext(http.MethodPut, "/api/registry-credentials/"+id, body, map[string]string{echo.HeaderContentType: echo.MIMEApplicationJSON})
+		c.SetParamNames("id")
+		c.SetParamValues(id)
+		return h.Update(c)
+	}
+
+	// The ID is taken from the path
+	if err := update("ghcr", `{"registry":"oci://ghcr.io/ourorg","secretName":"pull-secret"}`); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if store.credentials["ghcr"].SecretName != "pull-secret" {
+		t.Errorf("expected credential to be stored under ghcr, got %+v", store.credentials)
+	}
+
+	if err := update("ghcr", `{"id":"other","registry":"oci://ghcr.io/ourorg","secretName":"pull-secret"}`); httpStatus(err) != http.StatusBadRequest {
+		t.Errorf("expected 400 for mismatched id, got %v", err)
+	}
+}
@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	formatYAML  = "yaml"
+	mimeYAML    = "application/yaml"
+	maxYAMLBody = 4 << 20
+)
+
+// yamlMediaTypes are the media types accepted as YAML in Content-Type and
+// Accept headers
+var yamlMediaTypes = map[string]bool{
+	mimeYAML:             true,
+	"application/x-yaml": true,
+	"text/yaml":          true,
+	"text/x-yaml":        true,
+}
+
+// wantsYAML reports whether the response should be YAML, either because of
+// ?format=yaml or because the Accept header prefers a YAML media type.
+func wantsYAML(c echo.Context) bool {
+	if format := c.QueryParam("format"); format != "" {
+		return format == formatYAML
+	}
+
+	for _, part := range strings.Split(c.Request().Header.Get(echo.HeaderAccept), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		if yamlMediaTypes[mediaType] {
+			return true
+		}
+		if mediaType == echo.MIMEApplicationJSON {
+			return false
+		}
+	}
+	return false
+}
+
+// isYAMLRequest reports whether the request body is YAML, either because of
+// ?format=yaml or because of its Content-Type.
+func isYAMLRequest(c echo.Context) bool {
+	if c.QueryParam("format") == formatYAML {
+		return true
+	}
+
+	mediaType, _, err := mime.ParseMediaType(c.Request().Header.Get(echo.HeaderContentType))
+	return err == nil && yamlMediaTypes[mediaType]
+}
+
+// respond writes v as YAML or JSON depending on what the client asked for.
+// YAML output has its map keys sorted so that it diffs cleanly.
+func respond(c echo.Context, code int, v any) error {
+	if !wantsYAML(c) {
+		return c.JSON(code, v)
+	}
+
+	data, err := yaml.Marshal(v)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.Blob(code, mimeYAML+"; charset=UTF-8", data)
+}
+
+// readBody reads the request body, failing with 413 when it is longer than
+// limit. A truncated YAML document usually still parses, so it must not be
+// decoded with its tail missing.
+func readBody(c echo.Context, limit int64) ([]byte, error) {
+	body, err := io.ReadAll(http.MaxBytesReader(c.Response(), c.Request().Body, limit))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return nil, echo.NewHTTPError(http.StatusRequestEntityTooLarge, fmt.Sprintf("request body exceeds %d bytes", limit))
+		}
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "failed to read request body")
+	}
+	return body, nil
+}
+
+// bindYAMLValues decodes a YAML values document from the request body.
+// Comments are dropped; an empty document yields empty values.
+func bindYAMLValues(c echo.Context) (map[string]any, error) {
+	body, err := readBody(c, maxYAMLBody)
+	if err != nil {
+		return nil, err
+	}
+
+	values := map[string]any{}
+	if err := yaml.Unmarshal(body, &values); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "invalid YAML values: "+err.Error())
+	}
+	if values == nil {
+		values = map[string]any{}
+	}
+	return values, nil
+}
@@ -0,0 +1,182 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func newTestContext(method, target, body string, headers map[string]string) (echo.Context, *httptest.ResponseRecorder) {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	return echo.New().NewContext(req, rec), rec
+}
+
+func TestWantsYAML(t *testing.T) {
+	testCases := []struct {
+		name   string
+		target string
+		accept string
+		want   bool
+	}{
+		{name: "default", target: "/values", want: false},
+		{name: "format query", target: "/values?format=yaml", want: true},
+		{name: "format query overrides accept", target: "/values?format=json", accept: "application/yaml", want: false},
+		{name: "accept yaml", target: "/values", accept: "application/yaml", want: true},
+		{name: "accept x-yaml", target: "/values", accept: "application/x-yaml", want: true},
+		{name: "accept text yaml with params", target: "/values", accept: "text/yaml; charset=utf-8", want: true},
+		{name: "json preferred", target: "/values", accept: "application/json, application/yaml", want: false},
+		{name: "yaml preferred", target: "/values", accept: "application/yaml, application/json", want: true},
+		{name: "any", target: "/values", accept: "*/*", want: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, _ := newTestContext(http.MethodGet, tc.target, "", map[string]string{echo.HeaderAccept: tc.accept})
+			if got := wantsYAML(c); got != tc.want {
+				t.Errorf("expected %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestIsYAMLRequest(t *testing.T) {
+	testCases := []struct {
+		name        string
+		target      string
+		contentType string
+		want        bool
+	}{
+		{name: "json", target: "/values", contentType: "application/json", want: false},
+		{name: "yaml", target: "/values", contentType: "application/yaml", want: true},
+		{name: "yaml with charset", target: "/values", contentType: "application/yaml; charset=utf-8", want: true},
+		{name: "format query", target: "/values?format=yaml", contentType: "text/plain", want: true},
+		{name: "missing", target: "/values", want: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, _ := newTestContext(http.MethodPut, tc.target, "", map[string]string{echo.HeaderContentType: tc.contentType})
+			if got := isYAMLRequest(c); got != tc.want {
+				t.Errorf("expected %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestRespondYAMLSortsKeys(t *testing.T) {
+	values := map[string]any{
+		"service":      map[string]any{"type": "ClusterIP", "port": 80},
+		"image":        map[string]any{"tag": "1.25", "repository": "nginx"},
+		"replicaCount": 2,
+	}
+	want := `image:
+  repository: nginx
+  tag: "1.25"
+replicaCount: 2
+service:
+  port: 80
+  type: ClusterIP
+`
+
+	for i := 0; i < 5; i++ {
+		c, rec := newTestContext(http.MethodGet, "/values?format=yaml", "", nil)
+		if err := respond(c, http.StatusOK, values); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if ct := rec.Header().Get(echo.HeaderContentType); !strings.HasPrefix(ct, mimeYAML) {
+			t.Errorf("expected YAML content type, got %q", ct)
+		}
+		if rec.Body.String() != want {
+			t.Fatalf("expected\n%s\ngot\n%s", want, rec.Body.String())
+		}
+	}
+}
+
+func TestRespondJSON(t *testing.T) {
+	c, rec := newTestContext(http.MethodGet, "/values", "", nil)
+	if err := respond(c, http.StatusOK, map[string]any{"a": 1}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ct := rec.Header().Get(echo.HeaderContentType); !strings.HasPrefix(ct, echo.MIMEApplicationJSON) {
+		t.Errorf("expected JSON content type, got %q", ct)
+	}
+	if strings.TrimSpace(rec.Body.String()) != `{"a":1}` {
+		t.Errorf("unexpected body %q", rec.Body.String())
+	}
+}
+
+func TestReadBodyTooLarge(t *testing.T) {
+	c, _ := newTestContext(http.MethodPut, "/values", strings.Repeat("a", 11), nil)
+
+	_, err := readBody(c, 10)
+	var httpErr *echo.HTTPError
+	if !errors.As(err, &httpErr) || httpErr.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected 413, got %v", err)
+	}
+
+	c, _ = newTestContext(http.MethodPut, "/values", strings.Repeat("a", 10), nil)
+	if body, err := readBody(c, 10); err != nil || len(body) != 10 {
+		t.Errorf("expected a body at the limit to be read, got %d bytes and %v", len(body), err)
+	}
+}
+
+func TestBindYAMLValues(t *testing.T) {
+	testCases := []struct {
+		name    string
+		body    string
+		want    map[string]any
+		wantErr bool
+	}{
+		{
+			name: "values file with comments",
+			body: `# number of pods
+replicaCount: 3
+image:
+  tag: "1.26" # pinned
+  pullPolicy: ~
+args:
+  - --verbose
+`,
+			want: map[string]any{
+				"replicaCount": float64(3),
+				"image":        map[string]any{"tag": "1.26", "pullPolicy": nil},
+				"args":         []any{"--verbose"},
+			},
+		},
+		{name: "empty document", body: "", want: map[string]any{}},
+		{name: "null document", body: "null\n", want: map[string]any{}},
+		{name: "list document", body: "- a\n- b\n", wantErr: true},
+		{name: "malformed", body: "image: [unterminated\n", wantErr: true},
+		// Truncated at the limit it would still be a valid document
+		{name: "too large", body: "a: 1\n" + strings.Repeat("# padding\n", maxYAMLBody/10+1), wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, _ := newTestContext(http.MethodPut, "/values", tc.body, map[string]string{echo.HeaderContentType: mimeYAML})
+
+			got, err := bindYAMLValues(c)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("expected %#v, got %#v", tc.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/helm-version-manager/api/internal/lock"
+	"github.com/helm-version-manager/api/internal/model"
+	"github.com/helm-version-manager/api/internal/operation"
+	"github.com/labstack/echo/v4"
+)
+
+// sseHeartbeat is how often a comment is sent on an idle event stream so
+// proxies do not close it
+var sseHeartbeat = 15 * time.Second
+
+type OperationHandler struct {
+	operations *operation.Manager
+}
+
+func NewOperationHandler(operations *operation.Manager) *OperationHandler {
+	return &OperationHandler{
+		operations: operations,
+	}
+}
+
+func (h *OperationHandler) Get(c echo.Context) error {
+	id := c.Param("id")
+
+	op, err := h.operations.Get(c.Request().Context(), id)
+	if err != nil {
+		return operationError(err)
+	}
+
+	return respond(c, http.StatusOK, op)
+}
+
+// Events streams the events of an operation as server-sent messages, starting
+// with the ones that already happened. Each message's id is the event's
+// sequence number, so a reconnecting client that sends Last-Event-ID only
+// receives the events it missed. The stream ends after the done or failed
+// event; clients should stop reconnecting once they see either.
+func (h *OperationHandler) Events(c echo.Context) error {
+	id := c.Param("id")
+
+	op, events, unsubscribe, err := h.operations.Subscribe(c.Request().Context(), id)
+	if err != nil {
+		return operationError(err)
+	}
+	defer func() { unsubscribe() }()
+
+	last := 0
+	if lastID := c.Request().Header.Get("Last-Event-ID"); lastID != "" {
+		n, err := strconv.Atoi(lastID)
+		if err != nil || n < 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "Last-Event-ID must be an event sequence number")
+		}
+		last = n
+	}
+
+	w := c.Response()
+	w.Header().Set(echo.HeaderContentType, "text/event-stream")
+	w.Header().Set(echo.HeaderCacheControl, "no-cache")
+	w.Header().Set(echo.HeaderConnection, "keep-alive")
+	// Keep nginx based ingresses from buffering the stream
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	send := func(events []model.OperationEvent) error {
+		for _, e := range events {
+			if e.Sequence <= last {
+				continue
+			}
+			if err := writeEvent(w, e); err != nil {
+				return err
+			}
+			last = e.Sequence
+		}
+		w.Flush()
+		return nil
+	}
+
+	if err := send(op.Events); err != nil {
+		return nil
+	}
+
+	heartbeat := time.NewTicker(sseHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request().Context().Done():
+			return nil
+		case e, ok := <-events:
+			if !ok {
+				// The operation completed or this client fell behind;
+				// either way the events it missed are in the operation,
+				// and subscribing again follows it if it is still running
+				op, events, unsubscribe, err = h.operations.Subscribe(c.Request().Context(), id)
+				if err != nil {
+					return nil
+				}
+				if err := send(op.Events); err != nil || op.CompletedAt != nil {
+					return nil
+				}
+				continue
+			}
+			if err := send([]model.OperationEvent{e}); err != nil {
+				return nil
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return nil
+			}
+			w.Flush()
+		}
+	}
+}
+
+func writeEvent(w *echo.Response, e model.OperationEvent) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\ndata: %s\n\n", e.Sequence, data)
+	return err
+}
+
+// operationError reports an operation that could not be looked up, with 404
+// when it does not exist
+func operationError(err error) error {
+	if errors.Is(err, operation.ErrNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, "operation not found")
+	}
+	return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+}
+
+// accepted reports an operation that was started as 202 with its location
+func accepted(c echo.Context, op model.Operation) error {
+	c.Response().Header().Set(echo.HeaderLocation, "/api/operations/"+op.ID)
+	return respond(c, http.StatusAccepted, op)
+}
+
+// startError reports an operation that could not be started, with 409 and
+// the holder's details when another operation has the release locked
+func startError(err error) error {
+	var locked *lock.LockedError
+	if errors.As(err, &locked) {
+		return echo.NewHTTPError(http.StatusConflict, model.LockConflictResponse{
+			Message: locked.Error(),
+			Holder:  locked.Holder,
+		})
+	}
+	return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+}
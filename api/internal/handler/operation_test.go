@@ -0,0 +1,254 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/helm-version-manager/api/internal/lock"
+	"github.com/helm-version-manager/api/internal/model"
+	"github.com/helm-version-manager/api/internal/operation"
+	"github.com/labstack/echo/v4"
+)
+
+// completedOperation starts an operation reporting the given phases and
+// waits for it to complete
+func completedOperation(t *testing.T, m *operation.Manager, phases ...string) model.Operation {
+	t.Helper()
+	op, err := m.Start(context.Background(), model.OperationUpgrade, "apps", "web", func(ctx context.Context, progress model.ProgressFunc) (*model.Release, error) {
+		for _, phase := range phases {
+			progress.Report(phase, "%s started", phase)
+		}
+		return &model.Release{Name: "web", Namespace: "apps", Revision: 2}, nil
+	})
+	if err != nil {
+		t.Fatalf("failed to start operation: %v", err)
+	}
+
+	_, events, unsubscribe, _ := m.Subscribe(context.Background(), op.ID)
+	defer unsubscribe()
+	select {
+	case <-drain(events):
+	case <-time.After(5 * time.Second):
+		t.Fatal("operation did not complete")
+	}
+	op, _ = m.Get(context.Background(), op.ID)
+	return op
+}
+
+func drain(events <-chan model.OperationEvent) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		for range events {
+		}
+		close(done)
+	}()
+	return done
+}
+
+// parseEvents returns the id and decoded data of each message in an event
+// stream
+func parseEvents(t *testing.T, body string) ([]string, []model.OperationEvent) {
+	t.Helper()
+	var ids []string
+	var events []model.OperationEvent
+	for _, message := range strings.Split(strings.TrimSpace(body), "\n\n") {
+		for _, line := range strings.Split(message, "\n") {
+			switch {
+			case strings.HasPrefix(line, "id: "):
+				ids = append(ids, strings.TrimPrefix(line, "id: "))
+			case strings.HasPrefix(line, "data: "):
+				var e model.OperationEvent
+				if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &e); err != nil {
+					t.Fatalf("invalid event data %q: %v", line, err)
+				}
+				events = append(events, e)
+			}
+		}
+	}
+	return ids, events
+}
+
+func TestOperationHandlerGet(t *testing.T) {
+	m := operation.NewManager(operation.DefaultRetention, nil, nil)
+	h := NewOperationHandler(m)
+	op := completedOperation(t, m, model.PhaseApply)
+
+	c, rec := newTestContext(http.MethodGet, "/api/operations/"+op.ID, "", nil)
+	c.SetParamNames("id")
+	c.SetParamValues(op.ID)
+	if err := h.Get(c); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var got model.Operation
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	if got.Status != model.OperationSucceeded || got.Result == nil || got.Result.Revision != 2 {
+		t.Errorf("unexpected operation: %+v", got)
+	}
+
+	c, _ = newTestContext(http.MethodGet, "/api/operations/missing", "", nil)
+	c.SetParamNames("id")
+	c.SetParamValues("missing")
+	if err := h.Get(c); err == nil || !strings.Contains(err.Error(), "operation not found") {
+		t.Errorf("expected not found error, got %v", err)
+	}
+}
+
+func TestOperationHandlerEvents(t *testing.T) {
+	m := operation.NewManager(operation.DefaultRetention, nil, nil)
+	h := NewOperationHandler(m)
+	op := completedOperation(t, m, model.PhasePullChart, model.PhaseApply, model.PhaseWait)
+
+	testCases := []struct {
+		name        string
+		lastEventID string
+		wantIDs     []string
+		wantPhases  []string
+		wantErr     bool
+	}{
+		{
+			name:       "all events",
+			wantIDs:    []string{"1", "2", "3", "4"},
+			wantPhases: []string{model.PhasePullChart, model.PhaseApply, model.PhaseWait, model.PhaseDone},
+		},
+		{
+			name:        "resumed",
+			lastEventID: "2",
+			wantIDs:     []string{"3", "4"},
+			wantPhases:  []string{model.PhaseWait, model.PhaseDone},
+		},
+		{
+			name:        "invalid last event id",
+			lastEventID: "abc",
+			wantErr:     true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			headers := map[string]string{}
+			if tc.lastEventID != "" {
+				headers["Last-Event-ID"] = tc.lastEventID
+			}
+			c, rec := newTestContext(http.MethodGet, "/api/operations/"+op.ID+"/events", "", headers)
+			c.SetParamNames("id")
+			c.SetParamValues(op.ID)
+
+			err := h.Events(c)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != "text/event-stream" {
+				t.Errorf("expected event stream content type, got %q", ct)
+			}
+
+			ids, events := parseEvents(t, rec.Body.String())
+			if strings.Join(ids, ",") != strings.Join(tc.wantIDs, ",") {
+				t.Errorf("expected ids %v, got %v", tc.wantIDs, ids)
+			}
+			var phases []string
+			for _, e := range events {
+				phases = append(phases, e.Phase)
+			}
+			if strings.Join(phases, ",") != strings.Join(tc.wantPhases, ",") {
+				t.Errorf("expected phases %v, got %v", tc.wantPhases, phases)
+			}
+		})
+	}
+}
+
+func TestOperationHandlerEventsStreamsRunningOperation(t *testing.T) {
+	m := operation.NewManager(operation.DefaultRetention, nil, nil)
+	h := NewOperationHandler(m)
+
+	proceed := make(chan struct{})
+	op, err := m.Start(context.Background(), model.OperationRollback, "apps", "web", func(ctx context.Context, progress model.ProgressFunc) (*model.Release, error) {
+		<-proceed
+		progress.Report(model.PhaseApply, "Applying 2 resources")
+		return &model.Release{}, nil
+	})
+	if err != nil {
+		t.Fatalf("failed to start operation: %v", err)
+	}
+
+	c, rec := newTestContext(http.MethodGet, "/api/operations/"+op.ID+"/events", "", nil)
+	c.SetParamNames("id")
+	c.SetParamValues(op.ID)
+
+	done := make(chan error, 1)
+	go func() { done <- h.Events(c) }()
+	close(proceed)
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("event stream did not end after the operation completed")
+	}
+
+	_, events := parseEvents(t, rec.Body.String())
+	if len(events) != 2 || events[0].Phase != model.PhaseApply || events[1].Phase != model.PhaseDone {
+		t.Errorf("expected apply and done events, got %+v", events)
+	}
+}
+
+func TestStartError(t *testing.T) {
+	holder := model.LockHolder{
+		Namespace:   "apps",
+		Name:        "web",
+		OperationID: "op-1",
+		Operation:   model.OperationUpgrade,
+		Replica:     "helm-ui-0",
+		AcquiredAt:  time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+
+	testCases := []struct {
+		name       string
+		err        error
+		wantStatus int
+	}{
+		{name: "locked", err: fmt.Errorf("failed to lock: %w", &lock.LockedError{Holder: holder}), wantStatus: http.StatusConflict},
+		{name: "other", err: errors.New("failed to get lease: forbidden"), wantStatus: http.StatusInternalServerError},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var httpErr *echo.HTTPError
+			if !errors.As(startError(tc.err), &httpErr) {
+				t.Fatalf("expected HTTP error, got %v", tc.err)
+			}
+			if httpErr.Code != tc.wantStatus {
+				t.Errorf("expected status %d, got %d", tc.wantStatus, httpErr.Code)
+			}
+			if tc.wantStatus != http.StatusConflict {
+				return
+			}
+			body, ok := httpErr.Message.(model.LockConflictResponse)
+			if !ok {
+				t.Fatalf("expected lock conflict response, got %T", httpErr.Message)
+			}
+			if body.Holder != holder {
+				t.Errorf("expected holder %+v, got %+v", holder, body.Holder)
+			}
+			if !strings.Contains(body.Message, "locked by upgrade operation op-1") {
+				t.Errorf("unexpected message %q", body.Message)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/helm-version-manager/api/internal/model"
+	"github.com/helm-version-manager/api/internal/storage"
+	"github.com/labstack/echo/v4"
+)
+
+// RegistryRuleStore stores registry rules and explains how the registry of
+// a release is resolved
+type RegistryRuleStore interface {
+	GetRule(ctx context.Context, id string) (*model.RegistryRule, error)
+	SetRule(ctx context.Context, rule model.RegistryRule) error
+	DeleteRule(ctx context.Context, id string) error
+	ListRules(ctx context.Context) ([]model.RegistryRule, error)
+	ResolveMapping(ctx context.Context, namespace, releaseName string) (*model.RegistryResolution, error)
+}
+
+type RegistryHandler struct {
+	store RegistryRuleStore
+}
+
+func NewRegistryHandler(store RegistryRuleStore) *RegistryHandler {
+	return &RegistryHandler{
+		store: store,
+	}
+}
+
+// ListRules returns the registry rules in the order they are tried
+func (h *RegistryHandler) ListRules(c echo.Context) error {
+	rules, err := h.store.ListRules(c.Request().Context())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, rules)
+}
+
+func (h *RegistryHandler) GetRule(c echo.Context) error {
+	id := c.Param("id")
+
+	rule, err := h.store.GetRule(c.Request().Context(), id)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	if rule == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "registry rule not found")
+	}
+
+	return c.JSON(http.StatusOK, rule)
+}
+
+// CreateRule adds a rule, failing with 409 if a rule with its ID exists
+func (h *RegistryHandler) CreateRule(c echo.Context) error {
+	var rule model.RegistryRule
+	if err := c.Bind(&rule); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
+	}
+	if err := storage.ValidateRule(rule); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	ctx := c.Request().Context()
+	existing, err := h.store.GetRule(ctx, rule.ID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	if existing != nil {
+		return echo.NewHTTPError(http.StatusConflict, "registry rule "+rule.ID+" already exists")
+	}
+
+	if err := h.store.SetRule(ctx, rule); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	c.Response().Header().Set(echo.HeaderLocation, "/api/registry-rules/"+rule.ID)
+	return c.JSON(http.StatusCreated, rule)
+}
+
+// UpdateRule creates or replaces the rule named in the path
+func (h *RegistryHandler) UpdateRule(c echo.Context) error {
+	id := c.Param("id")
+
+	var rule model.RegistryRule
+	if err := c.Bind(&rule); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
+	}
+	if rule.ID != "" && rule.ID != id {
+		return echo.NewHTTPError(http.StatusBadRequest, "rule id does not match the path")
+	}
+	rule.ID = id
+	if err := storage.ValidateRule(rule); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if err := h.store.SetRule(c.Request().Context(), rule); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, rule)
+}
+
+func (h *RegistryHandler) DeleteRule(c echo.Context) error {
+	id := c.Param("id")
+
+	if err := h.store.DeleteRule(c.Request().Context(), id); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// Resolve explains which explicit mapping, rule or discovered source
+// provides the registry of a release
+func (h *RegistryHandler) Resolve(c echo.Context) error {
+	namespace := c.Param("namespace")
+	name := c.Param("name")
+
+	resolution, err := h.store.ResolveMapping(c.Request().Context(), namespace, name)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, resolution)
+}
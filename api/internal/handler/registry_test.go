@@ -0,0 +1,143 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/helm-version-manager/api/internal/model"
+	"github.com/labstack/echo/v4"
+)
+
+type fakeRuleStore struct {
+	rules map[string]model.RegistryRule
+}
+
+func (s *fakeRuleStore) GetRule(ctx context.Context, id string) (*model.RegistryRule, error) {
+	if rule, ok := s.rules[id]; ok {
+		return &rule, nil
+	}
+	return nil, nil
+}
+
+func (s *fakeRuleStore) SetRule(ctx context.Context, rule model.RegistryRule) error {
+	s.rules[rule.ID] = rule
+	return nil
+}
+
+func (s *fakeRuleStore) DeleteRule(ctx context.Context, id string) error {
+	delete(s.rules, id)
+	return nil
+}
+
+func (s *fakeRuleStore) ListRules(ctx context.Context) ([]model.RegistryRule, error) {
+	rules := make([]model.RegistryRule, 0, len(s.rules))
+	for _, rule := range s.rules {
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+func (s *fakeRuleStore) ResolveMapping(ctx context.Context, namespace, releaseName string) (*model.RegistryResolution, error) {
+	return &model.RegistryResolution{Namespace: namespace, ReleaseName: releaseName, Message: "No registry found"}, nil
+}
+
+func httpStatus(err error) int {
+	var httpErr *echo.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.Code
+	}
+	return 0
+}
+
+func TestRegistryHandlerCreateRule(t *testing.T) {
+	store := &fakeRuleStore{rules: map[string]model.RegistryRule{
+		"existing": {ID: "existing", Registry: "oci://ghcr.io/ourorg/charts"},
+	}}
+	h := NewRegistryHandler(store)
+
+	testCases := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{name: "created", body: `{"id":"ourorg","chart":"ourorg-*","priority":10,"registry":"oci://ghcr.io/ourorg/charts"}`, wantStatus: http.StatusCreated},
+		{name: "exists", body: `{"id":"existing","registry":"oci://ghcr.io/ourorg/other"}`, wantStatus: http.StatusConflict},
+		{name: "invalid glob", body: `{"id":"glob","chart":"[","registry":"oci://ghcr.io/ourorg/charts"}`, wantStatus: http.StatusBadRequest},
+		{name: "missing registry", body: `{"id":"empty"}`, wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, rec := newTestContext(http.MethodPost, "/api/registry-rules", tc.body, map[string]string{echo.HeaderContentType: echo.MIMEApplicationJSON})
+			err := h.CreateRule(c)
+			if tc.wantStatus != http.StatusCreated {
+				if got := httpStatus(err); got != tc.wantStatus {
+					t.Errorf("expected status %d, got %v", tc.wantStatus, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if rec.Code != http.StatusCreated || rec.Header().Get(echo.HeaderLocation) != "/api/registry-rules/ourorg" {
+				t.Errorf("expected 201 with location, got %d %q", rec.Code, rec.Header().Get(echo.HeaderLocation))
+			}
+		})
+	}
+
+	if store.rules["existing"].Registry != "oci://ghcr.io/ourorg/charts" {
+		t.Errorf("expected the existing rule to be kept, got %+v", store.rules["existing"])
+	}
+	if _, ok := store.rules["ourorg"]; !ok {
+		t.Error("expected the new rule to be stored")
+	}
+}
+
+func TestRegistryHandlerUpdateRule(t *testing.T) {
+	store := &fakeRuleStore{rules: map[string]model.RegistryRule{}}
+	h := NewRegistryHandler(store)
+
+	update := func(id, body string) (*model.RegistryRule, error) {
+		c, rec := newTestContext(http.MethodPut, "/api/registry-rules/"+id, body, map[string]string{echo.HeaderContentType: echo.MIMEApplicationJSON})
+		c.SetParamNames("id")
+		c.SetParamValues(id)
+		if err := h.UpdateRule(c); err != nil {
+			return nil, err
+		}
+		var rule model.RegistryRule
+		if err := json.Unmarshal(rec.Body.Bytes(), &rule); err != nil {
+			t.Fatalf("invalid response: %v", err)
+		}
+		return &rule, nil
+	}
+
+	// The ID is taken from the path
+	rule, err := update("ourorg", `{"namespaceSelector":"team=payments","registry":"oci://ghcr.io/ourorg/charts"}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rule.ID != "ourorg" || store.rules["ourorg"] != *rule {
+		t.Errorf("expected rule to be stored under ourorg, got %+v", store.rules)
+	}
+
+	if _, err := update("ourorg", `{"id":"other","registry":"oci://ghcr.io/ourorg/charts"}`); httpStatus(err) != http.StatusBadRequest {
+		t.Errorf("expected 400 for mismatched id, got %v", err)
+	}
+	if _, err := update("ourorg", `{"namespaceSelector":"team in","registry":"oci://ghcr.io/ourorg/charts"}`); httpStatus(err) != http.StatusBadRequest {
+		t.Errorf("expected 400 for invalid selector, got %v", err)
+	}
+}
+
+func TestRegistryHandlerGetRule(t *testing.T) {
+	h := NewRegistryHandler(&fakeRuleStore{rules: map[string]model.RegistryRule{}})
+
+	c, _ := newTestContext(http.MethodGet, "/api/registry-rules/missing", "", nil)
+	c.SetParamNames("id")
+	c.SetParamValues("missing")
+	if err := h.GetRule(c); httpStatus(err) != http.StatusNotFound {
+		t.Errorf("expected 404, got %v", err)
+	}
+}
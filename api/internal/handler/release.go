@@ -1,23 +1,33 @@
 package handler
 
 import (
+	"context"
+	"errors"
 	"net/http"
+	"sort"
+	"strconv"
+	"time"
 
+	"github.com/Masterminds/semver/v3"
 	"github.com/helm-version-manager/api/internal/helm"
 	"github.com/helm-version-manager/api/internal/model"
+	"github.com/helm-version-manager/api/internal/operation"
 	"github.com/helm-version-manager/api/internal/storage"
 	"github.com/labstack/echo/v4"
+	"sigs.k8s.io/yaml"
 )
 
 type ReleaseHandler struct {
 	helmClient    *helm.Client
-	registryStore *storage.RegistryStore
+	registryStore storage.MappingStore
+	operations    *operation.Manager
 }
 
-func NewReleaseHandler(client *helm.Client, store *storage.RegistryStore) *ReleaseHandler {
+func NewReleaseHandler(client *helm.Client, store storage.MappingStore, operations *operation.Manager) *ReleaseHandler {
 	return &ReleaseHandler{
 		helmClient:    client,
 		registryStore: store,
+		operations:    operations,
 	}
 }
 
@@ -83,14 +93,112 @@ func (h *ReleaseHandler) Get(c echo.Context) error {
 	if err != nil {
 		return echo.NewHTTPError(http.StatusNotFound, err.Error())
 	}
+
+	setETag(c, release.Revision)
 	return c.JSON(http.StatusOK, release)
 }
 
+func (h *ReleaseHandler) Install(c echo.Context) error {
+	namespace := c.Param("namespace")
+
+	var req model.InstallRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
+	}
+
+	if req.Name == "" || req.Chart == "" || req.Registry == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "name, chart and registry are required")
+	}
+
+	req.DeployedBy = deployedBy(c)
+
+	unlock, err := h.operations.Lock(c.Request().Context(), model.OperationInstall, namespace, req.Name)
+	if err != nil {
+		return startError(err)
+	}
+	defer unlock()
+
+	release, err := h.helmClient.InstallRelease(namespace, req)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusCreated, release)
+}
+
+func (h *ReleaseHandler) Uninstall(c echo.Context) error {
+	namespace := c.Param("namespace")
+	name := c.Param("name")
+
+	req := model.UninstallRequest{
+		Timeout: c.QueryParam("timeout"),
+	}
+
+	if keepHistory := c.QueryParam("keepHistory"); keepHistory != "" {
+		b, err := strconv.ParseBool(keepHistory)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "keepHistory must be true or false")
+		}
+		req.KeepHistory = b
+	}
+
+	if wait := c.QueryParam("wait"); wait != "" {
+		b, err := strconv.ParseBool(wait)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "wait must be true or false")
+		}
+		req.Wait = b
+	}
+
+	if req.Timeout != "" {
+		if d, err := time.ParseDuration(req.Timeout); err != nil || d <= 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "timeout must be a positive duration such as 5m")
+		}
+	}
+
+	unlock, err := h.operations.Lock(c.Request().Context(), model.OperationUninstall, namespace, name)
+	if err != nil {
+		return startError(err)
+	}
+	defer unlock()
+
+	result, err := h.helmClient.UninstallRelease(namespace, name, req)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, result)
+}
+
 func (h *ReleaseHandler) GetVersions(c echo.Context) error {
 	namespace := c.Param("namespace")
 	name := c.Param("name")
 
-	versions, err := h.helmClient.GetAvailableVersions(namespace, name)
+	query := model.VersionQuery{
+		Constraint: c.QueryParam("constraint"),
+	}
+
+	if limit := c.QueryParam("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n <= 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "limit must be a positive integer")
+		}
+		query.Limit = n
+	}
+
+	if includePrerelease := c.QueryParam("includePrerelease"); includePrerelease != "" {
+		b, err := strconv.ParseBool(includePrerelease)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "includePrerelease must be true or false")
+		}
+		query.IncludePrerelease = b
+	}
+
+	if query.Constraint != "" {
+		if _, err := semver.NewConstraint(query.Constraint); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid constraint: "+err.Error())
+		}
+	}
+
+	versions, err := h.helmClient.GetAvailableVersions(namespace, name, query)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
 	}
@@ -110,22 +218,228 @@ func (h *ReleaseHandler) Upgrade(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, "chartVersion is required")
 	}
 
-	release, err := h.helmClient.UpgradeRelease(namespace, name, req)
+	if err := validateUpgradeOptions(req.UpgradeOptions); err != nil {
+		return err
+	}
+	if req.Test != nil {
+		if err := validateTestRequest(*req.Test); err != nil {
+			return err
+		}
+	}
+
+	expected, err := expectedRevision(c, req.ExpectedRevision)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		return err
 	}
-	return c.JSON(http.StatusOK, release)
+	if err := h.checkRevision(namespace, name, expected); err != nil {
+		return err
+	}
+	req.ExpectedRevision = expected
+	req.DeployedBy = deployedBy(c)
+
+	op, err := h.operations.Start(c.Request().Context(), model.OperationUpgrade, namespace, name, func(ctx context.Context, progress model.ProgressFunc) (*model.Release, error) {
+		return h.helmClient.UpgradeRelease(ctx, namespace, name, req, progress)
+	})
+	if err != nil {
+		return startError(err)
+	}
+	return accepted(c, op)
+}
+
+func (h *ReleaseHandler) PreviewUpgrade(c echo.Context) error {
+	namespace := c.Param("namespace")
+	name := c.Param("name")
+
+	var req model.VersionUpgradeRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
+	}
+
+	if req.ChartVersion == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "chartVersion is required")
+	}
+
+	preview, err := h.helmClient.PreviewUpgrade(namespace, name, req)
+	if err != nil {
+		return valuesError(err)
+	}
+	return c.JSON(http.StatusOK, preview)
 }
 
 func (h *ReleaseHandler) GetHistory(c echo.Context) error {
 	namespace := c.Param("namespace")
 	name := c.Param("name")
 
-	history, err := h.helmClient.GetReleaseHistory(namespace, name)
+	var query model.HistoryQuery
+
+	if max := c.QueryParam("max"); max != "" {
+		n, err := strconv.Atoi(max)
+		if err != nil || n <= 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "max must be a positive integer")
+		}
+		query.Max = n
+	}
+
+	if before := c.QueryParam("before"); before != "" {
+		n, err := strconv.Atoi(before)
+		if err != nil || n <= 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "before must be a positive integer")
+		}
+		query.Before = n
+	}
+
+	page, err := h.helmClient.GetReleaseHistory(namespace, name, query)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	// nextBefore is the cursor for the next page, omitted on the last one
+	return c.JSON(http.StatusOK, page)
+}
+
+func (h *ReleaseHandler) GetRevisionValues(c echo.Context) error {
+	namespace := c.Param("namespace")
+	name := c.Param("name")
+
+	revision, err := strconv.Atoi(c.Param("revision"))
+	if err != nil || revision <= 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "revision must be a positive integer")
+	}
+
+	values, err := h.helmClient.GetRevisionValues(namespace, name, revision)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, err.Error())
+	}
+
+	return respond(c, http.StatusOK, values)
+}
+
+func (h *ReleaseHandler) Diff(c echo.Context) error {
+	namespace := c.Param("namespace")
+	name := c.Param("name")
+
+	from, err := strconv.Atoi(c.QueryParam("from"))
+	if err != nil || from <= 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "from must be a positive integer")
+	}
+
+	to, err := strconv.Atoi(c.QueryParam("to"))
+	if err != nil || to <= 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "to must be a positive integer")
+	}
+
+	diff, err := h.helmClient.DiffRevisions(namespace, name, from, to)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, diff)
+}
+
+func (h *ReleaseHandler) GetManifest(c echo.Context) error {
+	namespace := c.Param("namespace")
+	name := c.Param("name")
+
+	revision, err := revisionQuery(c)
+	if err != nil {
+		return err
+	}
+
+	manifest, err := h.helmClient.GetReleaseManifest(namespace, name, revision)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, err.Error())
+	}
+
+	// YAML clients get the manifest exactly as Helm applied it
+	if wantsYAML(c) {
+		return c.Blob(http.StatusOK, mimeYAML+"; charset=UTF-8", []byte(manifest.Manifest))
+	}
+	return c.JSON(http.StatusOK, manifest)
+}
+
+func (h *ReleaseHandler) GetNotes(c echo.Context) error {
+	namespace := c.Param("namespace")
+	name := c.Param("name")
+
+	revision, err := revisionQuery(c)
+	if err != nil {
+		return err
+	}
+
+	notes, err := h.helmClient.GetReleaseNotes(namespace, name, revision)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, notes)
+}
+
+func (h *ReleaseHandler) GetHooks(c echo.Context) error {
+	namespace := c.Param("namespace")
+	name := c.Param("name")
+
+	revision, err := revisionQuery(c)
+	if err != nil {
+		return err
+	}
+
+	hooks, err := h.helmClient.GetReleaseHooks(namespace, name, revision)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, hooks)
+}
+
+func (h *ReleaseHandler) GetAudit(c echo.Context) error {
+	namespace := c.Param("namespace")
+	name := c.Param("name")
+
+	records, err := h.helmClient.GetAuditRecords(namespace, name)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
 	}
-	return c.JSON(http.StatusOK, history)
+
+	return c.JSON(http.StatusOK, records)
+}
+
+func (h *ReleaseHandler) RunTests(c echo.Context) error {
+	namespace := c.Param("namespace")
+	name := c.Param("name")
+
+	var req model.TestRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := validateTestRequest(req); err != nil {
+		return err
+	}
+
+	// A missing release is reported now rather than as a failed operation
+	if _, err := h.helmClient.GetRelease(namespace, name); err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, err.Error())
+	}
+
+	op, err := h.operations.Start(c.Request().Context(), model.OperationTest, namespace, name, func(ctx context.Context, progress model.ProgressFunc) (*model.Release, error) {
+		return h.helmClient.RunReleaseTests(ctx, namespace, name, req, progress)
+	})
+	if err != nil {
+		return startError(err)
+	}
+	return accepted(c, op)
+}
+
+func (h *ReleaseHandler) GetResources(c echo.Context) error {
+	namespace := c.Param("namespace")
+	name := c.Param("name")
+
+	resources, err := h.helmClient.GetReleaseResources(c.Request().Context(), namespace, name)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, resources)
 }
 
 func (h *ReleaseHandler) GetRegistry(c echo.Context) error {
@@ -187,16 +501,126 @@ func (h *ReleaseHandler) DeleteRegistry(c echo.Context) error {
 	return c.NoContent(http.StatusNoContent)
 }
 
+// ExportRegistryMappings returns the explicit registry mappings as a
+// document ImportRegistryMappings accepts, in JSON or YAML
+func (h *ReleaseHandler) ExportRegistryMappings(c echo.Context) error {
+	mappings, err := h.registryStore.ListMappings(c.Request().Context())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	mappings = storage.ExplicitMappings(mappings)
+	sort.Slice(mappings, func(i, j int) bool {
+		if mappings[i].Namespace != mappings[j].Namespace {
+			return mappings[i].Namespace < mappings[j].Namespace
+		}
+		return mappings[i].ReleaseName < mappings[j].ReleaseName
+	})
+
+	return respond(c, http.StatusOK, model.RegistryMappings{Mappings: mappings})
+}
+
+// ImportRegistryMappings sets the mappings of an exported JSON or YAML
+// document. ?mode=replace also deletes the explicit mappings it lacks and
+// ?dryRun=true only reports what would change. The report is returned
+// with 422 when any entry is invalid, in which case nothing is written.
+func (h *ReleaseHandler) ImportRegistryMappings(c echo.Context) error {
+	opts := model.RegistryImportOptions{Mode: c.QueryParam("mode")}
+	if dryRun := c.QueryParam("dryRun"); dryRun != "" {
+		v, err := strconv.ParseBool(dryRun)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "dryRun must be true or false")
+		}
+		opts.DryRun = v
+	}
+	if opts.Mode != "" && opts.Mode != model.RegistryImportMerge && opts.Mode != model.RegistryImportReplace {
+		return echo.NewHTTPError(http.StatusBadRequest, "mode must be merge or replace")
+	}
+
+	// A truncated document would still parse, and in replace mode delete
+	// every mapping cut off its end
+	body, err := readBody(c, maxYAMLBody)
+	if err != nil {
+		return err
+	}
+	// YAML is a superset of JSON, so both are decoded the same way
+	var doc model.RegistryMappings
+	if err := yaml.Unmarshal(body, &doc); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid registry mappings: "+err.Error())
+	}
+
+	report, err := h.helmClient.ImportRegistryMappings(c.Request().Context(), doc.Mappings, opts)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	if report.Counts[model.RegistryImportInvalid] > 0 {
+		return respond(c, http.StatusUnprocessableEntity, report)
+	}
+	// Some entries were written and others not; the report tells which
+	if report.Counts[model.RegistryImportFailed] > 0 {
+		return respond(c, http.StatusInternalServerError, report)
+	}
+	return respond(c, http.StatusOK, report)
+}
+
 func (h *ReleaseHandler) GetValues(c echo.Context) error {
 	namespace := c.Param("namespace")
 	name := c.Param("name")
 
+	// The revision is read before the values so the ETag is never newer
+	// than them; a stale one only makes a later If-Match fail
+	release, err := h.helmClient.GetRelease(namespace, name)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, err.Error())
+	}
+
 	values, err := h.helmClient.GetReleaseValues(namespace, name)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusNotFound, err.Error())
 	}
 
-	return c.JSON(http.StatusOK, values)
+	setETag(c, release.Revision)
+	return respond(c, http.StatusOK, values)
+}
+
+func (h *ReleaseHandler) GetChartValues(c echo.Context) error {
+	namespace := c.Param("namespace")
+	name := c.Param("name")
+
+	values, err := h.helmClient.GetChartValues(namespace, name)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, err.Error())
+	}
+
+	return respond(c, http.StatusOK, values)
+}
+
+func (h *ReleaseHandler) GetComputedValues(c echo.Context) error {
+	namespace := c.Param("namespace")
+	name := c.Param("name")
+
+	values, err := h.helmClient.GetComputedValues(namespace, name)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, err.Error())
+	}
+
+	return respond(c, http.StatusOK, values)
+}
+
+func (h *ReleaseHandler) GetValuesSchema(c echo.Context) error {
+	namespace := c.Param("namespace")
+	name := c.Param("name")
+
+	schema, err := h.helmClient.GetValuesSchema(namespace, name)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, err.Error())
+	}
+	if schema == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "chart has no values schema")
+	}
+
+	return c.JSON(http.StatusOK, schema)
 }
 
 func (h *ReleaseHandler) UpdateValues(c echo.Context) error {
@@ -204,39 +628,188 @@ func (h *ReleaseHandler) UpdateValues(c echo.Context) error {
 	name := c.Param("name")
 
 	var req model.ValuesUpdateRequest
-	if err := c.Bind(&req); err != nil {
+	if isYAMLRequest(c) {
+		// A YAML body is a values file; the mode and verification come
+		// from the query
+		req.Mode = c.QueryParam("mode")
+		if req.Mode == model.ValuesModeJSONPatch {
+			return echo.NewHTTPError(http.StatusBadRequest, "json-patch mode requires a JSON body")
+		}
+		if verify := c.QueryParam("verify"); verify != "" {
+			v, err := strconv.ParseBool(verify)
+			if err != nil {
+				return echo.NewHTTPError(http.StatusBadRequest, "verify must be true or false")
+			}
+			req.Verify = &v
+		}
+		req.VerifyTimeout = c.QueryParam("verifyTimeout")
+		values, err := bindYAMLValues(c)
+		if err != nil {
+			return err
+		}
+		req.Values = values
+	} else if err := c.Bind(&req); err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
 	}
 
-	if req.Values == nil {
-		return echo.NewHTTPError(http.StatusBadRequest, "values is required")
+	if err := validateUpgradeOptions(req.UpgradeOptions); err != nil {
+		return err
 	}
 
-	release, err := h.helmClient.UpdateReleaseValues(namespace, name, req.Values)
+	switch req.Mode {
+	case "", model.ValuesModeMerge, model.ValuesModeReplace:
+		if req.Values == nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "values is required")
+		}
+	case model.ValuesModeJSONPatch:
+		if req.Patch == nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "patch is required in json-patch mode")
+		}
+	default:
+		return echo.NewHTTPError(http.StatusBadRequest, "mode must be one of merge, replace or json-patch")
+	}
+
+	expected, err := expectedRevision(c, req.ExpectedRevision)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		return err
 	}
+	if err := h.checkRevision(namespace, name, expected); err != nil {
+		return err
+	}
+	req.ExpectedRevision = expected
+	req.DeployedBy = deployedBy(c)
 
-	return c.JSON(http.StatusOK, release)
+	op, err := h.operations.Start(c.Request().Context(), model.OperationUpdateValues, namespace, name, func(ctx context.Context, progress model.ProgressFunc) (*model.Release, error) {
+		return h.helmClient.UpdateReleaseValues(ctx, namespace, name, req, progress)
+	})
+	if err != nil {
+		return startError(err)
+	}
+	return accepted(c, op)
 }
 
 func (h *ReleaseHandler) Rollback(c echo.Context) error {
 	namespace := c.Param("namespace")
 	name := c.Param("name")
 
-	var req model.RollbackRequest
-	if err := c.Bind(&req); err != nil {
+	// The upgrade-only options are bound so that they can be rejected
+	// instead of silently ignored
+	var body struct {
+		model.RollbackRequest
+		Atomic        *bool  `json:"atomic"`
+		Verify        *bool  `json:"verify"`
+		VerifyTimeout string `json:"verifyTimeout"`
+	}
+	if err := c.Bind(&body); err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, "Invalid request body")
 	}
+	req := body.RollbackRequest
 
 	if req.Revision <= 0 {
 		return echo.NewHTTPError(http.StatusBadRequest, "revision must be a positive integer")
 	}
 
-	release, err := h.helmClient.RollbackRelease(namespace, name, req.Revision)
+	if body.Atomic != nil || body.Verify != nil || body.VerifyTimeout != "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "atomic, verify and verifyTimeout only apply to upgrades")
+	}
+	if err := validateUpgradeOptions(model.UpgradeOptions{Timeout: req.Timeout, MaxHistory: req.MaxHistory}); err != nil {
+		return err
+	}
+
+	expected, err := expectedRevision(c, req.ExpectedRevision)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		return err
+	}
+	if err := h.checkRevision(namespace, name, expected); err != nil {
+		return err
 	}
+	req.ExpectedRevision = expected
+	req.DeployedBy = deployedBy(c)
 
-	return c.JSON(http.StatusOK, release)
+	op, err := h.operations.Start(c.Request().Context(), model.OperationRollback, namespace, name, func(ctx context.Context, progress model.ProgressFunc) (*model.Release, error) {
+		return h.helmClient.RollbackRelease(ctx, namespace, name, req, progress)
+	})
+	if err != nil {
+		return startError(err)
+	}
+	return accepted(c, op)
+}
+
+// revisionQuery parses the optional ?revision= parameter; zero means the
+// latest revision.
+func revisionQuery(c echo.Context) (int, error) {
+	param := c.QueryParam("revision")
+	if param == "" {
+		return 0, nil
+	}
+
+	revision, err := strconv.Atoi(param)
+	if err != nil || revision <= 0 {
+		return 0, echo.NewHTTPError(http.StatusBadRequest, "revision must be a positive integer")
+	}
+	return revision, nil
+}
+
+func validateUpgradeOptions(opts model.UpgradeOptions) error {
+	if opts.Timeout != "" {
+		if d, err := time.ParseDuration(opts.Timeout); err != nil || d <= 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "timeout must be a positive duration such as 5m")
+		}
+	}
+
+	if opts.VerifyTimeout != "" {
+		if d, err := time.ParseDuration(opts.VerifyTimeout); err != nil || d <= 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "verifyTimeout must be a positive duration such as 5m")
+		}
+	}
+
+	if opts.MaxHistory != nil && *opts.MaxHistory < 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "maxHistory must not be negative")
+	}
+
+	return nil
+}
+
+func validateTestRequest(req model.TestRequest) error {
+	if req.Timeout != "" {
+		if d, err := time.ParseDuration(req.Timeout); err != nil || d <= 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "test timeout must be a positive duration such as 5m")
+		}
+	}
+
+	for _, name := range req.Filter {
+		if name == "" || name == "!" {
+			return echo.NewHTTPError(http.StatusBadRequest, "test filter names must not be empty")
+		}
+	}
+
+	return nil
+}
+
+// deployedByHeaders are the headers an authenticating proxy in front of
+// helm-ui sets to the signed-in user, in order of preference
+var deployedByHeaders = []string{"X-Forwarded-User", "X-Forwarded-Email", "X-Remote-User"}
+
+// deployedBy returns who is making the request, as reported by an
+// authenticating proxy, or "" when there is no such proxy.
+func deployedBy(c echo.Context) string {
+	for _, header := range deployedByHeaders {
+		if user := c.Request().Header.Get(header); user != "" {
+			return user
+		}
+	}
+	return ""
+}
+
+// valuesError reports values rejected by the chart schema as 422 with the
+// offending fields, and any other failure as 500.
+func valuesError(err error) error {
+	var schemaErr *helm.SchemaValidationError
+	if errors.As(err, &schemaErr) {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, model.ValidationErrorResponse{
+			Message: "values do not match the chart schema",
+			Errors:  schemaErr.Errors,
+		})
+	}
+	return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
 }
@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/helm-version-manager/api/internal/lock"
+	"github.com/helm-version-manager/api/internal/model"
+	"github.com/helm-version-manager/api/internal/operation"
+	"github.com/labstack/echo/v4"
+)
+
+// lockedLocker reports every release as locked by holder
+type lockedLocker struct {
+	holder model.LockHolder
+}
+
+func (l *lockedLocker) Acquire(ctx context.Context, holder model.LockHolder, lost func(error)) (func(), error) {
+	return nil, &lock.LockedError{Holder: l.holder}
+}
+
+func TestRollbackRejectsUpgradeOptions(t *testing.T) {
+	h := &ReleaseHandler{}
+
+	for _, body := range []string{
+		`{"revision":2,"atomic":true}`,
+		`{"revision":2,"verify":true}`,
+		`{"revision":2,"verifyTimeout":"5m"}`,
+		`{"revision":2,"timeout":"never"}`,
+	} {
+		t.Run(body, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/releases/apps/web/rollback", strings.NewReader(body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			c := echo.New().NewContext(req, httptest.NewRecorder())
+			c.SetParamNames("namespace", "name")
+			c.SetParamValues("apps", "web")
+
+			err := h.Rollback(c)
+			if he, ok := err.(*echo.HTTPError); !ok || he.Code != http.StatusBadRequest {
+				t.Errorf("expected 400, got %v", err)
+			}
+		})
+	}
+}
+
+func TestLockedReleaseConflicts(t *testing.T) {
+	locker := &lockedLocker{holder: model.LockHolder{Namespace: "apps", Name: "web", OperationID: "op-1", Operation: model.OperationUpgrade}}
+	h := &ReleaseHandler{operations: operation.NewManager(operation.DefaultRetention, locker, nil)}
+
+	testCases := []struct {
+		name   string
+		method string
+		target string
+		body   string
+		call   func(c echo.Context) error
+	}{
+		{name: "install", method: http.MethodPost, target: "/api/releases/apps", body: `{"name":"web","chart":"web","registry":"oci://ghcr.io/example/charts"}`, call: h.Install},
+		{name: "uninstall", method: http.MethodDelete, target: "/api/releases/apps/web", call: h.Uninstall},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, _ := newTestContext(tc.method, tc.target, tc.body, map[string]string{echo.HeaderContentType: echo.MIMEApplicationJSON})
+			c.SetParamNames("namespace", "name")
+			c.SetParamValues("apps", "web")
+
+			err := tc.call(c)
+			if he, ok := err.(*echo.HTTPError); !ok || he.Code != http.StatusConflict {
+				t.Errorf("expected 409, got %v", err)
+			}
+		})
+	}
+}
+
+func TestDeployedBy(t *testing.T) {
+	testCases := []struct {
+		name    string
+		headers map[string]string
+		want    string
+	}{
+		{name: "no proxy", want: ""},
+		{name: "forwarded user", headers: map[string]string{"X-Forwarded-User": "alice", "X-Forwarded-Email": "alice@example.com"}, want: "alice"},
+		{name: "forwarded email", headers: map[string]string{"X-Forwarded-Email": "alice@example.com"}, want: "alice@example.com"},
+		{name: "remote user", headers: map[string]string{"X-Remote-User": "bob"}, want: "bob"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/releases/apps/web/rollback", nil)
+			for k, v := range tc.headers {
+				req.Header.Set(k, v)
+			}
+			c := echo.New().NewContext(req, httptest.NewRecorder())
+			if got := deployedBy(c); got != tc.want {
+				t.Errorf("expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/helm-version-manager/api/internal/helm"
+	"github.com/helm-version-manager/api/internal/model"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	headerETag    = "ETag"
+	headerIfMatch = "If-Match"
+)
+
+// setETag tags a response describing a release with its revision, so
+// clients can send it back in If-Match to only change the release if no one
+// else has in the meantime
+func setETag(c echo.Context, revision int) {
+	c.Response().Header().Set(headerETag, strconv.Quote(strconv.Itoa(revision)))
+}
+
+// expectedRevision returns the revision a mutation expects the release to be
+// at, taken from the If-Match header or the body's expectedRevision. Zero
+// means any revision.
+func expectedRevision(c echo.Context, body int) (int, error) {
+	if body < 0 {
+		return 0, echo.NewHTTPError(http.StatusBadRequest, "expectedRevision must be a positive integer")
+	}
+
+	header := strings.TrimSpace(c.Request().Header.Get(headerIfMatch))
+	if header == "" || header == "*" {
+		return body, nil
+	}
+
+	tag, err := strconv.Unquote(strings.TrimPrefix(header, "W/"))
+	revision, convErr := strconv.Atoi(tag)
+	if err != nil || convErr != nil || revision <= 0 {
+		return 0, echo.NewHTTPError(http.StatusBadRequest, "If-Match must be a single ETag returned for the release")
+	}
+	if body != 0 && body != revision {
+		return 0, echo.NewHTTPError(http.StatusBadRequest, "If-Match and expectedRevision name different revisions")
+	}
+	return revision, nil
+}
+
+// checkRevision rejects a mutation with 412 when the release is no longer at
+// the expected revision. The operation checks again once it holds the
+// release's lock, in case the release changes in between.
+func (h *ReleaseHandler) checkRevision(namespace, name string, expected int) error {
+	err := h.helmClient.CheckRevision(namespace, name, expected)
+	if err == nil {
+		return nil
+	}
+
+	var mismatch *helm.RevisionMismatchError
+	if errors.As(err, &mismatch) {
+		return revisionConflict(mismatch)
+	}
+	return echo.NewHTTPError(http.StatusNotFound, err.Error())
+}
+
+func revisionConflict(mismatch *helm.RevisionMismatchError) error {
+	return echo.NewHTTPError(http.StatusPreconditionFailed, model.RevisionConflictResponse{
+		Message:          mismatch.Error(),
+		ExpectedRevision: mismatch.Expected,
+		CurrentRevision:  mismatch.Current,
+	})
+}
@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/helm-version-manager/api/internal/helm"
+	"github.com/helm-version-manager/api/internal/model"
+	"github.com/labstack/echo/v4"
+)
+
+func TestExpectedRevision(t *testing.T) {
+	testCases := []struct {
+		name    string
+		ifMatch string
+		body    int
+		want    int
+		wantErr bool
+	}{
+		{name: "none", want: 0},
+		{name: "body", body: 3, want: 3},
+		{name: "if-match", ifMatch: `"3"`, want: 3},
+		{name: "weak if-match", ifMatch: `W/"3"`, want: 3},
+		{name: "if-match and matching body", ifMatch: `"3"`, body: 3, want: 3},
+		{name: "any", ifMatch: "*", body: 2, want: 2},
+		{name: "if-match and different body", ifMatch: `"3"`, body: 2, wantErr: true},
+		{name: "unquoted", ifMatch: "3", wantErr: true},
+		{name: "not a revision", ifMatch: `"abc"`, wantErr: true},
+		{name: "several tags", ifMatch: `"3", "4"`, wantErr: true},
+		{name: "zero", ifMatch: `"0"`, wantErr: true},
+		{name: "negative body", body: -1, wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, _ := newTestContext(http.MethodPut, "/api/releases/apps/web", "", map[string]string{headerIfMatch: tc.ifMatch})
+
+			got, err := expectedRevision(c, tc.body)
+			if tc.wantErr {
+				var httpErr *echo.HTTPError
+				if !errors.As(err, &httpErr) || httpErr.Code != http.StatusBadRequest {
+					t.Fatalf("expected 400, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("expected %d, got %d", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestSetETag(t *testing.T) {
+	c, rec := newTestContext(http.MethodGet, "/api/releases/apps/web", "", nil)
+	setETag(c, 7)
+
+	if got := rec.Header().Get(headerETag); got != `"7"` {
+		t.Errorf(`expected ETag "7", got %s`, got)
+	}
+
+	// The ETag is accepted back as If-Match
+	c, _ = newTestContext(http.MethodPut, "/api/releases/apps/web", "", map[string]string{headerIfMatch: rec.Header().Get(headerETag)})
+	if got, err := expectedRevision(c, 0); err != nil || got != 7 {
+		t.Errorf("expected revision 7, got %d (%v)", got, err)
+	}
+}
+
+func TestRevisionConflict(t *testing.T) {
+	err := revisionConflict(&helm.RevisionMismatchError{Namespace: "apps", Name: "web", Expected: 3, Current: 5})
+
+	var httpErr *echo.HTTPError
+	if !errors.As(err, &httpErr) {
+		t.Fatalf("expected HTTP error, got %v", err)
+	}
+	if httpErr.Code != http.StatusPreconditionFailed {
+		t.Errorf("expected 412, got %d", httpErr.Code)
+	}
+	want := model.RevisionConflictResponse{
+		Message:          "release apps/web is at revision 5, not the expected revision 3",
+		ExpectedRevision: 3,
+		CurrentRevision:  5,
+	}
+	if httpErr.Message != want {
+		t.Errorf("expected %+v, got %+v", want, httpErr.Message)
+	}
+}
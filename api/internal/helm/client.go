@@ -2,15 +2,17 @@ package helm
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
-	"path/filepath"
 	"strings"
 	"sync"
 
 	"github.com/helm-version-manager/api/internal/model"
+	"github.com/helm-version-manager/api/internal/status"
 	"github.com/helm-version-manager/api/internal/storage"
 	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart"
 	"helm.sh/helm/v3/pkg/chart/loader"
 	"helm.sh/helm/v3/pkg/cli"
 	"helm.sh/helm/v3/pkg/registry"
@@ -22,20 +24,49 @@ import (
 
 type Client struct {
 	settings      *cli.EnvSettings
-	registryStore *storage.RegistryStore
+	registryStore storage.MappingStore
+	auditStore    *storage.AuditStore
 	mu            sync.RWMutex
+	// chartMetadata caches Helm chart config blobs by manifest digest
+	chartMetadata sync.Map
+	// repoIndexes caches downloaded index files of classic chart repositories
+	repoIndexes sync.Map
+	// upgradeDefaults are applied to upgrade and rollback options left unset
+	upgradeDefaults model.UpgradeOptions
+	// plainHTTP makes OCI registries be accessed over HTTP instead of HTTPS
+	plainHTTP bool
+	// statusChecker reports the live status of release resources
+	statusChecker *status.Checker
+	// credentials authenticate tag listing and chart pulls to private
+	// registries and repositories
+	credentials CredentialSource
 }
 
-func NewClient(store *storage.RegistryStore) (*Client, error) {
+func NewClient(store storage.MappingStore, auditStore *storage.AuditStore, credentials CredentialSource) (*Client, error) {
 	settings := cli.New()
 
-	if _, err := config.GetConfig(); err != nil {
+	cfg, err := config.GetConfig()
+	if err != nil {
 		return nil, fmt.Errorf("failed to get kubernetes config: %w", err)
 	}
 
+	statusChecker, err := status.NewChecker(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create status checker: %w", err)
+	}
+
+	upgradeDefaults, err := upgradeDefaultsFromEnv()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load upgrade defaults: %w", err)
+	}
+
 	return &Client{
-		settings:      settings,
-		registryStore: store,
+		settings:        settings,
+		registryStore:   store,
+		auditStore:      auditStore,
+		upgradeDefaults: upgradeDefaults,
+		statusChecker:   statusChecker,
+		credentials:     credentials,
 	}, nil
 }
 
@@ -120,36 +151,37 @@ func (c *Client) GetRelease(namespace, name string) (*model.Release, error) {
 	return &result, nil
 }
 
-func (c *Client) GetReleaseHistory(namespace, name string) ([]model.ReleaseHistory, error) {
+func (c *Client) GetReleaseHistory(namespace, name string, query model.HistoryQuery) (*model.HistoryPage, error) {
 	actionConfig, err := c.getActionConfig(namespace)
 	if err != nil {
 		return nil, err
 	}
 
-	historyAction := action.NewHistory(actionConfig)
-	historyAction.Max = 10
-
-	releases, err := historyAction.Run(name)
+	releases, err := actionConfig.Releases.History(name)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get history for %s/%s: %w", namespace, name, err)
 	}
-
-	result := make([]model.ReleaseHistory, 0, len(releases))
-	for _, r := range releases {
-		result = append(result, model.ReleaseHistory{
-			Revision:    r.Version,
-			Updated:     r.Info.LastDeployed.Time,
-			Status:      string(r.Info.Status),
-			Chart:       r.Chart.Metadata.Version,
-			AppVersion:  r.Chart.Metadata.AppVersion,
-			Description: r.Info.Description,
-		})
+	if len(releases) == 0 {
+		return nil, fmt.Errorf("failed to get history for %s/%s: release not found", namespace, name)
 	}
 
-	return result, nil
+	page := pageHistory(releases, query)
+	return &page, nil
+}
+
+// upgradePlan holds the inputs of an upgrade of an existing release.
+type upgradePlan struct {
+	actionConfig *action.Configuration
+	current      *release.Release
+	chart        *chart.Chart
 }
 
-func (c *Client) UpgradeRelease(namespace, name string, req model.VersionUpgradeRequest) (*model.Release, error) {
+// planUpgrade loads the current release and the chart at chartVersion from
+// the release's registry mapping. An empty chartVersion keeps the currently
+// deployed version. A non-zero expectedRevision fails the plan unless the
+// release is at that revision. progress, which may be nil, receives the
+// phases of the upgrade from here on.
+func (c *Client) planUpgrade(namespace, name, chartVersion string, expectedRevision int, progress model.ProgressFunc) (*upgradePlan, error) {
 	actionConfig, err := c.getActionConfig(namespace)
 	if err != nil {
 		return nil, err
@@ -160,8 +192,14 @@ func (c *Client) UpgradeRelease(namespace, name string, req model.VersionUpgrade
 	if err != nil {
 		return nil, fmt.Errorf("failed to get current release: %w", err)
 	}
+	if err := checkRevision(currentRelease, expectedRevision); err != nil {
+		return nil, err
+	}
 
 	chartName := currentRelease.Chart.Metadata.Name
+	if chartVersion == "" {
+		chartVersion = currentRelease.Chart.Metadata.Version
+	}
 
 	mapping, err := c.registryStore.GetMapping(context.Background(), namespace, name)
 	if err != nil {
@@ -171,37 +209,216 @@ func (c *Client) UpgradeRelease(namespace, name string, req model.VersionUpgrade
 		return nil, fmt.Errorf("registry mapping not found for release %s/%s, please set registry first", namespace, name)
 	}
 
-	chartPath, err := c.locateChart(actionConfig, mapping.Registry, chartName, req.ChartVersion)
+	progress.Report(model.PhasePullChart, "Pulling chart %s %s from %s", chartName, chartVersion, mapping.Registry)
+	chartPath, err := c.locateChart(actionConfig, mapping.Registry, chartName, chartVersion)
 	if err != nil {
 		return nil, fmt.Errorf("failed to locate chart: %w", err)
 	}
 
-	chart, err := loader.Load(chartPath)
+	ch, err := loader.Load(chartPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load chart: %w", err)
 	}
+	withProgress(actionConfig, progress)
+
+	return &upgradePlan{
+		actionConfig: actionConfig,
+		current:      currentRelease,
+		chart:        ch,
+	}, nil
+}
+
+// UpgradeRelease upgrades a release to req.ChartVersion, reusing its values.
+// progress, which may be nil, receives the phases of the upgrade. ctx bounds
+// the verification and tests that follow the upgrade.
+func (c *Client) UpgradeRelease(ctx context.Context, namespace, name string, req model.VersionUpgradeRequest, progress model.ProgressFunc) (*model.Release, error) {
+	plan, err := c.planUpgrade(namespace, name, req.ChartVersion, req.ExpectedRevision, progress)
+	if err != nil {
+		return nil, err
+	}
 
-	upgradeAction := action.NewUpgrade(actionConfig)
+	upgradeAction := action.NewUpgrade(plan.actionConfig)
 	upgradeAction.Namespace = namespace
+	upgradeAction.Labels = upgradeLabels(req.DeployedBy)
 	upgradeAction.ReuseValues = true
-
-	vals := currentRelease.Config
-	if req.Values != nil {
-		for k, v := range req.Values {
-			vals[k] = v
+	opts := withDefaults(req.UpgradeOptions, c.upgradeDefaults)
+	if err := applyUpgradeOptions(upgradeAction, opts); err != nil {
+		return nil, err
+	}
+	if req.Test != nil {
+		if _, err := parseTimeout(req.Test.Timeout); err != nil {
+			return nil, err
 		}
 	}
 
-	r, err := upgradeAction.Run(name, chart, vals)
+	progress.Report(model.PhaseRender, "Rendering chart %s %s", plan.chart.Metadata.Name, plan.chart.Metadata.Version)
+	vals := overrideValues(plan.current.Config, req.Values)
+	if err := plan.validateReusedValues(vals); err != nil {
+		return nil, err
+	}
+
+	r, err := upgradeAction.Run(name, plan.chart, vals)
 	if err != nil {
 		return nil, fmt.Errorf("failed to upgrade release: %w", err)
 	}
 
 	result := toModelRelease(r)
+	if boolValue(opts.Verify) {
+		verified, err := c.verifyUpgrade(ctx, namespace, name, model.AuditActionUpgrade, req.DeployedBy, r, plan.current.Version, opts, progress)
+		if err != nil {
+			return nil, err
+		}
+		// Tests only run against a release that is still at the new revision
+		if req.Test == nil || verified.Verification.Outcome != model.VerificationVerified {
+			return verified, nil
+		}
+		result = *verified
+	}
+
+	if req.Test != nil {
+		return c.testUpgrade(ctx, namespace, name, req.DeployedBy, result, plan.current.Version, *req.Test, progress)
+	}
 	return &result, nil
 }
 
-func (c *Client) GetAvailableVersions(namespace, name string) ([]model.ChartVersion, error) {
+// PreviewUpgrade renders the upgrade described by req without applying it and
+// returns the per-resource difference to the currently deployed manifest.
+func (c *Client) PreviewUpgrade(namespace, name string, req model.VersionUpgradeRequest) (*model.UpgradePreview, error) {
+	plan, err := c.planUpgrade(namespace, name, req.ChartVersion, 0, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	upgradeAction := action.NewUpgrade(plan.actionConfig)
+	upgradeAction.Namespace = namespace
+	upgradeAction.ReuseValues = true
+	upgradeAction.DryRun = true
+
+	vals := overrideValues(plan.current.Config, req.Values)
+	if err := plan.validateReusedValues(vals); err != nil {
+		return nil, err
+	}
+
+	r, err := upgradeAction.Run(name, plan.chart, vals)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render upgrade: %w", err)
+	}
+
+	return &model.UpgradePreview{
+		Namespace:           namespace,
+		Name:                name,
+		CurrentRevision:     plan.current.Version,
+		CurrentChartVersion: plan.current.Chart.Metadata.Version,
+		TargetChartVersion:  plan.chart.Metadata.Version,
+		Diff:                diffManifests(plan.current.Manifest, r.Manifest),
+	}, nil
+}
+
+// InstallRelease installs a new release from a registry and records the
+// registry mapping for it so it can be upgraded afterwards.
+func (c *Client) InstallRelease(namespace string, req model.InstallRequest) (*model.Release, error) {
+	actionConfig, err := c.getActionConfig(namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	version := req.ChartVersion
+	if version == "" {
+		versions, err := c.searchChartVersions(req.Registry, req.Chart, model.VersionQuery{Limit: 1})
+		if err != nil {
+			return nil, fmt.Errorf("failed to find latest chart version: %w", err)
+		}
+		if len(versions) == 0 {
+			return nil, fmt.Errorf("no versions of chart %s found in %s", req.Chart, req.Registry)
+		}
+		version = versions[0].Version
+	}
+
+	chartPath, err := c.locateChart(actionConfig, req.Registry, req.Chart, version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to locate chart: %w", err)
+	}
+
+	ch, err := loader.Load(chartPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load chart: %w", err)
+	}
+
+	installAction := action.NewInstall(actionConfig)
+	installAction.ReleaseName = req.Name
+	installAction.Namespace = namespace
+	installAction.CreateNamespace = true
+	installAction.Labels = deployedByLabels(req.DeployedBy)
+
+	vals := req.Values
+	if vals == nil {
+		vals = map[string]any{}
+	}
+
+	r, err := installAction.Run(ch, vals)
+	if err != nil {
+		return nil, fmt.Errorf("failed to install release %s/%s: %w", namespace, req.Name, err)
+	}
+
+	mapping := model.RegistryMapping{
+		Namespace:   namespace,
+		ReleaseName: req.Name,
+		ChartName:   req.Chart,
+		Registry:    req.Registry,
+	}
+	if err := c.registryStore.SetMapping(context.Background(), mapping); err != nil {
+		return nil, fmt.Errorf("release %s/%s was installed but saving its registry mapping failed: %w", namespace, req.Name, err)
+	}
+
+	result := toModelRelease(r)
+	result.HasRegistry = true
+	return &result, nil
+}
+
+// UninstallRelease removes a release. Its registry mapping is deleted as well
+// unless the release history is kept, since the mapping is still needed to
+// reinstall or roll back in that case.
+func (c *Client) UninstallRelease(namespace, name string, req model.UninstallRequest) (*model.UninstallResult, error) {
+	timeout, err := parseTimeout(req.Timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	actionConfig, err := c.getActionConfig(namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	uninstallAction := action.NewUninstall(actionConfig)
+	uninstallAction.KeepHistory = req.KeepHistory
+	uninstallAction.Wait = req.Wait
+	uninstallAction.Timeout = timeout
+
+	resp, err := uninstallAction.Run(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to uninstall release %s/%s: %w", namespace, name, err)
+	}
+
+	result := &model.UninstallResult{
+		Namespace:   namespace,
+		Name:        name,
+		KeptHistory: req.KeepHistory,
+	}
+	if resp != nil {
+		result.Info = resp.Info
+	}
+
+	if !req.KeepHistory {
+		if err := c.registryStore.DeleteMapping(context.Background(), namespace, name); err != nil {
+			return nil, fmt.Errorf("release %s/%s was uninstalled but deleting its registry mapping failed: %w", namespace, name, err)
+		}
+		result.RegistryMappingDeleted = true
+	}
+
+	return result, nil
+}
+
+func (c *Client) GetAvailableVersions(namespace, name string, query model.VersionQuery) ([]model.ChartVersion, error) {
 	actionConfig, err := c.getActionConfig(namespace)
 	if err != nil {
 		return nil, err
@@ -223,13 +440,17 @@ func (c *Client) GetAvailableVersions(namespace, name string) ([]model.ChartVers
 		return nil, fmt.Errorf("registry mapping not found for release %s/%s, please set registry first", namespace, name)
 	}
 
-	return c.searchChartVersions(mapping.Registry, chartName)
+	return c.searchChartVersions(mapping.Registry, chartName, query)
 }
 
-func (c *Client) searchChartVersions(reg, chartName string) ([]model.ChartVersion, error) {
+func (c *Client) searchChartVersions(reg, chartName string, query model.VersionQuery) ([]model.ChartVersion, error) {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
+	if isHTTPRepository(reg) {
+		return c.searchRepositoryVersions(reg, chartName, query)
+	}
+
 	reg = strings.TrimPrefix(reg, "oci://")
 	repoRef := fmt.Sprintf("%s/%s", reg, chartName)
 
@@ -237,6 +458,10 @@ func (c *Client) searchChartVersions(reg, chartName string) ([]model.ChartVersio
 	if err != nil {
 		return nil, fmt.Errorf("failed to create OCI repository client: %w", err)
 	}
+	repo.PlainHTTP = c.plainHTTP
+	if err := c.authorizeRepository(repo); err != nil {
+		return nil, err
+	}
 
 	ctx := context.Background()
 	var tags []string
@@ -249,33 +474,49 @@ func (c *Client) searchChartVersions(reg, chartName string) ([]model.ChartVersio
 		return nil, fmt.Errorf("failed to list tags from OCI registry: %w", err)
 	}
 
-	// Reverse the order (newest first)
-	for i, j := 0, len(tags)-1; i < j; i, j = i+1, j-1 {
-		tags[i], tags[j] = tags[j], tags[i]
-	}
-
-	// Limit to 10 latest versions
-	if len(tags) > 10 {
-		tags = tags[:10]
+	versions, err := filterChartVersions(tags, query)
+	if err != nil {
+		return nil, err
 	}
 
-	versions := make([]model.ChartVersion, 0, len(tags))
-	for _, tag := range tags {
-		versions = append(versions, model.ChartVersion{
-			Version: tag,
-		})
+	// Metadata is best effort: a tag whose manifest cannot be read is still listed
+	for i := range versions {
+		metadata, err := c.fetchChartMetadata(ctx, repo, versionToTag(versions[i].Version))
+		if err != nil {
+			continue
+		}
+		versions[i].AppVersion = metadata.AppVersion
+		versions[i].Description = metadata.Description
 	}
 
 	return versions, nil
 }
 
 func (c *Client) locateChart(actionConfig *action.Configuration, reg, chartName, version string) (string, error) {
+	if isHTTPRepository(reg) {
+		return c.locateRepositoryChart(reg, chartName, version)
+	}
+
 	reg = strings.TrimPrefix(reg, "oci://")
 	ref := fmt.Sprintf("%s/%s:%s", reg, chartName, version)
 
-	registryClient, err := registry.NewClient(
+	opts := []registry.ClientOption{
 		registry.ClientOptCredentialsFile(c.settings.RegistryConfig),
-	)
+	}
+	if c.plainHTTP {
+		opts = append(opts, registry.ClientOptPlainHTTP())
+	}
+
+	// Configured credentials take precedence over the credentials file
+	credential, err := c.registryCredential(fmt.Sprintf("%s/%s", reg, chartName))
+	if err != nil {
+		return "", err
+	}
+	if credential != nil {
+		opts = append(opts, registry.ClientOptBasicAuth(credential.Username, credential.Password))
+	}
+
+	registryClient, err := registry.NewClient(opts...)
 	if err != nil {
 		return "", fmt.Errorf("failed to create registry client: %w", err)
 	}
@@ -285,25 +526,69 @@ func (c *Client) locateChart(actionConfig *action.Configuration, reg, chartName,
 		return "", fmt.Errorf("failed to pull chart %s: %w", ref, err)
 	}
 
-	// Save chart to cache directory
-	cacheDir := c.settings.RepositoryCache
-	if cacheDir == "" {
-		cacheDir = os.TempDir()
+	return c.writeChartToCache(chartName, version, result.Chart.Data)
+}
+
+func (c *Client) GetReleaseValues(namespace, name string) (map[string]any, error) {
+	actionConfig, err := c.getActionConfig(namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	getAction := action.NewGet(actionConfig)
+	r, err := getAction.Run(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get release %s/%s: %w", namespace, name, err)
 	}
-	if err := os.MkdirAll(cacheDir, 0755); err != nil {
-		return "", fmt.Errorf("failed to create cache directory %s: %w", cacheDir, err)
+
+	return r.Config, nil
+}
+
+// GetChartValues returns the default values.yaml of the chart the release
+// is currently deployed with.
+func (c *Client) GetChartValues(namespace, name string) (map[string]any, error) {
+	actionConfig, err := c.getActionConfig(namespace)
+	if err != nil {
+		return nil, err
 	}
-	chartFileName := fmt.Sprintf("%s-%s.tgz", chartName, version)
-	chartPath := filepath.Join(cacheDir, chartFileName)
 
-	if err := os.WriteFile(chartPath, result.Chart.Data, 0644); err != nil {
-		return "", fmt.Errorf("failed to write chart to %s: %w", chartPath, err)
+	getAction := action.NewGet(actionConfig)
+	r, err := getAction.Run(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get release %s/%s: %w", namespace, name, err)
 	}
 
-	return chartPath, nil
+	if r.Chart.Values == nil {
+		return map[string]any{}, nil
+	}
+	return r.Chart.Values, nil
 }
 
-func (c *Client) GetReleaseValues(namespace, name string) (map[string]any, error) {
+// GetComputedValues returns the values the release was rendered with: the
+// user-supplied values coalesced with the chart defaults.
+func (c *Client) GetComputedValues(namespace, name string) (map[string]any, error) {
+	actionConfig, err := c.getActionConfig(namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	getValuesAction := action.NewGetValues(actionConfig)
+	getValuesAction.AllValues = true
+
+	values, err := getValuesAction.Run(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get computed values of %s/%s: %w", namespace, name, err)
+	}
+
+	if values == nil {
+		values = map[string]any{}
+	}
+	return values, nil
+}
+
+// GetValuesSchema returns the values.schema.json of the chart the release is
+// currently deployed with, or nil if the chart has none.
+func (c *Client) GetValuesSchema(namespace, name string) (map[string]any, error) {
 	actionConfig, err := c.getActionConfig(namespace)
 	if err != nil {
 		return nil, err
@@ -315,72 +600,237 @@ func (c *Client) GetReleaseValues(namespace, name string) (map[string]any, error
 		return nil, fmt.Errorf("failed to get release %s/%s: %w", namespace, name, err)
 	}
 
-	return r.Config, nil
+	if len(r.Chart.Schema) == 0 {
+		return nil, nil
+	}
+
+	var schema map[string]any
+	if err := json.Unmarshal(r.Chart.Schema, &schema); err != nil {
+		return nil, fmt.Errorf("failed to parse values schema of chart %s: %w", r.Chart.Name(), err)
+	}
+	return schema, nil
 }
 
-func (c *Client) UpdateReleaseValues(namespace, name string, values map[string]any) (*model.Release, error) {
+// GetRevisionValues returns the user-supplied values of a specific revision.
+func (c *Client) GetRevisionValues(namespace, name string, revision int) (map[string]any, error) {
+	actionConfig, err := c.getActionConfig(namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	getValuesAction := action.NewGetValues(actionConfig)
+	getValuesAction.Version = revision
+
+	values, err := getValuesAction.Run(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get values of %s/%s revision %d: %w", namespace, name, revision, err)
+	}
+
+	if values == nil {
+		values = map[string]any{}
+	}
+	return values, nil
+}
+
+// DiffRevisions compares the user-supplied values and the rendered manifests
+// of two revisions of a release.
+func (c *Client) DiffRevisions(namespace, name string, from, to int) (*model.RevisionDiff, error) {
 	actionConfig, err := c.getActionConfig(namespace)
 	if err != nil {
 		return nil, err
 	}
 
 	getAction := action.NewGet(actionConfig)
-	currentRelease, err := getAction.Run(name)
+
+	getAction.Version = from
+	fromRelease, err := getAction.Run(name)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get current release: %w", err)
+		return nil, fmt.Errorf("failed to get %s/%s revision %d: %w", namespace, name, from, err)
 	}
 
-	chartName := currentRelease.Chart.Metadata.Name
-	chartVersion := currentRelease.Chart.Metadata.Version
+	getAction.Version = to
+	toRelease, err := getAction.Run(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s/%s revision %d: %w", namespace, name, to, err)
+	}
 
-	mapping, err := c.registryStore.GetMapping(context.Background(), namespace, name)
+	return &model.RevisionDiff{
+		Namespace: namespace,
+		Name:      name,
+		From:      from,
+		To:        to,
+		Values:    diffValues(fromRelease.Config, toRelease.Config),
+		Manifest:  diffManifests(fromRelease.Manifest, toRelease.Manifest),
+	}, nil
+}
+
+// getRevision returns the given revision of a release, or the latest one
+// when revision is zero.
+func (c *Client) getRevision(namespace, name string, revision int) (*release.Release, error) {
+	actionConfig, err := c.getActionConfig(namespace)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get registry mapping: %w", err)
+		return nil, err
 	}
-	if mapping == nil {
-		return nil, fmt.Errorf("registry mapping not found for release %s/%s, please set registry first", namespace, name)
+
+	getAction := action.NewGet(actionConfig)
+	getAction.Version = revision
+
+	r, err := getAction.Run(name)
+	if err != nil {
+		if revision > 0 {
+			return nil, fmt.Errorf("failed to get %s/%s revision %d: %w", namespace, name, revision, err)
+		}
+		return nil, fmt.Errorf("failed to get release %s/%s: %w", namespace, name, err)
 	}
+	return r, nil
+}
 
-	chartPath, err := c.locateChart(actionConfig, mapping.Registry, chartName, chartVersion)
+// GetReleaseManifest returns the manifest a release revision deployed, both
+// raw and split into objects. A zero revision selects the latest revision.
+func (c *Client) GetReleaseManifest(namespace, name string, revision int) (*model.ReleaseManifest, error) {
+	r, err := c.getRevision(namespace, name, revision)
 	if err != nil {
-		return nil, fmt.Errorf("failed to locate chart: %w", err)
+		return nil, err
 	}
 
-	chart, err := loader.Load(chartPath)
+	return &model.ReleaseManifest{
+		Namespace: namespace,
+		Name:      name,
+		Revision:  r.Version,
+		Manifest:  r.Manifest,
+		Objects:   parseManifest(r.Manifest),
+	}, nil
+}
+
+// GetReleaseNotes returns the rendered NOTES.txt of a release revision. A
+// zero revision selects the latest revision.
+func (c *Client) GetReleaseNotes(namespace, name string, revision int) (*model.ReleaseNotes, error) {
+	r, err := c.getRevision(namespace, name, revision)
 	if err != nil {
-		return nil, fmt.Errorf("failed to load chart: %w", err)
+		return nil, err
 	}
 
-	upgradeAction := action.NewUpgrade(actionConfig)
-	upgradeAction.Namespace = namespace
-	upgradeAction.ReuseValues = true
+	notes := model.ReleaseNotes{
+		Namespace: namespace,
+		Name:      name,
+		Revision:  r.Version,
+	}
+	if r.Info != nil {
+		notes.Notes = r.Info.Notes
+	}
+	return &notes, nil
+}
+
+// GetReleaseHooks returns the hooks of a release revision with the outcome of
+// their last run. A zero revision selects the latest revision.
+func (c *Client) GetReleaseHooks(namespace, name string, revision int) (*model.ReleaseHooks, error) {
+	r, err := c.getRevision(namespace, name, revision)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.ReleaseHooks{
+		Namespace: namespace,
+		Name:      name,
+		Revision:  r.Version,
+		Hooks:     toModelHooks(r.Hooks),
+	}, nil
+}
 
-	// Merge new values with existing values
-	vals := currentRelease.Config
-	for k, v := range values {
-		vals[k] = v
+// GetReleaseResources fetches every resource in the manifest of the latest
+// revision and reports its live status.
+func (c *Client) GetReleaseResources(ctx context.Context, namespace, name string) (*model.ReleaseResources, error) {
+	r, err := c.getRevision(namespace, name, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	resources := c.statusChecker.Check(ctx, namespace, parseManifest(r.Manifest))
+
+	result := &model.ReleaseResources{
+		Namespace: namespace,
+		Name:      name,
+		Revision:  r.Version,
+		Status:    status.Aggregate(resources),
+		Resources: resources,
+	}
+	if r.Info != nil {
+		result.ReleaseStatus = string(r.Info.Status)
+	}
+	result.Ready = result.Status == model.ResourceStatusCurrent
+	return result, nil
+}
+
+// UpdateReleaseValues upgrades a release to new values at its current chart
+// version. How req.Values or req.Patch are applied to the current values
+// depends on req.Mode; see applyValuesUpdate. progress, which may be nil,
+// receives the phases of the upgrade and ctx bounds its verification.
+func (c *Client) UpdateReleaseValues(ctx context.Context, namespace, name string, req model.ValuesUpdateRequest, progress model.ProgressFunc) (*model.Release, error) {
+	plan, err := c.planUpgrade(namespace, name, "", req.ExpectedRevision, progress)
+	if err != nil {
+		return nil, err
 	}
 
-	r, err := upgradeAction.Run(name, chart, vals)
+	progress.Report(model.PhaseRender, "Rendering chart %s %s with the updated values", plan.chart.Metadata.Name, plan.chart.Metadata.Version)
+	vals, err := applyValuesUpdate(plan.current.Config, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateValues(plan.chart, vals); err != nil {
+		return nil, err
+	}
+
+	upgradeAction := action.NewUpgrade(plan.actionConfig)
+	upgradeAction.Namespace = namespace
+	upgradeAction.Labels = upgradeLabels(req.DeployedBy)
+	// vals is the complete new configuration, so Helm must not merge the old
+	// values back in, which would resurrect removed keys
+	upgradeAction.ResetValues = true
+	opts := withDefaults(req.UpgradeOptions, c.upgradeDefaults)
+	if err := applyUpgradeOptions(upgradeAction, opts); err != nil {
+		return nil, err
+	}
+
+	r, err := upgradeAction.Run(name, plan.chart, vals)
 	if err != nil {
 		return nil, fmt.Errorf("failed to update release values: %w", err)
 	}
 
+	if boolValue(opts.Verify) {
+		return c.verifyUpgrade(ctx, namespace, name, model.AuditActionUpdateValues, req.DeployedBy, r, plan.current.Version, opts, progress)
+	}
+
 	result := toModelRelease(r)
 	return &result, nil
 }
 
-func (c *Client) RollbackRelease(namespace, name string, revision int) (*model.Release, error) {
+// RollbackRelease rolls a release back to req.Revision. progress, which may
+// be nil, receives the phases of the rollback. The rollback is not started
+// once ctx is done.
+func (c *Client) RollbackRelease(ctx context.Context, namespace, name string, req model.RollbackRequest, progress model.ProgressFunc) (*model.Release, error) {
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("rollback of release %s/%s cancelled: %w", namespace, name, context.Cause(ctx))
+	}
+
 	actionConfig, err := c.getActionConfig(namespace)
 	if err != nil {
 		return nil, err
 	}
+	withProgress(actionConfig, progress)
 
+	if err := c.CheckRevision(namespace, name, req.ExpectedRevision); err != nil {
+		return nil, err
+	}
+
+	labelRollback(actionConfig, req.DeployedBy)
 	rollbackAction := action.NewRollback(actionConfig)
-	rollbackAction.Version = revision
+	rollbackAction.Version = req.Revision
+	if err := applyRollbackOptions(rollbackAction, withRollbackDefaults(req.RollbackOptions, c.upgradeDefaults)); err != nil {
+		return nil, err
+	}
 
 	if err := rollbackAction.Run(name); err != nil {
-		return nil, fmt.Errorf("failed to rollback release %s/%s to revision %d: %w", namespace, name, revision, err)
+		return nil, fmt.Errorf("failed to rollback release %s/%s to revision %d: %w", namespace, name, req.Revision, err)
 	}
 
 	// Get the updated release after rollback
@@ -4,6 +4,7 @@ import (
 	"os"
 	"testing"
 
+	"github.com/helm-version-manager/api/internal/model"
 	"helm.sh/helm/v3/pkg/cli"
 )
 
@@ -91,7 +92,7 @@ func TestSearchChartVersions(t *testing.T) {
 		settings: cli.New(),
 	}
 
-	versions, err := c.searchChartVersions("oci://ghcr.io/takutakahashi/charts", "agentapi-ui")
+	versions, err := c.searchChartVersions("oci://ghcr.io/takutakahashi/charts", "agentapi-ui", model.VersionQuery{})
 	if err != nil {
 		t.Fatalf("searchChartVersions failed: %v", err)
 	}
@@ -131,4 +132,3 @@ func TestLocateChart(t *testing.T) {
 		t.Fatalf("chart file does not exist: %s", chartPath)
 	}
 }
-
@@ -0,0 +1,50 @@
+package helm
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/helm-version-manager/api/internal/storage"
+	"oras.land/oras-go/v2/registry/remote"
+	"oras.land/oras-go/v2/registry/remote/auth"
+	"oras.land/oras-go/v2/registry/remote/retry"
+)
+
+// CredentialSource looks up the username and password for a registry or
+// repository reference, returning nil when it is accessed anonymously
+type CredentialSource interface {
+	Lookup(ctx context.Context, ref string) (*storage.Credential, error)
+}
+
+// registryCredential returns the credentials configured for ref, or nil
+// when there are none
+func (c *Client) registryCredential(ref string) (*storage.Credential, error) {
+	if c.credentials == nil {
+		return nil, nil
+	}
+
+	credential, err := c.credentials.Lookup(context.Background(), ref)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up registry credentials: %w", err)
+	}
+	return credential, nil
+}
+
+// authorizeRepository makes repo authenticate with the credentials
+// configured for it, if any
+func (c *Client) authorizeRepository(repo *remote.Repository) error {
+	credential, err := c.registryCredential(repo.Reference.String())
+	if err != nil || credential == nil {
+		return err
+	}
+
+	repo.Client = &auth.Client{
+		Client: retry.DefaultClient,
+		Cache:  auth.NewCache(),
+		Credential: auth.StaticCredential(repo.Reference.Registry, auth.Credential{
+			Username: credential.Username,
+			Password: credential.Password,
+		}),
+	}
+	return nil
+}
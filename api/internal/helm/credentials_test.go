@@ -0,0 +1,72 @@
+package helm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/helm-version-manager/api/internal/model"
+	"github.com/helm-version-manager/api/internal/storage"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+)
+
+// staticCredentials returns the same credentials for every reference
+type staticCredentials storage.Credential
+
+func (s staticCredentials) Lookup(ctx context.Context, ref string) (*storage.Credential, error) {
+	credential := storage.Credential(s)
+	return &credential, nil
+}
+
+func TestPrivateRegistry(t *testing.T) {
+	reg := newAuthTestRegistry(t, "robot", "s3cret")
+	reg.pushChart(t, "charts", &chart.Metadata{APIVersion: chart.APIVersionV2, Name: "mychart", Version: "1.0.0"})
+	ociURL := "oci://" + reg.host() + "/charts"
+
+	repoURL := newAuthTestRepository(t, "robot", "s3cret",
+		&chart.Metadata{APIVersion: chart.APIVersionV2, Name: "mychart", Version: "1.0.0"},
+	)
+
+	testCases := []struct {
+		name        string
+		credentials CredentialSource
+		wantErr     bool
+	}{
+		{name: "anonymous", wantErr: true},
+		{name: "wrong password", credentials: staticCredentials{Username: "robot", Password: "wrong"}, wantErr: true},
+		{name: "valid credentials", credentials: staticCredentials{Username: "robot", Password: "s3cret"}},
+	}
+
+	for _, tc := range testCases {
+		for kind, registry := range map[string]string{"oci": ociURL, "repository": repoURL} {
+			t.Run(tc.name+" "+kind, func(t *testing.T) {
+				c := newTestRepositoryClient(t)
+				c.plainHTTP = true
+				c.credentials = tc.credentials
+
+				versions, err := c.searchChartVersions(registry, "mychart", model.VersionQuery{})
+				if (err != nil) != tc.wantErr {
+					t.Fatalf("searchChartVersions error = %v, wantErr %v", err, tc.wantErr)
+				}
+				if err == nil && (len(versions) != 1 || versions[0].Version != "1.0.0") {
+					t.Errorf("expected version 1.0.0, got %+v", versions)
+				}
+
+				chartPath, err := c.locateChart(nil, registry, "mychart", "1.0.0")
+				if (err != nil) != tc.wantErr {
+					t.Fatalf("locateChart error = %v, wantErr %v", err, tc.wantErr)
+				}
+				if err != nil {
+					return
+				}
+				ch, err := loader.Load(chartPath)
+				if err != nil {
+					t.Fatalf("failed to load pulled chart: %v", err)
+				}
+				if ch.Metadata.Version != "1.0.0" {
+					t.Errorf("expected chart version 1.0.0, got %s", ch.Metadata.Version)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package helm
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/helm-version-manager/api/internal/model"
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// diffManifests compares two rendered release manifests resource by resource
+// and returns a unified diff for every resource that was added, removed or
+// changed. Unchanged resources are omitted.
+func diffManifests(oldManifest, newManifest string) model.ManifestDiff {
+	oldObjects := parseManifest(oldManifest)
+	newObjects := parseManifest(newManifest)
+
+	oldByKey := make(map[string]model.ManifestObject, len(oldObjects))
+	for _, obj := range oldObjects {
+		oldByKey[resourceKey(obj)] = obj
+	}
+	newKeys := make(map[string]bool, len(newObjects))
+
+	result := model.ManifestDiff{Resources: []model.ResourceDiff{}}
+
+	for _, obj := range newObjects {
+		key := resourceKey(obj)
+		newKeys[key] = true
+
+		old, ok := oldByKey[key]
+		switch {
+		case !ok:
+			result.Added++
+			result.Resources = append(result.Resources, resourceDiff(obj, model.ChangeAdded, "", obj.Manifest))
+		case old.Manifest != obj.Manifest:
+			result.Changed++
+			result.Resources = append(result.Resources, resourceDiff(obj, model.ChangeChanged, old.Manifest, obj.Manifest))
+		}
+	}
+
+	for _, obj := range oldObjects {
+		if newKeys[resourceKey(obj)] {
+			continue
+		}
+		result.Removed++
+		result.Resources = append(result.Resources, resourceDiff(obj, model.ChangeRemoved, obj.Manifest, ""))
+	}
+
+	return result
+}
+
+func resourceDiff(obj model.ManifestObject, change, oldManifest, newManifest string) model.ResourceDiff {
+	name := fmt.Sprintf("%s/%s", obj.Kind, obj.Name)
+	if obj.Namespace != "" {
+		name = fmt.Sprintf("%s/%s/%s", obj.Kind, obj.Namespace, obj.Name)
+	}
+
+	diff, _ := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(oldManifest),
+		B:        difflib.SplitLines(newManifest),
+		FromFile: "a/" + name,
+		ToFile:   "b/" + name,
+		Context:  3,
+	})
+
+	return model.ResourceDiff{
+		Kind:      obj.Kind,
+		Namespace: obj.Namespace,
+		Name:      obj.Name,
+		Change:    change,
+		Diff:      diff,
+	}
+}
+
+// diffValues compares two sets of user-supplied values key path by key path.
+// Nested maps are descended into; any other value, including lists, is
+// compared as a whole. Changes are returned sorted by path.
+func diffValues(oldValues, newValues map[string]any) []model.ValueChange {
+	changes := []model.ValueChange{}
+	collectValueChanges("", oldValues, newValues, &changes)
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+
+	return changes
+}
+
+func collectValueChanges(prefix string, oldValues, newValues map[string]any, changes *[]model.ValueChange) {
+	for key, newValue := range newValues {
+		path := joinValuePath(prefix, key)
+
+		oldValue, ok := oldValues[key]
+		if !ok {
+			*changes = append(*changes, model.ValueChange{Path: path, Change: model.ChangeAdded, To: newValue})
+			continue
+		}
+
+		oldMap, oldIsMap := oldValue.(map[string]any)
+		newMap, newIsMap := newValue.(map[string]any)
+		if oldIsMap && newIsMap {
+			collectValueChanges(path, oldMap, newMap, changes)
+			continue
+		}
+
+		if !reflect.DeepEqual(oldValue, newValue) {
+			*changes = append(*changes, model.ValueChange{Path: path, Change: model.ChangeChanged, From: oldValue, To: newValue})
+		}
+	}
+
+	for key, oldValue := range oldValues {
+		if _, ok := newValues[key]; !ok {
+			*changes = append(*changes, model.ValueChange{Path: joinValuePath(prefix, key), Change: model.ChangeRemoved, From: oldValue})
+		}
+	}
+}
+
+func joinValuePath(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
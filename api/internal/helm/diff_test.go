@@ -0,0 +1,184 @@
+package helm
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/helm-version-manager/api/internal/model"
+)
+
+const oldTestManifest = `---
+# Source: mychart/templates/configmap.yaml
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: myrelease-config
+data:
+  key: old
+---
+# Source: mychart/templates/service.yaml
+apiVersion: v1
+kind: Service
+metadata:
+  name: myrelease
+  namespace: default
+spec:
+  ports:
+  - port: 80
+---
+# Source: mychart/templates/legacy.yaml
+apiVersion: v1
+kind: Secret
+metadata:
+  name: myrelease-legacy
+`
+
+const newTestManifest = `---
+# Source: mychart/templates/configmap.yaml
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: myrelease-config
+data:
+  key: new
+---
+# Source: mychart/templates/service.yaml
+apiVersion: v1
+kind: Service
+metadata:
+  name: myrelease
+  namespace: default
+spec:
+  ports:
+  - port: 80
+---
+# Source: mychart/templates/deployment.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: myrelease
+`
+
+func TestParseManifest(t *testing.T) {
+	objects := parseManifest(oldTestManifest + "---\n# empty document\n")
+
+	if len(objects) != 3 {
+		t.Fatalf("expected 3 objects, got %d", len(objects))
+	}
+
+	want := []model.ManifestObject{
+		{APIVersion: "v1", Kind: "ConfigMap", Name: "myrelease-config"},
+		{APIVersion: "v1", Kind: "Service", Namespace: "default", Name: "myrelease"},
+		{APIVersion: "v1", Kind: "Secret", Name: "myrelease-legacy"},
+	}
+	for i, w := range want {
+		got := objects[i]
+		if got.APIVersion != w.APIVersion || got.Kind != w.Kind || got.Namespace != w.Namespace || got.Name != w.Name {
+			t.Errorf("object %d: expected %s %s/%s/%s, got %s %s/%s/%s", i, w.APIVersion, w.Kind, w.Namespace, w.Name, got.APIVersion, got.Kind, got.Namespace, got.Name)
+		}
+		if !strings.Contains(got.Manifest, "name: "+w.Name) {
+			t.Errorf("object %d: manifest does not contain its name: %q", i, got.Manifest)
+		}
+	}
+}
+
+func TestDiffManifests(t *testing.T) {
+	diff := diffManifests(oldTestManifest, newTestManifest)
+
+	if diff.Added != 1 || diff.Removed != 1 || diff.Changed != 1 {
+		t.Fatalf("expected 1 added, 1 removed, 1 changed, got %d/%d/%d", diff.Added, diff.Removed, diff.Changed)
+	}
+
+	changes := make(map[string]model.ResourceDiff)
+	for _, r := range diff.Resources {
+		changes[r.Kind+"/"+r.Name] = r
+	}
+
+	if len(changes) != 3 {
+		t.Fatalf("expected 3 resources in diff (unchanged omitted), got %d", len(changes))
+	}
+
+	configMap := changes["ConfigMap/myrelease-config"]
+	if configMap.Change != model.ChangeChanged {
+		t.Errorf("expected ConfigMap to be changed, got %q", configMap.Change)
+	}
+	if !strings.Contains(configMap.Diff, "-  key: old") || !strings.Contains(configMap.Diff, "+  key: new") {
+		t.Errorf("unexpected ConfigMap diff:\n%s", configMap.Diff)
+	}
+	if !strings.Contains(configMap.Diff, "--- a/ConfigMap/myrelease-config") {
+		t.Errorf("expected diff header to name the resource:\n%s", configMap.Diff)
+	}
+
+	if changes["Deployment/myrelease"].Change != model.ChangeAdded {
+		t.Errorf("expected Deployment to be added, got %q", changes["Deployment/myrelease"].Change)
+	}
+	if changes["Secret/myrelease-legacy"].Change != model.ChangeRemoved {
+		t.Errorf("expected Secret to be removed, got %q", changes["Secret/myrelease-legacy"].Change)
+	}
+}
+
+func TestDiffManifestsIdentical(t *testing.T) {
+	diff := diffManifests(oldTestManifest, oldTestManifest)
+
+	if diff.Added != 0 || diff.Removed != 0 || diff.Changed != 0 || len(diff.Resources) != 0 {
+		t.Fatalf("expected empty diff, got %+v", diff)
+	}
+}
+
+func TestDiffValues(t *testing.T) {
+	oldValues := map[string]any{
+		"replicaCount": 1,
+		"image": map[string]any{
+			"repository": "nginx",
+			"tag":        "1.25",
+		},
+		"resources": map[string]any{
+			"limits": map[string]any{"cpu": "500m"},
+		},
+		"args":    []any{"--verbose"},
+		"removed": true,
+	}
+	newValues := map[string]any{
+		"replicaCount": 3,
+		"image": map[string]any{
+			"repository": "nginx",
+			"tag":        "1.26",
+		},
+		"resources": map[string]any{
+			"limits": map[string]any{"cpu": "500m", "memory": "256Mi"},
+		},
+		"args": []any{"--verbose", "--debug"},
+	}
+
+	want := []model.ValueChange{
+		{Path: "args", Change: model.ChangeChanged, From: []any{"--verbose"}, To: []any{"--verbose", "--debug"}},
+		{Path: "image.tag", Change: model.ChangeChanged, From: "1.25", To: "1.26"},
+		{Path: "removed", Change: model.ChangeRemoved, From: true},
+		{Path: "replicaCount", Change: model.ChangeChanged, From: 1, To: 3},
+		{Path: "resources.limits.memory", Change: model.ChangeAdded, To: "256Mi"},
+	}
+
+	got := diffValues(oldValues, newValues)
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %+v, got %+v", want, got)
+	}
+}
+
+func TestDiffValuesMapReplacedByScalar(t *testing.T) {
+	got := diffValues(
+		map[string]any{"ingress": map[string]any{"enabled": true}},
+		map[string]any{"ingress": false},
+	)
+
+	if len(got) != 1 || got[0].Path != "ingress" || got[0].Change != model.ChangeChanged {
+		t.Fatalf("expected ingress to be changed as a whole, got %+v", got)
+	}
+}
+
+func TestDiffValuesNil(t *testing.T) {
+	got := diffValues(nil, map[string]any{"a": 1})
+	if len(got) != 1 || got[0].Change != model.ChangeAdded {
+		t.Fatalf("expected a single addition, got %+v", got)
+	}
+}
@@ -0,0 +1,139 @@
+package helm
+
+import (
+	"maps"
+	"strings"
+
+	"github.com/helm-version-manager/api/internal/model"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/releaseutil"
+	"helm.sh/helm/v3/pkg/storage/driver"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const (
+	defaultHistoryMax = 10
+	// deployedByLabel is the release label recording who deployed a revision
+	// through helm-ui. Revisions deployed with the Helm CLI, or by a caller
+	// helm-ui could not identify, have none.
+	deployedByLabel = "helm-ui/deployed-by"
+)
+
+// deployedByLabels are the labels install sets on the revision it creates;
+// none when who deployed it is unknown
+func deployedByLabels(deployedBy string) map[string]string {
+	value := labelValue(deployedBy)
+	if value == "" {
+		return nil
+	}
+	return map[string]string{deployedByLabel: value}
+}
+
+// upgradeLabels are deployedByLabels for an upgrade, which otherwise keeps
+// the labels of the revision it replaces. Helm drops labels set to "null".
+func upgradeLabels(deployedBy string) map[string]string {
+	if labels := deployedByLabels(deployedBy); labels != nil {
+		return labels
+	}
+	return map[string]string{deployedByLabel: "null"}
+}
+
+// labelRollback makes the revision the next rollback with cfg creates carry
+// deployedBy. The rollback action cannot be given labels and copies those of
+// the revision it rolls back to, so they are replaced as the revision is
+// stored.
+func labelRollback(cfg *action.Configuration, deployedBy string) {
+	cfg.Releases.Driver = &rollbackLabeler{Driver: cfg.Releases.Driver, labels: deployedByLabels(deployedBy)}
+}
+
+type rollbackLabeler struct {
+	driver.Driver
+	labels map[string]string
+}
+
+func (d *rollbackLabeler) Create(key string, rls *release.Release) error {
+	// The labels are shared with the revision rolled back to
+	labels := maps.Clone(rls.Labels)
+	if labels == nil {
+		labels = make(map[string]string)
+	}
+	delete(labels, deployedByLabel)
+	maps.Copy(labels, d.labels)
+	rls.Labels = labels
+	return d.Driver.Create(key, rls)
+}
+
+// labelValue reduces s to a valid label value: characters a label value
+// cannot hold become "_", and it is cut to 63 characters that start and end
+// with a letter or digit
+func labelValue(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if isLabelAlphanumeric(r) || r == '-' || r == '_' || r == '.' {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte('_')
+		}
+	}
+	value := b.String()
+	if len(value) > validation.LabelValueMaxLength {
+		value = value[:validation.LabelValueMaxLength]
+	}
+	return strings.TrimFunc(value, func(r rune) bool { return !isLabelAlphanumeric(r) })
+}
+
+func isLabelAlphanumeric(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9'
+}
+
+// pageHistory returns up to query.Max revisions older than query.Before,
+// newest first.
+func pageHistory(releases []*release.Release, query model.HistoryQuery) model.HistoryPage {
+	max := query.Max
+	if max <= 0 {
+		max = defaultHistoryMax
+	}
+
+	releaseutil.Reverse(releases, releaseutil.SortByRevision)
+
+	// The current revision is the newest deployed one; if Helm history was
+	// corrupted by concurrent upgrades there may be several marked deployed.
+	current := 0
+	for _, r := range releases {
+		if r.Info != nil && r.Info.Status == release.StatusDeployed {
+			current = r.Version
+			break
+		}
+	}
+
+	page := model.HistoryPage{History: []model.ReleaseHistory{}}
+	for _, r := range releases {
+		if query.Before > 0 && r.Version >= query.Before {
+			continue
+		}
+
+		if len(page.History) == max {
+			page.NextBefore = page.History[len(page.History)-1].Revision
+			break
+		}
+
+		page.History = append(page.History, toModelHistory(r, r.Version == current))
+	}
+
+	return page
+}
+
+func toModelHistory(r *release.Release, current bool) model.ReleaseHistory {
+	return model.ReleaseHistory{
+		Revision:    r.Version,
+		Updated:     r.Info.LastDeployed.Time,
+		Status:      string(r.Info.Status),
+		Chart:       r.Chart.Metadata.Version,
+		ChartName:   r.Chart.Metadata.Name,
+		AppVersion:  r.Chart.Metadata.AppVersion,
+		Description: r.Info.Description,
+		DeployedBy:  r.Labels[deployedByLabel],
+		Current:     current,
+	}
+}
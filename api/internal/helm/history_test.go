@@ -0,0 +1,207 @@
+package helm
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/helm-version-manager/api/internal/model"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+	kubefake "helm.sh/helm/v3/pkg/kube/fake"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage"
+	"helm.sh/helm/v3/pkg/storage/driver"
+	helmtime "helm.sh/helm/v3/pkg/time"
+)
+
+func newTestHistory(statuses ...release.Status) []*release.Release {
+	releases := make([]*release.Release, 0, len(statuses))
+	for i, status := range statuses {
+		releases = append(releases, &release.Release{
+			Name:    "myrelease",
+			Version: i + 1,
+			Info: &release.Info{
+				Status:       status,
+				LastDeployed: helmtime.Time{Time: time.Unix(int64(i), 0)},
+			},
+			Chart: &chart.Chart{Metadata: &chart.Metadata{Name: "mychart", Version: "1.0.0"}},
+		})
+	}
+	return releases
+}
+
+func TestPageHistory(t *testing.T) {
+	statuses := make([]release.Status, 0, 25)
+	for i := 0; i < 24; i++ {
+		statuses = append(statuses, release.StatusSuperseded)
+	}
+	statuses = append(statuses, release.StatusDeployed)
+
+	testCases := []struct {
+		name           string
+		max            int
+		before         int
+		wantRevisions  []int
+		wantNextBefore int
+	}{
+		{name: "default page", wantRevisions: []int{25, 24, 23, 22, 21, 20, 19, 18, 17, 16}, wantNextBefore: 16},
+		{name: "custom max", max: 3, wantRevisions: []int{25, 24, 23}, wantNextBefore: 23},
+		{name: "before cursor", max: 3, before: 23, wantRevisions: []int{22, 21, 20}, wantNextBefore: 20},
+		{name: "last page", max: 5, before: 4, wantRevisions: []int{3, 2, 1}},
+		{name: "exact fit", max: 3, before: 4, wantRevisions: []int{3, 2, 1}},
+		{name: "max larger than history", max: 100, wantNextBefore: 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			releases := newTestHistory(statuses...)
+			// Storage drivers return revisions in no particular order
+			releases[0], releases[10] = releases[10], releases[0]
+
+			page := pageHistory(releases, model.HistoryQuery{Max: tc.max, Before: tc.before})
+
+			if tc.wantRevisions != nil {
+				if len(page.History) != len(tc.wantRevisions) {
+					t.Fatalf("expected %d entries, got %d", len(tc.wantRevisions), len(page.History))
+				}
+				for i, rev := range tc.wantRevisions {
+					if page.History[i].Revision != rev {
+						t.Errorf("entry %d: expected revision %d, got %d", i, rev, page.History[i].Revision)
+					}
+				}
+			} else if len(page.History) != 25 {
+				t.Fatalf("expected all 25 entries, got %d", len(page.History))
+			}
+
+			if page.NextBefore != tc.wantNextBefore {
+				t.Errorf("expected next before %d, got %d", tc.wantNextBefore, page.NextBefore)
+			}
+		})
+	}
+}
+
+func TestPageHistoryEntryDetails(t *testing.T) {
+	releases := newTestHistory(release.StatusSuperseded, release.StatusDeployed, release.StatusFailed)
+	releases[1].Labels = map[string]string{deployedByLabel: "alice"}
+
+	page := pageHistory(releases, model.HistoryQuery{})
+
+	if len(page.History) != 3 {
+		t.Fatalf("expected 3 entries, got %d", len(page.History))
+	}
+
+	for _, h := range page.History {
+		if h.ChartName != "mychart" {
+			t.Errorf("revision %d: expected chart name mychart, got %q", h.Revision, h.ChartName)
+		}
+		if h.Current != (h.Revision == 2) {
+			t.Errorf("revision %d: unexpected current flag %v", h.Revision, h.Current)
+		}
+	}
+
+	if page.History[1].DeployedBy != "alice" {
+		t.Errorf("expected revision 2 deployed by alice, got %q", page.History[1].DeployedBy)
+	}
+	if page.History[0].DeployedBy != "" {
+		t.Errorf("expected no deployer for revision 3, got %q", page.History[0].DeployedBy)
+	}
+}
+
+func TestLabelRollback(t *testing.T) {
+	tests := []struct {
+		name       string
+		deployedBy string
+		want       string
+	}{
+		{name: "known caller", deployedBy: "carol@example.com", want: "carol_example.com"},
+		{name: "unknown caller", deployedBy: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &action.Configuration{
+				Releases:     storage.Init(driver.NewMemory()),
+				KubeClient:   &kubefake.PrintingKubeClient{Out: io.Discard},
+				Capabilities: chartutil.DefaultCapabilities,
+				Log:          func(string, ...interface{}) {},
+			}
+			for i, status := range []release.Status{release.StatusSuperseded, release.StatusDeployed} {
+				rel := &release.Release{
+					Name:      "web",
+					Namespace: "apps",
+					Version:   i + 1,
+					Info:      &release.Info{Status: status},
+					Chart:     &chart.Chart{Metadata: &chart.Metadata{Name: "web", Version: "1.0.0"}},
+					Labels:    map[string]string{deployedByLabel: []string{"alice", "bob"}[i], "team": "payments"},
+				}
+				if err := cfg.Releases.Create(rel); err != nil {
+					t.Fatalf("failed to store release: %v", err)
+				}
+			}
+
+			labelRollback(cfg, tt.deployedBy)
+			rollback := action.NewRollback(cfg)
+			rollback.Version = 1
+			if err := rollback.Run("web"); err != nil {
+				t.Fatalf("rollback failed: %v", err)
+			}
+
+			rolledBack, err := cfg.Releases.Get("web", 3)
+			if err != nil {
+				t.Fatalf("failed to get rolled back revision: %v", err)
+			}
+			if got := rolledBack.Labels[deployedByLabel]; got != tt.want {
+				t.Errorf("expected revision 3 deployed by %q, got %q", tt.want, got)
+			}
+			if rolledBack.Labels["team"] != "payments" {
+				t.Errorf("expected other labels to be kept, got %v", rolledBack.Labels)
+			}
+
+			target, err := cfg.Releases.Get("web", 1)
+			if err != nil {
+				t.Fatalf("failed to get revision 1: %v", err)
+			}
+			if target.Labels[deployedByLabel] != "alice" {
+				t.Errorf("expected revision 1 to stay deployed by alice, got %v", target.Labels)
+			}
+		})
+	}
+}
+
+func TestUpgradeLabels(t *testing.T) {
+	if got := upgradeLabels("alice"); !reflect.DeepEqual(got, map[string]string{deployedByLabel: "alice"}) {
+		t.Errorf("expected alice to be recorded, got %v", got)
+	}
+	// An unknown caller must not inherit the previous revision's deployer
+	if got := upgradeLabels(""); !reflect.DeepEqual(got, map[string]string{deployedByLabel: "null"}) {
+		t.Errorf("expected the label to be dropped, got %v", got)
+	}
+	if got := deployedByLabels(""); got != nil {
+		t.Errorf("expected no install labels for an unknown caller, got %v", got)
+	}
+}
+
+func TestLabelValue(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: "alice", want: "alice"},
+		{in: "mcp.claude-desktop", want: "mcp.claude-desktop"},
+		{in: "jane.doe@example.com", want: "jane.doe_example.com"},
+		{in: "CN=Jane Doe", want: "CN_Jane_Doe"},
+		{in: "_admin_", want: "admin"},
+		{in: "@@@", want: ""},
+		{in: strings.Repeat("a", 62) + "-b", want: strings.Repeat("a", 62)},
+	}
+
+	for _, tt := range tests {
+		if got := labelValue(tt.in); got != tt.want {
+			t.Errorf("labelValue(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
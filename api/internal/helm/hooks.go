@@ -0,0 +1,47 @@
+package helm
+
+import (
+	"time"
+
+	"github.com/helm-version-manager/api/internal/model"
+	"helm.sh/helm/v3/pkg/release"
+	helmtime "helm.sh/helm/v3/pkg/time"
+)
+
+func toModelHooks(hooks []*release.Hook) []model.ReleaseHook {
+	result := make([]model.ReleaseHook, 0, len(hooks))
+	for _, h := range hooks {
+		events := make([]string, 0, len(h.Events))
+		for _, e := range h.Events {
+			events = append(events, string(e))
+		}
+
+		var policies []string
+		for _, p := range h.DeletePolicies {
+			policies = append(policies, string(p))
+		}
+
+		result = append(result, model.ReleaseHook{
+			Name:           h.Name,
+			Kind:           h.Kind,
+			Path:           h.Path,
+			Events:         events,
+			Weight:         h.Weight,
+			DeletePolicies: policies,
+			LastRun: model.HookRun{
+				Phase:       string(h.LastRun.Phase),
+				StartedAt:   timeOrNil(h.LastRun.StartedAt),
+				CompletedAt: timeOrNil(h.LastRun.CompletedAt),
+			},
+			Manifest: h.Manifest,
+		})
+	}
+	return result
+}
+
+func timeOrNil(t helmtime.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t.Time
+}
@@ -0,0 +1,76 @@
+package helm
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"helm.sh/helm/v3/pkg/release"
+	helmtime "helm.sh/helm/v3/pkg/time"
+)
+
+func TestToModelHooks(t *testing.T) {
+	started := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	completed := started.Add(30 * time.Second)
+
+	hooks := toModelHooks([]*release.Hook{
+		{
+			Name:           "db-migrate",
+			Kind:           "Job",
+			Path:           "app/templates/migrate.yaml",
+			Manifest:       "kind: Job",
+			Events:         []release.HookEvent{release.HookPreInstall, release.HookPreUpgrade},
+			Weight:         -5,
+			DeletePolicies: []release.HookDeletePolicy{release.HookBeforeHookCreation},
+			LastRun: release.HookExecution{
+				StartedAt:   helmtime.Time{Time: started},
+				CompletedAt: helmtime.Time{Time: completed},
+				Phase:       release.HookPhaseSucceeded,
+			},
+		},
+		{
+			Name:   "smoke-test",
+			Kind:   "Pod",
+			Events: []release.HookEvent{release.HookTest},
+		},
+	})
+
+	if len(hooks) != 2 {
+		t.Fatalf("expected 2 hooks, got %d", len(hooks))
+	}
+
+	migrate := hooks[0]
+	if !reflect.DeepEqual(migrate.Events, []string{"pre-install", "pre-upgrade"}) {
+		t.Errorf("unexpected events %v", migrate.Events)
+	}
+	if !reflect.DeepEqual(migrate.DeletePolicies, []string{"before-hook-creation"}) {
+		t.Errorf("unexpected delete policies %v", migrate.DeletePolicies)
+	}
+	if migrate.Weight != -5 || migrate.Path != "app/templates/migrate.yaml" {
+		t.Errorf("unexpected hook %+v", migrate)
+	}
+	if migrate.LastRun.Phase != "Succeeded" {
+		t.Errorf("expected phase Succeeded, got %q", migrate.LastRun.Phase)
+	}
+	if migrate.LastRun.StartedAt == nil || !migrate.LastRun.StartedAt.Equal(started) {
+		t.Errorf("expected startedAt %v, got %v", started, migrate.LastRun.StartedAt)
+	}
+	if migrate.LastRun.CompletedAt == nil || !migrate.LastRun.CompletedAt.Equal(completed) {
+		t.Errorf("expected completedAt %v, got %v", completed, migrate.LastRun.CompletedAt)
+	}
+
+	smoke := hooks[1]
+	if smoke.LastRun.StartedAt != nil || smoke.LastRun.CompletedAt != nil || smoke.LastRun.Phase != "" {
+		t.Errorf("expected a hook that never ran to have an empty last run, got %+v", smoke.LastRun)
+	}
+	if smoke.DeletePolicies != nil {
+		t.Errorf("expected no delete policies, got %v", smoke.DeletePolicies)
+	}
+}
+
+func TestToModelHooksEmpty(t *testing.T) {
+	hooks := toModelHooks(nil)
+	if hooks == nil || len(hooks) != 0 {
+		t.Errorf("expected an empty, non-nil list, got %#v", hooks)
+	}
+}
@@ -0,0 +1,60 @@
+package helm
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/helm-version-manager/api/internal/model"
+	"helm.sh/helm/v3/pkg/releaseutil"
+	"sigs.k8s.io/yaml"
+)
+
+type objectHeader struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Metadata   struct {
+		Name      string `json:"name"`
+		Namespace string `json:"namespace"`
+	} `json:"metadata"`
+}
+
+// parseManifest splits a rendered release manifest into its Kubernetes
+// objects, preserving the order in which Helm rendered them. Documents that
+// are empty or not Kubernetes objects are skipped.
+func parseManifest(manifest string) []model.ManifestObject {
+	docs := releaseutil.SplitManifests(manifest)
+
+	keys := make([]string, 0, len(docs))
+	for k := range docs {
+		keys = append(keys, k)
+	}
+	sort.Sort(releaseutil.BySplitManifestsOrder(keys))
+
+	objects := make([]model.ManifestObject, 0, len(keys))
+	for _, k := range keys {
+		doc := strings.TrimSpace(docs[k])
+		if doc == "" {
+			continue
+		}
+
+		var header objectHeader
+		if err := yaml.Unmarshal([]byte(doc), &header); err != nil || header.Kind == "" {
+			continue
+		}
+
+		objects = append(objects, model.ManifestObject{
+			APIVersion: header.APIVersion,
+			Kind:       header.Kind,
+			Namespace:  header.Metadata.Namespace,
+			Name:       header.Metadata.Name,
+			Manifest:   doc + "\n",
+		})
+	}
+
+	return objects
+}
+
+// resourceKey identifies an object across two renderings of a release.
+func resourceKey(obj model.ManifestObject) string {
+	return obj.Kind + "/" + obj.Namespace + "/" + obj.Name
+}
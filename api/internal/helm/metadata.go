@@ -0,0 +1,60 @@
+package helm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/registry"
+	"oras.land/oras-go/v2/content"
+	"oras.land/oras-go/v2/registry/remote"
+)
+
+// fetchChartMetadata returns the Chart.yaml metadata stored in the config blob
+// of the chart manifest tagged tag. Results are cached by manifest digest, so
+// only the tag resolution hits the registry on repeated listings.
+func (c *Client) fetchChartMetadata(ctx context.Context, repo *remote.Repository, tag string) (*chart.Metadata, error) {
+	desc, err := repo.Resolve(ctx, tag)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve tag %s: %w", tag, err)
+	}
+
+	if cached, ok := c.chartMetadata.Load(desc.Digest); ok {
+		return cached.(*chart.Metadata), nil
+	}
+
+	manifestData, err := content.FetchAll(ctx, repo, desc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch manifest for %s: %w", tag, err)
+	}
+
+	var manifest ocispec.Manifest
+	if err := json.Unmarshal(manifestData, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal manifest for %s: %w", tag, err)
+	}
+
+	if manifest.Config.MediaType != registry.ConfigMediaType {
+		return nil, fmt.Errorf("manifest for %s is not a Helm chart (config media type %q)", tag, manifest.Config.MediaType)
+	}
+
+	configData, err := content.FetchAll(ctx, repo, manifest.Config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch chart config for %s: %w", tag, err)
+	}
+
+	var metadata chart.Metadata
+	if err := json.Unmarshal(configData, &metadata); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal chart config for %s: %w", tag, err)
+	}
+
+	c.chartMetadata.Store(desc.Digest, &metadata)
+	return &metadata, nil
+}
+
+// versionToTag converts a chart version into the OCI tag Helm pushes it under.
+func versionToTag(version string) string {
+	return strings.ReplaceAll(version, "+", "_")
+}
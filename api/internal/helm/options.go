@@ -0,0 +1,221 @@
+package helm
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/helm-version-manager/api/internal/model"
+	"helm.sh/helm/v3/pkg/action"
+)
+
+// defaultTimeout matches the Helm CLI default for hooks and waits.
+const defaultTimeout = 5 * time.Minute
+
+// parseTimeout parses a Go duration string, returning defaultTimeout when
+// it is empty.
+func parseTimeout(s string) (time.Duration, error) {
+	if s == "" {
+		return defaultTimeout, nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid timeout %q: %w", s, err)
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("invalid timeout %q: must be positive", s)
+	}
+
+	return d, nil
+}
+
+// Environment variables operators can set to change the upgrade defaults
+const (
+	envUpgradeAtomic        = "HELM_UI_UPGRADE_ATOMIC"
+	envUpgradeWait          = "HELM_UI_UPGRADE_WAIT"
+	envUpgradeWaitForJobs   = "HELM_UI_UPGRADE_WAIT_FOR_JOBS"
+	envUpgradeTimeout       = "HELM_UI_UPGRADE_TIMEOUT"
+	envUpgradeForce         = "HELM_UI_UPGRADE_FORCE"
+	envUpgradeDisableHooks  = "HELM_UI_UPGRADE_DISABLE_HOOKS"
+	envUpgradeCleanupOnFail = "HELM_UI_UPGRADE_CLEANUP_ON_FAIL"
+	envUpgradeMaxHistory    = "HELM_UI_UPGRADE_MAX_HISTORY"
+	envUpgradeVerify        = "HELM_UI_UPGRADE_VERIFY"
+	envUpgradeVerifyTimeout = "HELM_UI_UPGRADE_VERIFY_TIMEOUT"
+)
+
+// defaultMaxHistory matches the Helm CLI default for upgrades.
+const defaultMaxHistory = 10
+
+// upgradeDefaultsFromEnv reads the server-side upgrade defaults from the
+// environment. Unset variables leave the Helm defaults in place.
+func upgradeDefaultsFromEnv() (model.UpgradeOptions, error) {
+	var defaults model.UpgradeOptions
+
+	bools := []struct {
+		env   string
+		field **bool
+	}{
+		{envUpgradeAtomic, &defaults.Atomic},
+		{envUpgradeWait, &defaults.Wait},
+		{envUpgradeWaitForJobs, &defaults.WaitForJobs},
+		{envUpgradeForce, &defaults.Force},
+		{envUpgradeDisableHooks, &defaults.DisableHooks},
+		{envUpgradeCleanupOnFail, &defaults.CleanupOnFail},
+		{envUpgradeVerify, &defaults.Verify},
+	}
+	for _, b := range bools {
+		value := os.Getenv(b.env)
+		if value == "" {
+			continue
+		}
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return model.UpgradeOptions{}, fmt.Errorf("invalid %s %q: %w", b.env, value, err)
+		}
+		*b.field = &parsed
+	}
+
+	if value := os.Getenv(envUpgradeTimeout); value != "" {
+		if _, err := parseTimeout(value); err != nil {
+			return model.UpgradeOptions{}, fmt.Errorf("invalid %s: %w", envUpgradeTimeout, err)
+		}
+		defaults.Timeout = value
+	}
+
+	if value := os.Getenv(envUpgradeVerifyTimeout); value != "" {
+		if _, err := parseTimeout(value); err != nil {
+			return model.UpgradeOptions{}, fmt.Errorf("invalid %s: %w", envUpgradeVerifyTimeout, err)
+		}
+		defaults.VerifyTimeout = value
+	}
+
+	if value := os.Getenv(envUpgradeMaxHistory); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			return model.UpgradeOptions{}, fmt.Errorf("invalid %s %q: must be a non-negative integer", envUpgradeMaxHistory, value)
+		}
+		defaults.MaxHistory = &parsed
+	}
+
+	return defaults, nil
+}
+
+// withDefaults fills the fields of opts that the caller left unset from
+// defaults.
+func withDefaults(opts, defaults model.UpgradeOptions) model.UpgradeOptions {
+	if opts.Atomic == nil {
+		opts.Atomic = defaults.Atomic
+	}
+	if opts.Wait == nil {
+		opts.Wait = defaults.Wait
+	}
+	if opts.WaitForJobs == nil {
+		opts.WaitForJobs = defaults.WaitForJobs
+	}
+	if opts.Timeout == "" {
+		opts.Timeout = defaults.Timeout
+	}
+	if opts.Force == nil {
+		opts.Force = defaults.Force
+	}
+	if opts.DisableHooks == nil {
+		opts.DisableHooks = defaults.DisableHooks
+	}
+	if opts.CleanupOnFail == nil {
+		opts.CleanupOnFail = defaults.CleanupOnFail
+	}
+	if opts.MaxHistory == nil {
+		opts.MaxHistory = defaults.MaxHistory
+	}
+	if opts.Verify == nil {
+		opts.Verify = defaults.Verify
+	}
+	if opts.VerifyTimeout == "" {
+		opts.VerifyTimeout = defaults.VerifyTimeout
+	}
+	return opts
+}
+
+// withRollbackDefaults fills the fields of opts that the caller left unset
+// from the upgrade defaults.
+func withRollbackDefaults(opts model.RollbackOptions, defaults model.UpgradeOptions) model.RollbackOptions {
+	if opts.Wait == nil {
+		opts.Wait = defaults.Wait
+	}
+	if opts.WaitForJobs == nil {
+		opts.WaitForJobs = defaults.WaitForJobs
+	}
+	if opts.Timeout == "" {
+		opts.Timeout = defaults.Timeout
+	}
+	if opts.Force == nil {
+		opts.Force = defaults.Force
+	}
+	if opts.DisableHooks == nil {
+		opts.DisableHooks = defaults.DisableHooks
+	}
+	if opts.CleanupOnFail == nil {
+		opts.CleanupOnFail = defaults.CleanupOnFail
+	}
+	if opts.MaxHistory == nil {
+		opts.MaxHistory = defaults.MaxHistory
+	}
+	return opts
+}
+
+// applyUpgradeOptions configures an upgrade action from opts, which should
+// already have the server defaults applied.
+func applyUpgradeOptions(u *action.Upgrade, opts model.UpgradeOptions) error {
+	timeout, err := parseTimeout(opts.Timeout)
+	if err != nil {
+		return err
+	}
+
+	// Verification runs after Helm, so a bad timeout has to fail up front
+	if _, err := parseTimeout(opts.VerifyTimeout); err != nil {
+		return err
+	}
+
+	u.Timeout = timeout
+	u.Atomic = boolValue(opts.Atomic)
+	// Atomic upgrades have to wait to know whether to roll back, as in the Helm CLI
+	u.Wait = boolValue(opts.Wait) || u.Atomic
+	u.WaitForJobs = boolValue(opts.WaitForJobs)
+	u.Force = boolValue(opts.Force)
+	u.DisableHooks = boolValue(opts.DisableHooks)
+	u.CleanupOnFail = boolValue(opts.CleanupOnFail)
+	u.MaxHistory = defaultMaxHistory
+	if opts.MaxHistory != nil {
+		u.MaxHistory = *opts.MaxHistory
+	}
+
+	return nil
+}
+
+// applyRollbackOptions configures a rollback action from opts, which should
+// already have the server defaults applied.
+func applyRollbackOptions(r *action.Rollback, opts model.RollbackOptions) error {
+	timeout, err := parseTimeout(opts.Timeout)
+	if err != nil {
+		return err
+	}
+
+	r.Timeout = timeout
+	r.Wait = boolValue(opts.Wait)
+	r.WaitForJobs = boolValue(opts.WaitForJobs)
+	r.Force = boolValue(opts.Force)
+	r.DisableHooks = boolValue(opts.DisableHooks)
+	r.CleanupOnFail = boolValue(opts.CleanupOnFail)
+	r.MaxHistory = defaultMaxHistory
+	if opts.MaxHistory != nil {
+		r.MaxHistory = *opts.MaxHistory
+	}
+
+	return nil
+}
+
+func boolValue(b *bool) bool {
+	return b != nil && *b
+}
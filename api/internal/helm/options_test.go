@@ -0,0 +1,201 @@
+package helm
+
+import (
+	"testing"
+	"time"
+
+	"github.com/helm-version-manager/api/internal/model"
+	"helm.sh/helm/v3/pkg/action"
+)
+
+func TestParseTimeout(t *testing.T) {
+	testCases := []struct {
+		input   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{input: "", want: defaultTimeout},
+		{input: "90s", want: 90 * time.Second},
+		{input: "10m", want: 10 * time.Minute},
+		{input: "0s", wantErr: true},
+		{input: "-1m", wantErr: true},
+		{input: "five minutes", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			got, err := parseTimeout(tc.input)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("expected error for %q", tc.input)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("expected %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestUpgradeDefaultsFromEnv(t *testing.T) {
+	t.Setenv(envUpgradeAtomic, "true")
+	t.Setenv(envUpgradeWaitForJobs, "false")
+	t.Setenv(envUpgradeTimeout, "15m")
+	t.Setenv(envUpgradeMaxHistory, "20")
+	t.Setenv(envUpgradeVerify, "true")
+	t.Setenv(envUpgradeVerifyTimeout, "10m")
+
+	defaults, err := upgradeDefaultsFromEnv()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if defaults.Atomic == nil || !*defaults.Atomic {
+		t.Error("expected atomic default true")
+	}
+	if defaults.WaitForJobs == nil || *defaults.WaitForJobs {
+		t.Error("expected waitForJobs default false")
+	}
+	if defaults.Wait != nil {
+		t.Error("expected wait default to be unset")
+	}
+	if defaults.Timeout != "15m" {
+		t.Errorf("expected timeout 15m, got %q", defaults.Timeout)
+	}
+	if defaults.MaxHistory == nil || *defaults.MaxHistory != 20 {
+		t.Errorf("expected max history 20, got %v", defaults.MaxHistory)
+	}
+	if defaults.Verify == nil || !*defaults.Verify {
+		t.Error("expected verify default true")
+	}
+	if defaults.VerifyTimeout != "10m" {
+		t.Errorf("expected verify timeout 10m, got %q", defaults.VerifyTimeout)
+	}
+}
+
+func TestUpgradeDefaultsFromEnvInvalid(t *testing.T) {
+	testCases := map[string]string{
+		envUpgradeForce:         "maybe",
+		envUpgradeTimeout:       "soon",
+		envUpgradeMaxHistory:    "-1",
+		envUpgradeVerifyTimeout: "0s",
+	}
+
+	for env, value := range testCases {
+		t.Run(env, func(t *testing.T) {
+			t.Setenv(env, value)
+			if _, err := upgradeDefaultsFromEnv(); err == nil {
+				t.Fatalf("expected error for %s=%s", env, value)
+			}
+		})
+	}
+}
+
+func TestWithDefaults(t *testing.T) {
+	yes, no := true, false
+	five, twenty := 5, 20
+
+	defaults := model.UpgradeOptions{Atomic: &yes, Wait: &yes, Timeout: "15m", MaxHistory: &twenty, Verify: &yes, VerifyTimeout: "10m"}
+	opts := model.UpgradeOptions{Atomic: &no, MaxHistory: &five, Verify: &no}
+
+	got := withDefaults(opts, defaults)
+
+	if got.Atomic == nil || *got.Atomic {
+		t.Error("expected explicit atomic=false to override the default")
+	}
+	if got.Wait == nil || !*got.Wait {
+		t.Error("expected wait to fall back to the default")
+	}
+	if got.Timeout != "15m" {
+		t.Errorf("expected timeout to fall back to 15m, got %q", got.Timeout)
+	}
+	if *got.MaxHistory != 5 {
+		t.Errorf("expected explicit max history 5, got %d", *got.MaxHistory)
+	}
+	if got.Force != nil {
+		t.Error("expected force to stay unset")
+	}
+	if got.Verify == nil || *got.Verify {
+		t.Error("expected explicit verify=false to override the default")
+	}
+	if got.VerifyTimeout != "10m" {
+		t.Errorf("expected verify timeout to fall back to 10m, got %q", got.VerifyTimeout)
+	}
+}
+
+func TestWithRollbackDefaults(t *testing.T) {
+	yes, no := true, false
+	five, twenty := 5, 20
+
+	defaults := model.UpgradeOptions{Atomic: &yes, Wait: &yes, Timeout: "15m", MaxHistory: &twenty}
+	got := withRollbackDefaults(model.RollbackOptions{Force: &no, MaxHistory: &five}, defaults)
+
+	if got.Wait == nil || !*got.Wait || got.Timeout != "15m" {
+		t.Errorf("expected wait and timeout to fall back to the defaults, got %+v", got)
+	}
+	if got.Force == nil || *got.Force || *got.MaxHistory != 5 {
+		t.Errorf("expected explicit force and max history to override the defaults, got %+v", got)
+	}
+}
+
+func TestApplyUpgradeOptions(t *testing.T) {
+	yes := true
+	zero := 0
+
+	u := action.NewUpgrade(&action.Configuration{})
+	err := applyUpgradeOptions(u, model.UpgradeOptions{
+		Atomic:        &yes,
+		WaitForJobs:   &yes,
+		Timeout:       "2m",
+		Force:         &yes,
+		DisableHooks:  &yes,
+		CleanupOnFail: &yes,
+		MaxHistory:    &zero,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !u.Atomic || !u.Wait {
+		t.Error("expected atomic to imply wait")
+	}
+	if !u.WaitForJobs || !u.Force || !u.DisableHooks || !u.CleanupOnFail {
+		t.Errorf("expected flags to be set, got %+v", u)
+	}
+	if u.Timeout != 2*time.Minute {
+		t.Errorf("expected timeout 2m, got %v", u.Timeout)
+	}
+	if u.MaxHistory != 0 {
+		t.Errorf("expected max history 0, got %d", u.MaxHistory)
+	}
+
+	// Unset options fall back to the Helm CLI defaults
+	u = action.NewUpgrade(&action.Configuration{})
+	if err := applyUpgradeOptions(u, model.UpgradeOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if u.Wait || u.Atomic || u.Timeout != defaultTimeout || u.MaxHistory != defaultMaxHistory {
+		t.Errorf("expected Helm defaults, got wait=%v atomic=%v timeout=%v maxHistory=%d", u.Wait, u.Atomic, u.Timeout, u.MaxHistory)
+	}
+
+	if err := applyUpgradeOptions(u, model.UpgradeOptions{Timeout: "never"}); err == nil {
+		t.Fatal("expected error for invalid timeout")
+	}
+}
+
+func TestApplyRollbackOptions(t *testing.T) {
+	yes := true
+
+	r := action.NewRollback(&action.Configuration{})
+	if err := applyRollbackOptions(r, model.RollbackOptions{Wait: &yes, Timeout: "1m"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !r.Wait || r.Timeout != time.Minute || r.MaxHistory != defaultMaxHistory {
+		t.Errorf("unexpected rollback options: wait=%v timeout=%v maxHistory=%d", r.Wait, r.Timeout, r.MaxHistory)
+	}
+}
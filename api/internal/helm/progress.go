@@ -0,0 +1,43 @@
+package helm
+
+import (
+	"time"
+
+	"github.com/helm-version-manager/api/internal/model"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/kube"
+)
+
+// progressKubeClient reports when Helm starts applying resources and when it
+// starts waiting for them to become ready. It embeds the concrete client so
+// the optional kube interfaces Helm type-asserts on remain available.
+type progressKubeClient struct {
+	*kube.Client
+	progress model.ProgressFunc
+}
+
+func (c *progressKubeClient) Update(original, target kube.ResourceList, force bool) (*kube.Result, error) {
+	c.progress.Report(model.PhaseApply, "Applying %d resources", len(target))
+	return c.Client.Update(original, target, force)
+}
+
+func (c *progressKubeClient) Wait(resources kube.ResourceList, timeout time.Duration) error {
+	c.progress.Report(model.PhaseWait, "Waiting up to %s for %d resources to become ready", timeout, len(resources))
+	return c.Client.Wait(resources, timeout)
+}
+
+func (c *progressKubeClient) WaitWithJobs(resources kube.ResourceList, timeout time.Duration) error {
+	c.progress.Report(model.PhaseWait, "Waiting up to %s for %d resources and jobs to become ready", timeout, len(resources))
+	return c.Client.WaitWithJobs(resources, timeout)
+}
+
+// withProgress makes the actions run with actionConfig report the apply and
+// wait phases to progress
+func withProgress(actionConfig *action.Configuration, progress model.ProgressFunc) {
+	if progress == nil {
+		return
+	}
+	if kc, ok := actionConfig.KubeClient.(*kube.Client); ok {
+		actionConfig.KubeClient = &progressKubeClient{Client: kc, progress: progress}
+	}
+}
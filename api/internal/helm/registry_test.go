@@ -0,0 +1,209 @@
+package helm
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/registry"
+)
+
+// testRegistry is a minimal in-process OCI distribution registry serving
+// Helm charts for tests.
+type testRegistry struct {
+	server *httptest.Server
+
+	mu                 sync.Mutex
+	manifests          map[string]map[string][]byte // repository -> tag or digest -> manifest
+	blobs              map[digest.Digest][]byte
+	manifestFetchCount int
+}
+
+func newTestRegistry(t *testing.T) *testRegistry {
+	return newAuthTestRegistry(t, "", "")
+}
+
+// newAuthTestRegistry is newTestRegistry requiring basic auth with username
+// and password when username is set
+func newAuthTestRegistry(t *testing.T, username, password string) *testRegistry {
+	t.Helper()
+
+	r := &testRegistry{
+		manifests: make(map[string]map[string][]byte),
+		blobs:     make(map[digest.Digest][]byte),
+	}
+	var handler http.Handler = http.HandlerFunc(r.serveHTTP)
+	if username != "" {
+		handler = requireBasicAuth(username, password, handler)
+	}
+	r.server = httptest.NewServer(handler)
+	t.Cleanup(r.server.Close)
+
+	return r
+}
+
+// requireBasicAuth rejects requests without the given credentials with a
+// basic auth challenge, like a private registry or repository
+func requireBasicAuth(username, password string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		u, p, ok := req.BasicAuth()
+		if !ok || u != username || p != password {
+			w.Header().Set("WWW-Authenticate", `Basic realm="test"`)
+			http.Error(w, `{"errors":[{"code":"UNAUTHORIZED"}]}`, http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, req)
+	})
+}
+
+// host returns the registry address without scheme, e.g. 127.0.0.1:12345.
+func (r *testRegistry) host() string {
+	return strings.TrimPrefix(r.server.URL, "http://")
+}
+
+// pushChart packages a chart with the given metadata and stores it under
+// repository/<name>:<version>, the way `helm push` does.
+func (r *testRegistry) pushChart(t *testing.T, repository string, metadata *chart.Metadata) {
+	t.Helper()
+
+	chartData, err := os.ReadFile(packageTestChart(t, t.TempDir(), metadata))
+	if err != nil {
+		t.Fatalf("failed to read chart package: %v", err)
+	}
+
+	configData, err := json.Marshal(metadata)
+	if err != nil {
+		t.Fatalf("failed to marshal chart metadata: %v", err)
+	}
+
+	manifest := ocispec.Manifest{
+		MediaType: ocispec.MediaTypeImageManifest,
+		Config:    r.addBlob(registry.ConfigMediaType, configData),
+		Layers:    []ocispec.Descriptor{r.addBlob(registry.ChartLayerMediaType, chartData)},
+	}
+	manifest.SchemaVersion = 2
+
+	manifestData, err := json.Marshal(manifest)
+	if err != nil {
+		t.Fatalf("failed to marshal manifest: %v", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	repo := repository + "/" + metadata.Name
+	if r.manifests[repo] == nil {
+		r.manifests[repo] = make(map[string][]byte)
+	}
+	r.manifests[repo][versionToTag(metadata.Version)] = manifestData
+	r.manifests[repo][digest.FromBytes(manifestData).String()] = manifestData
+}
+
+// packageTestChart writes a chart archive with the given metadata into dir and
+// returns its path.
+func packageTestChart(t *testing.T, dir string, metadata *chart.Metadata) string {
+	t.Helper()
+
+	ch := &chart.Chart{
+		Metadata: metadata,
+		Templates: []*chart.File{
+			{Name: "templates/configmap.yaml", Data: []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: {{ .Release.Name }}\ndata:\n  version: {{ .Chart.Version | quote }}\n")},
+		},
+		Values: map[string]any{},
+	}
+
+	path, err := chartutil.Save(ch, dir)
+	if err != nil {
+		t.Fatalf("failed to package chart: %v", err)
+	}
+
+	return path
+}
+
+func (r *testRegistry) addBlob(mediaType string, data []byte) ocispec.Descriptor {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	d := digest.FromBytes(data)
+	r.blobs[d] = data
+
+	return ocispec.Descriptor{
+		MediaType: mediaType,
+		Digest:    d,
+		Size:      int64(len(data)),
+	}
+}
+
+func (r *testRegistry) manifestFetches() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.manifestFetchCount
+}
+
+func (r *testRegistry) serveHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.URL.Path == "/v2/" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	path := strings.TrimPrefix(req.URL.Path, "/v2/")
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch {
+	case strings.HasSuffix(path, "/tags/list"):
+		repo := strings.TrimSuffix(path, "/tags/list")
+		tags := []string{}
+		for ref := range r.manifests[repo] {
+			if !strings.HasPrefix(ref, "sha256:") {
+				tags = append(tags, ref)
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"name": repo, "tags": tags})
+
+	case strings.Contains(path, "/manifests/"):
+		i := strings.LastIndex(path, "/manifests/")
+		repo, ref := path[:i], path[i+len("/manifests/"):]
+		data, ok := r.manifests[repo][ref]
+		if !ok {
+			http.Error(w, `{"errors":[{"code":"MANIFEST_UNKNOWN"}]}`, http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", ocispec.MediaTypeImageManifest)
+		w.Header().Set("Docker-Content-Digest", digest.FromBytes(data).String())
+		w.Header().Set("Content-Length", fmt.Sprint(len(data)))
+		if req.Method == http.MethodHead {
+			return
+		}
+		r.manifestFetchCount++
+		_, _ = w.Write(data)
+
+	case strings.Contains(path, "/blobs/"):
+		i := strings.LastIndex(path, "/blobs/")
+		data, ok := r.blobs[digest.Digest(path[i+len("/blobs/"):])]
+		if !ok {
+			http.Error(w, `{"errors":[{"code":"BLOB_UNKNOWN"}]}`, http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Length", fmt.Sprint(len(data)))
+		if req.Method == http.MethodHead {
+			return
+		}
+		_, _ = w.Write(data)
+
+	default:
+		http.NotFound(w, req)
+	}
+}
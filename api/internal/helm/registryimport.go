@@ -0,0 +1,186 @@
+package helm
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/helm-version-manager/api/internal/model"
+	"github.com/helm-version-manager/api/internal/storage"
+)
+
+// CheckRegistry verifies that registry can be reached and serves at least
+// one version of chartName
+func (c *Client) CheckRegistry(registry, chartName string) error {
+	versions, err := c.searchChartVersions(registry, chartName, model.VersionQuery{Limit: 1, IncludePrerelease: true})
+	if err != nil {
+		return fmt.Errorf("registry %s is not reachable: %w", registry, err)
+	}
+	if len(versions) == 0 {
+		return fmt.Errorf("registry %s has no versions of chart %s", registry, chartName)
+	}
+	return nil
+}
+
+// ImportRegistryMappings sets the explicit registry mappings exported from
+// another cluster. Every release must be installed here and every registry
+// must serve the release's chart; if any entry is invalid nothing is
+// written. In replace mode explicit mappings missing from mappings are
+// deleted.
+func (c *Client) ImportRegistryMappings(ctx context.Context, mappings []model.RegistryMapping, opts model.RegistryImportOptions) (*model.RegistryImportReport, error) {
+	return importRegistryMappings(ctx, c.registryStore, mappings, opts, c.releaseChart, c.CheckRegistry)
+}
+
+func (c *Client) releaseChart(namespace, name string) (string, error) {
+	release, err := c.GetRelease(namespace, name)
+	if err != nil {
+		return "", err
+	}
+	return release.Chart, nil
+}
+
+// importRegistryMappings implements ImportRegistryMappings. releaseChart
+// returns the chart name of an installed release and checkRegistry
+// verifies that a registry serves a chart.
+func importRegistryMappings(
+	ctx context.Context,
+	store storage.MappingStore,
+	mappings []model.RegistryMapping,
+	opts model.RegistryImportOptions,
+	releaseChart func(namespace, name string) (string, error),
+	checkRegistry func(registry, chartName string) error,
+) (*model.RegistryImportReport, error) {
+	mode := opts.Mode
+	switch mode {
+	case "":
+		mode = model.RegistryImportMerge
+	case model.RegistryImportMerge, model.RegistryImportReplace:
+	default:
+		return nil, fmt.Errorf("invalid import mode %q, expected %q or %q", mode, model.RegistryImportMerge, model.RegistryImportReplace)
+	}
+
+	stored, err := store.ListMappings(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list registry mappings: %w", err)
+	}
+	existing := make(map[string]model.RegistryMapping)
+	for _, mapping := range storage.ExplicitMappings(stored) {
+		existing[mapping.Namespace+"/"+mapping.ReleaseName] = mapping
+	}
+
+	report := &model.RegistryImportReport{
+		Mode:    mode,
+		DryRun:  opts.DryRun,
+		Results: make([]model.RegistryImportResult, 0, len(mappings)),
+		Counts:  map[string]int{},
+	}
+
+	// Registries are checked once per chart, however many releases use them
+	checked := make(map[string]error)
+	imported := make(map[string]bool, len(mappings))
+	valid := true
+	for _, mapping := range mappings {
+		result := model.RegistryImportResult{
+			Namespace:   mapping.Namespace,
+			ReleaseName: mapping.ReleaseName,
+			ChartName:   mapping.ChartName,
+			Registry:    mapping.Registry,
+		}
+		key := mapping.Namespace + "/" + mapping.ReleaseName
+
+		result.Action, result.Error = func() (string, string) {
+			if mapping.Namespace == "" || mapping.ReleaseName == "" || mapping.Registry == "" {
+				return model.RegistryImportInvalid, "namespace, releaseName and registry are required"
+			}
+			if imported[key] {
+				return model.RegistryImportInvalid, fmt.Sprintf("release %s is listed more than once", key)
+			}
+			imported[key] = true
+
+			chartName, err := releaseChart(mapping.Namespace, mapping.ReleaseName)
+			if err != nil {
+				return model.RegistryImportInvalid, err.Error()
+			}
+			// The installed chart wins over the exported one, as in SetRegistry
+			result.ChartName = chartName
+
+			checkKey := mapping.Registry + "\x00" + chartName
+			if _, ok := checked[checkKey]; !ok {
+				checked[checkKey] = checkRegistry(mapping.Registry, chartName)
+			}
+			if err := checked[checkKey]; err != nil {
+				return model.RegistryImportInvalid, err.Error()
+			}
+
+			current, ok := existing[key]
+			switch {
+			case !ok:
+				return model.RegistryImportCreate, ""
+			case current.Registry == mapping.Registry && current.ChartName == chartName:
+				return model.RegistryImportUnchanged, ""
+			default:
+				return model.RegistryImportUpdate, ""
+			}
+		}()
+		if result.Action == model.RegistryImportInvalid {
+			valid = false
+		}
+
+		report.Results = append(report.Results, result)
+	}
+
+	if mode == model.RegistryImportReplace {
+		var deleted []model.RegistryImportResult
+		for key, mapping := range existing {
+			if imported[key] {
+				continue
+			}
+			deleted = append(deleted, model.RegistryImportResult{
+				Namespace:   mapping.Namespace,
+				ReleaseName: mapping.ReleaseName,
+				ChartName:   mapping.ChartName,
+				Registry:    mapping.Registry,
+				Action:      model.RegistryImportDelete,
+			})
+		}
+		sort.Slice(deleted, func(i, j int) bool {
+			if deleted[i].Namespace != deleted[j].Namespace {
+				return deleted[i].Namespace < deleted[j].Namespace
+			}
+			return deleted[i].ReleaseName < deleted[j].ReleaseName
+		})
+		report.Results = append(report.Results, deleted...)
+	}
+
+	if valid && !opts.DryRun {
+		failed := false
+		for i := range report.Results {
+			result := &report.Results[i]
+
+			var err error
+			switch result.Action {
+			case model.RegistryImportCreate, model.RegistryImportUpdate:
+				err = store.SetMapping(ctx, model.RegistryMapping{
+					Namespace:   result.Namespace,
+					ReleaseName: result.ReleaseName,
+					ChartName:   result.ChartName,
+					Registry:    result.Registry,
+				})
+			case model.RegistryImportDelete:
+				err = store.DeleteMapping(ctx, result.Namespace, result.ReleaseName)
+			}
+			if err != nil {
+				result.Action = model.RegistryImportFailed
+				result.Error = err.Error()
+				failed = true
+			}
+		}
+		report.Applied = !failed
+	}
+
+	for _, result := range report.Results {
+		report.Counts[result.Action]++
+	}
+
+	return report, nil
+}
@@ -0,0 +1,292 @@
+package helm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/helm-version-manager/api/internal/model"
+	"helm.sh/helm/v3/pkg/chart"
+)
+
+// fakeMappingStore keeps explicit mappings in memory and reports them with
+// the source DiscoveringStore gives them
+type fakeMappingStore struct {
+	mappings map[string]model.RegistryMapping
+	// discovered are listed beside the explicit mappings but never stored
+	discovered []model.RegistryMapping
+	// failing are the releases whose mappings cannot be written
+	failing map[string]bool
+}
+
+func (s *fakeMappingStore) GetMapping(ctx context.Context, namespace, releaseName string) (*model.RegistryMapping, error) {
+	if mapping, ok := s.mappings[namespace+"/"+releaseName]; ok {
+		return &mapping, nil
+	}
+	return nil, nil
+}
+
+func (s *fakeMappingStore) SetMapping(ctx context.Context, mapping model.RegistryMapping) error {
+	if s.failing[mapping.Namespace+"/"+mapping.ReleaseName] {
+		return errors.New("configmap update conflict")
+	}
+	s.mappings[mapping.Namespace+"/"+mapping.ReleaseName] = mapping
+	return nil
+}
+
+func (s *fakeMappingStore) DeleteMapping(ctx context.Context, namespace, releaseName string) error {
+	if s.failing[namespace+"/"+releaseName] {
+		return errors.New("configmap update conflict")
+	}
+	delete(s.mappings, namespace+"/"+releaseName)
+	return nil
+}
+
+func (s *fakeMappingStore) ListMappings(ctx context.Context) ([]model.RegistryMapping, error) {
+	result := append([]model.RegistryMapping{}, s.discovered...)
+	for _, mapping := range s.mappings {
+		mapping.Source = model.RegistrySourceExplicit
+		result = append(result, mapping)
+	}
+	return result, nil
+}
+
+func (s *fakeMappingStore) GetRule(ctx context.Context, id string) (*model.RegistryRule, error) {
+	return nil, nil
+}
+
+func (s *fakeMappingStore) SetRule(ctx context.Context, rule model.RegistryRule) error {
+	return nil
+}
+
+func (s *fakeMappingStore) DeleteRule(ctx context.Context, id string) error {
+	return nil
+}
+
+func (s *fakeMappingStore) ListRules(ctx context.Context) ([]model.RegistryRule, error) {
+	return nil, nil
+}
+
+func TestImportRegistryMappings(t *testing.T) {
+	const (
+		registry = "oci://ghcr.io/example/charts"
+		mirror   = "oci://mirror.example.com/charts"
+		broken   = "oci://unreachable.example.com/charts"
+	)
+
+	// Installed releases and their charts
+	releases := map[string]string{
+		"apps/web":    "web",
+		"apps/api":    "api",
+		"apps/worker": "worker",
+	}
+	releaseChart := func(namespace, name string) (string, error) {
+		if chartName, ok := releases[namespace+"/"+name]; ok {
+			return chartName, nil
+		}
+		return "", fmt.Errorf("failed to get release %s/%s: release: not found", namespace, name)
+	}
+
+	newStore := func() *fakeMappingStore {
+		return &fakeMappingStore{
+			mappings: map[string]model.RegistryMapping{
+				"apps/web":    {Namespace: "apps", ReleaseName: "web", ChartName: "web", Registry: registry},
+				"apps/api":    {Namespace: "apps", ReleaseName: "api", ChartName: "api", Registry: registry},
+				"other/stale": {Namespace: "other", ReleaseName: "stale", ChartName: "stale", Registry: registry},
+			},
+			discovered: []model.RegistryMapping{
+				{Namespace: "apps", ReleaseName: "worker", ChartName: "worker", Registry: registry, Source: model.RegistrySourceNamespaceDefault},
+			},
+		}
+	}
+
+	imported := []model.RegistryMapping{
+		{Namespace: "apps", ReleaseName: "web", Registry: registry},
+		{Namespace: "apps", ReleaseName: "api", ChartName: "api", Registry: mirror},
+		{Namespace: "apps", ReleaseName: "worker", ChartName: "worker", Registry: mirror},
+	}
+
+	testCases := []struct {
+		name         string
+		mappings     []model.RegistryMapping
+		opts         model.RegistryImportOptions
+		failing      map[string]bool
+		wantApplied  bool
+		wantActions  []string
+		wantMappings map[string]string
+	}{
+		{
+			name:        "merge",
+			mappings:    imported,
+			wantApplied: true,
+			wantActions: []string{model.RegistryImportUnchanged, model.RegistryImportUpdate, model.RegistryImportCreate},
+			wantMappings: map[string]string{
+				"apps/web":    registry,
+				"apps/api":    mirror,
+				"apps/worker": mirror,
+				"other/stale": registry,
+			},
+		},
+		{
+			name:        "replace deletes missing explicit mappings",
+			mappings:    imported,
+			opts:        model.RegistryImportOptions{Mode: model.RegistryImportReplace},
+			wantApplied: true,
+			wantActions: []string{model.RegistryImportUnchanged, model.RegistryImportUpdate, model.RegistryImportCreate, model.RegistryImportDelete},
+			wantMappings: map[string]string{
+				"apps/web":    registry,
+				"apps/api":    mirror,
+				"apps/worker": mirror,
+			},
+		},
+		{
+			name:        "failed writes",
+			mappings:    imported,
+			opts:        model.RegistryImportOptions{Mode: model.RegistryImportReplace},
+			failing:     map[string]bool{"apps/worker": true, "other/stale": true},
+			wantActions: []string{model.RegistryImportUnchanged, model.RegistryImportUpdate, model.RegistryImportFailed, model.RegistryImportFailed},
+			wantMappings: map[string]string{
+				"apps/web":    registry,
+				"apps/api":    mirror,
+				"other/stale": registry,
+			},
+		},
+		{
+			name:        "dry run",
+			mappings:    imported,
+			opts:        model.RegistryImportOptions{Mode: model.RegistryImportReplace, DryRun: true},
+			wantActions: []string{model.RegistryImportUnchanged, model.RegistryImportUpdate, model.RegistryImportCreate, model.RegistryImportDelete},
+			wantMappings: map[string]string{
+				"apps/web":    registry,
+				"apps/api":    registry,
+				"other/stale": registry,
+			},
+		},
+		{
+			name: "invalid entries",
+			mappings: []model.RegistryMapping{
+				{Namespace: "apps", ReleaseName: "api", Registry: mirror},
+				{Namespace: "apps", ReleaseName: "missing", Registry: mirror},
+				{Namespace: "apps", ReleaseName: "worker", Registry: broken},
+				{Namespace: "apps", ReleaseName: "api", Registry: registry},
+				{Namespace: "apps", ReleaseName: "web"},
+			},
+			wantActions: []string{
+				model.RegistryImportUpdate,
+				model.RegistryImportInvalid,
+				model.RegistryImportInvalid,
+				model.RegistryImportInvalid,
+				model.RegistryImportInvalid,
+			},
+			wantMappings: map[string]string{
+				"apps/web":    registry,
+				"apps/api":    registry,
+				"other/stale": registry,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := newStore()
+			store.failing = tc.failing
+			checkRegistry := func(reg, chartName string) error {
+				if reg == broken {
+					return errors.New("registry is not reachable")
+				}
+				return nil
+			}
+
+			report, err := importRegistryMappings(context.Background(), store, tc.mappings, tc.opts, releaseChart, checkRegistry)
+			if err != nil {
+				t.Fatalf("importRegistryMappings failed: %v", err)
+			}
+
+			if report.Applied != tc.wantApplied {
+				t.Errorf("expected applied %v, got %v", tc.wantApplied, report.Applied)
+			}
+			var actions []string
+			for _, result := range report.Results {
+				actions = append(actions, result.Action)
+				if (result.Action == model.RegistryImportInvalid || result.Action == model.RegistryImportFailed) && result.Error == "" {
+					t.Errorf("expected an error for %s entry %s/%s", result.Action, result.Namespace, result.ReleaseName)
+				}
+			}
+			if !reflect.DeepEqual(actions, tc.wantActions) {
+				t.Errorf("expected actions %v, got %v", tc.wantActions, actions)
+			}
+
+			got := make(map[string]string, len(store.mappings))
+			for key, mapping := range store.mappings {
+				got[key] = mapping.Registry
+			}
+			if !reflect.DeepEqual(got, tc.wantMappings) {
+				t.Errorf("expected mappings %v, got %v", tc.wantMappings, got)
+			}
+		})
+	}
+}
+
+func TestImportRegistryMappingsUsesInstalledChart(t *testing.T) {
+	store := &fakeMappingStore{mappings: map[string]model.RegistryMapping{}}
+	releaseChart := func(namespace, name string) (string, error) { return "web", nil }
+	var checked []string
+	checkRegistry := func(reg, chartName string) error {
+		checked = append(checked, chartName)
+		return nil
+	}
+
+	mappings := []model.RegistryMapping{
+		{Namespace: "apps", ReleaseName: "web", ChartName: "old-web", Registry: "oci://ghcr.io/example/charts"},
+		{Namespace: "apps", ReleaseName: "web-canary", Registry: "oci://ghcr.io/example/charts"},
+	}
+	if _, err := importRegistryMappings(context.Background(), store, mappings, model.RegistryImportOptions{}, releaseChart, checkRegistry); err != nil {
+		t.Fatalf("importRegistryMappings failed: %v", err)
+	}
+
+	if got := store.mappings["apps/web"].ChartName; got != "web" {
+		t.Errorf("expected the installed chart name web, got %q", got)
+	}
+	if !reflect.DeepEqual(checked, []string{"web"}) {
+		t.Errorf("expected one check of chart web, got %v", checked)
+	}
+}
+
+func TestImportRegistryMappingsRejectsUnknownMode(t *testing.T) {
+	store := &fakeMappingStore{mappings: map[string]model.RegistryMapping{}}
+	_, err := importRegistryMappings(context.Background(), store, nil, model.RegistryImportOptions{Mode: "append"}, nil, nil)
+	if err == nil {
+		t.Fatal("expected an error for an unknown mode")
+	}
+}
+
+func TestCheckRegistry(t *testing.T) {
+	reg := newTestRegistry(t)
+	reg.pushChart(t, "charts", &chart.Metadata{APIVersion: chart.APIVersionV2, Name: "mychart", Version: "1.0.0-rc.1"})
+	repoURL := newTestRepository(t, &chart.Metadata{APIVersion: chart.APIVersionV2, Name: "mychart", Version: "1.0.0"})
+
+	c := newTestRepositoryClient(t)
+	c.plainHTTP = true
+	ociURL := "oci://" + reg.host() + "/charts"
+
+	testCases := []struct {
+		registry  string
+		chartName string
+		wantErr   bool
+	}{
+		{ociURL, "mychart", false},
+		{ociURL, "other", true},
+		{repoURL, "mychart", false},
+		{repoURL, "other", true},
+		{"oci://127.0.0.1:1/charts", "mychart", true},
+	}
+
+	for _, tc := range testCases {
+		err := c.CheckRegistry(tc.registry, tc.chartName)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("CheckRegistry(%q, %q) error = %v, wantErr %v", tc.registry, tc.chartName, err, tc.wantErr)
+		}
+	}
+}
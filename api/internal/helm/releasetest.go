@@ -0,0 +1,267 @@
+package helm
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/helm-version-manager/api/internal/model"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/release"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// testPhaseNotRun is reported for tests that were selected but did not run
+// because an earlier test failed
+const testPhaseNotRun = "NotRun"
+
+// maxTestLogBytes caps the logs captured per test pod
+const maxTestLogBytes = 64 << 10
+
+// RunReleaseTests runs the test hooks of the latest revision of a release,
+// like helm test, and records the outcome in the audit log. It returns the
+// release with the outcome of the tests attached; failing tests are reported
+// there rather than as an error. progress, which may be nil, receives the
+// test phase.
+func (c *Client) RunReleaseTests(ctx context.Context, namespace, name string, req model.TestRequest, progress model.ProgressFunc) (*model.Release, error) {
+	actionConfig, err := c.getActionConfig(namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := action.NewGet(actionConfig).Run(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get release %s/%s: %w", namespace, name, err)
+	}
+	progress.Report(model.PhaseTest, "Running the tests of revision %d", r.Version)
+
+	result, err := runTests(ctx, actionConfig, namespace, name, req)
+	if err != nil {
+		return nil, err
+	}
+
+	outcome := model.TestsPassed
+	if !result.Passed {
+		outcome = model.TestsFailed
+	}
+	c.recordAudit(model.AuditRecord{
+		Time:      result.CompletedAt,
+		Namespace: namespace,
+		Name:      name,
+		Action:    model.AuditActionTest,
+		Revision:  result.Revision,
+		Outcome:   outcome,
+		Message:   result.Message,
+	})
+
+	release := toModelRelease(r)
+	release.Tests = result
+	return &release, nil
+}
+
+// runTests runs the test hooks of a release and collects the outcome of each.
+// An error is only returned when the tests could not be started.
+func runTests(ctx context.Context, actionConfig *action.Configuration, namespace, name string, req model.TestRequest) (*model.TestResult, error) {
+	timeout, err := parseTimeout(req.Timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	testAction := action.NewReleaseTesting(actionConfig)
+	testAction.Namespace = namespace
+	testAction.Timeout = timeout
+	testAction.Filters = testFilters(req.Filter)
+
+	startedAt := time.Now().UTC()
+	r, runErr := testAction.Run(name)
+	if r == nil {
+		return nil, fmt.Errorf("failed to run tests for release %s/%s: %w", namespace, name, runErr)
+	}
+
+	result := &model.TestResult{
+		Namespace:   namespace,
+		Name:        name,
+		Revision:    r.Version,
+		Passed:      runErr == nil,
+		StartedAt:   startedAt,
+		CompletedAt: time.Now().UTC(),
+		Tests:       testHookResults(r.Hooks, testAction.Filters, startedAt),
+	}
+	if runErr != nil {
+		result.Message = runErr.Error()
+	}
+
+	if req.Logs {
+		clientset, err := actionConfig.KubernetesClientSet()
+		if err != nil {
+			err = fmt.Errorf("failed to get kubernetes client: %w", err)
+		}
+		attachTestLogs(ctx, clientset, err, namespace, result.Tests)
+	}
+
+	return result, nil
+}
+
+// testFilters converts test names into the name filters of
+// action.ReleaseTesting; names prefixed with "!" are excluded
+func testFilters(names []string) map[string][]string {
+	filters := map[string][]string{}
+	for _, n := range names {
+		if excluded, ok := strings.CutPrefix(n, "!"); ok {
+			filters[action.ExcludeNameFilter] = append(filters[action.ExcludeNameFilter], excluded)
+		} else {
+			filters[action.IncludeNameFilter] = append(filters[action.IncludeNameFilter], n)
+		}
+	}
+	return filters
+}
+
+// testHookResults reports the test hooks selected by filters. Hooks whose last
+// run started before startedAt did not run this time.
+func testHookResults(hooks []*release.Hook, filters map[string][]string, startedAt time.Time) []model.TestHookResult {
+	results := []model.TestHookResult{}
+	for _, h := range hooks {
+		if !isTestHook(h) || !testSelected(h.Name, filters) {
+			continue
+		}
+
+		result := model.TestHookResult{
+			Name:  h.Name,
+			Kind:  h.Kind,
+			Phase: string(h.LastRun.Phase),
+		}
+		if h.LastRun.StartedAt.IsZero() || h.LastRun.StartedAt.Time.Before(startedAt) {
+			result.Phase = testPhaseNotRun
+		} else {
+			result.StartedAt = timeOrNil(h.LastRun.StartedAt)
+			result.CompletedAt = timeOrNil(h.LastRun.CompletedAt)
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+func isTestHook(h *release.Hook) bool {
+	for _, e := range h.Events {
+		if e == release.HookTest {
+			return true
+		}
+	}
+	return false
+}
+
+func testSelected(name string, filters map[string][]string) bool {
+	for _, n := range filters[action.ExcludeNameFilter] {
+		if n == name {
+			return false
+		}
+	}
+	include := filters[action.IncludeNameFilter]
+	if len(include) == 0 {
+		return true
+	}
+	for _, n := range include {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// attachTestLogs fetches the logs of every test pod that ran. Logs are best
+// effort: clientErr, or failing to get the logs of one pod, is reported on the
+// affected tests only.
+func attachTestLogs(ctx context.Context, clientset kubernetes.Interface, clientErr error, namespace string, tests []model.TestHookResult) {
+	for i := range tests {
+		t := &tests[i]
+		if t.Kind != "Pod" || t.Phase == testPhaseNotRun {
+			continue
+		}
+		if clientErr != nil {
+			t.LogsError = clientErr.Error()
+			continue
+		}
+
+		logs, err := podLogs(ctx, clientset, namespace, t.Name)
+		if err != nil {
+			t.LogsError = err.Error()
+			continue
+		}
+		t.Logs = logs
+	}
+}
+
+func podLogs(ctx context.Context, clientset kubernetes.Interface, namespace, name string) (string, error) {
+	limit := int64(maxTestLogBytes)
+	stream, err := clientset.CoreV1().Pods(namespace).GetLogs(name, &corev1.PodLogOptions{LimitBytes: &limit}).Stream(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to get logs of pod %s: %w", name, err)
+	}
+	defer stream.Close()
+
+	logs, err := io.ReadAll(stream)
+	if err != nil {
+		return "", fmt.Errorf("failed to read logs of pod %s: %w", name, err)
+	}
+	return string(logs), nil
+}
+
+// testUpgrade runs the release tests after an upgrade and rolls back to the
+// previous revision if any fails. upgraded is the result of the upgrade so
+// far, possibly already verified. Once ctx is done the tests are not rolled
+// back.
+func (c *Client) testUpgrade(ctx context.Context, namespace, name, deployedBy string, upgraded model.Release, previous int, req model.TestRequest, progress model.ProgressFunc) (*model.Release, error) {
+	actionConfig, err := c.getActionConfig(namespace)
+	if err != nil {
+		return nil, err
+	}
+	progress.Report(model.PhaseTest, "Running the tests of revision %d", upgraded.Revision)
+
+	tests, err := runTests(ctx, actionConfig, namespace, name, req)
+	if err != nil {
+		return nil, err
+	}
+
+	result := upgraded
+	outcome := model.TestsPassed
+	message := "All tests passed"
+	switch {
+	case tests.Passed:
+	case ctx.Err() != nil:
+		outcome = model.VerificationAborted
+		message = fmt.Sprintf("Tests failed: %s; not rolling back to revision %d: %v", tests.Message, previous, context.Cause(ctx))
+		progress.Report(model.PhaseTest, "%s", message)
+	default:
+		outcome = model.VerificationRolledBack
+		message = "Tests failed: " + tests.Message
+
+		progress.Report(model.PhaseRollback, "%s; rolling back to revision %d", message, previous)
+		rolledBack, err := c.RollbackRelease(ctx, namespace, name, model.RollbackRequest{Revision: previous, DeployedBy: deployedBy}, progress)
+		if err != nil {
+			outcome = model.VerificationRollbackFailed
+			message += fmt.Sprintf("; rollback to revision %d failed: %v", previous, err)
+		} else {
+			tests.RolledBackTo = previous
+			result = *rolledBack
+			result.Verification = upgraded.Verification
+		}
+	}
+	result.Tests = tests
+
+	c.recordAudit(model.AuditRecord{
+		Time:         tests.CompletedAt,
+		Namespace:    namespace,
+		Name:         name,
+		Action:       model.AuditActionUpgrade,
+		ChartVersion: upgraded.ChartVersion,
+		Revision:     upgraded.Revision,
+		Outcome:      outcome,
+		RolledBackTo: tests.RolledBackTo,
+		Message:      message,
+	})
+
+	return &result, nil
+}
@@ -0,0 +1,216 @@
+package helm
+
+import (
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/helm-version-manager/api/internal/model"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+	kubefake "helm.sh/helm/v3/pkg/kube/fake"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage"
+	"helm.sh/helm/v3/pkg/storage/driver"
+	helmtime "helm.sh/helm/v3/pkg/time"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func testHook(name string, weight int, events ...release.HookEvent) *release.Hook {
+	return &release.Hook{
+		Name:     name,
+		Kind:     "Pod",
+		Path:     "app/templates/tests/" + name + ".yaml",
+		Manifest: "kind: Pod\nmetadata:\n  name: " + name,
+		Events:   events,
+		Weight:   weight,
+	}
+}
+
+func newTestActionConfig(t *testing.T, watchErr error, hooks ...*release.Hook) *action.Configuration {
+	t.Helper()
+
+	cfg := &action.Configuration{
+		Releases:     storage.Init(driver.NewMemory()),
+		KubeClient:   &kubefake.FailingKubeClient{PrintingKubeClient: kubefake.PrintingKubeClient{Out: io.Discard}, WatchUntilReadyError: watchErr},
+		Capabilities: chartutil.DefaultCapabilities,
+		Log:          func(string, ...interface{}) {},
+	}
+
+	rel := &release.Release{
+		Name:      "web",
+		Namespace: "apps",
+		Version:   2,
+		Info:      &release.Info{Status: release.StatusDeployed},
+		Chart:     &chart.Chart{Metadata: &chart.Metadata{Name: "web", Version: "1.0.0"}},
+		Hooks:     hooks,
+	}
+	if err := cfg.Releases.Create(rel); err != nil {
+		t.Fatalf("failed to store release: %v", err)
+	}
+	return cfg
+}
+
+func TestRunTests(t *testing.T) {
+	t.Run("passing tests", func(t *testing.T) {
+		cfg := newTestActionConfig(t, nil,
+			testHook("web-test-api", 1, release.HookTest),
+			testHook("web-test-ui", 0, release.HookTest),
+			testHook("web-migrate", 0, release.HookPreUpgrade),
+		)
+
+		result, err := runTests(context.Background(), cfg, "apps", "web", model.TestRequest{})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !result.Passed || result.Message != "" {
+			t.Errorf("expected tests to pass, got %+v", result)
+		}
+		if result.Revision != 2 {
+			t.Errorf("expected revision 2, got %d", result.Revision)
+		}
+		if len(result.Tests) != 2 {
+			t.Fatalf("expected 2 tests, got %+v", result.Tests)
+		}
+		for _, test := range result.Tests {
+			if test.Phase != string(release.HookPhaseSucceeded) {
+				t.Errorf("expected %s to succeed, got %s", test.Name, test.Phase)
+			}
+			if test.StartedAt == nil || test.CompletedAt == nil {
+				t.Errorf("expected %s to have timings", test.Name)
+			}
+		}
+	})
+
+	t.Run("failing test stops the run", func(t *testing.T) {
+		cfg := newTestActionConfig(t, errors.New("pod web-test-ui failed"),
+			testHook("web-test-api", 1, release.HookTest),
+			testHook("web-test-ui", 0, release.HookTest),
+		)
+
+		result, err := runTests(context.Background(), cfg, "apps", "web", model.TestRequest{})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.Passed {
+			t.Fatal("expected tests to fail")
+		}
+		if result.Message != "pod web-test-ui failed" {
+			t.Errorf("unexpected message %q", result.Message)
+		}
+
+		phases := map[string]string{}
+		for _, test := range result.Tests {
+			phases[test.Name] = test.Phase
+		}
+		want := map[string]string{
+			"web-test-ui":  string(release.HookPhaseFailed),
+			"web-test-api": testPhaseNotRun,
+		}
+		if !reflect.DeepEqual(phases, want) {
+			t.Errorf("expected phases %v, got %v", want, phases)
+		}
+	})
+
+	t.Run("filtered", func(t *testing.T) {
+		cfg := newTestActionConfig(t, nil,
+			testHook("web-test-api", 0, release.HookTest),
+			testHook("web-test-ui", 0, release.HookTest),
+		)
+
+		result, err := runTests(context.Background(), cfg, "apps", "web", model.TestRequest{Filter: []string{"!web-test-ui"}})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(result.Tests) != 1 || result.Tests[0].Name != "web-test-api" {
+			t.Errorf("expected only web-test-api, got %+v", result.Tests)
+		}
+	})
+
+	t.Run("unknown release", func(t *testing.T) {
+		cfg := newTestActionConfig(t, nil)
+
+		if _, err := runTests(context.Background(), cfg, "apps", "missing", model.TestRequest{}); err == nil {
+			t.Fatal("expected error for unknown release")
+		}
+	})
+
+	t.Run("invalid timeout", func(t *testing.T) {
+		cfg := newTestActionConfig(t, nil)
+
+		if _, err := runTests(context.Background(), cfg, "apps", "web", model.TestRequest{Timeout: "soon"}); err == nil {
+			t.Fatal("expected error for invalid timeout")
+		}
+	})
+}
+
+func TestTestFilters(t *testing.T) {
+	got := testFilters([]string{"a", "!b", "c"})
+	want := map[string][]string{
+		action.IncludeNameFilter: {"a", "c"},
+		action.ExcludeNameFilter: {"b"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	for _, tc := range []struct {
+		name string
+		want bool
+	}{
+		{"a", true},
+		{"b", false},
+		{"d", false},
+	} {
+		if got := testSelected(tc.name, want); got != tc.want {
+			t.Errorf("testSelected(%q) = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+	if !testSelected("d", testFilters(nil)) {
+		t.Error("expected every test to be selected without filters")
+	}
+}
+
+func TestTestHookResultsOmitsEarlierRuns(t *testing.T) {
+	startedAt := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	previous := testHook("web-test-api", 0, release.HookTest)
+	previous.LastRun = release.HookExecution{
+		StartedAt: helmtime.Time{Time: startedAt.Add(-time.Hour)},
+		Phase:     release.HookPhaseSucceeded,
+	}
+
+	results := testHookResults([]*release.Hook{previous}, map[string][]string{}, startedAt)
+
+	if len(results) != 1 || results[0].Phase != testPhaseNotRun || results[0].StartedAt != nil {
+		t.Errorf("expected the earlier run to be reported as not run, got %+v", results)
+	}
+}
+
+func TestAttachTestLogs(t *testing.T) {
+	clientset := fake.NewSimpleClientset(&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web-test-api", Namespace: "apps"}})
+	tests := []model.TestHookResult{
+		{Name: "web-test-api", Kind: "Pod", Phase: string(release.HookPhaseSucceeded)},
+		{Name: "web-test-job", Kind: "Job", Phase: string(release.HookPhaseSucceeded)},
+		{Name: "web-test-ui", Kind: "Pod", Phase: testPhaseNotRun},
+	}
+
+	attachTestLogs(context.Background(), clientset, nil, "apps", tests)
+
+	if tests[0].Logs == "" || tests[0].LogsError != "" {
+		t.Errorf("expected logs for the test pod, got %+v", tests[0])
+	}
+	if tests[1].Logs != "" || tests[2].Logs != "" {
+		t.Errorf("expected no logs for jobs and tests that did not run, got %+v", tests[1:])
+	}
+
+	attachTestLogs(context.Background(), nil, errors.New("no cluster"), "apps", tests[:1])
+	if tests[0].LogsError != "no cluster" {
+		t.Errorf("expected the client error on the test, got %q", tests[0].LogsError)
+	}
+}
@@ -0,0 +1,183 @@
+package helm
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/helm-version-manager/api/internal/model"
+	"helm.sh/helm/v3/pkg/getter"
+	"helm.sh/helm/v3/pkg/repo"
+)
+
+// indexCacheTTL is how long a downloaded repository index is reused before
+// it is fetched again.
+const indexCacheTTL = 5 * time.Minute
+
+type cachedIndex struct {
+	index   *repo.IndexFile
+	fetched time.Time
+}
+
+// isHTTPRepository reports whether reg points at a classic Helm chart
+// repository (served as index.yaml over HTTP) rather than an OCI registry.
+func isHTTPRepository(reg string) bool {
+	return strings.HasPrefix(reg, "http://") || strings.HasPrefix(reg, "https://")
+}
+
+// loadRepositoryIndex downloads the index.yaml of repoURL into the repository
+// cache, or returns the in-memory copy if it is fresh enough.
+func (c *Client) loadRepositoryIndex(repoURL string) (*repo.IndexFile, error) {
+	if cached, ok := c.repoIndexes.Load(repoURL); ok {
+		entry := cached.(*cachedIndex)
+		if time.Since(entry.fetched) < indexCacheTTL {
+			return entry.index, nil
+		}
+	}
+
+	entry := &repo.Entry{
+		Name: repositoryCacheName(repoURL),
+		URL:  repoURL,
+	}
+	credential, err := c.registryCredential(repoURL)
+	if err != nil {
+		return nil, err
+	}
+	if credential != nil {
+		entry.Username = credential.Username
+		entry.Password = credential.Password
+	}
+
+	chartRepo, err := repo.NewChartRepository(entry, getter.All(c.settings))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create chart repository client: %w", err)
+	}
+	if c.settings.RepositoryCache != "" {
+		chartRepo.CachePath = c.settings.RepositoryCache
+	}
+
+	indexPath, err := chartRepo.DownloadIndexFile()
+	if err != nil {
+		return nil, fmt.Errorf("failed to download index from %s: %w", repoURL, err)
+	}
+
+	index, err := repo.LoadIndexFile(indexPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load index from %s: %w", repoURL, err)
+	}
+
+	c.repoIndexes.Store(repoURL, &cachedIndex{index: index, fetched: time.Now()})
+	return index, nil
+}
+
+func (c *Client) searchRepositoryVersions(repoURL, chartName string, query model.VersionQuery) ([]model.ChartVersion, error) {
+	index, err := c.loadRepositoryIndex(repoURL)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, ok := index.Entries[chartName]
+	if !ok {
+		return nil, fmt.Errorf("chart %s not found in repository %s", chartName, repoURL)
+	}
+
+	byVersion := make(map[string]*repo.ChartVersion, len(entries))
+	tags := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		byVersion[entry.Version] = entry
+		tags = append(tags, entry.Version)
+	}
+
+	versions, err := filterChartVersions(tags, query)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range versions {
+		if entry, ok := byVersion[versions[i].Version]; ok {
+			versions[i].AppVersion = entry.AppVersion
+			versions[i].Description = entry.Description
+		}
+	}
+
+	return versions, nil
+}
+
+// locateRepositoryChart downloads the chart archive for chartName at version
+// from the repository at repoURL and returns its path in the cache directory.
+func (c *Client) locateRepositoryChart(repoURL, chartName, version string) (string, error) {
+	index, err := c.loadRepositoryIndex(repoURL)
+	if err != nil {
+		return "", err
+	}
+
+	entry, err := index.Get(chartName, version)
+	if err != nil {
+		return "", fmt.Errorf("chart %s-%s not found in repository %s: %w", chartName, version, repoURL, err)
+	}
+	if len(entry.URLs) == 0 {
+		return "", fmt.Errorf("chart %s-%s has no download URL in repository %s", chartName, version, repoURL)
+	}
+
+	chartURL, err := repo.ResolveReferenceURL(repoURL, entry.URLs[0])
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve chart URL %s: %w", entry.URLs[0], err)
+	}
+
+	u, err := url.Parse(chartURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid chart URL %s: %w", chartURL, err)
+	}
+
+	g, err := getter.All(c.settings).ByScheme(u.Scheme)
+	if err != nil {
+		return "", fmt.Errorf("unsupported chart URL scheme %s: %w", u.Scheme, err)
+	}
+
+	// Credentials are only sent when the chart is served from the
+	// repository's own host
+	getterOpts := []getter.Option{getter.WithURL(repoURL)}
+	credential, err := c.registryCredential(repoURL)
+	if err != nil {
+		return "", err
+	}
+	if credential != nil {
+		getterOpts = append(getterOpts, getter.WithBasicAuth(credential.Username, credential.Password))
+	}
+
+	data, err := g.Get(chartURL, getterOpts...)
+	if err != nil {
+		return "", fmt.Errorf("failed to download chart %s: %w", chartURL, err)
+	}
+
+	return c.writeChartToCache(chartName, version, data.Bytes())
+}
+
+// writeChartToCache stores a chart archive in the repository cache directory.
+func (c *Client) writeChartToCache(chartName, version string, data []byte) (string, error) {
+	cacheDir := c.settings.RepositoryCache
+	if cacheDir == "" {
+		cacheDir = os.TempDir()
+	}
+	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create cache directory %s: %w", cacheDir, err)
+	}
+	chartFileName := fmt.Sprintf("%s-%s.tgz", chartName, version)
+	chartPath := filepath.Join(cacheDir, chartFileName)
+
+	if err := os.WriteFile(chartPath, data, 0644); err != nil {
+		return "", fmt.Errorf("failed to write chart to %s: %w", chartPath, err)
+	}
+
+	return chartPath, nil
+}
+
+// repositoryCacheName derives a stable cache file name for a repository URL,
+// since mapped repositories are not registered in repositories.yaml.
+func repositoryCacheName(repoURL string) string {
+	return fmt.Sprintf("helm-ui-%x", sha256.Sum256([]byte(repoURL)))[:24]
+}
@@ -0,0 +1,131 @@
+package helm
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/helm-version-manager/api/internal/model"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/repo"
+)
+
+// newTestRepository serves a classic chart repository containing the given
+// charts and returns its URL.
+func newTestRepository(t *testing.T, charts ...*chart.Metadata) string {
+	return newAuthTestRepository(t, "", "", charts...)
+}
+
+// newAuthTestRepository is newTestRepository requiring basic auth with
+// username and password when username is set
+func newAuthTestRepository(t *testing.T, username, password string, charts ...*chart.Metadata) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for _, metadata := range charts {
+		packageTestChart(t, dir, metadata)
+	}
+
+	handler := http.FileServer(http.Dir(dir))
+	if username != "" {
+		handler = requireBasicAuth(username, password, handler)
+	}
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	index, err := repo.IndexDirectory(dir, server.URL)
+	if err != nil {
+		t.Fatalf("failed to index charts: %v", err)
+	}
+	if err := index.WriteFile(dir+"/index.yaml", 0644); err != nil {
+		t.Fatalf("failed to write index: %v", err)
+	}
+
+	return server.URL
+}
+
+func newTestRepositoryClient(t *testing.T) *Client {
+	t.Helper()
+
+	settings := cli.New()
+	settings.RepositoryCache = t.TempDir()
+	return &Client{settings: settings}
+}
+
+func TestIsHTTPRepository(t *testing.T) {
+	testCases := map[string]bool{
+		"https://charts.example.com":    true,
+		"http://charts.example.com/sub": true,
+		"oci://ghcr.io/myorg/charts":    false,
+		"ghcr.io/myorg/charts":          false,
+		"httpsfoo.example.com/charts":   false,
+	}
+
+	for reg, want := range testCases {
+		if got := isHTTPRepository(reg); got != want {
+			t.Errorf("isHTTPRepository(%q) = %v, want %v", reg, got, want)
+		}
+	}
+}
+
+func TestSearchChartVersionsHTTPRepository(t *testing.T) {
+	repoURL := newTestRepository(t,
+		&chart.Metadata{APIVersion: chart.APIVersionV2, Name: "mychart", Version: "1.0.0", AppVersion: "2.0", Description: "first"},
+		&chart.Metadata{APIVersion: chart.APIVersionV2, Name: "mychart", Version: "1.1.0", AppVersion: "2.1", Description: "second"},
+		&chart.Metadata{APIVersion: chart.APIVersionV2, Name: "mychart", Version: "2.0.0-beta.1", AppVersion: "3.0", Description: "beta"},
+		&chart.Metadata{APIVersion: chart.APIVersionV2, Name: "otherchart", Version: "9.9.9"},
+	)
+
+	c := newTestRepositoryClient(t)
+
+	versions, err := c.searchChartVersions(repoURL, "mychart", model.VersionQuery{})
+	if err != nil {
+		t.Fatalf("searchChartVersions failed: %v", err)
+	}
+
+	want := []model.ChartVersion{
+		{Version: "1.1.0", AppVersion: "2.1", Description: "second"},
+		{Version: "1.0.0", AppVersion: "2.0", Description: "first"},
+	}
+	if !reflect.DeepEqual(versions, want) {
+		t.Fatalf("expected %+v, got %+v", want, versions)
+	}
+
+	if _, err := c.searchChartVersions(repoURL, "missing", model.VersionQuery{}); err == nil {
+		t.Fatal("expected error for chart missing from index")
+	}
+}
+
+func TestLocateChartHTTPRepository(t *testing.T) {
+	repoURL := newTestRepository(t,
+		&chart.Metadata{APIVersion: chart.APIVersionV2, Name: "mychart", Version: "1.0.0"},
+		&chart.Metadata{APIVersion: chart.APIVersionV2, Name: "mychart", Version: "1.1.0"},
+	)
+
+	c := newTestRepositoryClient(t)
+
+	chartPath, err := c.locateChart(nil, repoURL, "mychart", "1.0.0")
+	if err != nil {
+		t.Fatalf("locateChart failed: %v", err)
+	}
+
+	if _, err := os.Stat(chartPath); err != nil {
+		t.Fatalf("chart file does not exist: %s", chartPath)
+	}
+
+	ch, err := loader.Load(chartPath)
+	if err != nil {
+		t.Fatalf("failed to load downloaded chart: %v", err)
+	}
+	if ch.Metadata.Version != "1.0.0" {
+		t.Errorf("expected chart version 1.0.0, got %s", ch.Metadata.Version)
+	}
+
+	if _, err := c.locateChart(nil, repoURL, "mychart", "3.0.0"); err == nil {
+		t.Fatal("expected error for missing chart version")
+	}
+}
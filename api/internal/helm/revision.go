@@ -0,0 +1,44 @@
+package helm
+
+import (
+	"fmt"
+
+	"helm.sh/helm/v3/pkg/release"
+)
+
+// RevisionMismatchError is returned when a release is no longer at the
+// revision a mutation expected, because something else changed it since the
+// caller last read it
+type RevisionMismatchError struct {
+	Namespace string
+	Name      string
+	Expected  int
+	Current   int
+}
+
+func (e *RevisionMismatchError) Error() string {
+	return fmt.Sprintf("release %s/%s is at revision %d, not the expected revision %d", e.Namespace, e.Name, e.Current, e.Expected)
+}
+
+// checkRevision fails with a *RevisionMismatchError unless r is at the
+// expected revision. Zero expects any revision.
+func checkRevision(r *release.Release, expected int) error {
+	if expected == 0 || r.Version == expected {
+		return nil
+	}
+	return &RevisionMismatchError{Namespace: r.Namespace, Name: r.Name, Expected: expected, Current: r.Version}
+}
+
+// CheckRevision fails with a *RevisionMismatchError unless the latest
+// revision of a release is the expected one. Zero expects any revision.
+func (c *Client) CheckRevision(namespace, name string, expected int) error {
+	if expected == 0 {
+		return nil
+	}
+
+	r, err := c.getRevision(namespace, name, 0)
+	if err != nil {
+		return err
+	}
+	return checkRevision(r, expected)
+}
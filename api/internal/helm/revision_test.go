@@ -0,0 +1,46 @@
+package helm
+
+import (
+	"errors"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/release"
+)
+
+func TestCheckRevision(t *testing.T) {
+	r := &release.Release{Name: "web", Namespace: "apps", Version: 4}
+
+	testCases := []struct {
+		name     string
+		expected int
+		wantErr  string
+	}{
+		{name: "any revision", expected: 0},
+		{name: "current revision", expected: 4},
+		{name: "older revision", expected: 3, wantErr: "release apps/web is at revision 4, not the expected revision 3"},
+		{name: "newer revision", expected: 5, wantErr: "release apps/web is at revision 4, not the expected revision 5"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := checkRevision(r, tc.expected)
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+
+			var mismatch *RevisionMismatchError
+			if !errors.As(err, &mismatch) {
+				t.Fatalf("expected RevisionMismatchError, got %v", err)
+			}
+			if mismatch.Current != 4 || mismatch.Expected != tc.expected {
+				t.Errorf("expected current 4 and expected %d, got %+v", tc.expected, mismatch)
+			}
+			if err.Error() != tc.wantErr {
+				t.Errorf("expected %q, got %q", tc.wantErr, err.Error())
+			}
+		})
+	}
+}
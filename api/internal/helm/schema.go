@@ -0,0 +1,188 @@
+package helm
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/helm-version-manager/api/internal/model"
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"github.com/santhosh-tekuri/jsonschema/v6/kind"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+)
+
+const schemaURL = "file:///values.schema.json"
+
+// SchemaValidationError reports values that do not match a chart's
+// values.schema.json, one entry per violation
+type SchemaValidationError struct {
+	Errors []model.FieldError
+}
+
+func (e *SchemaValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Errors))
+	for _, fe := range e.Errors {
+		if fe.Field == "" {
+			msgs = append(msgs, fe.Message)
+			continue
+		}
+		msgs = append(msgs, fe.Field+": "+fe.Message)
+	}
+	return "values do not match the chart schema: " + strings.Join(msgs, "; ")
+}
+
+// FieldErrors returns the individual violations
+func (e *SchemaValidationError) FieldErrors() []model.FieldError {
+	return e.Errors
+}
+
+// schemaLoader refuses every $ref that leaves the chart's schema. Charts come
+// from registries API callers choose, so following file or http refs would
+// let a chart read files of the server or make it send requests on their
+// behalf.
+type schemaLoader struct{}
+
+func (schemaLoader) Load(url string) (any, error) {
+	return nil, fmt.Errorf("$ref %s is not allowed, values schemas may only reference their own definitions", url)
+}
+
+// validateValues coalesces vals with the chart defaults, as Helm does before
+// rendering, and checks the result against the schema of the chart and its
+// subcharts. Violations are returned as a *SchemaValidationError.
+func validateValues(ch *chart.Chart, vals map[string]any) error {
+	coalesced, err := chartutil.CoalesceValues(ch, vals)
+	if err != nil {
+		return fmt.Errorf("failed to coalesce values: %w", err)
+	}
+
+	var fieldErrors []model.FieldError
+	if err := collectSchemaErrors(ch, coalesced.AsMap(), "", &fieldErrors); err != nil {
+		return err
+	}
+	if len(fieldErrors) > 0 {
+		sort.SliceStable(fieldErrors, func(i, j int) bool {
+			return fieldErrors[i].Field < fieldErrors[j].Field
+		})
+		return &SchemaValidationError{Errors: fieldErrors}
+	}
+	return nil
+}
+
+// validateReusedValues validates vals the way an upgrade with ReuseValues
+// renders them: Helm replaces the defaults of the target chart with the
+// coalesced values of the current release before rendering.
+func (p *upgradePlan) validateReusedValues(vals map[string]any) error {
+	defaults, err := chartutil.CoalesceValues(p.current.Chart, p.current.Config)
+	if err != nil {
+		return fmt.Errorf("failed to rebuild current values: %w", err)
+	}
+
+	target := *p.chart
+	target.Values = defaults
+	return validateValues(&target, vals)
+}
+
+func collectSchemaErrors(ch *chart.Chart, vals map[string]any, prefix string, fieldErrors *[]model.FieldError) error {
+	if len(ch.Schema) > 0 {
+		schema, err := compileSchema(ch.Schema)
+		if err != nil {
+			return fmt.Errorf("failed to compile values schema of chart %s: %w", ch.Name(), err)
+		}
+
+		instance, err := toJSONValue(vals)
+		if err != nil {
+			return err
+		}
+
+		if err := schema.Validate(instance); err != nil {
+			verr, ok := err.(*jsonschema.ValidationError)
+			if !ok {
+				return fmt.Errorf("failed to validate values against schema of chart %s: %w", ch.Name(), err)
+			}
+			appendFieldErrors(verr, prefix, fieldErrors)
+		}
+	}
+
+	for _, sub := range ch.Dependencies() {
+		raw, ok := vals[sub.Name()]
+		if !ok || raw == nil {
+			continue
+		}
+		subPrefix := joinValuePath(prefix, sub.Name())
+		subVals, ok := raw.(map[string]any)
+		if !ok {
+			*fieldErrors = append(*fieldErrors, model.FieldError{
+				Field:   subPrefix,
+				Message: fmt.Sprintf("subchart values must be an object, got %T", raw),
+			})
+			continue
+		}
+		if err := collectSchemaErrors(sub, subVals, subPrefix, fieldErrors); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func compileSchema(schemaJSON []byte) (*jsonschema.Schema, error) {
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(schemaJSON))
+	if err != nil {
+		return nil, err
+	}
+
+	compiler := jsonschema.NewCompiler()
+	compiler.UseLoader(schemaLoader{})
+	if err := compiler.AddResource(schemaURL, doc); err != nil {
+		return nil, err
+	}
+	return compiler.Compile(schemaURL)
+}
+
+// toJSONValue round-trips vals through JSON so that numbers and nested
+// types are in the form the validator expects
+func toJSONValue(vals map[string]any) (any, error) {
+	data, err := json.Marshal(vals)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode values: %w", err)
+	}
+	return jsonschema.UnmarshalJSON(bytes.NewReader(data))
+}
+
+var schemaMessages = message.NewPrinter(language.English)
+
+// appendFieldErrors flattens the leaves of a validation error tree into
+// field errors. A missing required property is reported on the property
+// itself rather than on its parent.
+func appendFieldErrors(verr *jsonschema.ValidationError, prefix string, fieldErrors *[]model.FieldError) {
+	if len(verr.Causes) > 0 {
+		for _, cause := range verr.Causes {
+			appendFieldErrors(cause, prefix, fieldErrors)
+		}
+		return
+	}
+
+	field := prefix
+	for _, token := range verr.InstanceLocation {
+		field = joinValuePath(field, token)
+	}
+
+	if required, ok := verr.ErrorKind.(*kind.Required); ok {
+		for _, missing := range required.Missing {
+			*fieldErrors = append(*fieldErrors, model.FieldError{
+				Field:   joinValuePath(field, missing),
+				Message: "is required",
+			})
+		}
+		return
+	}
+
+	*fieldErrors = append(*fieldErrors, model.FieldError{
+		Field:   field,
+		Message: verr.ErrorKind.LocalizedString(schemaMessages),
+	})
+}
@@ -0,0 +1,244 @@
+package helm
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/helm-version-manager/api/internal/model"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/release"
+)
+
+const testValuesSchema = `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "type": "object",
+  "required": ["image"],
+  "properties": {
+    "replicaCount": {"type": "integer", "minimum": 1},
+    "image": {
+      "type": "object",
+      "required": ["repository"],
+      "properties": {
+        "repository": {"type": "string"},
+        "tag": {"type": "string"},
+        "pullPolicy": {"enum": ["Always", "IfNotPresent", "Never"]}
+      }
+    }
+  }
+}`
+
+const testSubchartSchema = `{
+  "type": "object",
+  "properties": {
+    "enabled": {"type": "boolean"}
+  }
+}`
+
+func newSchemaTestChart() *chart.Chart {
+	ch := &chart.Chart{
+		Metadata: &chart.Metadata{APIVersion: chart.APIVersionV2, Name: "app", Version: "1.0.0"},
+		Values: map[string]any{
+			"replicaCount": 1,
+			"image": map[string]any{
+				"repository": "nginx",
+				"tag":        "1.25",
+			},
+		},
+		Schema: []byte(testValuesSchema),
+	}
+	ch.AddDependency(&chart.Chart{
+		Metadata: &chart.Metadata{APIVersion: chart.APIVersionV2, Name: "redis", Version: "2.0.0"},
+		Values:   map[string]any{"enabled": true},
+		Schema:   []byte(testSubchartSchema),
+	})
+	return ch
+}
+
+func TestValidateValues(t *testing.T) {
+	testCases := []struct {
+		name string
+		vals map[string]any
+		want []model.FieldError
+	}{
+		{
+			name: "valid overrides",
+			vals: map[string]any{"replicaCount": 3, "image": map[string]any{"tag": "1.26", "pullPolicy": "Always"}},
+		},
+		{
+			name: "no overrides",
+			vals: map[string]any{},
+		},
+		{
+			name: "wrong types",
+			vals: map[string]any{"replicaCount": "three", "image": map[string]any{"tag": 126}},
+			want: []model.FieldError{
+				{Field: "image.tag", Message: "got number, want string"},
+				{Field: "replicaCount", Message: "got string, want integer"},
+			},
+		},
+		{
+			name: "below minimum",
+			vals: map[string]any{"replicaCount": 0},
+			want: []model.FieldError{
+				{Field: "replicaCount", Message: "minimum: got 0, want 1"},
+			},
+		},
+		{
+			name: "removed required field",
+			vals: map[string]any{"image": map[string]any{"repository": nil}},
+			want: []model.FieldError{
+				{Field: "image.repository", Message: "is required"},
+			},
+		},
+		{
+			name: "subchart violation",
+			vals: map[string]any{"redis": map[string]any{"enabled": "yes"}},
+			want: []model.FieldError{
+				{Field: "redis.enabled", Message: "got string, want boolean"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateValues(newSchemaTestChart(), tc.vals)
+			if tc.want == nil {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+
+			var schemaErr *SchemaValidationError
+			if !errors.As(err, &schemaErr) {
+				t.Fatalf("expected SchemaValidationError, got %v", err)
+			}
+			if !reflect.DeepEqual(schemaErr.Errors, tc.want) {
+				t.Errorf("expected %+v, got %+v", tc.want, schemaErr.Errors)
+			}
+		})
+	}
+}
+
+func TestValidateValuesEnum(t *testing.T) {
+	err := validateValues(newSchemaTestChart(), map[string]any{"image": map[string]any{"pullPolicy": "Sometimes"}})
+
+	var schemaErr *SchemaValidationError
+	if !errors.As(err, &schemaErr) {
+		t.Fatalf("expected SchemaValidationError, got %v", err)
+	}
+	if len(schemaErr.Errors) != 1 || schemaErr.Errors[0].Field != "image.pullPolicy" {
+		t.Fatalf("expected one error on image.pullPolicy, got %+v", schemaErr.Errors)
+	}
+	if !strings.Contains(err.Error(), "image.pullPolicy: ") {
+		t.Errorf("expected error message to name the field, got %q", err.Error())
+	}
+}
+
+func TestValidateValuesWithoutSchema(t *testing.T) {
+	ch := &chart.Chart{
+		Metadata: &chart.Metadata{APIVersion: chart.APIVersionV2, Name: "plain", Version: "1.0.0"},
+		Values:   map[string]any{"replicaCount": 1},
+	}
+
+	if err := validateValues(ch, map[string]any{"replicaCount": "anything"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestValidateValuesInvalidSchema(t *testing.T) {
+	ch := newSchemaTestChart()
+	ch.Schema = []byte(`{"type": `)
+
+	err := validateValues(ch, map[string]any{})
+	if err == nil {
+		t.Fatal("expected error for invalid schema")
+	}
+	var schemaErr *SchemaValidationError
+	if errors.As(err, &schemaErr) {
+		t.Errorf("expected a plain error for an invalid schema, got %v", err)
+	}
+}
+
+func TestValidateValuesRefs(t *testing.T) {
+	fetched := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetched = true
+		w.Write([]byte(testSubchartSchema))
+	}))
+	defer server.Close()
+
+	// A schema the file $ref could load, were it allowed
+	path := filepath.Join(t.TempDir(), "external.schema.json")
+	if err := os.WriteFile(path, []byte(testSubchartSchema), 0o600); err != nil {
+		t.Fatalf("failed to write schema: %v", err)
+	}
+
+	testCases := map[string]struct {
+		ref     string
+		wantErr bool
+	}{
+		"internal": {ref: "#/$defs/settings"},
+		"file":     {ref: "file://" + path, wantErr: true},
+		"relative": {ref: "external.schema.json", wantErr: true},
+		"http":     {ref: server.URL + "/external.schema.json", wantErr: true},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			ch := &chart.Chart{
+				Metadata: &chart.Metadata{APIVersion: chart.APIVersionV2, Name: "app", Version: "1.0.0"},
+				Schema: []byte(`{
+  "$defs": {"settings": {"type": "object"}},
+  "type": "object",
+  "properties": {"settings": {"$ref": "` + tc.ref + `"}}
+}`),
+			}
+
+			err := validateValues(ch, map[string]any{"settings": map[string]any{"enabled": true}})
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("expected error %v, got %v", tc.wantErr, err)
+			}
+			if tc.wantErr && !strings.Contains(err.Error(), "not allowed") {
+				t.Errorf("expected the $ref to be refused, got %v", err)
+			}
+		})
+	}
+
+	if fetched {
+		t.Error("expected the http $ref not to be fetched")
+	}
+}
+
+func TestValidateReusedValues(t *testing.T) {
+	current := newSchemaTestChart()
+	target := newSchemaTestChart()
+	target.Metadata.Version = "2.0.0"
+	// the new chart no longer ships a default image, but the release reuses
+	// the defaults it was installed with
+	target.Values = map[string]any{}
+
+	plan := &upgradePlan{
+		current: &release.Release{Chart: current, Config: map[string]any{"replicaCount": 2}},
+		chart:   target,
+	}
+
+	if err := plan.validateReusedValues(map[string]any{"replicaCount": 2}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if target.Values == nil || len(target.Values) != 0 {
+		t.Errorf("target chart defaults were modified: %v", target.Values)
+	}
+
+	err := plan.validateReusedValues(map[string]any{"replicaCount": -1})
+	var schemaErr *SchemaValidationError
+	if !errors.As(err, &schemaErr) {
+		t.Fatalf("expected SchemaValidationError, got %v", err)
+	}
+}
@@ -0,0 +1,127 @@
+package helm
+
+import (
+	"encoding/json"
+	"fmt"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	"github.com/helm-version-manager/api/internal/model"
+)
+
+// applyValuesUpdate computes the new values of a release from its current
+// values and req. The current values are never modified.
+func applyValuesUpdate(current map[string]any, req model.ValuesUpdateRequest) (map[string]any, error) {
+	switch req.Mode {
+	case "", model.ValuesModeMerge:
+		if req.Values == nil {
+			return nil, fmt.Errorf("values are required in %s mode", model.ValuesModeMerge)
+		}
+		return mergeValues(copyValues(current), req.Values), nil
+
+	case model.ValuesModeReplace:
+		if req.Values == nil {
+			return nil, fmt.Errorf("values are required in %s mode", model.ValuesModeReplace)
+		}
+		return copyValues(req.Values), nil
+
+	case model.ValuesModeJSONPatch:
+		if req.Patch == nil {
+			return nil, fmt.Errorf("patch is required in %s mode", model.ValuesModeJSONPatch)
+		}
+		return patchValues(current, req.Patch)
+
+	default:
+		return nil, fmt.Errorf("unknown values update mode %q, expected %s, %s or %s", req.Mode, model.ValuesModeMerge, model.ValuesModeReplace, model.ValuesModeJSONPatch)
+	}
+}
+
+// mergeValues deep-merges src into dst and returns dst. Nested maps are
+// merged key by key, a nil value in src deletes the key from dst, and any
+// other value, including lists, replaces the value in dst.
+func mergeValues(dst, src map[string]any) map[string]any {
+	if dst == nil {
+		dst = map[string]any{}
+	}
+
+	for k, v := range src {
+		if v == nil {
+			delete(dst, k)
+			continue
+		}
+
+		srcMap, srcIsMap := v.(map[string]any)
+		dstMap, dstIsMap := dst[k].(map[string]any)
+		if srcIsMap && dstIsMap {
+			dst[k] = mergeValues(dstMap, srcMap)
+			continue
+		}
+		if srcIsMap {
+			// Strip null markers from maps that have nothing to merge into
+			dst[k] = mergeValues(map[string]any{}, srcMap)
+			continue
+		}
+
+		dst[k] = v
+	}
+
+	return dst
+}
+
+// patchValues applies an RFC 6902 JSON Patch to a copy of values.
+func patchValues(values map[string]any, patch []map[string]any) (map[string]any, error) {
+	patchData, err := json.Marshal(patch)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal patch: %w", err)
+	}
+
+	decoded, err := jsonpatch.DecodePatch(patchData)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSON patch: %w", err)
+	}
+
+	if values == nil {
+		values = map[string]any{}
+	}
+	doc, err := json.Marshal(values)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal current values: %w", err)
+	}
+
+	patched, err := decoded.Apply(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply JSON patch: %w", err)
+	}
+
+	var result map[string]any
+	if err := json.Unmarshal(patched, &result); err != nil {
+		return nil, fmt.Errorf("JSON patch must leave values as an object: %w", err)
+	}
+	if result == nil {
+		result = map[string]any{}
+	}
+
+	return result, nil
+}
+
+// overrideValues returns a copy of current with the top-level keys of
+// overrides replaced, as used by chart version upgrades.
+func overrideValues(current, overrides map[string]any) map[string]any {
+	vals := copyValues(current)
+	for k, v := range overrides {
+		vals[k] = v
+	}
+	return vals
+}
+
+// copyValues returns a copy of values in which nested maps are copied too,
+// so they can be merged into without touching the original.
+func copyValues(values map[string]any) map[string]any {
+	result := make(map[string]any, len(values))
+	for k, v := range values {
+		if m, ok := v.(map[string]any); ok {
+			v = copyValues(m)
+		}
+		result[k] = v
+	}
+	return result
+}
@@ -0,0 +1,205 @@
+package helm
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/helm-version-manager/api/internal/model"
+)
+
+func newTestValues() map[string]any {
+	return map[string]any{
+		"replicaCount": float64(1),
+		"image": map[string]any{
+			"repository": "nginx",
+			"tag":        "1.25",
+		},
+		"resources": map[string]any{
+			"limits": map[string]any{
+				"cpu":    "500m",
+				"memory": "256Mi",
+			},
+			"requests": map[string]any{
+				"cpu": "100m",
+			},
+		},
+		"args":        []any{"--verbose", "--port=80"},
+		"tolerations": []any{map[string]any{"key": "dedicated", "effect": "NoSchedule"}},
+	}
+}
+
+func TestApplyValuesUpdate(t *testing.T) {
+	testCases := []struct {
+		name    string
+		req     model.ValuesUpdateRequest
+		want    func(v map[string]any)
+		wantErr bool
+	}{
+		{
+			name: "merge nested map keeps siblings",
+			req: model.ValuesUpdateRequest{Values: map[string]any{
+				"resources": map[string]any{"limits": map[string]any{"cpu": "1"}},
+			}},
+			want: func(v map[string]any) {
+				v["resources"].(map[string]any)["limits"].(map[string]any)["cpu"] = "1"
+			},
+		},
+		{
+			name: "merge null removes nested key",
+			req: model.ValuesUpdateRequest{Mode: model.ValuesModeMerge, Values: map[string]any{
+				"resources": map[string]any{"limits": map[string]any{"memory": nil}},
+			}},
+			want: func(v map[string]any) {
+				delete(v["resources"].(map[string]any)["limits"].(map[string]any), "memory")
+			},
+		},
+		{
+			name: "merge null removes top-level key",
+			req:  model.ValuesUpdateRequest{Values: map[string]any{"image": nil, "missing": nil}},
+			want: func(v map[string]any) {
+				delete(v, "image")
+			},
+		},
+		{
+			name: "merge replaces arrays",
+			req:  model.ValuesUpdateRequest{Values: map[string]any{"args": []any{"--quiet"}}},
+			want: func(v map[string]any) {
+				v["args"] = []any{"--quiet"}
+			},
+		},
+		{
+			name: "merge map over scalar strips nulls",
+			req: model.ValuesUpdateRequest{Values: map[string]any{
+				"replicaCount": map[string]any{"min": float64(1), "max": nil},
+			}},
+			want: func(v map[string]any) {
+				v["replicaCount"] = map[string]any{"min": float64(1)}
+			},
+		},
+		{
+			name: "merge adds new nested map",
+			req: model.ValuesUpdateRequest{Values: map[string]any{
+				"ingress": map[string]any{"enabled": true},
+			}},
+			want: func(v map[string]any) {
+				v["ingress"] = map[string]any{"enabled": true}
+			},
+		},
+		{
+			name: "replace",
+			req: model.ValuesUpdateRequest{Mode: model.ValuesModeReplace, Values: map[string]any{
+				"image": map[string]any{"tag": "1.26"},
+			}},
+			want: func(v map[string]any) {
+				for k := range v {
+					delete(v, k)
+				}
+				v["image"] = map[string]any{"tag": "1.26"}
+			},
+		},
+		{
+			name: "replace with empty values resets",
+			req:  model.ValuesUpdateRequest{Mode: model.ValuesModeReplace, Values: map[string]any{}},
+			want: func(v map[string]any) {
+				for k := range v {
+					delete(v, k)
+				}
+			},
+		},
+		{
+			name: "json-patch on nested maps and arrays",
+			req: model.ValuesUpdateRequest{Mode: model.ValuesModeJSONPatch, Patch: []map[string]any{
+				{"op": "replace", "path": "/image/tag", "value": "1.26"},
+				{"op": "remove", "path": "/resources/requests"},
+				{"op": "add", "path": "/args/-", "value": "--debug"},
+				{"op": "remove", "path": "/tolerations/0"},
+			}},
+			want: func(v map[string]any) {
+				v["image"].(map[string]any)["tag"] = "1.26"
+				delete(v["resources"].(map[string]any), "requests")
+				v["args"] = []any{"--verbose", "--port=80", "--debug"}
+				v["tolerations"] = []any{}
+			},
+		},
+		{
+			name:    "json-patch test failure",
+			req:     model.ValuesUpdateRequest{Mode: model.ValuesModeJSONPatch, Patch: []map[string]any{{"op": "test", "path": "/image/tag", "value": "0.1"}}},
+			wantErr: true,
+		},
+		{
+			name:    "json-patch missing path",
+			req:     model.ValuesUpdateRequest{Mode: model.ValuesModeJSONPatch, Patch: []map[string]any{{"op": "remove", "path": "/nope"}}},
+			wantErr: true,
+		},
+		{
+			name:    "json-patch replacing the document",
+			req:     model.ValuesUpdateRequest{Mode: model.ValuesModeJSONPatch, Patch: []map[string]any{{"op": "replace", "path": "", "value": []any{}}}},
+			wantErr: true,
+		},
+		{
+			name:    "merge without values",
+			req:     model.ValuesUpdateRequest{},
+			wantErr: true,
+		},
+		{
+			name:    "unknown mode",
+			req:     model.ValuesUpdateRequest{Mode: "upsert", Values: map[string]any{}},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			current := newTestValues()
+
+			got, err := applyValuesUpdate(current, tc.req)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			want := newTestValues()
+			tc.want(want)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("expected %#v, got %#v", want, got)
+			}
+
+			if !reflect.DeepEqual(current, newTestValues()) {
+				t.Errorf("current values were modified: %#v", current)
+			}
+		})
+	}
+}
+
+func TestApplyValuesUpdateNilCurrent(t *testing.T) {
+	for _, req := range []model.ValuesUpdateRequest{
+		{Values: map[string]any{"a": float64(1)}},
+		{Mode: model.ValuesModeJSONPatch, Patch: []map[string]any{{"op": "add", "path": "/a", "value": 1}}},
+	} {
+		got, err := applyValuesUpdate(nil, req)
+		if err != nil {
+			t.Fatalf("unexpected error for mode %q: %v", req.Mode, err)
+		}
+		if !reflect.DeepEqual(got, map[string]any{"a": float64(1)}) {
+			t.Errorf("mode %q: expected {a: 1}, got %#v", req.Mode, got)
+		}
+	}
+}
+
+func TestOverrideValues(t *testing.T) {
+	current := newTestValues()
+
+	got := overrideValues(current, map[string]any{"image": map[string]any{"tag": "1.26"}})
+
+	if !reflect.DeepEqual(got["image"], map[string]any{"tag": "1.26"}) {
+		t.Errorf("expected image to be replaced at the top level, got %#v", got["image"])
+	}
+	if !reflect.DeepEqual(current, newTestValues()) {
+		t.Errorf("current values were modified: %#v", current)
+	}
+}
@@ -0,0 +1,156 @@
+package helm
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/helm-version-manager/api/internal/model"
+	"github.com/helm-version-manager/api/internal/status"
+	"helm.sh/helm/v3/pkg/release"
+)
+
+// verifyInterval is how often resources are polled while verifying an
+// upgrade
+var verifyInterval = 2 * time.Second
+
+// waitForResources polls check until every resource is Current, any
+// resource has Failed, or ctx is done. It returns the aggregated status and
+// the resource statuses of the last poll.
+func waitForResources(ctx context.Context, interval time.Duration, check func(context.Context) []model.ResourceStatus) (string, []model.ResourceStatus) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		resources := check(ctx)
+		aggregate := status.Aggregate(resources)
+		if aggregate == model.ResourceStatusCurrent || aggregate == model.ResourceStatusFailed {
+			return aggregate, resources
+		}
+
+		select {
+		case <-ctx.Done():
+			return aggregate, resources
+		case <-ticker.C:
+		}
+	}
+}
+
+// verifyUpgrade watches the resources of the upgraded release until they are
+// ready or the verify timeout passes, rolls back to the previous revision on
+// failure and records the outcome in the audit log. It returns the release as
+// it stands afterwards, with the verification attached. Once ctx is done,
+// typically because the operation lost its lock on the release, verification
+// stops and the release is left as it is.
+func (c *Client) verifyUpgrade(ctx context.Context, namespace, name, action, deployedBy string, upgraded *release.Release, previous int, opts model.UpgradeOptions, progress model.ProgressFunc) (*model.Release, error) {
+	timeout, err := parseTimeout(opts.VerifyTimeout)
+	if err != nil {
+		return nil, err
+	}
+	progress.Report(model.PhaseVerify, "Verifying that the resources of revision %d become ready within %s", upgraded.Version, timeout)
+
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	verification := &model.Verification{
+		Revision:  upgraded.Version,
+		StartedAt: time.Now().UTC(),
+	}
+
+	objects := parseManifest(upgraded.Manifest)
+	aggregate, resources := waitForResources(waitCtx, verifyInterval, func(ctx context.Context) []model.ResourceStatus {
+		return c.statusChecker.Check(ctx, namespace, objects)
+	})
+	verification.Resources = resources
+
+	result := toModelRelease(upgraded)
+	switch {
+	case aggregate == model.ResourceStatusCurrent:
+		verification.Outcome = model.VerificationVerified
+		verification.Message = "All resources are ready"
+
+	case ctx.Err() != nil:
+		// Another operation may have taken over the release, and rolling
+		// back would undo its changes
+		verification.Outcome = model.VerificationAborted
+		verification.Message = fmt.Sprintf("Verification stopped, not rolling back to revision %d: %v", previous, context.Cause(ctx))
+		progress.Report(model.PhaseVerify, "%s", verification.Message)
+
+	default:
+		if aggregate == model.ResourceStatusFailed {
+			verification.Outcome = model.VerificationRolledBack
+			verification.Message = "Resources failed: " + describeUnready(resources)
+		} else {
+			verification.Outcome = model.VerificationTimedOut
+			verification.Message = fmt.Sprintf("Resources not ready after %s: %s", timeout, describeUnready(resources))
+		}
+
+		progress.Report(model.PhaseRollback, "%s; rolling back to revision %d", verification.Message, previous)
+		rolledBack, err := c.RollbackRelease(ctx, namespace, name, model.RollbackRequest{Revision: previous, DeployedBy: deployedBy}, progress)
+		if err != nil {
+			verification.Outcome = model.VerificationRollbackFailed
+			verification.Message += fmt.Sprintf("; rollback to revision %d failed: %v", previous, err)
+		} else {
+			verification.RolledBackTo = previous
+			result = *rolledBack
+		}
+	}
+	verification.CompletedAt = time.Now().UTC()
+	result.Verification = verification
+
+	c.recordAudit(model.AuditRecord{
+		Time:         verification.CompletedAt,
+		Namespace:    namespace,
+		Name:         name,
+		Action:       action,
+		ChartVersion: upgraded.Chart.Metadata.Version,
+		Revision:     upgraded.Version,
+		Outcome:      verification.Outcome,
+		RolledBackTo: verification.RolledBackTo,
+		Message:      verification.Message,
+	})
+
+	return &result, nil
+}
+
+// recordAudit writes an audit record. Failing to record does not undo what
+// has already been done to the release, so errors are only logged.
+func (c *Client) recordAudit(record model.AuditRecord) {
+	if c.auditStore == nil {
+		return
+	}
+	if err := c.auditStore.Record(context.Background(), record); err != nil {
+		log.Printf("Failed to record audit entry for %s/%s: %v", record.Namespace, record.Name, err)
+	}
+}
+
+// GetAuditRecords returns the audit records of a release, newest first.
+func (c *Client) GetAuditRecords(namespace, name string) ([]model.AuditRecord, error) {
+	if c.auditStore == nil {
+		return []model.AuditRecord{}, nil
+	}
+
+	records, err := c.auditStore.ListRecords(context.Background(), namespace, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit records: %w", err)
+	}
+	return records, nil
+}
+
+// describeUnready lists the resources that are not Current with the reason
+func describeUnready(resources []model.ResourceStatus) string {
+	var parts []string
+	for _, r := range resources {
+		if r.Status == model.ResourceStatusCurrent {
+			continue
+		}
+		part := fmt.Sprintf("%s/%s is %s", r.Kind, r.Name, r.Status)
+		if r.Message != "" {
+			part += " (" + r.Message + ")"
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,212 @@
+package helm
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/helm-version-manager/api/internal/model"
+	"github.com/helm-version-manager/api/internal/status"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/release"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func statusSequence(sequence ...[]string) (func(context.Context) []model.ResourceStatus, *int) {
+	calls := 0
+	return func(context.Context) []model.ResourceStatus {
+		current := sequence[min(calls, len(sequence)-1)]
+		calls++
+		resources := make([]model.ResourceStatus, 0, len(current))
+		for _, s := range current {
+			resources = append(resources, model.ResourceStatus{Kind: "Deployment", Name: "web", Status: s})
+		}
+		return resources
+	}, &calls
+}
+
+func TestWaitForResources(t *testing.T) {
+	testCases := []struct {
+		name      string
+		sequence  [][]string
+		timeout   time.Duration
+		want      string
+		wantCalls int
+	}{
+		{
+			name:      "ready immediately",
+			sequence:  [][]string{{model.ResourceStatusCurrent, model.ResourceStatusCurrent}},
+			timeout:   time.Second,
+			want:      model.ResourceStatusCurrent,
+			wantCalls: 1,
+		},
+		{
+			name: "becomes ready",
+			sequence: [][]string{
+				{model.ResourceStatusInProgress, model.ResourceStatusCurrent},
+				{model.ResourceStatusInProgress, model.ResourceStatusCurrent},
+				{model.ResourceStatusCurrent, model.ResourceStatusCurrent},
+			},
+			timeout:   time.Second,
+			want:      model.ResourceStatusCurrent,
+			wantCalls: 3,
+		},
+		{
+			name: "fails",
+			sequence: [][]string{
+				{model.ResourceStatusInProgress},
+				{model.ResourceStatusFailed},
+			},
+			timeout:   time.Second,
+			want:      model.ResourceStatusFailed,
+			wantCalls: 2,
+		},
+		{
+			name:     "times out",
+			sequence: [][]string{{model.ResourceStatusInProgress}},
+			timeout:  50 * time.Millisecond,
+			want:     model.ResourceStatusInProgress,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), tc.timeout)
+			defer cancel()
+
+			check, calls := statusSequence(tc.sequence...)
+			got, resources := waitForResources(ctx, 5*time.Millisecond, check)
+
+			if got != tc.want {
+				t.Errorf("expected %s, got %s", tc.want, got)
+			}
+			if len(resources) == 0 {
+				t.Error("expected the statuses of the last poll")
+			}
+			if tc.wantCalls > 0 && *calls != tc.wantCalls {
+				t.Errorf("expected %d polls, got %d", tc.wantCalls, *calls)
+			}
+		})
+	}
+}
+
+func TestDescribeUnready(t *testing.T) {
+	got := describeUnready([]model.ResourceStatus{
+		{Kind: "Deployment", Name: "web", Status: model.ResourceStatusInProgress, Message: "Available: 0/2"},
+		{Kind: "Service", Name: "web", Status: model.ResourceStatusCurrent},
+		{Kind: "Job", Name: "migrate", Status: model.ResourceStatusFailed},
+	})
+
+	want := "Deployment/web is InProgress (Available: 0/2), Job/migrate is Failed"
+	if got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+// newVerifyFixture returns a client whose only resource is the Deployment
+// apps/web with readyReplicas of its one replica ready, and revision 4 of
+// the release web deploying it
+func newVerifyFixture(readyReplicas int64) (*Client, *release.Release) {
+	deploymentGVK := schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(deploymentGVK, meta.RESTScopeNamespace)
+
+	live := &unstructured.Unstructured{Object: map[string]any{
+		"spec": map[string]any{"replicas": int64(1)},
+		"status": map[string]any{
+			"replicas": int64(1), "updatedReplicas": int64(1), "readyReplicas": readyReplicas, "availableReplicas": readyReplicas,
+		},
+	}}
+	live.SetGroupVersionKind(deploymentGVK)
+	live.SetNamespace("apps")
+	live.SetName("web")
+
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		{Group: "apps", Version: "v1", Resource: "deployments"}: "DeploymentList",
+	}, live)
+
+	c := &Client{statusChecker: status.NewCheckerForClient(dynamicClient, mapper)}
+
+	upgraded := &release.Release{
+		Name:      "web",
+		Namespace: "apps",
+		Version:   4,
+		Info:      &release.Info{Status: release.StatusDeployed},
+		Chart:     &chart.Chart{Metadata: &chart.Metadata{Name: "web", Version: "1.2.0"}},
+		Manifest:  "---\n# Source: web/templates/deployment.yaml\napiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: web\n",
+	}
+	return c, upgraded
+}
+
+func TestVerifyUpgradeVerified(t *testing.T) {
+	c, upgraded := newVerifyFixture(1)
+
+	var phases []string
+	progress := func(phase, message string) { phases = append(phases, phase) }
+
+	result, err := c.verifyUpgrade(context.Background(), "apps", "web", model.AuditActionUpgrade, "", upgraded, 3, model.UpgradeOptions{VerifyTimeout: "5s"}, progress)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(phases) != 1 || phases[0] != model.PhaseVerify {
+		t.Errorf("expected only the verify phase to be reported, got %v", phases)
+	}
+
+	v := result.Verification
+	if v == nil {
+		t.Fatal("expected verification to be set")
+	}
+	if v.Outcome != model.VerificationVerified {
+		t.Errorf("expected outcome verified, got %s (%s)", v.Outcome, v.Message)
+	}
+	if v.Revision != 4 || v.RolledBackTo != 0 {
+		t.Errorf("expected revision 4 without rollback, got %+v", v)
+	}
+	if result.Revision != 4 {
+		t.Errorf("expected the upgraded release to be returned, got revision %d", result.Revision)
+	}
+	if len(v.Resources) != 1 || v.Resources[0].Status != model.ResourceStatusCurrent {
+		t.Errorf("unexpected resources %+v", v.Resources)
+	}
+	if v.CompletedAt.Before(v.StartedAt) {
+		t.Error("expected completion after start")
+	}
+}
+
+func TestVerifyUpgradeAborted(t *testing.T) {
+	c, upgraded := newVerifyFixture(0)
+
+	// The operation lost its lock while the Deployment was still rolling out
+	ctx, cancel := context.WithCancelCause(context.Background())
+	cancel(errors.New("lost the lock"))
+
+	var phases []string
+	progress := func(phase, message string) { phases = append(phases, phase) }
+
+	result, err := c.verifyUpgrade(ctx, "apps", "web", model.AuditActionUpgrade, "", upgraded, 3, model.UpgradeOptions{VerifyTimeout: "5s"}, progress)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, phase := range phases {
+		if phase == model.PhaseRollback {
+			t.Errorf("expected no rollback once the context is done, got phases %v", phases)
+		}
+	}
+
+	v := result.Verification
+	if v.Outcome != model.VerificationAborted || v.RolledBackTo != 0 {
+		t.Errorf("expected verification to be aborted without rollback, got %+v", v)
+	}
+	if !strings.Contains(v.Message, "lost the lock") {
+		t.Errorf("expected the cause in the message, got %q", v.Message)
+	}
+	if result.Revision != 4 {
+		t.Errorf("expected the upgraded release to be returned, got revision %d", result.Revision)
+	}
+}
@@ -0,0 +1,82 @@
+package helm
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/helm-version-manager/api/internal/model"
+)
+
+const defaultVersionLimit = 10
+
+// filterChartVersions parses tags as semantic versions and returns them newest
+// first. Tags that are not valid semver (e.g. "latest" or "sha-abc") are
+// dropped, as are prereleases unless query.IncludePrerelease is set.
+func filterChartVersions(tags []string, query model.VersionQuery) ([]model.ChartVersion, error) {
+	var constraint *semver.Constraints
+	if query.Constraint != "" {
+		c, err := semver.NewConstraint(query.Constraint)
+		if err != nil {
+			return nil, fmt.Errorf("invalid version constraint %q: %w", query.Constraint, err)
+		}
+		constraint = c
+	}
+
+	limit := query.Limit
+	if limit <= 0 {
+		limit = defaultVersionLimit
+	}
+
+	versions := make([]*semver.Version, 0, len(tags))
+	for _, tag := range tags {
+		// OCI tags cannot contain '+', so Helm pushes build metadata with '_' instead
+		v, err := semver.StrictNewVersion(strings.ReplaceAll(tag, "_", "+"))
+		if err != nil {
+			continue
+		}
+
+		if v.Prerelease() != "" && !query.IncludePrerelease {
+			continue
+		}
+
+		if constraint != nil && !matchesConstraint(constraint, v) {
+			continue
+		}
+
+		versions = append(versions, v)
+	}
+
+	sort.Sort(sort.Reverse(semver.Collection(versions)))
+
+	if len(versions) > limit {
+		versions = versions[:limit]
+	}
+
+	result := make([]model.ChartVersion, 0, len(versions))
+	for _, v := range versions {
+		result = append(result, model.ChartVersion{
+			Version: v.Original(),
+		})
+	}
+
+	return result, nil
+}
+
+// matchesConstraint checks v against c. Constraints never match prereleases
+// unless they name one explicitly, so when prereleases were requested they are
+// checked by their release version instead.
+func matchesConstraint(c *semver.Constraints, v *semver.Version) bool {
+	if c.Check(v) {
+		return true
+	}
+	if v.Prerelease() == "" {
+		return false
+	}
+	release, err := v.SetPrerelease("")
+	if err != nil {
+		return false
+	}
+	return c.Check(&release)
+}
@@ -0,0 +1,139 @@
+package helm
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/helm-version-manager/api/internal/model"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/cli"
+)
+
+func TestFilterChartVersions(t *testing.T) {
+	tags := []string{"0.9.0", "latest", "1.0.0", "1.10.0", "1.2.0", "sha-abc123", "2.0.0-rc.1", "2.0.0", "2.1.0", "1.3.0_build.5", "v3"}
+
+	testCases := []struct {
+		name  string
+		query model.VersionQuery
+		want  []string
+	}{
+		{
+			name:  "semver order without prereleases",
+			query: model.VersionQuery{},
+			want:  []string{"2.1.0", "2.0.0", "1.10.0", "1.3.0+build.5", "1.2.0", "1.0.0", "0.9.0"},
+		},
+		{
+			name:  "include prereleases",
+			query: model.VersionQuery{IncludePrerelease: true},
+			want:  []string{"2.1.0", "2.0.0", "2.0.0-rc.1", "1.10.0", "1.3.0+build.5", "1.2.0", "1.0.0", "0.9.0"},
+		},
+		{
+			name:  "limit",
+			query: model.VersionQuery{Limit: 2},
+			want:  []string{"2.1.0", "2.0.0"},
+		},
+		{
+			name:  "caret constraint",
+			query: model.VersionQuery{Constraint: "^1.x"},
+			want:  []string{"1.10.0", "1.3.0+build.5", "1.2.0", "1.0.0"},
+		},
+		{
+			name:  "constraint with prereleases",
+			query: model.VersionQuery{Constraint: "^2.x", IncludePrerelease: true},
+			want:  []string{"2.1.0", "2.0.0", "2.0.0-rc.1"},
+		},
+		{
+			name:  "range constraint",
+			query: model.VersionQuery{Constraint: ">=1.2.0 <2.0.0"},
+			want:  []string{"1.10.0", "1.3.0+build.5", "1.2.0"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			versions, err := filterChartVersions(tags, tc.query)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			got := make([]string, 0, len(versions))
+			for _, v := range versions {
+				got = append(got, v.Version)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("expected %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestFilterChartVersionsDefaultLimit(t *testing.T) {
+	var tags []string
+	for i := 0; i < 15; i++ {
+		tags = append(tags, "1."+strconv.Itoa(i)+".0")
+	}
+
+	versions, err := filterChartVersions(tags, model.VersionQuery{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(versions) != defaultVersionLimit {
+		t.Fatalf("expected %d versions, got %d", defaultVersionLimit, len(versions))
+	}
+	if versions[0].Version != "1.14.0" {
+		t.Errorf("expected newest version 1.14.0 first, got %s", versions[0].Version)
+	}
+}
+
+func TestFilterChartVersionsInvalidConstraint(t *testing.T) {
+	if _, err := filterChartVersions([]string{"1.0.0"}, model.VersionQuery{Constraint: "not a constraint"}); err == nil {
+		t.Fatal("expected error for invalid constraint")
+	}
+}
+
+func TestSearchChartVersionsMetadata(t *testing.T) {
+	reg := newTestRegistry(t)
+	for _, v := range []struct{ version, appVersion string }{
+		{"1.0.0", "v2.3.0"},
+		{"1.1.0", "v2.4.0"},
+		{"1.2.0-rc.1", "v2.5.0-beta"},
+	} {
+		reg.pushChart(t, "charts", &chart.Metadata{
+			APIVersion:  chart.APIVersionV2,
+			Name:        "mychart",
+			Version:     v.version,
+			AppVersion:  v.appVersion,
+			Description: "mychart " + v.version,
+		})
+	}
+
+	c := &Client{settings: cli.New(), plainHTTP: true}
+	registryURL := "oci://" + reg.host() + "/charts"
+
+	versions, err := c.searchChartVersions(registryURL, "mychart", model.VersionQuery{})
+	if err != nil {
+		t.Fatalf("searchChartVersions failed: %v", err)
+	}
+
+	want := []model.ChartVersion{
+		{Version: "1.1.0", AppVersion: "v2.4.0", Description: "mychart 1.1.0"},
+		{Version: "1.0.0", AppVersion: "v2.3.0", Description: "mychart 1.0.0"},
+	}
+	if !reflect.DeepEqual(versions, want) {
+		t.Fatalf("expected %+v, got %+v", want, versions)
+	}
+
+	fetches := reg.manifestFetches()
+	if fetches != 2 {
+		t.Fatalf("expected 2 manifest fetches, got %d", fetches)
+	}
+
+	// A second listing is served from the digest cache
+	if _, err := c.searchChartVersions(registryURL, "mychart", model.VersionQuery{}); err != nil {
+		t.Fatalf("searchChartVersions failed: %v", err)
+	}
+	if got := reg.manifestFetches(); got != fetches {
+		t.Errorf("expected cached metadata to avoid manifest fetches, got %d fetches", got-fetches)
+	}
+}
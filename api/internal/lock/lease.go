@@ -0,0 +1,266 @@
+// Package lock serializes mutations of a release across helm-ui replicas
+// using Kubernetes Leases.
+package lock
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/helm-version-manager/api/internal/model"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/controller-runtime/pkg/client/config"
+)
+
+const (
+	leaseNamePrefix  = "helm-version-manager-lock"
+	defaultNamespace = "default"
+	unknownReplica   = "unknown"
+
+	// leaseDuration is how long a lock outlives a replica that stopped
+	// renewing it, e.g. because it crashed mid-upgrade
+	leaseDuration = 60 * time.Second
+	renewInterval = leaseDuration / 3
+	// releaseTimeout bounds deleting the lease once the operation is done
+	releaseTimeout = 10 * time.Second
+	// maxAcquireAttempts bounds retries when another replica creates or
+	// takes over the lease at the same time
+	maxAcquireAttempts = 3
+
+	managedByLabel      = "app.kubernetes.io/managed-by"
+	managedByLabelValue = "helm-version-manager"
+	releaseNamespaceKey = "helm-version-manager/release-namespace"
+	releaseNameKey      = "helm-version-manager/release-name"
+	operationKey        = "helm-version-manager/operation"
+	replicaKey          = "helm-version-manager/replica"
+)
+
+// LockedError is returned when a release is locked by another operation
+type LockedError struct {
+	Holder model.LockHolder
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("release %s/%s is locked by %s operation %s on %s since %s",
+		e.Holder.Namespace, e.Holder.Name, e.Holder.Operation, e.Holder.OperationID,
+		e.Holder.Replica, e.Holder.AcquiredAt.Format(time.RFC3339))
+}
+
+// LeaseLocker locks releases with one Lease per release in the namespace
+// helm-ui runs in. A lease that has not been renewed for leaseDuration is
+// considered released.
+type LeaseLocker struct {
+	clientset kubernetes.Interface
+	namespace string
+	replica   string
+	now       func() time.Time
+}
+
+func NewLeaseLocker() (*LeaseLocker, error) {
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get kubernetes config: %w", err)
+	}
+
+	clientset, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create kubernetes client: %w", err)
+	}
+
+	namespace := os.Getenv("NAMESPACE")
+	if namespace == "" {
+		namespace = defaultNamespace
+	}
+
+	// The hostname is the pod name when running in a cluster
+	replica, err := os.Hostname()
+	if err != nil || replica == "" {
+		replica = unknownReplica
+	}
+
+	return newLeaseLocker(clientset, namespace, replica), nil
+}
+
+func newLeaseLocker(clientset kubernetes.Interface, namespace, replica string) *LeaseLocker {
+	return &LeaseLocker{
+		clientset: clientset,
+		namespace: namespace,
+		replica:   replica,
+		now:       time.Now,
+	}
+}
+
+// Acquire locks the release named in holder for its operation, or returns a
+// *LockedError describing the current holder. The lease is renewed until the
+// returned function is called to release it. If another replica takes the
+// lease over in the meantime, lost is called with the reason, unless it is
+// nil.
+func (l *LeaseLocker) Acquire(ctx context.Context, holder model.LockHolder, lost func(error)) (func(), error) {
+	leases := l.clientset.CoordinationV1().Leases(l.namespace)
+	name := leaseName(holder.Namespace, holder.Name)
+
+	for attempt := 0; attempt < maxAcquireAttempts; attempt++ {
+		existing, err := leases.Get(ctx, name, metav1.GetOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return nil, fmt.Errorf("failed to get lease %s: %w", name, err)
+		}
+
+		var lease *coordinationv1.Lease
+		if errors.IsNotFound(err) {
+			lease, err = leases.Create(ctx, l.newLease(name, holder), metav1.CreateOptions{})
+			if errors.IsAlreadyExists(err) {
+				continue
+			}
+		} else {
+			if l.held(existing) {
+				return nil, &LockedError{Holder: holderFromLease(existing)}
+			}
+			taken := l.newLease(name, holder)
+			taken.ResourceVersion = existing.ResourceVersion
+			lease, err = leases.Update(ctx, taken, metav1.UpdateOptions{})
+			if errors.IsConflict(err) {
+				continue
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to acquire lease %s: %w", name, err)
+		}
+
+		return l.keep(lease, lost), nil
+	}
+
+	return nil, fmt.Errorf("failed to acquire lease %s: too many concurrent attempts", name)
+}
+
+// keep renews lease in the background and returns the function releasing
+// it. lost is called when the lease can no longer be renewed because it was
+// taken over or deleted.
+func (l *LeaseLocker) keep(lease *coordinationv1.Lease, lost func(error)) func() {
+	stop := make(chan struct{})
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(renewInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				renewed, err := l.renew(lease)
+				if errors.IsConflict(err) || errors.IsNotFound(err) {
+					log.Printf("Lost lease %s: %v", lease.Name, err)
+					if lost != nil {
+						lost(fmt.Errorf("lost lease %s: %w", lease.Name, err))
+					}
+					return
+				}
+				if err != nil {
+					// Retried on the next tick, well before the lease expires
+					log.Printf("Failed to renew lease %s: %v", lease.Name, err)
+					continue
+				}
+				lease = renewed
+			}
+		}
+	}()
+
+	return func() {
+		close(stop)
+		<-done
+
+		ctx, cancel := context.WithTimeout(context.Background(), releaseTimeout)
+		defer cancel()
+		// The preconditions keep a lease another replica took over after it
+		// expired from being deleted
+		err := l.clientset.CoordinationV1().Leases(l.namespace).Delete(ctx, lease.Name, metav1.DeleteOptions{
+			Preconditions: &metav1.Preconditions{UID: &lease.UID, ResourceVersion: &lease.ResourceVersion},
+		})
+		if err != nil && !errors.IsNotFound(err) && !errors.IsConflict(err) {
+			log.Printf("Failed to release lease %s: %v", lease.Name, err)
+		}
+	}
+}
+
+func (l *LeaseLocker) renew(lease *coordinationv1.Lease) (*coordinationv1.Lease, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), renewInterval)
+	defer cancel()
+
+	renewed := lease.DeepCopy()
+	now := metav1.NewMicroTime(l.now())
+	renewed.Spec.RenewTime = &now
+	return l.clientset.CoordinationV1().Leases(l.namespace).Update(ctx, renewed, metav1.UpdateOptions{})
+}
+
+// held reports whether lease is held and was renewed recently enough
+func (l *LeaseLocker) held(lease *coordinationv1.Lease) bool {
+	spec := lease.Spec
+	if spec.HolderIdentity == nil || *spec.HolderIdentity == "" || spec.RenewTime == nil {
+		return false
+	}
+	duration := leaseDuration
+	if spec.LeaseDurationSeconds != nil {
+		duration = time.Duration(*spec.LeaseDurationSeconds) * time.Second
+	}
+	return l.now().Before(spec.RenewTime.Add(duration))
+}
+
+func (l *LeaseLocker) newLease(name string, holder model.LockHolder) *coordinationv1.Lease {
+	now := metav1.NewMicroTime(l.now())
+	identity := holder.OperationID
+	seconds := int32(leaseDuration / time.Second)
+
+	return &coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: l.namespace,
+			Labels: map[string]string{
+				managedByLabel: managedByLabelValue,
+			},
+			Annotations: map[string]string{
+				releaseNamespaceKey: holder.Namespace,
+				releaseNameKey:      holder.Name,
+				operationKey:        holder.Operation,
+				replicaKey:          l.replica,
+			},
+		},
+		Spec: coordinationv1.LeaseSpec{
+			HolderIdentity:       &identity,
+			LeaseDurationSeconds: &seconds,
+			AcquireTime:          &now,
+			RenewTime:            &now,
+		},
+	}
+}
+
+func holderFromLease(lease *coordinationv1.Lease) model.LockHolder {
+	holder := model.LockHolder{
+		Namespace: lease.Annotations[releaseNamespaceKey],
+		Name:      lease.Annotations[releaseNameKey],
+		Operation: lease.Annotations[operationKey],
+		Replica:   lease.Annotations[replicaKey],
+	}
+	if lease.Spec.HolderIdentity != nil {
+		holder.OperationID = *lease.Spec.HolderIdentity
+	}
+	if lease.Spec.AcquireTime != nil {
+		holder.AcquiredAt = lease.Spec.AcquireTime.UTC()
+	}
+	if lease.Spec.RenewTime != nil {
+		holder.RenewedAt = lease.Spec.RenewTime.UTC()
+	}
+	return holder
+}
+
+// leaseName returns the name of the lease locking a release. Namespaces and
+// release names are DNS labels, so the result is a valid object name.
+func leaseName(namespace, name string) string {
+	return leaseNamePrefix + "." + namespace + "." + name
+}
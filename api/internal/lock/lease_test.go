@@ -0,0 +1,156 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/helm-version-manager/api/internal/model"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newTestLocker(replica string, now *time.Time) *LeaseLocker {
+	l := newLeaseLocker(fake.NewClientset(), "helm-ui", replica)
+	l.now = func() time.Time { return *now }
+	return l
+}
+
+func holder(operationID, operation string) model.LockHolder {
+	return model.LockHolder{Namespace: "apps", Name: "web", OperationID: operationID, Operation: operation}
+}
+
+func TestAcquireConflict(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	l := newTestLocker("helm-ui-0", &now)
+	ctx := context.Background()
+
+	release, err := l.Acquire(ctx, holder("op-1", model.OperationUpgrade), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer release()
+
+	lease, err := l.clientset.CoordinationV1().Leases("helm-ui").Get(ctx, "helm-version-manager-lock.apps.web", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("expected lease to be created: %v", err)
+	}
+	if *lease.Spec.HolderIdentity != "op-1" {
+		t.Errorf("expected holder op-1, got %s", *lease.Spec.HolderIdentity)
+	}
+
+	// Another replica sharing the cluster sees the lock
+	other := newLeaseLocker(l.clientset, "helm-ui", "helm-ui-1")
+	other.now = l.now
+	_, err = other.Acquire(ctx, holder("op-2", model.OperationUpdateValues), nil)
+	var locked *LockedError
+	if !errors.As(err, &locked) {
+		t.Fatalf("expected LockedError, got %v", err)
+	}
+	want := model.LockHolder{
+		Namespace:   "apps",
+		Name:        "web",
+		OperationID: "op-1",
+		Operation:   model.OperationUpgrade,
+		Replica:     "helm-ui-0",
+		AcquiredAt:  now,
+		RenewedAt:   now,
+	}
+	if locked.Holder != want {
+		t.Errorf("expected holder %+v, got %+v", want, locked.Holder)
+	}
+	if got := locked.Error(); got != "release apps/web is locked by upgrade operation op-1 on helm-ui-0 since 2026-01-02T03:04:05Z" {
+		t.Errorf("unexpected message %q", got)
+	}
+
+	// Other releases are not affected
+	releaseOther, err := other.Acquire(ctx, model.LockHolder{Namespace: "apps", Name: "api", OperationID: "op-3"}, nil)
+	if err != nil {
+		t.Fatalf("expected a different release to be lockable: %v", err)
+	}
+	releaseOther()
+}
+
+func TestRelease(t *testing.T) {
+	now := time.Now()
+	l := newTestLocker("helm-ui-0", &now)
+	ctx := context.Background()
+
+	release, err := l.Acquire(ctx, holder("op-1", model.OperationRollback), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	release()
+
+	_, err = l.clientset.CoordinationV1().Leases("helm-ui").Get(ctx, "helm-version-manager-lock.apps.web", metav1.GetOptions{})
+	if !apierrors.IsNotFound(err) {
+		t.Errorf("expected lease to be deleted, got %v", err)
+	}
+
+	release, err = l.Acquire(ctx, holder("op-2", model.OperationUpgrade), nil)
+	if err != nil {
+		t.Fatalf("expected released lock to be acquired again: %v", err)
+	}
+	release()
+}
+
+func TestAcquireTakesOverExpiredLease(t *testing.T) {
+	now := time.Now()
+	l := newTestLocker("helm-ui-0", &now)
+	ctx := context.Background()
+
+	// Acquired by a replica that went away without releasing it
+	if _, err := l.Acquire(ctx, holder("op-1", model.OperationUpgrade), nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	now = now.Add(leaseDuration - time.Second)
+	if _, err := l.Acquire(ctx, holder("op-2", model.OperationUpgrade), nil); err == nil {
+		t.Fatal("expected lease to still be held before it expires")
+	}
+
+	now = now.Add(2 * time.Second)
+	release, err := l.Acquire(ctx, holder("op-2", model.OperationUpgrade), nil)
+	if err != nil {
+		t.Fatalf("expected expired lease to be taken over: %v", err)
+	}
+	defer release()
+
+	lease, err := l.clientset.CoordinationV1().Leases("helm-ui").Get(ctx, "helm-version-manager-lock.apps.web", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if *lease.Spec.HolderIdentity != "op-2" || !lease.Spec.AcquireTime.Time.Equal(now) {
+		t.Errorf("expected lease to be held by op-2 since %s, got %s since %s", now, *lease.Spec.HolderIdentity, lease.Spec.AcquireTime)
+	}
+}
+
+func TestRenew(t *testing.T) {
+	now := time.Now()
+	l := newTestLocker("helm-ui-0", &now)
+	ctx := context.Background()
+
+	lease, err := l.clientset.CoordinationV1().Leases("helm-ui").Create(ctx, l.newLease("helm-version-manager-lock.apps.web", holder("op-1", model.OperationUpgrade)), metav1.CreateOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	now = now.Add(renewInterval)
+	renewed, err := l.renew(lease)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !renewed.Spec.RenewTime.Time.Equal(now) {
+		t.Errorf("expected renew time %s, got %s", now, renewed.Spec.RenewTime)
+	}
+	if !l.held(renewed) {
+		t.Error("expected renewed lease to be held")
+	}
+
+	now = now.Add(leaseDuration)
+	if l.held(renewed) {
+		t.Error("expected lease not renewed for its duration to have expired")
+	}
+}
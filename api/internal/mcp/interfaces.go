@@ -10,18 +10,38 @@ import (
 type HelmClient interface {
 	ListReleases() ([]model.Release, error)
 	GetRelease(namespace, name string) (*model.Release, error)
-	GetAvailableVersions(namespace, name string) ([]model.ChartVersion, error)
-	UpgradeRelease(namespace, name string, req model.VersionUpgradeRequest) (*model.Release, error)
-	GetReleaseHistory(namespace, name string) ([]model.ReleaseHistory, error)
+	InstallRelease(namespace string, req model.InstallRequest) (*model.Release, error)
+	UninstallRelease(namespace, name string, req model.UninstallRequest) (*model.UninstallResult, error)
+	GetAvailableVersions(namespace, name string, query model.VersionQuery) ([]model.ChartVersion, error)
+	UpgradeRelease(ctx context.Context, namespace, name string, req model.VersionUpgradeRequest, progress model.ProgressFunc) (*model.Release, error)
+	PreviewUpgrade(namespace, name string, req model.VersionUpgradeRequest) (*model.UpgradePreview, error)
+	GetReleaseHistory(namespace, name string, query model.HistoryQuery) (*model.HistoryPage, error)
+	GetReleaseManifest(namespace, name string, revision int) (*model.ReleaseManifest, error)
+	GetReleaseNotes(namespace, name string, revision int) (*model.ReleaseNotes, error)
+	GetReleaseHooks(namespace, name string, revision int) (*model.ReleaseHooks, error)
+	GetReleaseResources(ctx context.Context, namespace, name string) (*model.ReleaseResources, error)
+	GetAuditRecords(namespace, name string) ([]model.AuditRecord, error)
+	RunReleaseTests(ctx context.Context, namespace, name string, req model.TestRequest, progress model.ProgressFunc) (*model.Release, error)
 	GetReleaseValues(namespace, name string) (map[string]any, error)
-	UpdateReleaseValues(namespace, name string, values map[string]any) (*model.Release, error)
-	RollbackRelease(namespace, name string, revision int) (*model.Release, error)
+	GetChartValues(namespace, name string) (map[string]any, error)
+	GetComputedValues(namespace, name string) (map[string]any, error)
+	GetValuesSchema(namespace, name string) (map[string]any, error)
+	GetRevisionValues(namespace, name string, revision int) (map[string]any, error)
+	DiffRevisions(namespace, name string, from, to int) (*model.RevisionDiff, error)
+	UpdateReleaseValues(ctx context.Context, namespace, name string, req model.ValuesUpdateRequest, progress model.ProgressFunc) (*model.Release, error)
+	RollbackRelease(ctx context.Context, namespace, name string, req model.RollbackRequest, progress model.ProgressFunc) (*model.Release, error)
 }
 
-// RegistryStore defines the interface for registry mapping storage
+// RegistryStore defines the interface for registry mapping and rule storage
 type RegistryStore interface {
 	GetMapping(ctx context.Context, namespace, releaseName string) (*model.RegistryMapping, error)
 	SetMapping(ctx context.Context, mapping model.RegistryMapping) error
 	DeleteMapping(ctx context.Context, namespace, releaseName string) error
 	ListMappings(ctx context.Context) ([]model.RegistryMapping, error)
+	ResolveMapping(ctx context.Context, namespace, releaseName string) (*model.RegistryResolution, error)
+
+	GetRule(ctx context.Context, id string) (*model.RegistryRule, error)
+	SetRule(ctx context.Context, rule model.RegistryRule) error
+	DeleteRule(ctx context.Context, id string) error
+	ListRules(ctx context.Context) ([]model.RegistryRule, error)
 }
@@ -0,0 +1,117 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/helm-version-manager/api/internal/model"
+	"github.com/helm-version-manager/api/internal/operation"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// operationError is returned by runOperation when the operation failed. It
+// keeps the values schema violations that caused the failure, if any.
+type operationError struct {
+	message string
+	errors  []model.FieldError
+}
+
+func (e *operationError) Error() string {
+	return e.message
+}
+
+// runOperation runs an operation on the manager shared with the REST API and
+// waits for it. It fails at once when another operation holds the release's
+// lock. When the client sent a progress token, each event of the operation is
+// forwarded as a progress notification. If ctx ends first the operation keeps
+// running and can be looked up with get_operation.
+func (s *Server) runOperation(ctx context.Context, req *mcp.CallToolRequest, opType, namespace, name string, run operation.RunFunc) (*model.Release, string, error) {
+	started, err := s.operations.Start(ctx, opType, namespace, name, run)
+	if err != nil {
+		return nil, "", err
+	}
+	op, events, unsubscribe, err := s.operations.Subscribe(ctx, started.ID)
+	if err != nil {
+		return nil, started.ID, fmt.Errorf("failed to follow operation %s: %w", started.ID, err)
+	}
+	defer func() { unsubscribe() }()
+
+	notify := progressNotifier(ctx, req)
+	last := 0
+	notifyNew := func(events []model.OperationEvent) {
+		for _, e := range events {
+			if e.Sequence > last {
+				notify(e)
+				last = e.Sequence
+			}
+		}
+	}
+	notifyNew(op.Events)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, op.ID, fmt.Errorf("stopped waiting for operation %s, which is still running: %w", op.ID, ctx.Err())
+		case e, ok := <-events:
+			if ok {
+				notifyNew([]model.OperationEvent{e})
+				continue
+			}
+			// The operation completed, or we fell behind and catch up
+			// from its events before following it again
+			op, events, unsubscribe, err = s.operations.Subscribe(ctx, op.ID)
+			if err != nil {
+				return nil, started.ID, fmt.Errorf("failed to follow operation %s: %w", started.ID, err)
+			}
+			notifyNew(op.Events)
+			if op.CompletedAt == nil {
+				continue
+			}
+			if op.Status == model.OperationFailed {
+				return nil, op.ID, &operationError{message: op.Error, errors: op.Errors}
+			}
+			return op.Result, op.ID, nil
+		}
+	}
+}
+
+// operationFailure reports a tool whose operation failed. Values schema
+// violations are returned as structured output next to the error message,
+// so a client can point at the offending fields; other failures are plain
+// tool errors.
+func operationFailure(message, opID string, err error) (*mcp.CallToolResult, ReleaseOutput, error) {
+	var failed *operationError
+	if !errors.As(err, &failed) || len(failed.errors) == 0 {
+		return nil, ReleaseOutput{}, fmt.Errorf("%s: %w", message, err)
+	}
+
+	result := &mcp.CallToolResult{
+		IsError: true,
+		Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("%s: %v", message, err)}},
+	}
+	return result, ReleaseOutput{OperationID: opID, Errors: failed.errors}, nil
+}
+
+// progressNotifier returns a function sending an operation event to the
+// client as a progress notification, or doing nothing when the client did not
+// ask for progress
+func progressNotifier(ctx context.Context, req *mcp.CallToolRequest) func(model.OperationEvent) {
+	if req == nil || req.Session == nil || req.Params == nil {
+		return func(model.OperationEvent) {}
+	}
+	token := req.Params.GetProgressToken()
+	if token == nil {
+		return func(model.OperationEvent) {}
+	}
+
+	return func(e model.OperationEvent) {
+		// Progress is informational; a client that cannot receive it still
+		// gets the result
+		_ = req.Session.NotifyProgress(ctx, &mcp.ProgressNotificationParams{
+			ProgressToken: token,
+			Progress:      float64(e.Sequence),
+			Message:       fmt.Sprintf("%s: %s", e.Phase, e.Message),
+		})
+	}
+}
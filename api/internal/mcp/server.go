@@ -2,9 +2,12 @@ package mcp
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/helm-version-manager/api/internal/model"
+	"github.com/helm-version-manager/api/internal/operation"
+	"github.com/helm-version-manager/api/internal/storage"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
@@ -13,6 +16,7 @@ type Server struct {
 	mcpServer     *mcp.Server
 	helmClient    HelmClient
 	registryStore RegistryStore
+	operations    *operation.Manager
 }
 
 // Input/Output types for MCP tools
@@ -33,20 +37,151 @@ type ReleaseInput struct {
 
 type ReleaseOutput struct {
 	Release *model.Release `json:"release"`
+	// OperationID is set by tools that run as operations
+	OperationID string `json:"operation_id,omitempty"`
+	// Errors lists the values schema violations an operation failed on
+	Errors []model.FieldError `json:"errors,omitempty"`
+}
+
+type InstallInput struct {
+	Namespace    string         `json:"namespace" jsonschema:"The namespace to install the release into (created if missing)"`
+	Name         string         `json:"name" jsonschema:"The name of the new release"`
+	Chart        string         `json:"chart" jsonschema:"The name of the chart to install"`
+	Registry     string         `json:"registry" jsonschema:"The OCI registry (e.g. oci://ghcr.io/myorg/charts) or chart repository URL (e.g. https://charts.example.com)"`
+	ChartVersion string         `json:"chart_version,omitempty" jsonschema:"The chart version to install (optional, defaults to the latest stable version)"`
+	Values       map[string]any `json:"values,omitempty" jsonschema:"Values to install the release with (optional)"`
+}
+
+type UninstallInput struct {
+	Namespace   string `json:"namespace" jsonschema:"The namespace of the release"`
+	Name        string `json:"name" jsonschema:"The name of the release"`
+	KeepHistory bool   `json:"keep_history,omitempty" jsonschema:"Keep the release history (and its registry mapping) so it can be rolled back (optional)"`
+	Wait        bool   `json:"wait,omitempty" jsonschema:"Wait until all resources are deleted before returning (optional)"`
+	Timeout     string `json:"timeout,omitempty" jsonschema:"Timeout for hooks and waiting, as a duration such as 5m (optional, default 5m)"`
+}
+
+type UninstallOutput struct {
+	Result *model.UninstallResult `json:"result"`
+}
+
+type VersionsInput struct {
+	Namespace         string `json:"namespace" jsonschema:"The namespace of the release"`
+	Name              string `json:"name" jsonschema:"The name of the release"`
+	Limit             int    `json:"limit,omitempty" jsonschema:"Maximum number of versions to return, newest first (optional, default 10)"`
+	IncludePrerelease bool   `json:"include_prerelease,omitempty" jsonschema:"Include prerelease versions such as 1.2.0-rc.1 (optional)"`
+	Constraint        string `json:"constraint,omitempty" jsonschema:"Semver constraint to filter versions (e.g. ^2.x or >=1.4 <2) (optional)"`
 }
 
 type VersionsOutput struct {
 	Versions []model.ChartVersion `json:"versions"`
 }
 
+// RollbackOptionsInput holds the Helm options shared by upgrade and rollback
+// tools. Options left unset use the server defaults.
+type RollbackOptionsInput struct {
+	Wait          *bool  `json:"wait,omitempty" jsonschema:"Wait until all resources are ready before returning (optional)"`
+	WaitForJobs   *bool  `json:"wait_for_jobs,omitempty" jsonschema:"With wait, also wait until all Jobs have completed (optional)"`
+	Timeout       string `json:"timeout,omitempty" jsonschema:"Timeout for hooks and waiting, as a duration such as 5m (optional)"`
+	Force         *bool  `json:"force,omitempty" jsonschema:"Force resource updates through a replacement strategy (optional)"`
+	DisableHooks  *bool  `json:"disable_hooks,omitempty" jsonschema:"Do not run chart hooks (optional)"`
+	CleanupOnFail *bool  `json:"cleanup_on_fail,omitempty" jsonschema:"Delete new resources created by the operation when it fails (optional)"`
+	MaxHistory    *int   `json:"max_history,omitempty" jsonschema:"Maximum number of revisions kept in history, 0 for no limit (optional)"`
+}
+
+func (o RollbackOptionsInput) toModel() model.RollbackOptions {
+	return model.RollbackOptions{
+		Wait:          o.Wait,
+		WaitForJobs:   o.WaitForJobs,
+		Timeout:       o.Timeout,
+		Force:         o.Force,
+		DisableHooks:  o.DisableHooks,
+		CleanupOnFail: o.CleanupOnFail,
+		MaxHistory:    o.MaxHistory,
+	}
+}
+
+// UpgradeOptionsInput adds the options that only apply to upgrades
+type UpgradeOptionsInput struct {
+	Atomic        *bool  `json:"atomic,omitempty" jsonschema:"Roll back automatically if the operation fails; implies wait (optional)"`
+	Verify        *bool  `json:"verify,omitempty" jsonschema:"After an upgrade, watch the release's resources and roll back to the previous revision unless they all become ready; the outcome is returned in verification (optional, upgrades only)"`
+	VerifyTimeout string `json:"verify_timeout,omitempty" jsonschema:"How long verification waits for resources to become ready, as a duration such as 5m (optional)"`
+	RollbackOptionsInput
+}
+
+func (o UpgradeOptionsInput) toModel() model.UpgradeOptions {
+	return model.UpgradeOptions{
+		Atomic:        o.Atomic,
+		Wait:          o.Wait,
+		WaitForJobs:   o.WaitForJobs,
+		Timeout:       o.Timeout,
+		Force:         o.Force,
+		DisableHooks:  o.DisableHooks,
+		CleanupOnFail: o.CleanupOnFail,
+		MaxHistory:    o.MaxHistory,
+		Verify:        o.Verify,
+		VerifyTimeout: o.VerifyTimeout,
+	}
+}
+
 type UpgradeInput struct {
-	Namespace    string `json:"namespace" jsonschema:"The namespace of the release"`
-	Name         string `json:"name" jsonschema:"The name of the release"`
-	ChartVersion string `json:"chart_version" jsonschema:"The target chart version to upgrade to"`
+	Namespace        string   `json:"namespace" jsonschema:"The namespace of the release"`
+	Name             string   `json:"name" jsonschema:"The name of the release"`
+	ChartVersion     string   `json:"chart_version" jsonschema:"The target chart version to upgrade to"`
+	RunTests         bool     `json:"run_tests,omitempty" jsonschema:"Run the release tests after the upgrade, and after a successful verification if verify is set, and roll back to the previous revision if any fails; the outcome is returned in tests (optional, upgrades only)"`
+	TestFilter       []string `json:"test_filter,omitempty" jsonschema:"With run_tests, the names of the tests to run; prefix a name with ! to skip it instead (optional)"`
+	TestTimeout      string   `json:"test_timeout,omitempty" jsonschema:"With run_tests, the timeout for each test as a duration such as 5m (optional)"`
+	TestLogs         bool     `json:"test_logs,omitempty" jsonschema:"With run_tests, capture the logs of the test pods (optional)"`
+	ExpectedRevision int      `json:"expected_revision,omitempty" jsonschema:"Only upgrade if the release is still at this revision, e.g. the revision you last read; fails if someone changed it since (optional)"`
+	UpgradeOptionsInput
+}
+
+type PreviewOutput struct {
+	Preview *model.UpgradePreview `json:"preview"`
+}
+
+type HistoryInput struct {
+	Namespace string `json:"namespace" jsonschema:"The namespace of the release"`
+	Name      string `json:"name" jsonschema:"The name of the release"`
+	Max       int    `json:"max,omitempty" jsonschema:"Maximum number of revisions to return, newest first (optional, default 10)"`
+	Before    int    `json:"before,omitempty" jsonschema:"Only return revisions older than this revision; pass next_before from a previous call to page (optional)"`
 }
 
 type HistoryOutput struct {
-	History []model.ReleaseHistory `json:"history"`
+	History    []model.ReleaseHistory `json:"history"`
+	NextBefore int                    `json:"next_before,omitempty"`
+}
+
+type DiffRevisionsInput struct {
+	Namespace string `json:"namespace" jsonschema:"The namespace of the release"`
+	Name      string `json:"name" jsonschema:"The name of the release"`
+	From      int    `json:"from" jsonschema:"The revision to compare from"`
+	To        int    `json:"to" jsonschema:"The revision to compare to"`
+}
+
+type DiffRevisionsOutput struct {
+	Diff *model.RevisionDiff `json:"diff"`
+}
+
+type RevisionInput struct {
+	Namespace string `json:"namespace" jsonschema:"The namespace of the release"`
+	Name      string `json:"name" jsonschema:"The name of the release"`
+	Revision  int    `json:"revision,omitempty" jsonschema:"The revision to inspect (optional, defaults to the latest revision)"`
+}
+
+type ManifestOutput struct {
+	Manifest *model.ReleaseManifest `json:"manifest"`
+}
+
+type NotesOutput struct {
+	Notes *model.ReleaseNotes `json:"notes"`
+}
+
+type HooksOutput struct {
+	Hooks *model.ReleaseHooks `json:"hooks"`
+}
+
+type ResourcesOutput struct {
+	Resources *model.ReleaseResources `json:"resources"`
 }
 
 type RegistryInput struct {
@@ -62,7 +197,7 @@ type RegistryOutput struct {
 type SetRegistryInput struct {
 	Namespace string `json:"namespace" jsonschema:"The namespace of the release"`
 	Name      string `json:"name" jsonschema:"The name of the release"`
-	Registry  string `json:"registry" jsonschema:"The OCI registry URL (e.g. oci://ghcr.io/myorg/charts)"`
+	Registry  string `json:"registry" jsonschema:"The OCI registry (e.g. oci://ghcr.io/myorg/charts) or chart repository URL (e.g. https://charts.example.com)"`
 }
 
 type DeleteRegistryOutput struct {
@@ -70,27 +205,96 @@ type DeleteRegistryOutput struct {
 	Message string `json:"message"`
 }
 
+type RegistryResolutionOutput struct {
+	Resolution *model.RegistryResolution `json:"resolution"`
+}
+
+type ListRegistryRulesInput struct{}
+
+type RegistryRulesOutput struct {
+	Rules []model.RegistryRule `json:"rules"`
+}
+
+type SetRegistryRuleInput struct {
+	ID                string `json:"id" jsonschema:"The rule ID, a lowercase DNS subdomain name such as ourorg-charts; an existing rule with this ID is replaced"`
+	Chart             string `json:"chart,omitempty" jsonschema:"Glob matched against the chart name, e.g. ourorg-* (optional, matches every chart when empty)"`
+	NamespaceSelector string `json:"namespace_selector,omitempty" jsonschema:"Kubernetes label selector matched against the labels of the release's namespace, e.g. team=payments (optional, matches every namespace when empty)"`
+	Priority          int    `json:"priority,omitempty" jsonschema:"Rules are tried highest priority first, ties by ID (default 0)"`
+	Registry          string `json:"registry" jsonschema:"The OCI registry (e.g. oci://ghcr.io/myorg/charts) or chart repository URL (e.g. https://charts.example.com)"`
+}
+
+type RegistryRuleInput struct {
+	ID string `json:"id" jsonschema:"The rule ID"`
+}
+
+type RegistryRuleOutput struct {
+	Rule *model.RegistryRule `json:"rule"`
+}
+
 type ValuesOutput struct {
 	Values map[string]any `json:"values"`
 }
 
+type ValuesSchemaOutput struct {
+	HasSchema bool           `json:"has_schema"`
+	Schema    map[string]any `json:"schema,omitempty"`
+}
+
 type UpdateValuesInput struct {
-	Namespace string         `json:"namespace" jsonschema:"The namespace of the release"`
-	Name      string         `json:"name" jsonschema:"The name of the release"`
-	Values    map[string]any `json:"values" jsonschema:"The new values to set for the release"`
+	Namespace        string           `json:"namespace" jsonschema:"The namespace of the release"`
+	Name             string           `json:"name" jsonschema:"The name of the release"`
+	Mode             string           `json:"mode,omitempty" jsonschema:"How to apply the update: merge (default) deep-merges values and removes keys set to null, replace replaces all values, json-patch applies patch"`
+	Values           map[string]any   `json:"values,omitempty" jsonschema:"The values to merge or replace with (merge and replace modes)"`
+	Patch            []map[string]any `json:"patch,omitempty" jsonschema:"RFC 6902 JSON Patch operations to apply to the current values (json-patch mode)"`
+	ExpectedRevision int              `json:"expected_revision,omitempty" jsonschema:"Only update the values if the release is still at this revision, e.g. the revision you last read; fails if someone changed it since (optional)"`
+	UpgradeOptionsInput
 }
 
-type RollbackInput struct {
+type AuditInput struct {
 	Namespace string `json:"namespace" jsonschema:"The namespace of the release"`
 	Name      string `json:"name" jsonschema:"The name of the release"`
-	Revision  int    `json:"revision" jsonschema:"The revision number to rollback to"`
 }
 
-// NewServer creates a new MCP server with Helm tools
-func NewServer(helmClient HelmClient, registryStore RegistryStore) *Server {
+type AuditOutput struct {
+	Records []model.AuditRecord `json:"records"`
+}
+
+type RunTestsInput struct {
+	Namespace string   `json:"namespace" jsonschema:"The namespace of the release"`
+	Name      string   `json:"name" jsonschema:"The name of the release"`
+	Filter    []string `json:"filter,omitempty" jsonschema:"The names of the tests to run; prefix a name with ! to skip it instead (optional, defaults to all tests)"`
+	Timeout   string   `json:"timeout,omitempty" jsonschema:"Timeout for each test, as a duration such as 5m (optional)"`
+	Logs      bool     `json:"logs,omitempty" jsonschema:"Capture the logs of the test pods (optional)"`
+}
+
+type TestsOutput struct {
+	Result      *model.TestResult `json:"result"`
+	OperationID string            `json:"operation_id,omitempty"`
+}
+
+type OperationInput struct {
+	ID string `json:"id" jsonschema:"The operation ID returned by upgrade_release, update_release_values, rollback_release or run_release_tests"`
+}
+
+type OperationOutput struct {
+	Operation *model.Operation `json:"operation"`
+}
+
+type RollbackInput struct {
+	Namespace        string `json:"namespace" jsonschema:"The namespace of the release"`
+	Name             string `json:"name" jsonschema:"The name of the release"`
+	Revision         int    `json:"revision" jsonschema:"The revision number to rollback to"`
+	ExpectedRevision int    `json:"expected_revision,omitempty" jsonschema:"Only roll back if the release is still at this revision, e.g. the revision you last read; fails if someone changed it since (optional)"`
+	RollbackOptionsInput
+}
+
+// NewServer creates a new MCP server with Helm tools. Upgrades, values
+// updates and rollbacks run as operations on operations.
+func NewServer(helmClient HelmClient, registryStore RegistryStore, operations *operation.Manager) *Server {
 	s := &Server{
 		helmClient:    helmClient,
 		registryStore: registryStore,
+		operations:    operations,
 	}
 
 	mcpServer := mcp.NewServer(
@@ -125,34 +329,94 @@ func (s *Server) registerTools() {
 		Description: "Get details of a specific Helm release",
 	}, s.handleGetRelease)
 
+	// Install release tool
+	mcp.AddTool(s.mcpServer, &mcp.Tool{
+		Name:        "install_release",
+		Description: "Install a new Helm release from a registry. The namespace is created if it does not exist, and a registry mapping is saved for the release so it can be upgraded later. Fails while another operation holds the release's lock; the error names the operation holding it.",
+	}, s.handleInstallRelease)
+
+	// Uninstall release tool
+	mcp.AddTool(s.mcpServer, &mcp.Tool{
+		Name:        "uninstall_release",
+		Description: "Uninstall a Helm release. Its registry mapping is deleted unless keep_history is set. Fails without changing anything while another operation holds the release's lock; the error names the operation holding it.",
+	}, s.handleUninstallRelease)
+
 	// Get available versions tool
 	mcp.AddTool(s.mcpServer, &mcp.Tool{
 		Name:        "get_available_versions",
-		Description: "Get available chart versions for a Helm release. Requires a registry mapping to be configured for the release.",
+		Description: "Get available chart versions for a Helm release, newest first. Non-semver tags and prereleases are excluded by default. Requires a registry mapping to be configured for the release.",
 	}, s.handleGetAvailableVersions)
 
 	// Upgrade release tool
 	mcp.AddTool(s.mcpServer, &mcp.Tool{
 		Name:        "upgrade_release",
-		Description: "Upgrade a Helm release to a specific chart version. Requires a registry mapping to be configured for the release.",
+		Description: "Upgrade a Helm release to a specific chart version. With verify, the release's resources are watched afterwards and the release is rolled back automatically if they do not become ready; check verification.outcome in the result. With run_tests, the release tests run afterwards and the release is rolled back if any fails; check tests.passed in the result. Requires a registry mapping to be configured for the release. Phases (pull-chart, render, apply, wait, ...) are sent as progress notifications when a progress token is given. Fails without changing anything while another upgrade, values update or rollback of the release is in progress; the error names the operation holding it.",
 	}, s.handleUpgradeRelease)
 
+	// Preview upgrade tool
+	mcp.AddTool(s.mcpServer, &mcp.Tool{
+		Name:        "preview_upgrade",
+		Description: "Preview an upgrade of a Helm release to a specific chart version without applying it. Returns a unified diff of the rendered manifest per Kubernetes resource. Requires a registry mapping to be configured for the release.",
+	}, s.handlePreviewUpgrade)
+
 	// Get release history tool
 	mcp.AddTool(s.mcpServer, &mcp.Tool{
 		Name:        "get_release_history",
-		Description: "Get the revision history of a Helm release",
+		Description: "Get the revision history of a Helm release, newest first. Use max and before to page through older revisions.",
 	}, s.handleGetReleaseHistory)
 
+	// Diff revisions tool
+	mcp.AddTool(s.mcpServer, &mcp.Tool{
+		Name:        "diff_revisions",
+		Description: "Compare two revisions of a Helm release. Returns the added, removed and changed user-supplied values by key path, and a per-resource diff of the rendered manifests.",
+	}, s.handleDiffRevisions)
+
+	// Get release manifest tool
+	mcp.AddTool(s.mcpServer, &mcp.Tool{
+		Name:        "get_release_manifest",
+		Description: "Get the Kubernetes manifest deployed by a Helm release revision, as raw YAML and as a list of objects with apiVersion, kind, namespace and name. Defaults to the latest revision.",
+	}, s.handleGetReleaseManifest)
+
+	// Get release notes tool
+	mcp.AddTool(s.mcpServer, &mcp.Tool{
+		Name:        "get_release_notes",
+		Description: "Get the rendered NOTES.txt of a Helm release revision. Defaults to the latest revision.",
+	}, s.handleGetReleaseNotes)
+
+	// Get release hooks tool
+	mcp.AddTool(s.mcpServer, &mcp.Tool{
+		Name:        "get_release_hooks",
+		Description: "Get the hooks of a Helm release revision with their events, weights, delete policies and the outcome of their last run. Defaults to the latest revision.",
+	}, s.handleGetReleaseHooks)
+
+	// Get release resources tool
+	mcp.AddTool(s.mcpServer, &mcp.Tool{
+		Name:        "get_release_resources",
+		Description: "Get the live status of every Kubernetes resource deployed by a Helm release (Current, InProgress, Failed, Terminating, NotFound or Unknown) with a message explaining it. Use this to check whether a deployed release is actually healthy.",
+	}, s.handleGetReleaseResources)
+
+	// Get audit records tool
+	mcp.AddTool(s.mcpServer, &mcp.Tool{
+		Name:        "get_audit_records",
+		Description: "Get the audit log of a Helm release, newest first: the outcome of verified or tested upgrades and values updates (verified, tests-passed, rolled-back, timed-out or rollback-failed) and of test runs (tests-passed or tests-failed).",
+	}, s.handleGetAuditRecords)
+
+	// Run release tests tool
+	mcp.AddTool(s.mcpServer, &mcp.Tool{
+		Name:        "run_release_tests",
+		Description: "Run the tests of a Helm release, like helm test. Returns the phase and timing of each test hook and, with logs, the logs of the test pods. Failing tests are reported with passed set to false rather than as an error. Runs as an operation; with a progress token each phase is sent as a progress notification. Fails while another operation holds the release's lock; the error names the operation holding it.",
+	}, s.handleRunReleaseTests)
+
 	// Get registry mapping tool
 	mcp.AddTool(s.mcpServer, &mcp.Tool{
 		Name:        "get_registry",
-		Description: "Get the registry mapping for a Helm release",
+		Description: "Get the registry mapping for a Helm release. Without an explicit mapping, the registry comes from the first matching registry rule, or is discovered from the helm-ui/registry annotation of the release's chart, the helm-ui/registry label of the release or the helm-ui/registry annotation of its namespace, in that order; source tells where the mapping came from. Use resolve_registry to see why.",
 	}, s.handleGetRegistry)
 
 	// Set registry mapping tool
 	mcp.AddTool(s.mcpServer, &mcp.Tool{
 		Name:        "set_registry",
-		Description: "Set the registry mapping for a Helm release. This is required before upgrading a release.",
+		Description: "Set the explicit registry mapping for a Helm release. It takes precedence over registry rules and discovered registries and is required before upgrading a release whose registry cannot be discovered.",
 	}, s.handleSetRegistry)
 
 	// Delete registry mapping tool
@@ -161,23 +425,71 @@ func (s *Server) registerTools() {
 		Description: "Delete the registry mapping for a Helm release",
 	}, s.handleDeleteRegistry)
 
+	// Resolve registry tool
+	mcp.AddTool(s.mcpServer, &mcp.Tool{
+		Name:        "resolve_registry",
+		Description: "Explain how the registry of a Helm release is resolved: the explicit mapping, then registry rules by priority, then the chart annotation, release label and namespace annotation. Returns each source tried, why it matched or not, and the resulting mapping.",
+	}, s.handleResolveRegistry)
+
+	// List registry rules tool
+	mcp.AddTool(s.mcpServer, &mcp.Tool{
+		Name:        "list_registry_rules",
+		Description: "List the registry rules in the order they are tried. Rules give releases without an explicit registry mapping a registry by chart name glob and namespace label selector.",
+	}, s.handleListRegistryRules)
+
+	// Set registry rule tool
+	mcp.AddTool(s.mcpServer, &mcp.Tool{
+		Name:        "set_registry_rule",
+		Description: "Create or replace a registry rule. Every release without an explicit registry mapping whose chart name matches chart and whose namespace labels match namespace_selector uses registry, unless a higher priority rule matches first. A rule with neither chart nor namespace_selector is a default for all releases.",
+	}, s.handleSetRegistryRule)
+
+	// Delete registry rule tool
+	mcp.AddTool(s.mcpServer, &mcp.Tool{
+		Name:        "delete_registry_rule",
+		Description: "Delete a registry rule by ID",
+	}, s.handleDeleteRegistryRule)
+
 	// Get release values tool
 	mcp.AddTool(s.mcpServer, &mcp.Tool{
 		Name:        "get_release_values",
 		Description: "Get the current values (configuration) of a Helm release",
 	}, s.handleGetReleaseValues)
 
+	// Get chart default values tool
+	mcp.AddTool(s.mcpServer, &mcp.Tool{
+		Name:        "get_chart_default_values",
+		Description: "Get the default values.yaml of the chart a Helm release is deployed with",
+	}, s.handleGetChartValues)
+
+	// Get computed values tool
+	mcp.AddTool(s.mcpServer, &mcp.Tool{
+		Name:        "get_computed_values",
+		Description: "Get the computed values of a Helm release: the user-supplied values merged over the chart defaults, as used for rendering",
+	}, s.handleGetComputedValues)
+
+	// Get values schema tool
+	mcp.AddTool(s.mcpServer, &mcp.Tool{
+		Name:        "get_values_schema",
+		Description: "Get the values.schema.json of the chart a Helm release is deployed with. Values passed to update_release_values and upgrade_release are validated against it.",
+	}, s.handleGetValuesSchema)
+
 	// Update release values tool
 	mcp.AddTool(s.mcpServer, &mcp.Tool{
 		Name:        "update_release_values",
-		Description: "Update the values (configuration) of a Helm release. Only the specified values will be updated; existing values are preserved. Requires a registry mapping to be configured for the release.",
+		Description: "Update the values (configuration) of a Helm release. By default values are deep-merged into the existing values and keys set to null are removed; mode replace sets exactly the given values and mode json-patch applies RFC 6902 operations. Requires a registry mapping to be configured for the release; values that do not match the chart's values schema fail the tool with each violation listed in errors. Phases (pull-chart, render, apply, wait, ...) are sent as progress notifications when a progress token is given. Fails without changing anything while another upgrade, values update or rollback of the release is in progress; the error names the operation holding it.",
 	}, s.handleUpdateReleaseValues)
 
 	// Rollback release tool
 	mcp.AddTool(s.mcpServer, &mcp.Tool{
 		Name:        "rollback_release",
-		Description: "Rollback a Helm release to a specific revision. This allows you to revert to a previous version of the release.",
+		Description: "Rollback a Helm release to a specific revision. This allows you to revert to a previous version of the release. Phases (apply, wait) are sent as progress notifications when a progress token is given. Fails without changing anything while another upgrade, values update or rollback of the release is in progress; the error names the operation holding it.",
 	}, s.handleRollbackRelease)
+
+	// Get operation tool
+	mcp.AddTool(s.mcpServer, &mcp.Tool{
+		Name:        "get_operation",
+		Description: "Get the status, phase, events and result of an upgrade, values update, rollback or test operation by the operation_id its tool returned. Use this when a call stopped waiting while the operation kept running.",
+	}, s.handleGetOperation)
 }
 
 func (s *Server) handleListReleases(ctx context.Context, req *mcp.CallToolRequest, input ListReleasesInput) (*mcp.CallToolResult, ListReleasesOutput, error) {
@@ -186,12 +498,20 @@ func (s *Server) handleListReleases(ctx context.Context, req *mcp.CallToolReques
 		return nil, ListReleasesOutput{}, fmt.Errorf("failed to list releases: %w", err)
 	}
 
+	// Resolve the registries of all releases at once rather than one by one
+	mappings, err := s.registryStore.ListMappings(ctx)
+	if err != nil {
+		return nil, ListReleasesOutput{}, fmt.Errorf("failed to list registry mappings: %w", err)
+	}
+	registrySet := make(map[string]bool, len(mappings))
+	for _, m := range mappings {
+		registrySet[m.Namespace+"/"+m.ReleaseName] = true
+	}
+
 	// Enrich with registry info and filter
 	filteredReleases := make([]model.Release, 0)
 	for _, r := range releases {
-		// Check registry mapping
-		mapping, _ := s.registryStore.GetMapping(ctx, r.Namespace, r.Name)
-		r.HasRegistry = mapping != nil
+		r.HasRegistry = registrySet[r.Namespace+"/"+r.Name]
 
 		// Apply namespace filter
 		if input.Namespace != "" && r.Namespace != input.Namespace {
@@ -226,12 +546,75 @@ func (s *Server) handleGetRelease(ctx context.Context, req *mcp.CallToolRequest,
 	return nil, ReleaseOutput{Release: release}, nil
 }
 
-func (s *Server) handleGetAvailableVersions(ctx context.Context, req *mcp.CallToolRequest, input ReleaseInput) (*mcp.CallToolResult, VersionsOutput, error) {
+func (s *Server) handleInstallRelease(ctx context.Context, req *mcp.CallToolRequest, input InstallInput) (*mcp.CallToolResult, ReleaseOutput, error) {
+	if input.Namespace == "" || input.Name == "" || input.Chart == "" || input.Registry == "" {
+		return nil, ReleaseOutput{}, fmt.Errorf("namespace, name, chart, and registry are required")
+	}
+
+	installReq := model.InstallRequest{
+		Name:         input.Name,
+		Chart:        input.Chart,
+		Registry:     input.Registry,
+		ChartVersion: input.ChartVersion,
+		Values:       input.Values,
+		DeployedBy:   deployedBy(req),
+	}
+
+	unlock, err := s.operations.Lock(ctx, model.OperationInstall, input.Namespace, input.Name)
+	if err != nil {
+		return nil, ReleaseOutput{}, fmt.Errorf("failed to install release: %w", err)
+	}
+	defer unlock()
+
+	release, err := s.helmClient.InstallRelease(input.Namespace, installReq)
+	if err != nil {
+		return nil, ReleaseOutput{}, fmt.Errorf("failed to install release: %w", err)
+	}
+
+	return nil, ReleaseOutput{Release: release}, nil
+}
+
+func (s *Server) handleUninstallRelease(ctx context.Context, req *mcp.CallToolRequest, input UninstallInput) (*mcp.CallToolResult, UninstallOutput, error) {
+	if input.Namespace == "" || input.Name == "" {
+		return nil, UninstallOutput{}, fmt.Errorf("namespace and name are required")
+	}
+
+	uninstallReq := model.UninstallRequest{
+		KeepHistory: input.KeepHistory,
+		Wait:        input.Wait,
+		Timeout:     input.Timeout,
+	}
+
+	unlock, err := s.operations.Lock(ctx, model.OperationUninstall, input.Namespace, input.Name)
+	if err != nil {
+		return nil, UninstallOutput{}, fmt.Errorf("failed to uninstall release: %w", err)
+	}
+	defer unlock()
+
+	result, err := s.helmClient.UninstallRelease(input.Namespace, input.Name, uninstallReq)
+	if err != nil {
+		return nil, UninstallOutput{}, fmt.Errorf("failed to uninstall release: %w", err)
+	}
+
+	return nil, UninstallOutput{Result: result}, nil
+}
+
+func (s *Server) handleGetAvailableVersions(ctx context.Context, req *mcp.CallToolRequest, input VersionsInput) (*mcp.CallToolResult, VersionsOutput, error) {
 	if input.Namespace == "" || input.Name == "" {
 		return nil, VersionsOutput{}, fmt.Errorf("namespace and name are required")
 	}
 
-	versions, err := s.helmClient.GetAvailableVersions(input.Namespace, input.Name)
+	if input.Limit < 0 {
+		return nil, VersionsOutput{}, fmt.Errorf("limit must not be negative")
+	}
+
+	query := model.VersionQuery{
+		Limit:             input.Limit,
+		IncludePrerelease: input.IncludePrerelease,
+		Constraint:        input.Constraint,
+	}
+
+	versions, err := s.helmClient.GetAvailableVersions(input.Namespace, input.Name, query)
 	if err != nil {
 		return nil, VersionsOutput{}, fmt.Errorf("failed to get available versions: %w", err)
 	}
@@ -244,29 +627,185 @@ func (s *Server) handleUpgradeRelease(ctx context.Context, req *mcp.CallToolRequ
 		return nil, ReleaseOutput{}, fmt.Errorf("namespace, name, and chart_version are required")
 	}
 
+	if input.ExpectedRevision < 0 {
+		return nil, ReleaseOutput{}, fmt.Errorf("expected_revision must be a positive integer")
+	}
+
+	upgradeReq := model.VersionUpgradeRequest{
+		ChartVersion:     input.ChartVersion,
+		ExpectedRevision: input.ExpectedRevision,
+		DeployedBy:       deployedBy(req),
+		UpgradeOptions:   input.toModel(),
+	}
+	if input.RunTests {
+		upgradeReq.Test = &model.TestRequest{
+			Filter:  input.TestFilter,
+			Timeout: input.TestTimeout,
+			Logs:    input.TestLogs,
+		}
+	}
+
+	release, opID, err := s.runOperation(ctx, req, model.OperationUpgrade, input.Namespace, input.Name, func(ctx context.Context, progress model.ProgressFunc) (*model.Release, error) {
+		return s.helmClient.UpgradeRelease(ctx, input.Namespace, input.Name, upgradeReq, progress)
+	})
+	if err != nil {
+		return operationFailure("failed to upgrade release", opID, err)
+	}
+
+	return nil, ReleaseOutput{Release: release, OperationID: opID}, nil
+}
+
+func (s *Server) handlePreviewUpgrade(ctx context.Context, req *mcp.CallToolRequest, input UpgradeInput) (*mcp.CallToolResult, PreviewOutput, error) {
+	if input.Namespace == "" || input.Name == "" || input.ChartVersion == "" {
+		return nil, PreviewOutput{}, fmt.Errorf("namespace, name, and chart_version are required")
+	}
+
 	upgradeReq := model.VersionUpgradeRequest{
 		ChartVersion: input.ChartVersion,
 	}
 
-	release, err := s.helmClient.UpgradeRelease(input.Namespace, input.Name, upgradeReq)
+	preview, err := s.helmClient.PreviewUpgrade(input.Namespace, input.Name, upgradeReq)
 	if err != nil {
-		return nil, ReleaseOutput{}, fmt.Errorf("failed to upgrade release: %w", err)
+		return nil, PreviewOutput{}, fmt.Errorf("failed to preview upgrade: %w", err)
 	}
 
-	return nil, ReleaseOutput{Release: release}, nil
+	return nil, PreviewOutput{Preview: preview}, nil
 }
 
-func (s *Server) handleGetReleaseHistory(ctx context.Context, req *mcp.CallToolRequest, input ReleaseInput) (*mcp.CallToolResult, HistoryOutput, error) {
+func (s *Server) handleGetReleaseHistory(ctx context.Context, req *mcp.CallToolRequest, input HistoryInput) (*mcp.CallToolResult, HistoryOutput, error) {
 	if input.Namespace == "" || input.Name == "" {
 		return nil, HistoryOutput{}, fmt.Errorf("namespace and name are required")
 	}
 
-	history, err := s.helmClient.GetReleaseHistory(input.Namespace, input.Name)
+	if input.Max < 0 || input.Before < 0 {
+		return nil, HistoryOutput{}, fmt.Errorf("max and before must not be negative")
+	}
+
+	query := model.HistoryQuery{
+		Max:    input.Max,
+		Before: input.Before,
+	}
+
+	page, err := s.helmClient.GetReleaseHistory(input.Namespace, input.Name, query)
 	if err != nil {
 		return nil, HistoryOutput{}, fmt.Errorf("failed to get release history: %w", err)
 	}
 
-	return nil, HistoryOutput{History: history}, nil
+	return nil, HistoryOutput{History: page.History, NextBefore: page.NextBefore}, nil
+}
+
+func (s *Server) handleDiffRevisions(ctx context.Context, req *mcp.CallToolRequest, input DiffRevisionsInput) (*mcp.CallToolResult, DiffRevisionsOutput, error) {
+	if input.Namespace == "" || input.Name == "" {
+		return nil, DiffRevisionsOutput{}, fmt.Errorf("namespace and name are required")
+	}
+
+	if input.From <= 0 || input.To <= 0 {
+		return nil, DiffRevisionsOutput{}, fmt.Errorf("from and to must be positive integers")
+	}
+
+	diff, err := s.helmClient.DiffRevisions(input.Namespace, input.Name, input.From, input.To)
+	if err != nil {
+		return nil, DiffRevisionsOutput{}, fmt.Errorf("failed to diff revisions: %w", err)
+	}
+
+	return nil, DiffRevisionsOutput{Diff: diff}, nil
+}
+
+func (s *Server) handleGetReleaseManifest(ctx context.Context, req *mcp.CallToolRequest, input RevisionInput) (*mcp.CallToolResult, ManifestOutput, error) {
+	if input.Namespace == "" || input.Name == "" {
+		return nil, ManifestOutput{}, fmt.Errorf("namespace and name are required")
+	}
+
+	if input.Revision < 0 {
+		return nil, ManifestOutput{}, fmt.Errorf("revision must not be negative")
+	}
+
+	manifest, err := s.helmClient.GetReleaseManifest(input.Namespace, input.Name, input.Revision)
+	if err != nil {
+		return nil, ManifestOutput{}, fmt.Errorf("failed to get release manifest: %w", err)
+	}
+
+	return nil, ManifestOutput{Manifest: manifest}, nil
+}
+
+func (s *Server) handleGetReleaseNotes(ctx context.Context, req *mcp.CallToolRequest, input RevisionInput) (*mcp.CallToolResult, NotesOutput, error) {
+	if input.Namespace == "" || input.Name == "" {
+		return nil, NotesOutput{}, fmt.Errorf("namespace and name are required")
+	}
+
+	if input.Revision < 0 {
+		return nil, NotesOutput{}, fmt.Errorf("revision must not be negative")
+	}
+
+	notes, err := s.helmClient.GetReleaseNotes(input.Namespace, input.Name, input.Revision)
+	if err != nil {
+		return nil, NotesOutput{}, fmt.Errorf("failed to get release notes: %w", err)
+	}
+
+	return nil, NotesOutput{Notes: notes}, nil
+}
+
+func (s *Server) handleGetReleaseHooks(ctx context.Context, req *mcp.CallToolRequest, input RevisionInput) (*mcp.CallToolResult, HooksOutput, error) {
+	if input.Namespace == "" || input.Name == "" {
+		return nil, HooksOutput{}, fmt.Errorf("namespace and name are required")
+	}
+
+	if input.Revision < 0 {
+		return nil, HooksOutput{}, fmt.Errorf("revision must not be negative")
+	}
+
+	hooks, err := s.helmClient.GetReleaseHooks(input.Namespace, input.Name, input.Revision)
+	if err != nil {
+		return nil, HooksOutput{}, fmt.Errorf("failed to get release hooks: %w", err)
+	}
+
+	return nil, HooksOutput{Hooks: hooks}, nil
+}
+
+func (s *Server) handleGetReleaseResources(ctx context.Context, req *mcp.CallToolRequest, input ReleaseInput) (*mcp.CallToolResult, ResourcesOutput, error) {
+	if input.Namespace == "" || input.Name == "" {
+		return nil, ResourcesOutput{}, fmt.Errorf("namespace and name are required")
+	}
+
+	resources, err := s.helmClient.GetReleaseResources(ctx, input.Namespace, input.Name)
+	if err != nil {
+		return nil, ResourcesOutput{}, fmt.Errorf("failed to get release resources: %w", err)
+	}
+
+	return nil, ResourcesOutput{Resources: resources}, nil
+}
+
+func (s *Server) handleGetAuditRecords(ctx context.Context, req *mcp.CallToolRequest, input AuditInput) (*mcp.CallToolResult, AuditOutput, error) {
+	if input.Namespace == "" || input.Name == "" {
+		return nil, AuditOutput{}, fmt.Errorf("namespace and name are required")
+	}
+
+	records, err := s.helmClient.GetAuditRecords(input.Namespace, input.Name)
+	if err != nil {
+		return nil, AuditOutput{}, fmt.Errorf("failed to get audit records: %w", err)
+	}
+
+	return nil, AuditOutput{Records: records}, nil
+}
+
+func (s *Server) handleRunReleaseTests(ctx context.Context, req *mcp.CallToolRequest, input RunTestsInput) (*mcp.CallToolResult, TestsOutput, error) {
+	if input.Namespace == "" || input.Name == "" {
+		return nil, TestsOutput{}, fmt.Errorf("namespace and name are required")
+	}
+
+	testReq := model.TestRequest{
+		Filter:  input.Filter,
+		Timeout: input.Timeout,
+		Logs:    input.Logs,
+	}
+	release, opID, err := s.runOperation(ctx, req, model.OperationTest, input.Namespace, input.Name, func(ctx context.Context, progress model.ProgressFunc) (*model.Release, error) {
+		return s.helmClient.RunReleaseTests(ctx, input.Namespace, input.Name, testReq, progress)
+	})
+	if err != nil {
+		return nil, TestsOutput{}, fmt.Errorf("failed to run release tests: %w", err)
+	}
+
+	return nil, TestsOutput{Result: release.Tests, OperationID: opID}, nil
 }
 
 func (s *Server) handleGetRegistry(ctx context.Context, req *mcp.CallToolRequest, input RegistryInput) (*mcp.CallToolResult, RegistryOutput, error) {
@@ -323,6 +862,59 @@ func (s *Server) handleDeleteRegistry(ctx context.Context, req *mcp.CallToolRequ
 	return nil, DeleteRegistryOutput{Success: true, Message: "registry mapping deleted"}, nil
 }
 
+func (s *Server) handleResolveRegistry(ctx context.Context, req *mcp.CallToolRequest, input RegistryInput) (*mcp.CallToolResult, RegistryResolutionOutput, error) {
+	if input.Namespace == "" || input.Name == "" {
+		return nil, RegistryResolutionOutput{}, fmt.Errorf("namespace and name are required")
+	}
+
+	resolution, err := s.registryStore.ResolveMapping(ctx, input.Namespace, input.Name)
+	if err != nil {
+		return nil, RegistryResolutionOutput{}, fmt.Errorf("failed to resolve registry: %w", err)
+	}
+
+	return nil, RegistryResolutionOutput{Resolution: resolution}, nil
+}
+
+func (s *Server) handleListRegistryRules(ctx context.Context, req *mcp.CallToolRequest, input ListRegistryRulesInput) (*mcp.CallToolResult, RegistryRulesOutput, error) {
+	rules, err := s.registryStore.ListRules(ctx)
+	if err != nil {
+		return nil, RegistryRulesOutput{}, fmt.Errorf("failed to list registry rules: %w", err)
+	}
+
+	return nil, RegistryRulesOutput{Rules: rules}, nil
+}
+
+func (s *Server) handleSetRegistryRule(ctx context.Context, req *mcp.CallToolRequest, input SetRegistryRuleInput) (*mcp.CallToolResult, RegistryRuleOutput, error) {
+	rule := model.RegistryRule{
+		ID:                input.ID,
+		Chart:             input.Chart,
+		NamespaceSelector: input.NamespaceSelector,
+		Priority:          input.Priority,
+		Registry:          input.Registry,
+	}
+	if err := storage.ValidateRule(rule); err != nil {
+		return nil, RegistryRuleOutput{}, err
+	}
+
+	if err := s.registryStore.SetRule(ctx, rule); err != nil {
+		return nil, RegistryRuleOutput{}, fmt.Errorf("failed to set registry rule: %w", err)
+	}
+
+	return nil, RegistryRuleOutput{Rule: &rule}, nil
+}
+
+func (s *Server) handleDeleteRegistryRule(ctx context.Context, req *mcp.CallToolRequest, input RegistryRuleInput) (*mcp.CallToolResult, DeleteRegistryOutput, error) {
+	if input.ID == "" {
+		return nil, DeleteRegistryOutput{}, fmt.Errorf("id is required")
+	}
+
+	if err := s.registryStore.DeleteRule(ctx, input.ID); err != nil {
+		return nil, DeleteRegistryOutput{}, fmt.Errorf("failed to delete registry rule: %w", err)
+	}
+
+	return nil, DeleteRegistryOutput{Success: true, Message: "registry rule deleted"}, nil
+}
+
 func (s *Server) handleGetReleaseValues(ctx context.Context, req *mcp.CallToolRequest, input ReleaseInput) (*mcp.CallToolResult, ValuesOutput, error) {
 	if input.Namespace == "" || input.Name == "" {
 		return nil, ValuesOutput{}, fmt.Errorf("namespace and name are required")
@@ -336,21 +928,84 @@ func (s *Server) handleGetReleaseValues(ctx context.Context, req *mcp.CallToolRe
 	return nil, ValuesOutput{Values: values}, nil
 }
 
+func (s *Server) handleGetChartValues(ctx context.Context, req *mcp.CallToolRequest, input ReleaseInput) (*mcp.CallToolResult, ValuesOutput, error) {
+	if input.Namespace == "" || input.Name == "" {
+		return nil, ValuesOutput{}, fmt.Errorf("namespace and name are required")
+	}
+
+	values, err := s.helmClient.GetChartValues(input.Namespace, input.Name)
+	if err != nil {
+		return nil, ValuesOutput{}, fmt.Errorf("failed to get chart default values: %w", err)
+	}
+
+	return nil, ValuesOutput{Values: values}, nil
+}
+
+func (s *Server) handleGetComputedValues(ctx context.Context, req *mcp.CallToolRequest, input ReleaseInput) (*mcp.CallToolResult, ValuesOutput, error) {
+	if input.Namespace == "" || input.Name == "" {
+		return nil, ValuesOutput{}, fmt.Errorf("namespace and name are required")
+	}
+
+	values, err := s.helmClient.GetComputedValues(input.Namespace, input.Name)
+	if err != nil {
+		return nil, ValuesOutput{}, fmt.Errorf("failed to get computed values: %w", err)
+	}
+
+	return nil, ValuesOutput{Values: values}, nil
+}
+
+func (s *Server) handleGetValuesSchema(ctx context.Context, req *mcp.CallToolRequest, input ReleaseInput) (*mcp.CallToolResult, ValuesSchemaOutput, error) {
+	if input.Namespace == "" || input.Name == "" {
+		return nil, ValuesSchemaOutput{}, fmt.Errorf("namespace and name are required")
+	}
+
+	schema, err := s.helmClient.GetValuesSchema(input.Namespace, input.Name)
+	if err != nil {
+		return nil, ValuesSchemaOutput{}, fmt.Errorf("failed to get values schema: %w", err)
+	}
+
+	return nil, ValuesSchemaOutput{HasSchema: schema != nil, Schema: schema}, nil
+}
+
 func (s *Server) handleUpdateReleaseValues(ctx context.Context, req *mcp.CallToolRequest, input UpdateValuesInput) (*mcp.CallToolResult, ReleaseOutput, error) {
 	if input.Namespace == "" || input.Name == "" {
 		return nil, ReleaseOutput{}, fmt.Errorf("namespace and name are required")
 	}
 
-	if input.Values == nil {
-		return nil, ReleaseOutput{}, fmt.Errorf("values are required")
+	switch input.Mode {
+	case "", model.ValuesModeMerge, model.ValuesModeReplace:
+		if input.Values == nil {
+			return nil, ReleaseOutput{}, fmt.Errorf("values are required")
+		}
+	case model.ValuesModeJSONPatch:
+		if input.Patch == nil {
+			return nil, ReleaseOutput{}, fmt.Errorf("patch is required in json-patch mode")
+		}
+	default:
+		return nil, ReleaseOutput{}, fmt.Errorf("mode must be one of merge, replace or json-patch")
+	}
+
+	if input.ExpectedRevision < 0 {
+		return nil, ReleaseOutput{}, fmt.Errorf("expected_revision must be a positive integer")
 	}
 
-	release, err := s.helmClient.UpdateReleaseValues(input.Namespace, input.Name, input.Values)
+	updateReq := model.ValuesUpdateRequest{
+		Mode:             input.Mode,
+		Values:           input.Values,
+		Patch:            input.Patch,
+		ExpectedRevision: input.ExpectedRevision,
+		DeployedBy:       deployedBy(req),
+		UpgradeOptions:   input.toModel(),
+	}
+
+	release, opID, err := s.runOperation(ctx, req, model.OperationUpdateValues, input.Namespace, input.Name, func(ctx context.Context, progress model.ProgressFunc) (*model.Release, error) {
+		return s.helmClient.UpdateReleaseValues(ctx, input.Namespace, input.Name, updateReq, progress)
+	})
 	if err != nil {
-		return nil, ReleaseOutput{}, fmt.Errorf("failed to update release values: %w", err)
+		return operationFailure("failed to update release values", opID, err)
 	}
 
-	return nil, ReleaseOutput{Release: release}, nil
+	return nil, ReleaseOutput{Release: release, OperationID: opID}, nil
 }
 
 func (s *Server) handleRollbackRelease(ctx context.Context, req *mcp.CallToolRequest, input RollbackInput) (*mcp.CallToolResult, ReleaseOutput, error) {
@@ -361,11 +1016,52 @@ func (s *Server) handleRollbackRelease(ctx context.Context, req *mcp.CallToolReq
 	if input.Revision <= 0 {
 		return nil, ReleaseOutput{}, fmt.Errorf("revision must be a positive integer")
 	}
+	if input.ExpectedRevision < 0 {
+		return nil, ReleaseOutput{}, fmt.Errorf("expected_revision must be a positive integer")
+	}
+
+	rollbackReq := model.RollbackRequest{
+		Revision:         input.Revision,
+		ExpectedRevision: input.ExpectedRevision,
+		DeployedBy:       deployedBy(req),
+		RollbackOptions:  input.toModel(),
+	}
 
-	release, err := s.helmClient.RollbackRelease(input.Namespace, input.Name, input.Revision)
+	release, opID, err := s.runOperation(ctx, req, model.OperationRollback, input.Namespace, input.Name, func(ctx context.Context, progress model.ProgressFunc) (*model.Release, error) {
+		return s.helmClient.RollbackRelease(ctx, input.Namespace, input.Name, rollbackReq, progress)
+	})
 	if err != nil {
-		return nil, ReleaseOutput{}, fmt.Errorf("failed to rollback release: %w", err)
+		return operationFailure("failed to rollback release", opID, err)
 	}
 
-	return nil, ReleaseOutput{Release: release}, nil
+	return nil, ReleaseOutput{Release: release, OperationID: opID}, nil
+}
+
+func (s *Server) handleGetOperation(ctx context.Context, req *mcp.CallToolRequest, input OperationInput) (*mcp.CallToolResult, OperationOutput, error) {
+	if input.ID == "" {
+		return nil, OperationOutput{}, fmt.Errorf("id is required")
+	}
+
+	op, err := s.operations.Get(ctx, input.ID)
+	if errors.Is(err, operation.ErrNotFound) {
+		return nil, OperationOutput{}, fmt.Errorf("operation %s not found", input.ID)
+	}
+	if err != nil {
+		return nil, OperationOutput{}, fmt.Errorf("failed to get operation: %w", err)
+	}
+
+	return nil, OperationOutput{Operation: &op}, nil
+}
+
+// deployedBy names the MCP client making the request, as it introduced itself
+// when the session was initialized, or returns "" when it did not
+func deployedBy(req *mcp.CallToolRequest) string {
+	if req == nil || req.Session == nil {
+		return ""
+	}
+	params := req.Session.InitializeParams()
+	if params == nil || params.ClientInfo == nil || params.ClientInfo.Name == "" {
+		return ""
+	}
+	return "mcp." + params.ClientInfo.Name
 }
@@ -2,29 +2,53 @@ package mcp
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/helm-version-manager/api/internal/lock"
 	"github.com/helm-version-manager/api/internal/model"
+	"github.com/helm-version-manager/api/internal/operation"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
 // Mock implementations
 
 type mockHelmClient struct {
-	releases       []model.Release
-	releaseDetails map[string]*model.Release
-	versions       map[string][]model.ChartVersion
-	history        map[string][]model.ReleaseHistory
-	values         map[string]map[string]any
-	listErr        error
-	getErr         error
-	versionsErr    error
-	upgradeErr     error
-	historyErr     error
-	valuesErr      error
+	releases        []model.Release
+	releaseDetails  map[string]*model.Release
+	versions        map[string][]model.ChartVersion
+	history         map[string][]model.ReleaseHistory
+	values          map[string]map[string]any
+	chartValues     map[string]map[string]any
+	computedValues  map[string]map[string]any
+	schemas         map[string]map[string]any
+	auditRecords    map[string][]model.AuditRecord
+	testResults     map[string]*model.TestResult
+	listErr         error
+	getErr          error
+	versionsErr     error
+	upgradeErr      error
+	historyErr      error
+	valuesErr       error
 	updateValuesErr error
-	rollbackErr    error
+	rollbackErr     error
+	previewErr      error
+	diffErr         error
+	installErr      error
+	uninstallErr    error
+	manifestErr     error
+	testErr         error
+
+	lastVersionQuery model.VersionQuery
+	lastUpgrade      model.VersionUpgradeRequest
+	lastRollback     model.RollbackRequest
+	lastValuesUpdate model.ValuesUpdateRequest
+	lastRevision     int
+	lastTest         model.TestRequest
 }
 
 func (m *mockHelmClient) ListReleases() ([]model.Release, error) {
@@ -45,34 +69,119 @@ func (m *mockHelmClient) GetRelease(namespace, name string) (*model.Release, err
 	return nil, nil
 }
 
-func (m *mockHelmClient) GetAvailableVersions(namespace, name string) ([]model.ChartVersion, error) {
+func (m *mockHelmClient) InstallRelease(namespace string, req model.InstallRequest) (*model.Release, error) {
+	if m.installErr != nil {
+		return nil, m.installErr
+	}
+	installed := &model.Release{
+		Name:         req.Name,
+		Namespace:    namespace,
+		Chart:        req.Chart,
+		ChartVersion: req.ChartVersion,
+		Status:       "deployed",
+		Revision:     1,
+		HasRegistry:  true,
+	}
+	if m.releaseDetails == nil {
+		m.releaseDetails = make(map[string]*model.Release)
+	}
+	m.releaseDetails[namespace+"/"+req.Name] = installed
+	return installed, nil
+}
+
+func (m *mockHelmClient) UninstallRelease(namespace, name string, req model.UninstallRequest) (*model.UninstallResult, error) {
+	if m.uninstallErr != nil {
+		return nil, m.uninstallErr
+	}
+	key := namespace + "/" + name
+	if _, ok := m.releaseDetails[key]; !ok {
+		return nil, fmt.Errorf("release %s not found", key)
+	}
+	delete(m.releaseDetails, key)
+	return &model.UninstallResult{
+		Namespace:              namespace,
+		Name:                   name,
+		KeptHistory:            req.KeepHistory,
+		RegistryMappingDeleted: !req.KeepHistory,
+	}, nil
+}
+
+func (m *mockHelmClient) GetAvailableVersions(namespace, name string, query model.VersionQuery) ([]model.ChartVersion, error) {
 	if m.versionsErr != nil {
 		return nil, m.versionsErr
 	}
+	m.lastVersionQuery = query
 	key := namespace + "/" + name
 	return m.versions[key], nil
 }
 
-func (m *mockHelmClient) UpgradeRelease(namespace, name string, req model.VersionUpgradeRequest) (*model.Release, error) {
+func (m *mockHelmClient) UpgradeRelease(ctx context.Context, namespace, name string, req model.VersionUpgradeRequest, progress model.ProgressFunc) (*model.Release, error) {
 	if m.upgradeErr != nil {
 		return nil, m.upgradeErr
 	}
+	m.lastUpgrade = req
 	key := namespace + "/" + name
 	if r, ok := m.releaseDetails[key]; ok {
+		if err := checkRevision(r, req.ExpectedRevision); err != nil {
+			return nil, err
+		}
+		progress.Report(model.PhaseApply, "Applying %d resources", 3)
 		upgraded := *r
 		upgraded.ChartVersion = req.ChartVersion
 		upgraded.Revision = r.Revision + 1
+		if req.Verify != nil && *req.Verify {
+			upgraded.Verification = &model.Verification{Outcome: model.VerificationVerified, Revision: upgraded.Revision}
+		}
+		if req.Test != nil {
+			upgraded.Tests = &model.TestResult{Namespace: namespace, Name: name, Revision: upgraded.Revision, Passed: true}
+		}
 		return &upgraded, nil
 	}
 	return nil, nil
 }
 
-func (m *mockHelmClient) GetReleaseHistory(namespace, name string) ([]model.ReleaseHistory, error) {
+// checkRevision mirrors the helm client's check of an expected revision
+func checkRevision(r *model.Release, expected int) error {
+	if expected != 0 && r.Revision != expected {
+		return fmt.Errorf("release %s/%s is at revision %d, not the expected revision %d", r.Namespace, r.Name, r.Revision, expected)
+	}
+	return nil
+}
+
+func (m *mockHelmClient) PreviewUpgrade(namespace, name string, req model.VersionUpgradeRequest) (*model.UpgradePreview, error) {
+	if m.previewErr != nil {
+		return nil, m.previewErr
+	}
+	key := namespace + "/" + name
+	if r, ok := m.releaseDetails[key]; ok {
+		return &model.UpgradePreview{
+			Namespace:           namespace,
+			Name:                name,
+			CurrentRevision:     r.Revision,
+			CurrentChartVersion: r.ChartVersion,
+			TargetChartVersion:  req.ChartVersion,
+		}, nil
+	}
+	return nil, nil
+}
+
+func (m *mockHelmClient) GetReleaseHistory(namespace, name string, query model.HistoryQuery) (*model.HistoryPage, error) {
 	if m.historyErr != nil {
 		return nil, m.historyErr
 	}
 	key := namespace + "/" + name
-	return m.history[key], nil
+	page := &model.HistoryPage{}
+	for _, h := range m.history[key] {
+		if query.Before > 0 && h.Revision >= query.Before {
+			continue
+		}
+		if query.Max > 0 && len(page.History) == query.Max {
+			page.NextBefore = page.History[len(page.History)-1].Revision
+			break
+		}
+		page.History = append(page.History, h)
+	}
+	return page, nil
 }
 
 func (m *mockHelmClient) GetReleaseValues(namespace, name string) (map[string]any, error) {
@@ -83,29 +192,174 @@ func (m *mockHelmClient) GetReleaseValues(namespace, name string) (map[string]an
 	return m.values[key], nil
 }
 
-func (m *mockHelmClient) UpdateReleaseValues(namespace, name string, values map[string]any) (*model.Release, error) {
+func (m *mockHelmClient) GetChartValues(namespace, name string) (map[string]any, error) {
+	if m.valuesErr != nil {
+		return nil, m.valuesErr
+	}
+	key := namespace + "/" + name
+	return m.chartValues[key], nil
+}
+
+func (m *mockHelmClient) GetComputedValues(namespace, name string) (map[string]any, error) {
+	if m.valuesErr != nil {
+		return nil, m.valuesErr
+	}
+	key := namespace + "/" + name
+	return m.computedValues[key], nil
+}
+
+func (m *mockHelmClient) GetValuesSchema(namespace, name string) (map[string]any, error) {
+	if m.valuesErr != nil {
+		return nil, m.valuesErr
+	}
+	key := namespace + "/" + name
+	return m.schemas[key], nil
+}
+
+func (m *mockHelmClient) GetRevisionValues(namespace, name string, revision int) (map[string]any, error) {
+	if m.valuesErr != nil {
+		return nil, m.valuesErr
+	}
+	key := namespace + "/" + name
+	return m.values[key], nil
+}
+
+func (m *mockHelmClient) DiffRevisions(namespace, name string, from, to int) (*model.RevisionDiff, error) {
+	if m.diffErr != nil {
+		return nil, m.diffErr
+	}
+	return &model.RevisionDiff{
+		Namespace: namespace,
+		Name:      name,
+		From:      from,
+		To:        to,
+		Values: []model.ValueChange{
+			{Path: "image.tag", Change: model.ChangeChanged, From: "1.0", To: "1.1"},
+		},
+	}, nil
+}
+
+// mockLatestRevision is the revision the mock reports when none is requested
+const mockLatestRevision = 3
+
+func (m *mockHelmClient) revision(revision int) int {
+	m.lastRevision = revision
+	if revision == 0 {
+		return mockLatestRevision
+	}
+	return revision
+}
+
+func (m *mockHelmClient) GetReleaseManifest(namespace, name string, revision int) (*model.ReleaseManifest, error) {
+	if m.manifestErr != nil {
+		return nil, m.manifestErr
+	}
+	return &model.ReleaseManifest{
+		Namespace: namespace,
+		Name:      name,
+		Revision:  m.revision(revision),
+		Manifest:  "---\napiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: web\n",
+		Objects: []model.ManifestObject{
+			{APIVersion: "apps/v1", Kind: "Deployment", Name: "web", Manifest: "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: web"},
+		},
+	}, nil
+}
+
+func (m *mockHelmClient) GetReleaseNotes(namespace, name string, revision int) (*model.ReleaseNotes, error) {
+	if m.manifestErr != nil {
+		return nil, m.manifestErr
+	}
+	return &model.ReleaseNotes{
+		Namespace: namespace,
+		Name:      name,
+		Revision:  m.revision(revision),
+		Notes:     "Visit http://web.example.com",
+	}, nil
+}
+
+func (m *mockHelmClient) GetReleaseHooks(namespace, name string, revision int) (*model.ReleaseHooks, error) {
+	if m.manifestErr != nil {
+		return nil, m.manifestErr
+	}
+	return &model.ReleaseHooks{
+		Namespace: namespace,
+		Name:      name,
+		Revision:  m.revision(revision),
+		Hooks: []model.ReleaseHook{
+			{Name: "db-migrate", Kind: "Job", Events: []string{"pre-upgrade"}, LastRun: model.HookRun{Phase: "Succeeded"}},
+		},
+	}, nil
+}
+
+func (m *mockHelmClient) GetReleaseResources(ctx context.Context, namespace, name string) (*model.ReleaseResources, error) {
+	if m.manifestErr != nil {
+		return nil, m.manifestErr
+	}
+	return &model.ReleaseResources{
+		Namespace:     namespace,
+		Name:          name,
+		Revision:      mockLatestRevision,
+		ReleaseStatus: "deployed",
+		Status:        model.ResourceStatusInProgress,
+		Resources: []model.ResourceStatus{
+			{APIVersion: "apps/v1", Kind: "Deployment", Namespace: namespace, Name: "web", Status: model.ResourceStatusInProgress, Message: "Available: 1/2"},
+			{APIVersion: "v1", Kind: "Service", Namespace: namespace, Name: "web", Status: model.ResourceStatusCurrent},
+		},
+	}, nil
+}
+
+func (m *mockHelmClient) GetAuditRecords(namespace, name string) ([]model.AuditRecord, error) {
+	if m.historyErr != nil {
+		return nil, m.historyErr
+	}
+	key := namespace + "/" + name
+	return m.auditRecords[key], nil
+}
+
+func (m *mockHelmClient) RunReleaseTests(ctx context.Context, namespace, name string, req model.TestRequest, progress model.ProgressFunc) (*model.Release, error) {
+	if m.testErr != nil {
+		return nil, m.testErr
+	}
+	m.lastTest = req
+	key := namespace + "/" + name
+	if r, ok := m.testResults[key]; ok {
+		progress.Report(model.PhaseTest, "Running the tests of revision %d", r.Revision)
+		return &model.Release{Namespace: namespace, Name: name, Revision: r.Revision, Tests: r}, nil
+	}
+	return nil, fmt.Errorf("release not found")
+}
+
+func (m *mockHelmClient) UpdateReleaseValues(ctx context.Context, namespace, name string, req model.ValuesUpdateRequest, progress model.ProgressFunc) (*model.Release, error) {
 	if m.updateValuesErr != nil {
 		return nil, m.updateValuesErr
 	}
 	key := namespace + "/" + name
 	if r, ok := m.releaseDetails[key]; ok {
+		if err := checkRevision(r, req.ExpectedRevision); err != nil {
+			return nil, err
+		}
 		updated := *r
 		updated.Revision = r.Revision + 1
 		if m.values == nil {
 			m.values = make(map[string]map[string]any)
 		}
-		m.values[key] = values
+		m.values[key] = req.Values
+		m.lastValuesUpdate = req
 		return &updated, nil
 	}
 	return nil, nil
 }
 
-func (m *mockHelmClient) RollbackRelease(namespace, name string, revision int) (*model.Release, error) {
+func (m *mockHelmClient) RollbackRelease(ctx context.Context, namespace, name string, req model.RollbackRequest, progress model.ProgressFunc) (*model.Release, error) {
 	if m.rollbackErr != nil {
 		return nil, m.rollbackErr
 	}
+	m.lastRollback = req
 	key := namespace + "/" + name
 	if r, ok := m.releaseDetails[key]; ok {
+		if err := checkRevision(r, req.ExpectedRevision); err != nil {
+			return nil, err
+		}
 		rolledBack := *r
 		// Simulate rollback by creating a new revision
 		rolledBack.Revision = r.Revision + 1
@@ -116,12 +370,16 @@ func (m *mockHelmClient) RollbackRelease(namespace, name string, revision int) (
 
 type mockRegistryStore struct {
 	mappings  map[string]*model.RegistryMapping
+	rules     map[string]model.RegistryRule
 	getErr    error
 	setErr    error
 	deleteErr error
+	getCalls  int
+	listCalls int
 }
 
 func (m *mockRegistryStore) GetMapping(ctx context.Context, namespace, releaseName string) (*model.RegistryMapping, error) {
+	m.getCalls++
 	if m.getErr != nil {
 		return nil, m.getErr
 	}
@@ -148,6 +406,7 @@ func (m *mockRegistryStore) DeleteMapping(ctx context.Context, namespace, releas
 }
 
 func (m *mockRegistryStore) ListMappings(ctx context.Context) ([]model.RegistryMapping, error) {
+	m.listCalls++
 	result := make([]model.RegistryMapping, 0, len(m.mappings))
 	for _, mapping := range m.mappings {
 		result = append(result, *mapping)
@@ -155,9 +414,62 @@ func (m *mockRegistryStore) ListMappings(ctx context.Context) ([]model.RegistryM
 	return result, nil
 }
 
+// ResolveMapping only knows explicit mappings
+func (m *mockRegistryStore) ResolveMapping(ctx context.Context, namespace, releaseName string) (*model.RegistryResolution, error) {
+	mapping, err := m.GetMapping(ctx, namespace, releaseName)
+	if err != nil {
+		return nil, err
+	}
+	resolution := &model.RegistryResolution{
+		Namespace:   namespace,
+		ReleaseName: releaseName,
+		Mapping:     mapping,
+		Message:     "No registry found",
+		Steps:       []model.RegistryResolutionStep{{Source: model.RegistrySourceExplicit, Matched: mapping != nil}},
+	}
+	if mapping != nil {
+		resolution.Message = "Resolved by the explicit mapping"
+	}
+	return resolution, nil
+}
+
+func (m *mockRegistryStore) GetRule(ctx context.Context, id string) (*model.RegistryRule, error) {
+	if rule, ok := m.rules[id]; ok {
+		return &rule, nil
+	}
+	return nil, nil
+}
+
+func (m *mockRegistryStore) SetRule(ctx context.Context, rule model.RegistryRule) error {
+	if m.setErr != nil {
+		return m.setErr
+	}
+	if m.rules == nil {
+		m.rules = make(map[string]model.RegistryRule)
+	}
+	m.rules[rule.ID] = rule
+	return nil
+}
+
+func (m *mockRegistryStore) DeleteRule(ctx context.Context, id string) error {
+	if m.deleteErr != nil {
+		return m.deleteErr
+	}
+	delete(m.rules, id)
+	return nil
+}
+
+func (m *mockRegistryStore) ListRules(ctx context.Context) ([]model.RegistryRule, error) {
+	result := make([]model.RegistryRule, 0, len(m.rules))
+	for _, rule := range m.rules {
+		result = append(result, rule)
+	}
+	return result, nil
+}
+
 // Helper function to create test server
 func newTestServer(helmClient *mockHelmClient, registryStore *mockRegistryStore) *Server {
-	return NewServer(helmClient, registryStore)
+	return NewServer(helmClient, registryStore, operation.NewManager(operation.DefaultRetention, nil, nil))
 }
 
 // Tests
@@ -166,7 +478,7 @@ func TestNewServer(t *testing.T) {
 	helmClient := &mockHelmClient{}
 	registryStore := &mockRegistryStore{mappings: make(map[string]*model.RegistryMapping)}
 
-	server := NewServer(helmClient, registryStore)
+	server := NewServer(helmClient, registryStore, operation.NewManager(operation.DefaultRetention, nil, nil))
 
 	if server == nil {
 		t.Fatal("expected server to be non-nil")
@@ -213,6 +525,9 @@ func TestHandleListReleases(t *testing.T) {
 		if len(output.Releases) != 2 {
 			t.Errorf("expected 2 releases, got %d", len(output.Releases))
 		}
+		if registryStore.listCalls != 1 || registryStore.getCalls != 0 {
+			t.Errorf("expected mappings to be listed once, got %d lists and %d gets", registryStore.listCalls, registryStore.getCalls)
+		}
 	})
 
 	t.Run("filter by namespace", func(t *testing.T) {
@@ -328,6 +643,102 @@ func TestHandleGetRelease(t *testing.T) {
 	})
 }
 
+func TestHandleInstallRelease(t *testing.T) {
+	helmClient := &mockHelmClient{}
+	registryStore := &mockRegistryStore{mappings: make(map[string]*model.RegistryMapping)}
+
+	server := newTestServer(helmClient, registryStore)
+	ctx := context.Background()
+
+	t.Run("install release", func(t *testing.T) {
+		result, output, err := server.handleInstallRelease(ctx, &mcp.CallToolRequest{}, InstallInput{
+			Namespace:    "staging",
+			Name:         "myrelease",
+			Chart:        "mychart",
+			Registry:     "oci://example.com/charts",
+			ChartVersion: "1.0.0",
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result != nil {
+			t.Fatal("expected result to be nil for success")
+		}
+		if output.Release == nil {
+			t.Fatal("expected release to be non-nil")
+		}
+		if output.Release.Namespace != "staging" || output.Release.Name != "myrelease" {
+			t.Errorf("expected staging/myrelease, got %s/%s", output.Release.Namespace, output.Release.Name)
+		}
+		if output.Release.Revision != 1 {
+			t.Errorf("expected revision 1, got %d", output.Release.Revision)
+		}
+	})
+
+	t.Run("missing registry", func(t *testing.T) {
+		_, _, err := server.handleInstallRelease(ctx, &mcp.CallToolRequest{}, InstallInput{
+			Namespace: "staging",
+			Name:      "myrelease",
+			Chart:     "mychart",
+		})
+		if err == nil {
+			t.Fatal("expected error for missing registry")
+		}
+	})
+}
+
+func TestHandleUninstallRelease(t *testing.T) {
+	helmClient := &mockHelmClient{
+		releaseDetails: map[string]*model.Release{
+			"default/myrelease": {Name: "myrelease", Namespace: "default"},
+			"default/other":     {Name: "other", Namespace: "default"},
+		},
+	}
+	registryStore := &mockRegistryStore{mappings: make(map[string]*model.RegistryMapping)}
+
+	server := newTestServer(helmClient, registryStore)
+	ctx := context.Background()
+
+	t.Run("uninstall release", func(t *testing.T) {
+		result, output, err := server.handleUninstallRelease(ctx, &mcp.CallToolRequest{}, UninstallInput{
+			Namespace: "default",
+			Name:      "myrelease",
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result != nil {
+			t.Fatal("expected result to be nil for success")
+		}
+		if output.Result == nil || !output.Result.RegistryMappingDeleted {
+			t.Fatalf("expected registry mapping to be deleted, got %+v", output.Result)
+		}
+	})
+
+	t.Run("keep history", func(t *testing.T) {
+		_, output, err := server.handleUninstallRelease(ctx, &mcp.CallToolRequest{}, UninstallInput{
+			Namespace:   "default",
+			Name:        "other",
+			KeepHistory: true,
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !output.Result.KeptHistory || output.Result.RegistryMappingDeleted {
+			t.Errorf("expected history and registry mapping to be kept, got %+v", output.Result)
+		}
+	})
+
+	t.Run("missing name", func(t *testing.T) {
+		_, _, err := server.handleUninstallRelease(ctx, &mcp.CallToolRequest{}, UninstallInput{
+			Namespace: "default",
+		})
+		if err == nil {
+			t.Fatal("expected error for missing name")
+		}
+	})
+}
+
 func TestHandleGetAvailableVersions(t *testing.T) {
 	versions := []model.ChartVersion{
 		{Version: "1.2.0"},
@@ -346,7 +757,7 @@ func TestHandleGetAvailableVersions(t *testing.T) {
 	ctx := context.Background()
 
 	t.Run("get versions", func(t *testing.T) {
-		result, output, err := server.handleGetAvailableVersions(ctx, &mcp.CallToolRequest{}, ReleaseInput{
+		result, output, err := server.handleGetAvailableVersions(ctx, &mcp.CallToolRequest{}, VersionsInput{
 			Namespace: "default",
 			Name:      "myrelease",
 		})
@@ -360,6 +771,34 @@ func TestHandleGetAvailableVersions(t *testing.T) {
 			t.Errorf("expected 3 versions, got %d", len(output.Versions))
 		}
 	})
+
+	t.Run("passes query options", func(t *testing.T) {
+		_, _, err := server.handleGetAvailableVersions(ctx, &mcp.CallToolRequest{}, VersionsInput{
+			Namespace:         "default",
+			Name:              "myrelease",
+			Limit:             5,
+			IncludePrerelease: true,
+			Constraint:        "^1.x",
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		want := model.VersionQuery{Limit: 5, IncludePrerelease: true, Constraint: "^1.x"}
+		if helmClient.lastVersionQuery != want {
+			t.Errorf("expected query %+v, got %+v", want, helmClient.lastVersionQuery)
+		}
+	})
+
+	t.Run("negative limit", func(t *testing.T) {
+		_, _, err := server.handleGetAvailableVersions(ctx, &mcp.CallToolRequest{}, VersionsInput{
+			Namespace: "default",
+			Name:      "myrelease",
+			Limit:     -1,
+		})
+		if err == nil {
+			t.Fatal("expected error for negative limit")
+		}
+	})
 }
 
 func TestHandleUpgradeRelease(t *testing.T) {
@@ -407,6 +846,92 @@ func TestHandleUpgradeRelease(t *testing.T) {
 		}
 	})
 
+	t.Run("verified upgrade", func(t *testing.T) {
+		verify := true
+		_, output, err := server.handleUpgradeRelease(ctx, &mcp.CallToolRequest{}, UpgradeInput{
+			Namespace:    "default",
+			Name:         "myrelease",
+			ChartVersion: "1.1.0",
+			UpgradeOptionsInput: UpgradeOptionsInput{
+				Verify:        &verify,
+				VerifyTimeout: "2m",
+			},
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if helmClient.lastUpgrade.Verify == nil || !*helmClient.lastUpgrade.Verify {
+			t.Error("expected verify to be passed through")
+		}
+		if helmClient.lastUpgrade.VerifyTimeout != "2m" {
+			t.Errorf("expected verify timeout 2m, got %q", helmClient.lastUpgrade.VerifyTimeout)
+		}
+		if output.Release.Verification == nil || output.Release.Verification.Outcome != model.VerificationVerified {
+			t.Errorf("expected verified outcome, got %+v", output.Release.Verification)
+		}
+	})
+
+	t.Run("tested upgrade", func(t *testing.T) {
+		_, output, err := server.handleUpgradeRelease(ctx, &mcp.CallToolRequest{}, UpgradeInput{
+			Namespace:    "default",
+			Name:         "myrelease",
+			ChartVersion: "1.1.0",
+			RunTests:     true,
+			TestFilter:   []string{"smoke"},
+			TestTimeout:  "1m",
+			TestLogs:     true,
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		test := helmClient.lastUpgrade.Test
+		if test == nil {
+			t.Fatal("expected test request to be passed through")
+		}
+		if len(test.Filter) != 1 || test.Filter[0] != "smoke" || test.Timeout != "1m" || !test.Logs {
+			t.Errorf("unexpected test request %+v", test)
+		}
+		if output.Release.Tests == nil || !output.Release.Tests.Passed {
+			t.Errorf("expected passed tests, got %+v", output.Release.Tests)
+		}
+	})
+
+	t.Run("upgrade options", func(t *testing.T) {
+		atomic := true
+		maxHistory := 5
+		_, _, err := server.handleUpgradeRelease(ctx, &mcp.CallToolRequest{}, UpgradeInput{
+			Namespace:    "default",
+			Name:         "myrelease",
+			ChartVersion: "1.1.0",
+			UpgradeOptionsInput: UpgradeOptionsInput{
+				Atomic: &atomic,
+				RollbackOptionsInput: RollbackOptionsInput{
+					Timeout:    "10m",
+					MaxHistory: &maxHistory,
+				},
+			},
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		opts := helmClient.lastUpgrade.UpgradeOptions
+		if opts.Atomic == nil || !*opts.Atomic {
+			t.Error("expected atomic to be passed through")
+		}
+		if opts.Timeout != "10m" {
+			t.Errorf("expected timeout 10m, got %q", opts.Timeout)
+		}
+		if opts.MaxHistory == nil || *opts.MaxHistory != 5 {
+			t.Errorf("expected max history 5, got %v", opts.MaxHistory)
+		}
+		if opts.Wait != nil {
+			t.Error("expected unset wait to stay unset so server defaults apply")
+		}
+		if helmClient.lastUpgrade.Test != nil {
+			t.Error("expected tests not to run unless run_tests is set")
+		}
+	})
+
 	t.Run("missing chart_version", func(t *testing.T) {
 		_, _, err := server.handleUpgradeRelease(ctx, &mcp.CallToolRequest{}, UpgradeInput{
 			Namespace: "default",
@@ -418,17 +943,18 @@ func TestHandleUpgradeRelease(t *testing.T) {
 	})
 }
 
-func TestHandleGetReleaseHistory(t *testing.T) {
-	now := time.Now()
-	history := []model.ReleaseHistory{
-		{Revision: 3, Updated: now, Status: "deployed", Chart: "1.2.0"},
-		{Revision: 2, Updated: now.Add(-time.Hour), Status: "superseded", Chart: "1.1.0"},
-		{Revision: 1, Updated: now.Add(-2 * time.Hour), Status: "superseded", Chart: "1.0.0"},
+func TestHandlePreviewUpgrade(t *testing.T) {
+	release := &model.Release{
+		Name:         "myrelease",
+		Namespace:    "default",
+		Chart:        "mychart",
+		ChartVersion: "1.0.0",
+		Revision:     4,
 	}
 
 	helmClient := &mockHelmClient{
-		history: map[string][]model.ReleaseHistory{
-			"default/myrelease": history,
+		releaseDetails: map[string]*model.Release{
+			"default/myrelease": release,
 		},
 	}
 	registryStore := &mockRegistryStore{mappings: make(map[string]*model.RegistryMapping)}
@@ -436,9 +962,61 @@ func TestHandleGetReleaseHistory(t *testing.T) {
 	server := newTestServer(helmClient, registryStore)
 	ctx := context.Background()
 
-	t.Run("get history", func(t *testing.T) {
-		result, output, err := server.handleGetReleaseHistory(ctx, &mcp.CallToolRequest{}, ReleaseInput{
-			Namespace: "default",
+	t.Run("preview upgrade", func(t *testing.T) {
+		result, output, err := server.handlePreviewUpgrade(ctx, &mcp.CallToolRequest{}, UpgradeInput{
+			Namespace:    "default",
+			Name:         "myrelease",
+			ChartVersion: "1.1.0",
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result != nil {
+			t.Fatal("expected result to be nil for success")
+		}
+		if output.Preview == nil {
+			t.Fatal("expected preview to be non-nil")
+		}
+		if output.Preview.TargetChartVersion != "1.1.0" {
+			t.Errorf("expected target version 1.1.0, got %s", output.Preview.TargetChartVersion)
+		}
+		if output.Preview.CurrentRevision != 4 {
+			t.Errorf("expected current revision 4, got %d", output.Preview.CurrentRevision)
+		}
+	})
+
+	t.Run("missing chart_version", func(t *testing.T) {
+		_, _, err := server.handlePreviewUpgrade(ctx, &mcp.CallToolRequest{}, UpgradeInput{
+			Namespace: "default",
+			Name:      "myrelease",
+		})
+		if err == nil {
+			t.Fatal("expected error for missing chart_version")
+		}
+	})
+}
+
+func TestHandleGetReleaseHistory(t *testing.T) {
+	now := time.Now()
+	history := []model.ReleaseHistory{
+		{Revision: 3, Updated: now, Status: "deployed", Chart: "1.2.0"},
+		{Revision: 2, Updated: now.Add(-time.Hour), Status: "superseded", Chart: "1.1.0"},
+		{Revision: 1, Updated: now.Add(-2 * time.Hour), Status: "superseded", Chart: "1.0.0"},
+	}
+
+	helmClient := &mockHelmClient{
+		history: map[string][]model.ReleaseHistory{
+			"default/myrelease": history,
+		},
+	}
+	registryStore := &mockRegistryStore{mappings: make(map[string]*model.RegistryMapping)}
+
+	server := newTestServer(helmClient, registryStore)
+	ctx := context.Background()
+
+	t.Run("get history", func(t *testing.T) {
+		result, output, err := server.handleGetReleaseHistory(ctx, &mcp.CallToolRequest{}, HistoryInput{
+			Namespace: "default",
 			Name:      "myrelease",
 		})
 		if err != nil {
@@ -450,6 +1028,328 @@ func TestHandleGetReleaseHistory(t *testing.T) {
 		if len(output.History) != 3 {
 			t.Errorf("expected 3 history entries, got %d", len(output.History))
 		}
+		if output.NextBefore != 0 {
+			t.Errorf("expected no next page, got next_before %d", output.NextBefore)
+		}
+	})
+
+	t.Run("paginate history", func(t *testing.T) {
+		_, output, err := server.handleGetReleaseHistory(ctx, &mcp.CallToolRequest{}, HistoryInput{
+			Namespace: "default",
+			Name:      "myrelease",
+			Max:       1,
+			Before:    3,
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(output.History) != 1 || output.History[0].Revision != 2 {
+			t.Fatalf("expected revision 2 only, got %+v", output.History)
+		}
+		if output.NextBefore != 2 {
+			t.Errorf("expected next_before 2, got %d", output.NextBefore)
+		}
+	})
+
+	t.Run("negative max", func(t *testing.T) {
+		_, _, err := server.handleGetReleaseHistory(ctx, &mcp.CallToolRequest{}, HistoryInput{
+			Namespace: "default",
+			Name:      "myrelease",
+			Max:       -1,
+		})
+		if err == nil {
+			t.Fatal("expected error for negative max")
+		}
+	})
+}
+
+func TestHandleDiffRevisions(t *testing.T) {
+	helmClient := &mockHelmClient{}
+	registryStore := &mockRegistryStore{mappings: make(map[string]*model.RegistryMapping)}
+
+	server := newTestServer(helmClient, registryStore)
+	ctx := context.Background()
+
+	t.Run("diff revisions", func(t *testing.T) {
+		result, output, err := server.handleDiffRevisions(ctx, &mcp.CallToolRequest{}, DiffRevisionsInput{
+			Namespace: "default",
+			Name:      "myrelease",
+			From:      2,
+			To:        3,
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result != nil {
+			t.Fatal("expected result to be nil for success")
+		}
+		if output.Diff == nil {
+			t.Fatal("expected diff to be non-nil")
+		}
+		if output.Diff.From != 2 || output.Diff.To != 3 {
+			t.Errorf("expected diff from 2 to 3, got %d to %d", output.Diff.From, output.Diff.To)
+		}
+		if len(output.Diff.Values) != 1 {
+			t.Errorf("expected 1 value change, got %d", len(output.Diff.Values))
+		}
+	})
+
+	t.Run("invalid revision", func(t *testing.T) {
+		_, _, err := server.handleDiffRevisions(ctx, &mcp.CallToolRequest{}, DiffRevisionsInput{
+			Namespace: "default",
+			Name:      "myrelease",
+			From:      0,
+			To:        3,
+		})
+		if err == nil {
+			t.Fatal("expected error for invalid revision")
+		}
+	})
+}
+
+func TestHandleGetReleaseManifest(t *testing.T) {
+	helmClient := &mockHelmClient{}
+	registryStore := &mockRegistryStore{mappings: make(map[string]*model.RegistryMapping)}
+
+	server := newTestServer(helmClient, registryStore)
+	ctx := context.Background()
+
+	t.Run("latest revision", func(t *testing.T) {
+		_, output, err := server.handleGetReleaseManifest(ctx, &mcp.CallToolRequest{}, RevisionInput{
+			Namespace: "default",
+			Name:      "myrelease",
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if output.Manifest.Revision != mockLatestRevision {
+			t.Errorf("expected revision %d, got %d", mockLatestRevision, output.Manifest.Revision)
+		}
+		if len(output.Manifest.Objects) != 1 || output.Manifest.Objects[0].Kind != "Deployment" {
+			t.Errorf("expected one Deployment object, got %+v", output.Manifest.Objects)
+		}
+	})
+
+	t.Run("specific revision", func(t *testing.T) {
+		_, output, err := server.handleGetReleaseManifest(ctx, &mcp.CallToolRequest{}, RevisionInput{
+			Namespace: "default",
+			Name:      "myrelease",
+			Revision:  1,
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if helmClient.lastRevision != 1 || output.Manifest.Revision != 1 {
+			t.Errorf("expected revision 1, got %d", output.Manifest.Revision)
+		}
+	})
+
+	t.Run("negative revision", func(t *testing.T) {
+		_, _, err := server.handleGetReleaseManifest(ctx, &mcp.CallToolRequest{}, RevisionInput{
+			Namespace: "default",
+			Name:      "myrelease",
+			Revision:  -1,
+		})
+		if err == nil {
+			t.Fatal("expected error for negative revision")
+		}
+	})
+
+	t.Run("missing name", func(t *testing.T) {
+		_, _, err := server.handleGetReleaseManifest(ctx, &mcp.CallToolRequest{}, RevisionInput{Namespace: "default"})
+		if err == nil {
+			t.Fatal("expected error for missing name")
+		}
+	})
+
+	t.Run("client error", func(t *testing.T) {
+		helmClient.manifestErr = fmt.Errorf("release: not found")
+		defer func() { helmClient.manifestErr = nil }()
+
+		_, _, err := server.handleGetReleaseManifest(ctx, &mcp.CallToolRequest{}, RevisionInput{
+			Namespace: "default",
+			Name:      "myrelease",
+		})
+		if err == nil {
+			t.Fatal("expected error")
+		}
+	})
+}
+
+func TestHandleGetReleaseNotesAndHooks(t *testing.T) {
+	helmClient := &mockHelmClient{}
+	registryStore := &mockRegistryStore{mappings: make(map[string]*model.RegistryMapping)}
+
+	server := newTestServer(helmClient, registryStore)
+	ctx := context.Background()
+	input := RevisionInput{Namespace: "default", Name: "myrelease", Revision: 2}
+
+	_, notes, err := server.handleGetReleaseNotes(ctx, &mcp.CallToolRequest{}, input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if notes.Notes.Revision != 2 || notes.Notes.Notes == "" {
+		t.Errorf("expected notes of revision 2, got %+v", notes.Notes)
+	}
+
+	_, hooks, err := server.handleGetReleaseHooks(ctx, &mcp.CallToolRequest{}, input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(hooks.Hooks.Hooks) != 1 || hooks.Hooks.Hooks[0].LastRun.Phase != "Succeeded" {
+		t.Errorf("expected one succeeded hook, got %+v", hooks.Hooks.Hooks)
+	}
+
+	if _, _, err := server.handleGetReleaseHooks(ctx, &mcp.CallToolRequest{}, RevisionInput{Name: "myrelease"}); err == nil {
+		t.Error("expected error for missing namespace")
+	}
+}
+
+func TestHandleGetReleaseResources(t *testing.T) {
+	helmClient := &mockHelmClient{}
+	registryStore := &mockRegistryStore{mappings: make(map[string]*model.RegistryMapping)}
+
+	server := newTestServer(helmClient, registryStore)
+	ctx := context.Background()
+
+	t.Run("get resources", func(t *testing.T) {
+		_, output, err := server.handleGetReleaseResources(ctx, &mcp.CallToolRequest{}, ReleaseInput{
+			Namespace: "default",
+			Name:      "myrelease",
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if output.Resources.Ready {
+			t.Error("expected release with an unavailable deployment not to be ready")
+		}
+		if len(output.Resources.Resources) != 2 {
+			t.Errorf("expected 2 resources, got %d", len(output.Resources.Resources))
+		}
+	})
+
+	t.Run("missing namespace", func(t *testing.T) {
+		_, _, err := server.handleGetReleaseResources(ctx, &mcp.CallToolRequest{}, ReleaseInput{Name: "myrelease"})
+		if err == nil {
+			t.Fatal("expected error for missing namespace")
+		}
+	})
+
+	t.Run("client error", func(t *testing.T) {
+		helmClient.manifestErr = fmt.Errorf("release: not found")
+		defer func() { helmClient.manifestErr = nil }()
+
+		_, _, err := server.handleGetReleaseResources(ctx, &mcp.CallToolRequest{}, ReleaseInput{
+			Namespace: "default",
+			Name:      "myrelease",
+		})
+		if err == nil {
+			t.Fatal("expected error")
+		}
+	})
+}
+
+func TestHandleGetAuditRecords(t *testing.T) {
+	helmClient := &mockHelmClient{
+		auditRecords: map[string][]model.AuditRecord{
+			"default/myrelease": {
+				{ID: "b", Namespace: "default", Name: "myrelease", Action: model.AuditActionUpgrade, Revision: 3, Outcome: model.VerificationRolledBack, RolledBackTo: 2},
+				{ID: "a", Namespace: "default", Name: "myrelease", Action: model.AuditActionUpdateValues, Revision: 2, Outcome: model.VerificationVerified},
+			},
+		},
+	}
+	registryStore := &mockRegistryStore{mappings: make(map[string]*model.RegistryMapping)}
+
+	server := newTestServer(helmClient, registryStore)
+	ctx := context.Background()
+
+	t.Run("get records", func(t *testing.T) {
+		_, output, err := server.handleGetAuditRecords(ctx, &mcp.CallToolRequest{}, AuditInput{
+			Namespace: "default",
+			Name:      "myrelease",
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(output.Records) != 2 {
+			t.Fatalf("expected 2 records, got %d", len(output.Records))
+		}
+		if output.Records[0].Outcome != model.VerificationRolledBack || output.Records[0].RolledBackTo != 2 {
+			t.Errorf("unexpected first record %+v", output.Records[0])
+		}
+	})
+
+	t.Run("missing name", func(t *testing.T) {
+		_, _, err := server.handleGetAuditRecords(ctx, &mcp.CallToolRequest{}, AuditInput{Namespace: "default"})
+		if err == nil {
+			t.Fatal("expected error for missing name")
+		}
+	})
+}
+
+func TestHandleRunReleaseTests(t *testing.T) {
+	helmClient := &mockHelmClient{
+		testResults: map[string]*model.TestResult{
+			"default/myrelease": {
+				Namespace: "default",
+				Name:      "myrelease",
+				Revision:  3,
+				Passed:    false,
+				Message:   "pod myrelease-test-connection failed",
+				Tests: []model.TestHookResult{
+					{Name: "myrelease-test-connection", Kind: "Pod", Phase: "Failed", Logs: "connection refused"},
+				},
+			},
+		},
+	}
+	registryStore := &mockRegistryStore{mappings: make(map[string]*model.RegistryMapping)}
+
+	server := newTestServer(helmClient, registryStore)
+	ctx := context.Background()
+
+	t.Run("run tests", func(t *testing.T) {
+		_, output, err := server.handleRunReleaseTests(ctx, &mcp.CallToolRequest{}, RunTestsInput{
+			Namespace: "default",
+			Name:      "myrelease",
+			Filter:    []string{"!myrelease-test-slow"},
+			Timeout:   "2m",
+			Logs:      true,
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if output.Result == nil || output.Result.Passed {
+			t.Fatalf("expected a failed test result, got %+v", output.Result)
+		}
+		if len(output.Result.Tests) != 1 || output.Result.Tests[0].Logs != "connection refused" {
+			t.Errorf("unexpected tests %+v", output.Result.Tests)
+		}
+		if len(helmClient.lastTest.Filter) != 1 || helmClient.lastTest.Timeout != "2m" || !helmClient.lastTest.Logs {
+			t.Errorf("unexpected test request %+v", helmClient.lastTest)
+		}
+		if op, err := server.operations.Get(ctx, output.OperationID); err != nil || op.Type != model.OperationTest || op.Status != model.OperationSucceeded {
+			t.Errorf("expected a succeeded test operation, got %+v", op)
+		}
+	})
+
+	t.Run("missing name", func(t *testing.T) {
+		_, _, err := server.handleRunReleaseTests(ctx, &mcp.CallToolRequest{}, RunTestsInput{Namespace: "default"})
+		if err == nil {
+			t.Fatal("expected error for missing name")
+		}
+	})
+
+	t.Run("test error", func(t *testing.T) {
+		helmClient.testErr = fmt.Errorf("release not found")
+		defer func() { helmClient.testErr = nil }()
+
+		_, _, err := server.handleRunReleaseTests(ctx, &mcp.CallToolRequest{}, RunTestsInput{
+			Namespace: "default",
+			Name:      "myrelease",
+		})
+		if err == nil {
+			t.Fatal("expected error")
+		}
 	})
 }
 
@@ -607,14 +1507,623 @@ func TestHandleDeleteRegistry(t *testing.T) {
 	})
 }
 
-func TestMCPServer(t *testing.T) {
-	helmClient := &mockHelmClient{}
+func TestHandleRegistryRules(t *testing.T) {
 	registryStore := &mockRegistryStore{mappings: make(map[string]*model.RegistryMapping)}
+	server := newTestServer(&mockHelmClient{}, registryStore)
+	ctx := context.Background()
 
-	server := NewServer(helmClient, registryStore)
-
-	mcpServer := server.MCPServer()
-	if mcpServer == nil {
-		t.Fatal("expected MCPServer to be non-nil")
+	t.Run("set rule", func(t *testing.T) {
+		_, output, err := server.handleSetRegistryRule(ctx, &mcp.CallToolRequest{}, SetRegistryRuleInput{
+			ID:                "ourorg-charts",
+			Chart:             "ourorg-*",
+			NamespaceSelector: "team=payments",
+			Priority:          10,
+			Registry:          "oci://ghcr.io/ourorg/charts",
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		want := model.RegistryRule{ID: "ourorg-charts", Chart: "ourorg-*", NamespaceSelector: "team=payments", Priority: 10, Registry: "oci://ghcr.io/ourorg/charts"}
+		if output.Rule == nil || *output.Rule != want {
+			t.Errorf("expected rule %+v, got %+v", want, output.Rule)
+		}
+		if registryStore.rules["ourorg-charts"] != want {
+			t.Errorf("expected rule to be stored, got %+v", registryStore.rules)
+		}
+	})
+
+	t.Run("invalid rules are rejected", func(t *testing.T) {
+		for _, input := range []SetRegistryRuleInput{
+			{ID: "Not_A_Name", Registry: "oci://ghcr.io/ourorg/charts"},
+			{ID: "no-registry"},
+			{ID: "bad-glob", Chart: "[", Registry: "oci://ghcr.io/ourorg/charts"},
+			{ID: "bad-selector", NamespaceSelector: "team in", Registry: "oci://ghcr.io/ourorg/charts"},
+		} {
+			if _, _, err := server.handleSetRegistryRule(ctx, &mcp.CallToolRequest{}, input); err == nil {
+				t.Errorf("expected %+v to be rejected", input)
+			}
+			if _, exists := registryStore.rules[input.ID]; exists {
+				t.Errorf("expected invalid rule %s not to be stored", input.ID)
+			}
+		}
+	})
+
+	t.Run("list rules", func(t *testing.T) {
+		_, output, err := server.handleListRegistryRules(ctx, &mcp.CallToolRequest{}, ListRegistryRulesInput{})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(output.Rules) != 1 || output.Rules[0].ID != "ourorg-charts" {
+			t.Errorf("expected the stored rule, got %+v", output.Rules)
+		}
+	})
+
+	t.Run("delete rule", func(t *testing.T) {
+		_, output, err := server.handleDeleteRegistryRule(ctx, &mcp.CallToolRequest{}, RegistryRuleInput{ID: "ourorg-charts"})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !output.Success {
+			t.Error("expected success")
+		}
+		if len(registryStore.rules) != 0 {
+			t.Errorf("expected rule to be deleted, got %+v", registryStore.rules)
+		}
+
+		if _, _, err := server.handleDeleteRegistryRule(ctx, &mcp.CallToolRequest{}, RegistryRuleInput{}); err == nil {
+			t.Error("expected error for missing id")
+		}
+	})
+}
+
+func TestHandleResolveRegistry(t *testing.T) {
+	registryStore := &mockRegistryStore{
+		mappings: map[string]*model.RegistryMapping{
+			"default/myrelease": {Namespace: "default", ReleaseName: "myrelease", Registry: "oci://example.com/charts"},
+		},
+	}
+	server := newTestServer(&mockHelmClient{}, registryStore)
+
+	_, output, err := server.handleResolveRegistry(context.Background(), &mcp.CallToolRequest{}, RegistryInput{Namespace: "default", Name: "myrelease"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if output.Resolution == nil || output.Resolution.Mapping == nil || output.Resolution.Mapping.Registry != "oci://example.com/charts" {
+		t.Errorf("expected resolution to the explicit mapping, got %+v", output.Resolution)
+	}
+
+	if _, _, err := server.handleResolveRegistry(context.Background(), &mcp.CallToolRequest{}, RegistryInput{Namespace: "default"}); err == nil {
+		t.Error("expected error for missing name")
+	}
+}
+
+func TestHandleGetChartValues(t *testing.T) {
+	helmClient := &mockHelmClient{
+		chartValues: map[string]map[string]any{
+			"default/myrelease": {"replicaCount": 1, "image": map[string]any{"tag": "latest"}},
+		},
+		computedValues: map[string]map[string]any{
+			"default/myrelease": {"replicaCount": 3, "image": map[string]any{"tag": "latest"}},
+		},
+	}
+	registryStore := &mockRegistryStore{mappings: make(map[string]*model.RegistryMapping)}
+
+	server := newTestServer(helmClient, registryStore)
+	ctx := context.Background()
+
+	t.Run("default values", func(t *testing.T) {
+		_, output, err := server.handleGetChartValues(ctx, &mcp.CallToolRequest{}, ReleaseInput{
+			Namespace: "default",
+			Name:      "myrelease",
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if output.Values["replicaCount"] != 1 {
+			t.Errorf("expected default replicaCount 1, got %v", output.Values["replicaCount"])
+		}
+	})
+
+	t.Run("computed values", func(t *testing.T) {
+		_, output, err := server.handleGetComputedValues(ctx, &mcp.CallToolRequest{}, ReleaseInput{
+			Namespace: "default",
+			Name:      "myrelease",
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if output.Values["replicaCount"] != 3 {
+			t.Errorf("expected computed replicaCount 3, got %v", output.Values["replicaCount"])
+		}
+	})
+
+	t.Run("missing name", func(t *testing.T) {
+		_, _, err := server.handleGetComputedValues(ctx, &mcp.CallToolRequest{}, ReleaseInput{Namespace: "default"})
+		if err == nil {
+			t.Fatal("expected error for missing name")
+		}
+	})
+
+	t.Run("client error", func(t *testing.T) {
+		helmClient.valuesErr = fmt.Errorf("release not found")
+		defer func() { helmClient.valuesErr = nil }()
+
+		_, _, err := server.handleGetChartValues(ctx, &mcp.CallToolRequest{}, ReleaseInput{
+			Namespace: "default",
+			Name:      "myrelease",
+		})
+		if err == nil {
+			t.Fatal("expected error")
+		}
+	})
+}
+
+func TestHandleGetValuesSchema(t *testing.T) {
+	helmClient := &mockHelmClient{
+		schemas: map[string]map[string]any{
+			"default/myrelease": {"type": "object", "required": []any{"image"}},
+		},
+	}
+	registryStore := &mockRegistryStore{mappings: make(map[string]*model.RegistryMapping)}
+
+	server := newTestServer(helmClient, registryStore)
+	ctx := context.Background()
+
+	t.Run("chart with schema", func(t *testing.T) {
+		_, output, err := server.handleGetValuesSchema(ctx, &mcp.CallToolRequest{}, ReleaseInput{
+			Namespace: "default",
+			Name:      "myrelease",
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !output.HasSchema {
+			t.Error("expected has_schema to be true")
+		}
+		if output.Schema["type"] != "object" {
+			t.Errorf("expected schema type object, got %v", output.Schema["type"])
+		}
+	})
+
+	t.Run("chart without schema", func(t *testing.T) {
+		_, output, err := server.handleGetValuesSchema(ctx, &mcp.CallToolRequest{}, ReleaseInput{
+			Namespace: "default",
+			Name:      "other",
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if output.HasSchema || output.Schema != nil {
+			t.Errorf("expected no schema, got %v", output.Schema)
+		}
+	})
+}
+
+func TestHandleUpdateReleaseValues(t *testing.T) {
+	helmClient := &mockHelmClient{
+		releaseDetails: map[string]*model.Release{
+			"default/myrelease": {Name: "myrelease", Namespace: "default", Revision: 1},
+		},
+	}
+	registryStore := &mockRegistryStore{mappings: make(map[string]*model.RegistryMapping)}
+
+	server := newTestServer(helmClient, registryStore)
+	ctx := context.Background()
+	verify := true
+
+	testCases := []struct {
+		name    string
+		input   UpdateValuesInput
+		wantErr bool
+	}{
+		{name: "default merge", input: UpdateValuesInput{Values: map[string]any{"replicaCount": 2}}},
+		{name: "replace", input: UpdateValuesInput{Mode: model.ValuesModeReplace, Values: map[string]any{}}},
+		{name: "verified merge", input: UpdateValuesInput{Values: map[string]any{"replicaCount": 2}, UpgradeOptionsInput: UpgradeOptionsInput{Verify: &verify}}},
+		{name: "json-patch", input: UpdateValuesInput{Mode: model.ValuesModeJSONPatch, Patch: []map[string]any{{"op": "remove", "path": "/replicaCount"}}}},
+		{name: "merge without values", input: UpdateValuesInput{Mode: model.ValuesModeMerge}, wantErr: true},
+		{name: "json-patch without patch", input: UpdateValuesInput{Mode: model.ValuesModeJSONPatch, Values: map[string]any{"a": 1}}, wantErr: true},
+		{name: "unknown mode", input: UpdateValuesInput{Mode: "upsert", Values: map[string]any{"a": 1}}, wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.input.Namespace = "default"
+			tc.input.Name = "myrelease"
+
+			result, output, err := server.handleUpdateReleaseValues(ctx, &mcp.CallToolRequest{}, tc.input)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result != nil {
+				t.Fatal("expected result to be nil for success")
+			}
+			if output.Release == nil {
+				t.Fatal("expected release to be non-nil")
+			}
+			if helmClient.lastValuesUpdate.Mode != tc.input.Mode {
+				t.Errorf("expected mode %q to be passed through, got %q", tc.input.Mode, helmClient.lastValuesUpdate.Mode)
+			}
+			if helmClient.lastValuesUpdate.Verify != tc.input.Verify {
+				t.Errorf("expected verify %v to be passed through, got %v", tc.input.Verify, helmClient.lastValuesUpdate.Verify)
+			}
+		})
+	}
+}
+
+func TestHandleRollbackRelease(t *testing.T) {
+	helmClient := &mockHelmClient{
+		releaseDetails: map[string]*model.Release{
+			"default/myrelease": {Name: "myrelease", Namespace: "default", Revision: 3},
+		},
+	}
+	registryStore := &mockRegistryStore{mappings: make(map[string]*model.RegistryMapping)}
+
+	server := newTestServer(helmClient, registryStore)
+	ctx := context.Background()
+
+	t.Run("rollback release", func(t *testing.T) {
+		wait := true
+		result, output, err := server.handleRollbackRelease(ctx, &mcp.CallToolRequest{}, RollbackInput{
+			Namespace:            "default",
+			Name:                 "myrelease",
+			Revision:             2,
+			RollbackOptionsInput: RollbackOptionsInput{Wait: &wait},
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result != nil {
+			t.Fatal("expected result to be nil for success")
+		}
+		if output.Release == nil || output.Release.Revision != 4 {
+			t.Fatalf("expected new revision 4, got %+v", output.Release)
+		}
+		if helmClient.lastRollback.Revision != 2 {
+			t.Errorf("expected rollback to revision 2, got %d", helmClient.lastRollback.Revision)
+		}
+		if helmClient.lastRollback.Wait == nil || !*helmClient.lastRollback.Wait {
+			t.Error("expected wait to be passed through")
+		}
+	})
+
+	t.Run("invalid revision", func(t *testing.T) {
+		_, _, err := server.handleRollbackRelease(ctx, &mcp.CallToolRequest{}, RollbackInput{
+			Namespace: "default",
+			Name:      "myrelease",
+		})
+		if err == nil {
+			t.Fatal("expected error for missing revision")
+		}
+	})
+}
+
+func TestMCPServer(t *testing.T) {
+	helmClient := &mockHelmClient{}
+	registryStore := &mockRegistryStore{mappings: make(map[string]*model.RegistryMapping)}
+
+	server := NewServer(helmClient, registryStore, operation.NewManager(operation.DefaultRetention, nil, nil))
+
+	mcpServer := server.MCPServer()
+	if mcpServer == nil {
+		t.Fatal("expected MCPServer to be non-nil")
+	}
+}
+
+func TestHandleGetOperation(t *testing.T) {
+	helmClient := &mockHelmClient{
+		releaseDetails: map[string]*model.Release{
+			"default/myrelease": {Name: "myrelease", Namespace: "default", ChartVersion: "1.0.0", Revision: 1},
+		},
+	}
+	server := newTestServer(helmClient, &mockRegistryStore{mappings: make(map[string]*model.RegistryMapping)})
+	ctx := context.Background()
+
+	_, upgraded, err := server.handleUpgradeRelease(ctx, &mcp.CallToolRequest{}, UpgradeInput{
+		Namespace:    "default",
+		Name:         "myrelease",
+		ChartVersion: "1.1.0",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if upgraded.OperationID == "" {
+		t.Fatal("expected operation id in upgrade output")
+	}
+
+	t.Run("get operation", func(t *testing.T) {
+		_, output, err := server.handleGetOperation(ctx, &mcp.CallToolRequest{}, OperationInput{ID: upgraded.OperationID})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		op := output.Operation
+		if op.Status != model.OperationSucceeded {
+			t.Errorf("expected succeeded operation, got %s", op.Status)
+		}
+		if op.Type != model.OperationUpgrade || op.Namespace != "default" || op.Name != "myrelease" {
+			t.Errorf("unexpected operation: %+v", op)
+		}
+		if len(op.Events) != 2 || op.Events[0].Phase != model.PhaseApply || op.Events[1].Phase != model.PhaseDone {
+			t.Errorf("expected apply and done events, got %+v", op.Events)
+		}
+	})
+
+	t.Run("missing id", func(t *testing.T) {
+		if _, _, err := server.handleGetOperation(ctx, &mcp.CallToolRequest{}, OperationInput{}); err == nil {
+			t.Fatal("expected error for missing id")
+		}
+	})
+
+	t.Run("unknown operation", func(t *testing.T) {
+		if _, _, err := server.handleGetOperation(ctx, &mcp.CallToolRequest{}, OperationInput{ID: "missing"}); err == nil {
+			t.Fatal("expected error for unknown operation")
+		}
+	})
+
+	t.Run("failed operation", func(t *testing.T) {
+		helmClient.upgradeErr = fmt.Errorf("chart not found")
+		defer func() { helmClient.upgradeErr = nil }()
+
+		_, _, err := server.handleUpgradeRelease(ctx, &mcp.CallToolRequest{}, UpgradeInput{
+			Namespace:    "default",
+			Name:         "myrelease",
+			ChartVersion: "9.9.9",
+		})
+		if err == nil || !strings.Contains(err.Error(), "chart not found") {
+			t.Fatalf("expected error from the operation, got %v", err)
+		}
+	})
+}
+
+func TestUpgradeReportsProgress(t *testing.T) {
+	helmClient := &mockHelmClient{
+		releaseDetails: map[string]*model.Release{
+			"default/myrelease": {Name: "myrelease", Namespace: "default", ChartVersion: "1.0.0", Revision: 1},
+		},
+	}
+	server := newTestServer(helmClient, &mockRegistryStore{mappings: make(map[string]*model.RegistryMapping)})
+	ctx := context.Background()
+
+	var mu sync.Mutex
+	var messages []string
+	client := mcp.NewClient(&mcp.Implementation{Name: "test", Version: "v0.0.1"}, &mcp.ClientOptions{
+		ProgressNotificationHandler: func(ctx context.Context, req *mcp.ProgressNotificationClientRequest) {
+			mu.Lock()
+			defer mu.Unlock()
+			messages = append(messages, req.Params.Message)
+		},
+	})
+
+	clientTransport, serverTransport := mcp.NewInMemoryTransports()
+	serverSession, err := server.MCPServer().Connect(ctx, serverTransport, nil)
+	if err != nil {
+		t.Fatalf("failed to connect server: %v", err)
+	}
+	defer serverSession.Close()
+	session, err := client.Connect(ctx, clientTransport, nil)
+	if err != nil {
+		t.Fatalf("failed to connect client: %v", err)
+	}
+	defer session.Close()
+
+	// SetProgressToken loses the token when Meta is nil, so set it directly
+	result, err := session.CallTool(ctx, &mcp.CallToolParams{
+		Meta:      mcp.Meta{"progressToken": "upgrade-1"},
+		Name:      "upgrade_release",
+		Arguments: map[string]any{"namespace": "default", "name": "myrelease", "chart_version": "1.1.0"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("unexpected tool error: %+v", result.Content)
+	}
+	if helmClient.lastUpgrade.DeployedBy != "mcp.test" {
+		t.Errorf("expected the upgrade to be deployed by mcp.test, got %q", helmClient.lastUpgrade.DeployedBy)
+	}
+
+	want := []string{"apply: Applying 3 resources", "done: Operation completed"}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		mu.Lock()
+		got := append([]string{}, messages...)
+		mu.Unlock()
+		if len(got) >= len(want) {
+			if fmt.Sprint(got) != fmt.Sprint(want) {
+				t.Errorf("expected progress %v, got %v", want, got)
+			}
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected progress %v, got %v", want, got)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// schemaError stands in for helm.SchemaValidationError
+type schemaError struct {
+	errs []model.FieldError
+}
+
+func (e *schemaError) Error() string                   { return "values do not match the chart schema" }
+func (e *schemaError) FieldErrors() []model.FieldError { return e.errs }
+
+func TestOperationReturnsSchemaErrors(t *testing.T) {
+	helmClient := &mockHelmClient{
+		releaseDetails: map[string]*model.Release{
+			"default/myrelease": {Name: "myrelease", Namespace: "default", ChartVersion: "1.0.0", Revision: 1},
+		},
+		updateValuesErr: &schemaError{errs: []model.FieldError{{Field: "replicaCount", Message: "expected integer"}}},
+	}
+	server := newTestServer(helmClient, &mockRegistryStore{mappings: make(map[string]*model.RegistryMapping)})
+	ctx := context.Background()
+
+	clientTransport, serverTransport := mcp.NewInMemoryTransports()
+	serverSession, err := server.MCPServer().Connect(ctx, serverTransport, nil)
+	if err != nil {
+		t.Fatalf("failed to connect server: %v", err)
+	}
+	defer serverSession.Close()
+	session, err := mcp.NewClient(&mcp.Implementation{Name: "test", Version: "v0.0.1"}, nil).Connect(ctx, clientTransport, nil)
+	if err != nil {
+		t.Fatalf("failed to connect client: %v", err)
+	}
+	defer session.Close()
+
+	result, err := session.CallTool(ctx, &mcp.CallToolParams{
+		Name:      "update_release_values",
+		Arguments: map[string]any{"namespace": "default", "name": "myrelease", "values": map[string]any{"replicaCount": "two"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.IsError {
+		t.Fatal("expected a tool error")
+	}
+
+	data, err := json.Marshal(result.StructuredContent)
+	if err != nil {
+		t.Fatalf("failed to marshal structured content: %v", err)
+	}
+	var output ReleaseOutput
+	if err := json.Unmarshal(data, &output); err != nil {
+		t.Fatalf("failed to decode structured content: %v", err)
+	}
+	if len(output.Errors) != 1 || output.Errors[0].Field != "replicaCount" || output.OperationID == "" {
+		t.Errorf("expected the field error and operation ID, got %+v", output)
+	}
+	if text, ok := result.Content[0].(*mcp.TextContent); !ok || !strings.Contains(text.Text, "values do not match the chart schema") {
+		t.Errorf("expected the error message as text, got %+v", result.Content)
+	}
+}
+
+type lockedLocker struct {
+	holder model.LockHolder
+}
+
+func (l *lockedLocker) Acquire(ctx context.Context, holder model.LockHolder, lost func(error)) (func(), error) {
+	return nil, &lock.LockedError{Holder: l.holder}
+}
+
+func TestOperationOnLockedRelease(t *testing.T) {
+	helmClient := &mockHelmClient{
+		releaseDetails: map[string]*model.Release{
+			"default/myrelease": {Name: "myrelease", Namespace: "default", ChartVersion: "1.0.0", Revision: 1},
+		},
+	}
+	locker := &lockedLocker{holder: model.LockHolder{
+		Namespace:   "default",
+		Name:        "myrelease",
+		OperationID: "op-1",
+		Operation:   model.OperationUpdateValues,
+		Replica:     "helm-ui-1",
+		AcquiredAt:  time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+	}}
+	server := NewServer(helmClient, &mockRegistryStore{mappings: make(map[string]*model.RegistryMapping)}, operation.NewManager(operation.DefaultRetention, locker, nil))
+
+	_, output, err := server.handleUpgradeRelease(context.Background(), &mcp.CallToolRequest{}, UpgradeInput{
+		Namespace:    "default",
+		Name:         "myrelease",
+		ChartVersion: "1.1.0",
+	})
+	if err == nil {
+		t.Fatal("expected error for locked release")
+	}
+	want := "failed to upgrade release: release default/myrelease is locked by update-values operation op-1 on helm-ui-1 since 2026-01-02T03:04:05Z"
+	if err.Error() != want {
+		t.Errorf("expected %q, got %q", want, err.Error())
+	}
+	if output.OperationID != "" {
+		t.Errorf("expected no operation to be started, got %s", output.OperationID)
+	}
+	if helmClient.lastUpgrade.ChartVersion != "" {
+		t.Error("expected upgrade not to run")
+	}
+
+	ctx := context.Background()
+	if _, _, err := server.handleInstallRelease(ctx, &mcp.CallToolRequest{}, InstallInput{Namespace: "default", Name: "myrelease", Chart: "mychart", Registry: "oci://ghcr.io/example/charts"}); err == nil {
+		t.Error("expected install of a locked release to fail")
+	}
+	if _, _, err := server.handleUninstallRelease(ctx, &mcp.CallToolRequest{}, UninstallInput{Namespace: "default", Name: "myrelease"}); err == nil {
+		t.Error("expected uninstall of a locked release to fail")
+	}
+	if _, ok := helmClient.releaseDetails["default/myrelease"]; !ok {
+		t.Error("expected uninstall not to run")
+	}
+	if _, _, err := server.handleRunReleaseTests(ctx, &mcp.CallToolRequest{}, RunTestsInput{Namespace: "default", Name: "myrelease"}); err == nil {
+		t.Error("expected tests of a locked release to fail")
+	}
+}
+
+func TestExpectedRevision(t *testing.T) {
+	helmClient := &mockHelmClient{
+		releaseDetails: map[string]*model.Release{
+			"default/myrelease": {Name: "myrelease", Namespace: "default", ChartVersion: "1.0.0", Revision: 4},
+		},
+	}
+	server := newTestServer(helmClient, &mockRegistryStore{mappings: make(map[string]*model.RegistryMapping)})
+	ctx := context.Background()
+
+	calls := []struct {
+		name string
+		call func(expected int) error
+	}{
+		{
+			name: "upgrade",
+			call: func(expected int) error {
+				_, _, err := server.handleUpgradeRelease(ctx, &mcp.CallToolRequest{}, UpgradeInput{
+					Namespace: "default", Name: "myrelease", ChartVersion: "1.1.0", ExpectedRevision: expected,
+				})
+				return err
+			},
+		},
+		{
+			name: "update values",
+			call: func(expected int) error {
+				_, _, err := server.handleUpdateReleaseValues(ctx, &mcp.CallToolRequest{}, UpdateValuesInput{
+					Namespace: "default", Name: "myrelease", Values: map[string]any{"replicaCount": 2}, ExpectedRevision: expected,
+				})
+				return err
+			},
+		},
+		{
+			name: "rollback",
+			call: func(expected int) error {
+				_, _, err := server.handleRollbackRelease(ctx, &mcp.CallToolRequest{}, RollbackInput{
+					Namespace: "default", Name: "myrelease", Revision: 3, ExpectedRevision: expected,
+				})
+				return err
+			},
+		},
+	}
+
+	for _, c := range calls {
+		t.Run(c.name, func(t *testing.T) {
+			if err := c.call(4); err != nil {
+				t.Errorf("expected current revision to be accepted, got %v", err)
+			}
+			if err := c.call(0); err != nil {
+				t.Errorf("expected no revision to be accepted, got %v", err)
+			}
+
+			err := c.call(3)
+			if err == nil || !strings.Contains(err.Error(), "release default/myrelease is at revision 4, not the expected revision 3") {
+				t.Errorf("expected revision mismatch, got %v", err)
+			}
+
+			if err := c.call(-1); err == nil || !strings.Contains(err.Error(), "expected_revision must be a positive integer") {
+				t.Errorf("expected validation error, got %v", err)
+			}
+		})
+	}
+
+	if helmClient.lastUpgrade.ExpectedRevision != 3 || helmClient.lastRollback.ExpectedRevision != 3 {
+		t.Errorf("expected the expected revision to be passed through, got %d and %d", helmClient.lastUpgrade.ExpectedRevision, helmClient.lastRollback.ExpectedRevision)
 	}
 }
@@ -0,0 +1,61 @@
+package model
+
+import "time"
+
+// Outcomes of the verification that follows an upgrade
+const (
+	// VerificationVerified means every resource became ready in time
+	VerificationVerified = "verified"
+	// VerificationRolledBack means a resource failed and the release was
+	// rolled back to the previous revision
+	VerificationRolledBack = "rolled-back"
+	// VerificationTimedOut means the resources were not ready before the
+	// deadline and the release was rolled back to the previous revision
+	VerificationTimedOut = "timed-out"
+	// VerificationRollbackFailed means verification failed and the
+	// automatic rollback failed too
+	VerificationRollbackFailed = "rollback-failed"
+	// VerificationAborted means verification stopped before it finished,
+	// because the operation lost its lock on the release, and the release
+	// was left as it was
+	VerificationAborted = "aborted"
+)
+
+// Verification is the result of watching a release's resources after an
+// upgrade. RolledBackTo is the revision the release was rolled back to, if
+// any.
+type Verification struct {
+	Outcome      string           `json:"outcome"`
+	Revision     int              `json:"revision"`
+	RolledBackTo int              `json:"rolledBackTo,omitempty"`
+	Message      string           `json:"message"`
+	StartedAt    time.Time        `json:"startedAt"`
+	CompletedAt  time.Time        `json:"completedAt"`
+	Resources    []ResourceStatus `json:"resources"`
+}
+
+// Outcomes of a release test run
+const (
+	TestsPassed = "tests-passed"
+	TestsFailed = "tests-failed"
+)
+
+// Actions recorded in the audit log
+const (
+	AuditActionUpgrade      = "upgrade"
+	AuditActionUpdateValues = "update-values"
+	AuditActionTest         = "test"
+)
+
+type AuditRecord struct {
+	ID           string    `json:"id"`
+	Time         time.Time `json:"time"`
+	Namespace    string    `json:"namespace"`
+	Name         string    `json:"name"`
+	Action       string    `json:"action"`
+	ChartVersion string    `json:"chartVersion"`
+	Revision     int       `json:"revision"`
+	Outcome      string    `json:"outcome"`
+	RolledBackTo int       `json:"rolledBackTo,omitempty"`
+	Message      string    `json:"message"`
+}
@@ -0,0 +1,56 @@
+package model
+
+// Change types reported for resources in a manifest diff
+const (
+	ChangeAdded   = "added"
+	ChangeRemoved = "removed"
+	ChangeChanged = "changed"
+)
+
+type ManifestObject struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name"`
+	Manifest   string `json:"manifest"`
+}
+
+type ResourceDiff struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	Change    string `json:"change"`
+	Diff      string `json:"diff"`
+}
+
+type ManifestDiff struct {
+	Added     int            `json:"added"`
+	Removed   int            `json:"removed"`
+	Changed   int            `json:"changed"`
+	Resources []ResourceDiff `json:"resources"`
+}
+
+type UpgradePreview struct {
+	Namespace           string       `json:"namespace"`
+	Name                string       `json:"name"`
+	CurrentRevision     int          `json:"currentRevision"`
+	CurrentChartVersion string       `json:"currentChartVersion"`
+	TargetChartVersion  string       `json:"targetChartVersion"`
+	Diff                ManifestDiff `json:"diff"`
+}
+
+type ValueChange struct {
+	Path   string `json:"path"`
+	Change string `json:"change"`
+	From   any    `json:"from,omitempty"`
+	To     any    `json:"to,omitempty"`
+}
+
+type RevisionDiff struct {
+	Namespace string        `json:"namespace"`
+	Name      string        `json:"name"`
+	From      int           `json:"from"`
+	To        int           `json:"to"`
+	Values    []ValueChange `json:"values"`
+	Manifest  ManifestDiff  `json:"manifest"`
+}
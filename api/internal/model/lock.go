@@ -0,0 +1,22 @@
+package model
+
+import "time"
+
+// LockHolder describes the operation holding the lock on a release.
+// Replica is the helm-ui instance running it.
+type LockHolder struct {
+	Namespace   string    `json:"namespace"`
+	Name        string    `json:"name"`
+	OperationID string    `json:"operationId"`
+	Operation   string    `json:"operation"`
+	Replica     string    `json:"replica"`
+	AcquiredAt  time.Time `json:"acquiredAt"`
+	RenewedAt   time.Time `json:"renewedAt"`
+}
+
+// LockConflictResponse is returned with 409 Conflict when a release is
+// locked by another operation
+type LockConflictResponse struct {
+	Message string     `json:"message"`
+	Holder  LockHolder `json:"holder"`
+}
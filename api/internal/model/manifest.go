@@ -0,0 +1,77 @@
+package model
+
+import "time"
+
+// ReleaseManifest is the rendered manifest of a release revision, both as
+// the raw multi-document YAML and split into objects
+type ReleaseManifest struct {
+	Namespace string           `json:"namespace"`
+	Name      string           `json:"name"`
+	Revision  int              `json:"revision"`
+	Manifest  string           `json:"manifest"`
+	Objects   []ManifestObject `json:"objects"`
+}
+
+type ReleaseNotes struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Revision  int    `json:"revision"`
+	Notes     string `json:"notes"`
+}
+
+// HookRun is the outcome of the last execution of a hook. Times are nil
+// when the hook has not started or completed.
+type HookRun struct {
+	Phase       string     `json:"phase,omitempty"`
+	StartedAt   *time.Time `json:"startedAt,omitempty"`
+	CompletedAt *time.Time `json:"completedAt,omitempty"`
+}
+
+type ReleaseHook struct {
+	Name           string   `json:"name"`
+	Kind           string   `json:"kind"`
+	Path           string   `json:"path"`
+	Events         []string `json:"events"`
+	Weight         int      `json:"weight"`
+	DeletePolicies []string `json:"deletePolicies,omitempty"`
+	LastRun        HookRun  `json:"lastRun"`
+	Manifest       string   `json:"manifest"`
+}
+
+type ReleaseHooks struct {
+	Namespace string        `json:"namespace"`
+	Name      string        `json:"name"`
+	Revision  int           `json:"revision"`
+	Hooks     []ReleaseHook `json:"hooks"`
+}
+
+// Live statuses of release resources, following the kstatus conventions
+const (
+	ResourceStatusCurrent     = "Current"
+	ResourceStatusInProgress  = "InProgress"
+	ResourceStatusFailed      = "Failed"
+	ResourceStatusTerminating = "Terminating"
+	ResourceStatusNotFound    = "NotFound"
+	ResourceStatusUnknown     = "Unknown"
+)
+
+type ResourceStatus struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name"`
+	Status     string `json:"status"`
+	Message    string `json:"message,omitempty"`
+}
+
+// ReleaseResources is the live status of the resources in the manifest of
+// the latest revision. Status is Current only when every resource is.
+type ReleaseResources struct {
+	Namespace     string           `json:"namespace"`
+	Name          string           `json:"name"`
+	Revision      int              `json:"revision"`
+	ReleaseStatus string           `json:"releaseStatus"`
+	Status        string           `json:"status"`
+	Ready         bool             `json:"ready"`
+	Resources     []ResourceStatus `json:"resources"`
+}
@@ -0,0 +1,85 @@
+package model
+
+import (
+	"fmt"
+	"time"
+)
+
+// Types of asynchronous operations
+const (
+	OperationUpgrade      = "upgrade"
+	OperationUpdateValues = "update-values"
+	OperationRollback     = "rollback"
+	OperationTest         = "test"
+)
+
+// Types of operations that run synchronously but lock the release like the
+// asynchronous ones
+const (
+	OperationInstall   = "install"
+	OperationUninstall = "uninstall"
+)
+
+// Statuses of an operation
+const (
+	OperationRunning   = "running"
+	OperationSucceeded = "succeeded"
+	OperationFailed    = "failed"
+)
+
+// Phases an operation reports while it runs. Not every operation goes
+// through every phase; done or failed is always the last one.
+const (
+	PhasePullChart = "pull-chart"
+	PhaseRender    = "render"
+	PhaseApply     = "apply"
+	PhaseWait      = "wait"
+	PhaseVerify    = "verify"
+	PhaseTest      = "test"
+	PhaseRollback  = "rollback"
+	PhaseDone      = "done"
+	PhaseFailed    = "failed"
+	// PhaseLockLost is reported when another replica took over the lock on
+	// the release while the operation was still running, so something else
+	// may have changed the release concurrently
+	PhaseLockLost = "lock-lost"
+)
+
+// ProgressFunc is called when a long running operation enters a phase
+type ProgressFunc func(phase, message string)
+
+// Report calls f if it is set, formatting the message like fmt.Sprintf
+func (f ProgressFunc) Report(phase, format string, args ...any) {
+	if f == nil {
+		return
+	}
+	f(phase, fmt.Sprintf(format, args...))
+}
+
+// OperationEvent is a phase an operation entered. Sequence numbers start at
+// 1 and increase by one per event of the operation.
+type OperationEvent struct {
+	Sequence int       `json:"sequence"`
+	Time     time.Time `json:"time"`
+	Phase    string    `json:"phase"`
+	Message  string    `json:"message"`
+}
+
+// Operation is an upgrade, values update or rollback running in the
+// background. Result is set when it succeeded, Error when it failed, and
+// Errors in addition when the values did not match the chart schema.
+type Operation struct {
+	ID          string           `json:"id"`
+	Type        string           `json:"type"`
+	Namespace   string           `json:"namespace"`
+	Name        string           `json:"name"`
+	Status      string           `json:"status"`
+	Phase       string           `json:"phase"`
+	CreatedAt   time.Time        `json:"createdAt"`
+	UpdatedAt   time.Time        `json:"updatedAt"`
+	CompletedAt *time.Time       `json:"completedAt,omitempty"`
+	Error       string           `json:"error,omitempty"`
+	Errors      []FieldError     `json:"errors,omitempty"`
+	Result      *Release         `json:"result,omitempty"`
+	Events      []OperationEvent `json:"events"`
+}
@@ -1,12 +1,133 @@
 package model
 
+// Sources of a registry mapping, in the order they are looked up
+const (
+	// RegistrySourceExplicit mappings were set through the API or on install
+	RegistrySourceExplicit = "explicit"
+	// RegistrySourceRule mappings come from the first matching RegistryRule
+	RegistrySourceRule = "rule"
+	// RegistrySourceChartAnnotation mappings come from an annotation in the
+	// release chart's Chart.yaml
+	RegistrySourceChartAnnotation = "chart-annotation"
+	// RegistrySourceReleaseLabel mappings come from a label on the Helm
+	// release secret
+	RegistrySourceReleaseLabel = "release-label"
+	// RegistrySourceNamespaceDefault mappings come from an annotation on the
+	// release's namespace
+	RegistrySourceNamespaceDefault = "namespace-default"
+)
+
 type RegistryMapping struct {
 	Namespace   string `json:"namespace"`
 	ReleaseName string `json:"releaseName"`
 	ChartName   string `json:"chartName"`
 	Registry    string `json:"registry"`
+	// Source tells where the mapping came from; it is not stored
+	Source string `json:"source,omitempty"`
+	// RuleID is the rule the mapping came from when Source is rule
+	RuleID string `json:"ruleId,omitempty"`
 }
 
 type SetRegistryRequest struct {
 	Registry string `json:"registry" validate:"required"`
 }
+
+// RegistryRule maps every release whose chart name matches the Chart glob
+// and whose namespace labels match NamespaceSelector to Registry. Empty
+// patterns match everything, so a rule with neither is a default. Rules
+// are tried after explicit mappings, highest Priority first.
+type RegistryRule struct {
+	ID                string `json:"id"`
+	Chart             string `json:"chart,omitempty"`
+	NamespaceSelector string `json:"namespaceSelector,omitempty"`
+	Priority          int    `json:"priority"`
+	Registry          string `json:"registry"`
+}
+
+// RegistryResolutionStep is one source tried while resolving the registry
+// of a release
+type RegistryResolutionStep struct {
+	Source  string `json:"source"`
+	RuleID  string `json:"ruleId,omitempty"`
+	Matched bool   `json:"matched"`
+	Reason  string `json:"reason"`
+}
+
+// RegistryResolution explains how the registry of a release was resolved.
+// Steps end at the first source that matched.
+type RegistryResolution struct {
+	Namespace   string                   `json:"namespace"`
+	ReleaseName string                   `json:"releaseName"`
+	Mapping     *RegistryMapping         `json:"mapping,omitempty"`
+	Message     string                   `json:"message"`
+	Steps       []RegistryResolutionStep `json:"steps"`
+}
+
+// Modes of a registry mapping import
+const (
+	// RegistryImportMerge sets the imported mappings and keeps the others
+	RegistryImportMerge = "merge"
+	// RegistryImportReplace also deletes the explicit mappings missing from
+	// the import
+	RegistryImportReplace = "replace"
+)
+
+// Actions reported for each entry of a registry mapping import
+const (
+	RegistryImportCreate    = "create"
+	RegistryImportUpdate    = "update"
+	RegistryImportUnchanged = "unchanged"
+	RegistryImportDelete    = "delete"
+	// RegistryImportInvalid entries failed validation, so nothing was applied
+	RegistryImportInvalid = "invalid"
+	// RegistryImportFailed entries were valid but could not be written
+	RegistryImportFailed = "failed"
+)
+
+// RegistryMappings is the document exported and imported to move explicit
+// registry mappings between clusters
+type RegistryMappings struct {
+	Mappings []RegistryMapping `json:"mappings"`
+}
+
+type RegistryImportOptions struct {
+	Mode   string
+	DryRun bool
+}
+
+// RegistryImportResult is what an import did, or would do in a dry run,
+// with one imported or deleted mapping
+type RegistryImportResult struct {
+	Namespace   string `json:"namespace"`
+	ReleaseName string `json:"releaseName"`
+	ChartName   string `json:"chartName,omitempty"`
+	Registry    string `json:"registry"`
+	Action      string `json:"action"`
+	Error       string `json:"error,omitempty"`
+}
+
+// RegistryImportReport lists the result of every entry. Applied is true
+// only when every entry was written: it is false for dry runs, when any
+// entry is invalid and nothing was written, and when some writes failed, in
+// which case the entries with the failed action are the only ones not
+// written.
+type RegistryImportReport struct {
+	Mode    string                 `json:"mode"`
+	DryRun  bool                   `json:"dryRun"`
+	Applied bool                   `json:"applied"`
+	Results []RegistryImportResult `json:"results"`
+	// Counts holds the number of results per action
+	Counts map[string]int `json:"counts"`
+}
+
+// RegistryCredential makes helm-ui authenticate to the registries and
+// repositories whose references start with Registry, e.g. ghcr.io/myorg or
+// https://charts.example.com, using the kubernetes.io/dockerconfigjson or
+// kubernetes.io/basic-auth Secret SecretName in helm-ui's namespace. The
+// Secret has to list Registry, or a registry it is under, in its
+// helm-ui/registries annotation. The longest matching Registry wins.
+type RegistryCredential struct {
+	ID         string `json:"id"`
+	Registry   string `json:"registry"`
+	SecretName string `json:"secretName"`
+}
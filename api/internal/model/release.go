@@ -12,6 +12,11 @@ type Release struct {
 	Updated      time.Time `json:"updated"`
 	Revision     int       `json:"revision"`
 	HasRegistry  bool      `json:"hasRegistry"`
+	// Verification is set on the result of an upgrade that was verified
+	Verification *Verification `json:"verification,omitempty"`
+	// Tests is set on the result of a test run and of an upgrade that ran
+	// the release tests
+	Tests *TestResult `json:"tests,omitempty"`
 }
 
 type ReleaseFilter struct {
@@ -19,9 +24,74 @@ type ReleaseFilter struct {
 	HasRegistry *bool
 }
 
+// UpgradeOptions tunes how Helm applies an upgrade. Fields left unset fall
+// back to the server defaults. Timeout is a Go duration string such as "5m".
+type UpgradeOptions struct {
+	Atomic        *bool  `json:"atomic,omitempty"`
+	Wait          *bool  `json:"wait,omitempty"`
+	WaitForJobs   *bool  `json:"waitForJobs,omitempty"`
+	Timeout       string `json:"timeout,omitempty"`
+	Force         *bool  `json:"force,omitempty"`
+	DisableHooks  *bool  `json:"disableHooks,omitempty"`
+	CleanupOnFail *bool  `json:"cleanupOnFail,omitempty"`
+	MaxHistory    *int   `json:"maxHistory,omitempty"`
+	// Verify watches the release's resources after the upgrade and rolls
+	// back to the previous revision unless they all become ready within
+	// VerifyTimeout
+	Verify        *bool  `json:"verify,omitempty"`
+	VerifyTimeout string `json:"verifyTimeout,omitempty"`
+}
+
+// VersionUpgradeRequest upgrades a release to another chart version. When
+// Test is set the release tests run after the upgrade, and after a
+// successful verification if Verify is set too; the release is rolled back to
+// the previous revision if any test fails. A non-zero ExpectedRevision
+// makes the upgrade fail unless the release is still at that revision.
 type VersionUpgradeRequest struct {
-	ChartVersion string         `json:"chartVersion"`
+	ChartVersion     string         `json:"chartVersion"`
+	Values           map[string]any `json:"values,omitempty"`
+	Test             *TestRequest   `json:"test,omitempty"`
+	ExpectedRevision int            `json:"expectedRevision,omitempty"`
+	// DeployedBy identifies the caller for the revision's deployed-by label.
+	// It is set by the server, never from the request body.
+	DeployedBy string `json:"-"`
+	UpgradeOptions
+}
+
+// VersionQuery controls which chart versions are returned and how many.
+type VersionQuery struct {
+	Limit             int
+	IncludePrerelease bool
+	Constraint        string
+}
+
+// InstallRequest describes a new release. When ChartVersion is empty the
+// latest stable version in the registry is installed.
+type InstallRequest struct {
+	Name         string         `json:"name"`
+	Chart        string         `json:"chart"`
+	Registry     string         `json:"registry"`
+	ChartVersion string         `json:"chartVersion,omitempty"`
 	Values       map[string]any `json:"values,omitempty"`
+	// Set by the server, like VersionUpgradeRequest.DeployedBy
+	DeployedBy string `json:"-"`
+}
+
+// UninstallRequest controls how a release is removed. Timeout is a Go
+// duration string such as "5m"; it applies to hooks and, with Wait, to
+// waiting for resources to be deleted.
+type UninstallRequest struct {
+	KeepHistory bool   `json:"keepHistory"`
+	Wait        bool   `json:"wait"`
+	Timeout     string `json:"timeout,omitempty"`
+}
+
+type UninstallResult struct {
+	Namespace              string `json:"namespace"`
+	Name                   string `json:"name"`
+	Info                   string `json:"info,omitempty"`
+	KeptHistory            bool   `json:"keptHistory"`
+	RegistryMappingDeleted bool   `json:"registryMappingDeleted"`
 }
 
 type ChartVersion struct {
@@ -30,19 +100,103 @@ type ChartVersion struct {
 	Description string `json:"description"`
 }
 
+// HistoryQuery selects a page of release history. Before is a revision
+// cursor: only revisions older than it are returned.
+type HistoryQuery struct {
+	Max    int
+	Before int
+}
+
 type ReleaseHistory struct {
 	Revision    int       `json:"revision"`
 	Updated     time.Time `json:"updated"`
 	Status      string    `json:"status"`
 	Chart       string    `json:"chart"`
+	ChartName   string    `json:"chartName"`
 	AppVersion  string    `json:"appVersion"`
 	Description string    `json:"description"`
+	// DeployedBy is who deployed the revision through helm-ui, as far as
+	// helm-ui could tell: the user named by an authenticating proxy or the
+	// MCP client. It is empty for revisions deployed with the Helm CLI.
+	DeployedBy string `json:"deployedBy,omitempty"`
+	Current    bool   `json:"current"`
 }
 
+// HistoryPage is a page of release history, newest first. NextBefore is the
+// cursor for the next page and is zero when there are no older revisions.
+type HistoryPage struct {
+	History    []ReleaseHistory `json:"history"`
+	NextBefore int              `json:"nextBefore,omitempty"`
+}
+
+// Modes of applying a values update
+const (
+	// ValuesModeMerge deep-merges values into the current values; a null
+	// value removes the key
+	ValuesModeMerge = "merge"
+	// ValuesModeReplace replaces the current values entirely
+	ValuesModeReplace = "replace"
+	// ValuesModeJSONPatch applies an RFC 6902 JSON Patch to the current values
+	ValuesModeJSONPatch = "json-patch"
+)
+
+// ValuesUpdateRequest updates the values of a release. Values is used by the
+// merge (default) and replace modes, Patch by the json-patch mode. A
+// non-zero ExpectedRevision makes the update fail unless the release is still
+// at that revision, so values edited from an older revision are not applied
+// on top of a newer one.
 type ValuesUpdateRequest struct {
-	Values map[string]any `json:"values"`
+	Mode             string           `json:"mode,omitempty"`
+	Values           map[string]any   `json:"values,omitempty"`
+	Patch            []map[string]any `json:"patch,omitempty"`
+	ExpectedRevision int              `json:"expectedRevision,omitempty"`
+	// Set by the server, like VersionUpgradeRequest.DeployedBy
+	DeployedBy string `json:"-"`
+	UpgradeOptions
 }
 
+// FieldError is a single values schema violation. Field is the dotted path of
+// the offending value and is empty for errors on the values as a whole.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationErrorResponse is returned when values do not match the chart's
+// values.schema.json
+type ValidationErrorResponse struct {
+	Message string       `json:"message"`
+	Errors  []FieldError `json:"errors"`
+}
+
+// RollbackRequest rolls a release back to Revision. A non-zero
+// ExpectedRevision makes the rollback fail unless the release is still at
+// that revision.
 type RollbackRequest struct {
-	Revision int `json:"revision"`
+	Revision         int `json:"revision"`
+	ExpectedRevision int `json:"expectedRevision,omitempty"`
+	// Set by the server, like VersionUpgradeRequest.DeployedBy
+	DeployedBy string `json:"-"`
+	RollbackOptions
+}
+
+// RollbackOptions tunes how Helm applies a rollback. They are the upgrade
+// options without Atomic and Verify, which only apply to upgrades; fields
+// left unset fall back to the server's upgrade defaults.
+type RollbackOptions struct {
+	Wait          *bool  `json:"wait,omitempty"`
+	WaitForJobs   *bool  `json:"waitForJobs,omitempty"`
+	Timeout       string `json:"timeout,omitempty"`
+	Force         *bool  `json:"force,omitempty"`
+	DisableHooks  *bool  `json:"disableHooks,omitempty"`
+	CleanupOnFail *bool  `json:"cleanupOnFail,omitempty"`
+	MaxHistory    *int   `json:"maxHistory,omitempty"`
+}
+
+// RevisionConflictResponse is returned with 412 Precondition Failed when a
+// release is no longer at the revision a request expected
+type RevisionConflictResponse struct {
+	Message          string `json:"message"`
+	ExpectedRevision int    `json:"expectedRevision"`
+	CurrentRevision  int    `json:"currentRevision"`
 }
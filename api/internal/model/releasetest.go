@@ -0,0 +1,40 @@
+package model
+
+import "time"
+
+// TestRequest controls a helm test run. Filter names the tests to run; a
+// name prefixed with "!" is skipped instead. Timeout is a Go duration string
+// such as "5m" and applies to each test. Logs captures the logs of test pods.
+type TestRequest struct {
+	Filter  []string `json:"filter,omitempty"`
+	Timeout string   `json:"timeout,omitempty"`
+	Logs    bool     `json:"logs,omitempty"`
+}
+
+// TestHookResult is the outcome of a single test hook. LogsError explains why
+// logs were requested but could not be captured, e.g. because the test pod
+// was already deleted by its hook delete policy.
+type TestHookResult struct {
+	Name        string     `json:"name"`
+	Kind        string     `json:"kind"`
+	Phase       string     `json:"phase"`
+	StartedAt   *time.Time `json:"startedAt,omitempty"`
+	CompletedAt *time.Time `json:"completedAt,omitempty"`
+	Logs        string     `json:"logs,omitempty"`
+	LogsError   string     `json:"logsError,omitempty"`
+}
+
+// TestResult is the outcome of running the tests of a release revision.
+// RolledBackTo is set when the tests ran after an upgrade, failed and the
+// release was rolled back to that revision.
+type TestResult struct {
+	Namespace    string           `json:"namespace"`
+	Name         string           `json:"name"`
+	Revision     int              `json:"revision"`
+	Passed       bool             `json:"passed"`
+	Message      string           `json:"message,omitempty"`
+	StartedAt    time.Time        `json:"startedAt"`
+	CompletedAt  time.Time        `json:"completedAt"`
+	Tests        []TestHookResult `json:"tests"`
+	RolledBackTo int              `json:"rolledBackTo,omitempty"`
+}
@@ -0,0 +1,479 @@
+// Package operation runs long release operations in the background and
+// tracks their progress so clients can poll it or stream it.
+package operation
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/helm-version-manager/api/internal/model"
+)
+
+// DefaultRetention is how long completed operations are kept
+const DefaultRetention = time.Hour
+
+// subscriberBuffer is the number of events a subscriber may fall behind by
+// before it is unsubscribed
+const subscriberBuffer = 64
+
+const (
+	// storeTimeout bounds each read and write of the store
+	storeTimeout = 10 * time.Second
+	// finalSaveAttempts is how often saving a completed operation is tried;
+	// other replicas would otherwise report it as running forever
+	finalSaveAttempts = 3
+)
+
+// storePollInterval is how often an operation running on another replica is
+// read from the store while it is being streamed
+var storePollInterval = time.Second
+
+// ErrNotFound is returned for an operation that does not exist or was pruned
+var ErrNotFound = errors.New("operation not found")
+
+// RunFunc performs an operation, reporting the phases it enters to progress.
+// ctx is cancelled when the operation loses its lock on the release, so that
+// it stops before changing the release any further.
+type RunFunc func(ctx context.Context, progress model.ProgressFunc) (*model.Release, error)
+
+// fieldErrors is implemented by errors that carry per-field validation
+// failures, such as helm.SchemaValidationError
+type fieldErrors interface {
+	FieldErrors() []model.FieldError
+}
+
+// Locker serializes operations on a release. Acquire fails when another
+// operation holds the lock; the returned function releases it. lost, unless
+// nil, is called if the lock is taken away before it is released.
+type Locker interface {
+	Acquire(ctx context.Context, holder model.LockHolder, lost func(error)) (func(), error)
+}
+
+// Store keeps operations where every replica can read them. GetOperation
+// returns nil for an operation it does not have.
+type Store interface {
+	SaveOperation(ctx context.Context, op model.Operation) error
+	GetOperation(ctx context.Context, id string) (*model.Operation, error)
+	PruneOperations(ctx context.Context, completedBefore time.Time) error
+}
+
+// Manager runs operations and keeps them, with their events, in memory until
+// retention has passed since they completed. Operations on the same release
+// are serialized with locker unless it is nil. Unless store is nil, every
+// operation is also saved to it as it progresses, so that it can be polled
+// and streamed through any replica, not only the one running it.
+type Manager struct {
+	mu        sync.Mutex
+	ops       map[string]*entry
+	retention time.Duration
+	locker    Locker
+	store     Store
+}
+
+type entry struct {
+	op          model.Operation
+	subscribers map[chan model.OperationEvent]struct{}
+	// changed holds a signal while op has changes not yet saved to the
+	// store; it is nil without a store
+	changed chan struct{}
+}
+
+func NewManager(retention time.Duration, locker Locker, store Store) *Manager {
+	return &Manager{
+		ops:       make(map[string]*entry),
+		retention: retention,
+		locker:    locker,
+		store:     store,
+	}
+}
+
+// Start locks the release and runs run in the background, returning the new
+// operation. The lock is held until run returns; if another operation holds
+// it, the locker's error is returned and run is not called. Losing the lock
+// while run is still going is recorded as a lock-lost event and cancels the
+// context passed to run.
+func (m *Manager) Start(ctx context.Context, opType, namespace, name string, run RunFunc) (model.Operation, error) {
+	now := time.Now().UTC()
+	id := newOperationID()
+
+	e := &entry{
+		op: model.Operation{
+			ID:        id,
+			Type:      opType,
+			Namespace: namespace,
+			Name:      name,
+			Status:    model.OperationRunning,
+			CreatedAt: now,
+			UpdatedAt: now,
+			Events:    []model.OperationEvent{},
+		},
+		subscribers: make(map[chan model.OperationEvent]struct{}),
+	}
+	if m.store != nil {
+		e.changed = make(chan struct{}, 1)
+	}
+
+	// The operation outlives the request that started it, so its context
+	// is only cancelled when the lock is lost
+	runCtx, cancel := context.WithCancelCause(context.Background())
+	unlock, err := m.acquire(ctx, model.LockHolder{
+		Namespace:   namespace,
+		Name:        name,
+		OperationID: id,
+		Operation:   opType,
+		AcquiredAt:  now,
+	}, func(err error) {
+		m.emit(e, model.PhaseLockLost, fmt.Sprintf("Lost the lock on the release, another operation may be changing it: %v", err))
+		cancel(fmt.Errorf("lost the lock on release %s/%s: %w", namespace, name, err))
+	})
+	if err != nil {
+		cancel(nil)
+		return model.Operation{}, err
+	}
+
+	m.mu.Lock()
+	m.prune(now)
+	m.ops[e.op.ID] = e
+	snapshot := e.snapshot()
+	m.mu.Unlock()
+
+	if m.store != nil {
+		// Saved before the client gets the ID, so that it can look the
+		// operation up on any replica right away
+		if err := m.save(ctx, snapshot); err != nil {
+			log.Printf("Failed to save operation %s: %v", id, err)
+		}
+		go m.persist(e)
+		go m.pruneStore(now)
+	}
+
+	go m.run(runCtx, e, run, func() {
+		unlock()
+		cancel(nil)
+	})
+	return snapshot, nil
+}
+
+// Lock locks the release for an operation of opType that the caller runs
+// itself, such as an install or uninstall, and returns the function
+// releasing the lock. Like Start, it fails with the locker's error if another
+// operation holds the lock. The operation is not tracked, so losing the lock
+// is only logged by the locker.
+func (m *Manager) Lock(ctx context.Context, opType, namespace, name string) (func(), error) {
+	return m.acquire(ctx, model.LockHolder{
+		Namespace:   namespace,
+		Name:        name,
+		OperationID: newOperationID(),
+		Operation:   opType,
+		AcquiredAt:  time.Now().UTC(),
+	}, nil)
+}
+
+func (m *Manager) acquire(ctx context.Context, holder model.LockHolder, lost func(error)) (func(), error) {
+	if m.locker == nil {
+		return func() {}, nil
+	}
+	return m.locker.Acquire(ctx, holder, lost)
+}
+
+func (m *Manager) run(ctx context.Context, e *entry, run RunFunc, unlock func()) {
+	var (
+		result *model.Release
+		err    error
+	)
+	func() {
+		// A panic must not take the server down with it, now that the
+		// request's recover middleware no longer covers the operation
+		defer func() {
+			if r := recover(); r != nil {
+				log.Printf("Operation %s panicked: %v", e.op.ID, r)
+				err = fmt.Errorf("operation panicked: %v", r)
+			}
+		}()
+		result, err = run(ctx, func(phase, message string) {
+			m.emit(e, phase, message)
+		})
+	}()
+	// Unlock first so a client that sees the operation complete can start
+	// the next one right away
+	unlock()
+	m.finish(e, result, err)
+}
+
+// emit records an event and passes it on to subscribers. Events reported
+// after the operation completed are ignored.
+func (m *Manager) emit(e *entry, phase, message string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if e.op.CompletedAt != nil {
+		return
+	}
+	e.appendEvent(phase, message)
+}
+
+func (m *Manager) finish(e *entry, result *model.Release, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err != nil {
+		e.op.Status = model.OperationFailed
+		e.op.Error = err.Error()
+		var fe fieldErrors
+		if errors.As(err, &fe) {
+			e.op.Errors = fe.FieldErrors()
+		}
+		e.appendEvent(model.PhaseFailed, err.Error())
+	} else {
+		e.op.Status = model.OperationSucceeded
+		e.op.Result = result
+		e.appendEvent(model.PhaseDone, "Operation completed")
+	}
+	completedAt := e.op.UpdatedAt
+	e.op.CompletedAt = &completedAt
+
+	for ch := range e.subscribers {
+		close(ch)
+	}
+	e.subscribers = nil
+}
+
+func (e *entry) appendEvent(phase, message string) {
+	event := model.OperationEvent{
+		Sequence: len(e.op.Events) + 1,
+		Time:     time.Now().UTC(),
+		Phase:    phase,
+		Message:  message,
+	}
+	e.op.Events = append(e.op.Events, event)
+	e.op.Phase = phase
+	e.op.UpdatedAt = event.Time
+	e.markChanged()
+
+	for ch := range e.subscribers {
+		select {
+		case ch <- event:
+		default:
+			// Closing the channel of a subscriber that fell behind tells
+			// it to catch up from the operation's events and subscribe
+			// again, rather than silently missing this one
+			delete(e.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// markChanged has the operation saved to the store, unless a save is already
+// pending
+func (e *entry) markChanged() {
+	if e.changed == nil {
+		return
+	}
+	select {
+	case e.changed <- struct{}{}:
+	default:
+	}
+}
+
+func (e *entry) snapshot() model.Operation {
+	op := e.op
+	op.Events = append([]model.OperationEvent{}, e.op.Events...)
+	return op
+}
+
+// Get returns the operation with the given ID, from the store when another
+// replica runs it
+func (m *Manager) Get(ctx context.Context, id string) (model.Operation, error) {
+	m.mu.Lock()
+	e, ok := m.ops[id]
+	if ok {
+		defer m.mu.Unlock()
+		return e.snapshot(), nil
+	}
+	m.mu.Unlock()
+
+	return m.load(ctx, id)
+}
+
+// Subscribe returns the operation as it stands and a channel receiving the
+// events that follow. The channel is closed when the operation completes, at
+// once if it already has, and when the subscriber falls more than
+// subscriberBuffer events behind; the operation then has the events it
+// missed. The returned function stops the subscription. Operations another
+// replica runs are followed by reading the store every storePollInterval.
+func (m *Manager) Subscribe(ctx context.Context, id string) (model.Operation, <-chan model.OperationEvent, func(), error) {
+	m.mu.Lock()
+	if e, ok := m.ops[id]; ok {
+		defer m.mu.Unlock()
+		op, ch, unsubscribe := m.subscribe(e)
+		return op, ch, unsubscribe, nil
+	}
+	m.mu.Unlock()
+
+	op, err := m.load(ctx, id)
+	if err != nil {
+		return model.Operation{}, nil, nil, err
+	}
+
+	ch := make(chan model.OperationEvent, subscriberBuffer)
+	if op.CompletedAt != nil {
+		close(ch)
+		return op, ch, func() {}, nil
+	}
+
+	stop := make(chan struct{})
+	var once sync.Once
+	go m.follow(op, ch, stop)
+	return op, ch, func() { once.Do(func() { close(stop) }) }, nil
+}
+
+// subscribe subscribes to an operation running on this replica. m.mu must be
+// held.
+func (m *Manager) subscribe(e *entry) (model.Operation, <-chan model.OperationEvent, func()) {
+	ch := make(chan model.OperationEvent, subscriberBuffer)
+	if e.subscribers == nil {
+		close(ch)
+		return e.snapshot(), ch, func() {}
+	}
+	e.subscribers[ch] = struct{}{}
+
+	unsubscribe := func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		if _, ok := e.subscribers[ch]; ok {
+			delete(e.subscribers, ch)
+			close(ch)
+		}
+	}
+	return e.snapshot(), ch, unsubscribe
+}
+
+// follow passes the events of an operation running on another replica to ch
+// as they show up in the store. Like a local subscription, ch is closed once
+// the operation completes or the subscriber falls behind, and when stop is
+// closed.
+func (m *Manager) follow(op model.Operation, ch chan<- model.OperationEvent, stop <-chan struct{}) {
+	defer close(ch)
+
+	last := 0
+	if len(op.Events) > 0 {
+		last = op.Events[len(op.Events)-1].Sequence
+	}
+
+	ticker := time.NewTicker(storePollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		stored, err := m.load(context.Background(), op.ID)
+		if errors.Is(err, ErrNotFound) {
+			return
+		}
+		if err != nil {
+			log.Printf("Failed to read operation %s: %v", op.ID, err)
+			continue
+		}
+
+		for _, event := range stored.Events {
+			if event.Sequence <= last {
+				continue
+			}
+			select {
+			case ch <- event:
+				last = event.Sequence
+			default:
+				return
+			}
+		}
+		if stored.CompletedAt != nil {
+			return
+		}
+	}
+}
+
+// load reads an operation from the store
+func (m *Manager) load(ctx context.Context, id string) (model.Operation, error) {
+	if m.store == nil {
+		return model.Operation{}, ErrNotFound
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, storeTimeout)
+	defer cancel()
+
+	op, err := m.store.GetOperation(ctx, id)
+	if err != nil {
+		return model.Operation{}, err
+	}
+	if op == nil {
+		return model.Operation{}, ErrNotFound
+	}
+	return *op, nil
+}
+
+// persist saves the operation to the store whenever it changes, until it has
+// saved it completed. Changes made while a save is under way are saved
+// together by the next one.
+func (m *Manager) persist(e *entry) {
+	for range e.changed {
+		m.mu.Lock()
+		op := e.snapshot()
+		m.mu.Unlock()
+
+		err := m.save(context.Background(), op)
+		for attempt := 1; err != nil && op.CompletedAt != nil && attempt < finalSaveAttempts; attempt++ {
+			time.Sleep(time.Duration(attempt) * time.Second)
+			err = m.save(context.Background(), op)
+		}
+		if err != nil {
+			log.Printf("Failed to save operation %s: %v", op.ID, err)
+		}
+
+		if op.CompletedAt != nil {
+			return
+		}
+	}
+}
+
+func (m *Manager) save(ctx context.Context, op model.Operation) error {
+	ctx, cancel := context.WithTimeout(ctx, storeTimeout)
+	defer cancel()
+	return m.store.SaveOperation(ctx, op)
+}
+
+// pruneStore drops operations that completed more than retention before now
+// from the store, whichever replica ran them
+func (m *Manager) pruneStore(now time.Time) {
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+
+	if err := m.store.PruneOperations(ctx, now.Add(-m.retention)); err != nil {
+		log.Printf("Failed to prune operations: %v", err)
+	}
+}
+
+// prune drops operations that completed more than retention ago
+func (m *Manager) prune(now time.Time) {
+	for id, e := range m.ops {
+		if e.op.CompletedAt != nil && now.Sub(*e.op.CompletedAt) > m.retention {
+			delete(m.ops, id)
+		}
+	}
+}
+
+func newOperationID() string {
+	b := make([]byte, 8)
+	// crypto/rand.Read never returns an error
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,484 @@
+package operation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/helm-version-manager/api/internal/model"
+)
+
+type testFieldError struct {
+	errs []model.FieldError
+}
+
+func (e *testFieldError) Error() string                   { return "values do not match the schema" }
+func (e *testFieldError) FieldErrors() []model.FieldError { return e.errs }
+
+// start starts an operation on apps/web, failing the test if it cannot be
+// started
+func start(t *testing.T, m *Manager, opType string, run RunFunc) model.Operation {
+	t.Helper()
+	op, err := m.Start(context.Background(), opType, "apps", "web", run)
+	if err != nil {
+		t.Fatalf("failed to start operation: %v", err)
+	}
+	return op
+}
+
+// wait blocks until the operation completes and returns it
+func wait(t *testing.T, m *Manager, id string) model.Operation {
+	t.Helper()
+	_, events, unsubscribe, err := m.Subscribe(context.Background(), id)
+	if err != nil {
+		t.Fatalf("failed to subscribe to operation %s: %v", id, err)
+	}
+	defer unsubscribe()
+
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case _, ok := <-events:
+			if !ok {
+				op, _ := m.Get(context.Background(), id)
+				return op
+			}
+		case <-timeout:
+			t.Fatalf("operation %s did not complete", id)
+		}
+	}
+}
+
+func phases(events []model.OperationEvent) []string {
+	var out []string
+	for _, e := range events {
+		out = append(out, e.Phase)
+	}
+	return out
+}
+
+func TestManagerRecordsEvents(t *testing.T) {
+	testCases := []struct {
+		name       string
+		run        RunFunc
+		wantStatus string
+		wantPhases []string
+		wantError  string
+		wantFields int
+	}{
+		{
+			name: "succeeded",
+			run: func(ctx context.Context, progress model.ProgressFunc) (*model.Release, error) {
+				progress.Report(model.PhasePullChart, "Pulling %s", "1.2.0")
+				progress.Report(model.PhaseApply, "Applying")
+				return &model.Release{Name: "web", Revision: 4}, nil
+			},
+			wantStatus: model.OperationSucceeded,
+			wantPhases: []string{model.PhasePullChart, model.PhaseApply, model.PhaseDone},
+		},
+		{
+			name: "failed",
+			run: func(ctx context.Context, progress model.ProgressFunc) (*model.Release, error) {
+				progress.Report(model.PhaseRender, "Rendering")
+				return nil, errors.New("failed to upgrade release: boom")
+			},
+			wantStatus: model.OperationFailed,
+			wantPhases: []string{model.PhaseRender, model.PhaseFailed},
+			wantError:  "failed to upgrade release: boom",
+		},
+		{
+			name: "field errors",
+			run: func(ctx context.Context, progress model.ProgressFunc) (*model.Release, error) {
+				return nil, fmt.Errorf("invalid values: %w", &testFieldError{errs: []model.FieldError{{Field: "replicaCount", Message: "must be an integer"}}})
+			},
+			wantStatus: model.OperationFailed,
+			wantPhases: []string{model.PhaseFailed},
+			wantError:  "invalid values: values do not match the schema",
+			wantFields: 1,
+		},
+		{
+			name: "panic",
+			run: func(ctx context.Context, progress model.ProgressFunc) (*model.Release, error) {
+				panic("unexpected")
+			},
+			wantStatus: model.OperationFailed,
+			wantPhases: []string{model.PhaseFailed},
+			wantError:  "operation panicked: unexpected",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			m := NewManager(DefaultRetention, nil, nil)
+			started := start(t, m, model.OperationUpgrade, tc.run)
+			if started.Status != model.OperationRunning {
+				t.Errorf("expected new operation to be running, got %s", started.Status)
+			}
+
+			op := wait(t, m, started.ID)
+			if op.Status != tc.wantStatus {
+				t.Errorf("expected status %s, got %s", tc.wantStatus, op.Status)
+			}
+			if got := phases(op.Events); fmt.Sprint(got) != fmt.Sprint(tc.wantPhases) {
+				t.Errorf("expected phases %v, got %v", tc.wantPhases, got)
+			}
+			for i, e := range op.Events {
+				if e.Sequence != i+1 {
+					t.Errorf("expected event %d to have sequence %d, got %d", i, i+1, e.Sequence)
+				}
+			}
+			if op.Error != tc.wantError {
+				t.Errorf("expected error %q, got %q", tc.wantError, op.Error)
+			}
+			if len(op.Errors) != tc.wantFields {
+				t.Errorf("expected %d field errors, got %v", tc.wantFields, op.Errors)
+			}
+			if op.CompletedAt == nil {
+				t.Error("expected completed operation to have completedAt")
+			}
+			if tc.wantStatus == model.OperationSucceeded && (op.Result == nil || op.Result.Revision != 4) {
+				t.Errorf("expected result revision 4, got %+v", op.Result)
+			}
+		})
+	}
+}
+
+func TestManagerIgnoresEventsAfterCompletion(t *testing.T) {
+	m := NewManager(DefaultRetention, nil, nil)
+	var saved model.ProgressFunc
+	started := start(t, m, model.OperationRollback, func(ctx context.Context, progress model.ProgressFunc) (*model.Release, error) {
+		saved = progress
+		return &model.Release{}, nil
+	})
+	wait(t, m, started.ID)
+
+	saved.Report(model.PhaseApply, "late")
+	op, _ := m.Get(context.Background(), started.ID)
+	if op.Phase != model.PhaseDone || len(op.Events) != 1 {
+		t.Errorf("expected only the done event, got %v", phases(op.Events))
+	}
+}
+
+func TestManagerSubscribe(t *testing.T) {
+	m := NewManager(DefaultRetention, nil, nil)
+	release := make(chan struct{})
+	started := start(t, m, model.OperationUpdateValues, func(ctx context.Context, progress model.ProgressFunc) (*model.Release, error) {
+		progress.Report(model.PhaseRender, "Rendering")
+		<-release
+		progress.Report(model.PhaseApply, "Applying")
+		return &model.Release{}, nil
+	})
+
+	// Wait for the first event so the subscription starts mid-operation
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		op, _ := m.Get(context.Background(), started.ID)
+		if len(op.Events) == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("operation did not report its first event")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	op, events, unsubscribe, err := m.Subscribe(context.Background(), started.ID)
+	if err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	defer unsubscribe()
+	if got := phases(op.Events); fmt.Sprint(got) != fmt.Sprint([]string{model.PhaseRender}) {
+		t.Errorf("expected snapshot with the render event, got %v", got)
+	}
+
+	close(release)
+	var received []model.OperationEvent
+	for e := range events {
+		received = append(received, e)
+	}
+	if got := phases(received); fmt.Sprint(got) != fmt.Sprint([]string{model.PhaseApply, model.PhaseDone}) {
+		t.Errorf("expected apply and done events, got %v", got)
+	}
+
+	// Subscribing to a completed operation yields a closed channel
+	_, events, _, _ = m.Subscribe(context.Background(), started.ID)
+	if _, ok := <-events; ok {
+		t.Error("expected closed channel for completed operation")
+	}
+
+	if _, _, _, err := m.Subscribe(context.Background(), "missing"); !errors.Is(err, ErrNotFound) {
+		t.Error("expected unknown operation not to be found")
+	}
+}
+
+func TestManagerUnsubscribesLaggingSubscriber(t *testing.T) {
+	m := NewManager(DefaultRetention, nil, nil)
+	release := make(chan struct{})
+	started := start(t, m, model.OperationUpgrade, func(ctx context.Context, progress model.ProgressFunc) (*model.Release, error) {
+		<-release
+		for i := 0; i <= subscriberBuffer; i++ {
+			progress.Report(model.PhaseWait, "Waiting %d", i)
+		}
+		return &model.Release{}, nil
+	})
+
+	_, events, unsubscribe, _ := m.Subscribe(context.Background(), started.ID)
+	defer unsubscribe()
+
+	// Nothing is read until the operation is done, so the subscriber falls
+	// behind on the event after its buffer is full
+	close(release)
+	op := wait(t, m, started.ID)
+
+	received := 0
+	for range events {
+		received++
+	}
+	if received != subscriberBuffer {
+		t.Errorf("expected the channel to be closed after %d events, got %d", subscriberBuffer, received)
+	}
+	if len(op.Events) != subscriberBuffer+2 {
+		t.Errorf("expected the operation to keep all %d events, got %d", subscriberBuffer+2, len(op.Events))
+	}
+}
+
+func TestManagerPrunesCompletedOperations(t *testing.T) {
+	m := NewManager(time.Millisecond, nil, nil)
+	first := start(t, m, model.OperationUpgrade, func(context.Context, model.ProgressFunc) (*model.Release, error) {
+		return &model.Release{}, nil
+	})
+	wait(t, m, first.ID)
+	time.Sleep(5 * time.Millisecond)
+
+	release := make(chan struct{})
+	defer close(release)
+	second := start(t, m, model.OperationUpgrade, func(context.Context, model.ProgressFunc) (*model.Release, error) {
+		<-release
+		return &model.Release{}, nil
+	})
+
+	if _, err := m.Get(context.Background(), first.ID); !errors.Is(err, ErrNotFound) {
+		t.Error("expected completed operation past retention to be pruned")
+	}
+	if _, err := m.Get(context.Background(), second.ID); err != nil {
+		t.Error("expected running operation to be kept")
+	}
+}
+
+// testLocker holds at most one lock per release, like the lease locker
+type testLocker struct {
+	mu    sync.Mutex
+	held  map[string]model.LockHolder
+	lost  map[string]func(error)
+	freed chan string
+}
+
+func (l *testLocker) Acquire(ctx context.Context, holder model.LockHolder, lost func(error)) (func(), error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	key := holder.Namespace + "/" + holder.Name
+	if current, ok := l.held[key]; ok {
+		return nil, fmt.Errorf("locked by %s", current.OperationID)
+	}
+	l.held[key] = holder
+	if l.lost != nil {
+		l.lost[key] = lost
+	}
+	return func() {
+		l.mu.Lock()
+		delete(l.held, key)
+		l.mu.Unlock()
+		l.freed <- holder.OperationID
+	}, nil
+}
+
+func TestManagerLocksRelease(t *testing.T) {
+	locker := &testLocker{held: make(map[string]model.LockHolder), freed: make(chan string, 1)}
+	m := NewManager(DefaultRetention, locker, nil)
+	ctx := context.Background()
+
+	proceed := make(chan struct{})
+	first, err := m.Start(ctx, model.OperationUpgrade, "apps", "web", func(context.Context, model.ProgressFunc) (*model.Release, error) {
+		<-proceed
+		return &model.Release{}, nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if holder := locker.held["apps/web"]; holder.OperationID != first.ID || holder.Operation != model.OperationUpgrade {
+		t.Errorf("expected lock held by the upgrade operation %s, got %+v", first.ID, holder)
+	}
+
+	_, err = m.Start(ctx, model.OperationUpdateValues, "apps", "web", func(context.Context, model.ProgressFunc) (*model.Release, error) {
+		t.Error("operation on a locked release must not run")
+		return nil, nil
+	})
+	if err == nil || err.Error() != "locked by "+first.ID {
+		t.Errorf("expected lock error, got %v", err)
+	}
+
+	close(proceed)
+	if freed := <-locker.freed; freed != first.ID {
+		t.Errorf("expected lock of %s to be released, got %s", first.ID, freed)
+	}
+	wait(t, m, first.ID)
+
+	second := start(t, m, model.OperationUpdateValues, func(context.Context, model.ProgressFunc) (*model.Release, error) {
+		return &model.Release{}, nil
+	})
+	if op := wait(t, m, second.ID); op.Status != model.OperationSucceeded {
+		t.Errorf("expected operation after the lock was released to succeed, got %s", op.Status)
+	}
+}
+
+func TestManagerLock(t *testing.T) {
+	locker := &testLocker{held: make(map[string]model.LockHolder), freed: make(chan string, 1)}
+	m := NewManager(DefaultRetention, locker, nil)
+	ctx := context.Background()
+
+	unlock, err := m.Lock(ctx, model.OperationUninstall, "apps", "web")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if holder := locker.held["apps/web"]; holder.Operation != model.OperationUninstall || holder.OperationID == "" {
+		t.Errorf("expected lock held by an uninstall, got %+v", holder)
+	}
+
+	if _, err := m.Start(ctx, model.OperationUpgrade, "apps", "web", func(context.Context, model.ProgressFunc) (*model.Release, error) {
+		t.Error("operation on a locked release must not run")
+		return nil, nil
+	}); err == nil {
+		t.Error("expected upgrade to fail while the release is locked")
+	}
+
+	unlock()
+	<-locker.freed
+	if _, err := m.Lock(ctx, model.OperationTest, "apps", "web"); err != nil {
+		t.Errorf("expected lock to be free after unlocking, got %v", err)
+	}
+}
+
+func TestManagerRecordsLostLock(t *testing.T) {
+	locker := &testLocker{held: make(map[string]model.LockHolder), lost: make(map[string]func(error)), freed: make(chan string, 1)}
+	m := NewManager(DefaultRetention, locker, nil)
+
+	// The operation stops when its context is cancelled
+	op := start(t, m, model.OperationUpgrade, func(ctx context.Context, progress model.ProgressFunc) (*model.Release, error) {
+		<-ctx.Done()
+		return nil, context.Cause(ctx)
+	})
+
+	locker.lost["apps/web"](errors.New("taken over"))
+	<-locker.freed
+
+	got := wait(t, m, op.ID)
+	if want := []string{model.PhaseLockLost, model.PhaseFailed}; fmt.Sprint(phases(got.Events)) != fmt.Sprint(want) {
+		t.Errorf("expected phases %v, got %v", want, phases(got.Events))
+	}
+	if want := "lost the lock on release apps/web: taken over"; got.Error != want {
+		t.Errorf("expected error %q, got %q", want, got.Error)
+	}
+}
+
+// memoryStore is a Store shared by the managers of a test, standing in for
+// the replicas of a deployment
+type memoryStore struct {
+	mu  sync.Mutex
+	ops map[string]model.Operation
+}
+
+func (s *memoryStore) SaveOperation(ctx context.Context, op model.Operation) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ops[op.ID] = op
+	return nil
+}
+
+func (s *memoryStore) GetOperation(ctx context.Context, id string) (*model.Operation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	op, ok := s.ops[id]
+	if !ok {
+		return nil, nil
+	}
+	op.Events = append([]model.OperationEvent{}, op.Events...)
+	return &op, nil
+}
+
+func (s *memoryStore) PruneOperations(ctx context.Context, completedBefore time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, op := range s.ops {
+		if op.CompletedAt != nil && op.CompletedAt.Before(completedBefore) {
+			delete(s.ops, id)
+		}
+	}
+	return nil
+}
+
+func TestManagerServesOperationsOfOtherReplicas(t *testing.T) {
+	defer func(interval time.Duration) { storePollInterval = interval }(storePollInterval)
+	storePollInterval = 10 * time.Millisecond
+
+	store := &memoryStore{ops: make(map[string]model.Operation)}
+	running := NewManager(DefaultRetention, nil, store)
+	other := NewManager(DefaultRetention, nil, store)
+	ctx := context.Background()
+
+	release := make(chan struct{})
+	started := start(t, running, model.OperationUpgrade, func(ctx context.Context, progress model.ProgressFunc) (*model.Release, error) {
+		progress.Report(model.PhaseRender, "Rendering")
+		<-release
+		progress.Report(model.PhaseApply, "Applying")
+		return &model.Release{Name: "web", Revision: 4}, nil
+	})
+
+	// The operation is stored before Start returns
+	op, err := other.Get(ctx, started.ID)
+	if err != nil {
+		t.Fatalf("expected the operation to be found on the other replica: %v", err)
+	}
+	if op.Status != model.OperationRunning {
+		t.Errorf("expected running operation, got %s", op.Status)
+	}
+
+	op, events, unsubscribe, err := other.Subscribe(ctx, started.ID)
+	if err != nil {
+		t.Fatalf("failed to subscribe on the other replica: %v", err)
+	}
+	defer unsubscribe()
+
+	close(release)
+	received := op.Events
+	timeout := time.After(5 * time.Second)
+	for done := false; !done; {
+		select {
+		case e, ok := <-events:
+			if !ok {
+				done = true
+				break
+			}
+			received = append(received, e)
+		case <-timeout:
+			t.Fatal("the other replica did not see the operation complete")
+		}
+	}
+
+	want := []string{model.PhaseRender, model.PhaseApply, model.PhaseDone}
+	if got := phases(received); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("expected phases %v, got %v", want, got)
+	}
+
+	op, err = other.Get(ctx, started.ID)
+	if err != nil || op.Status != model.OperationSucceeded || op.Result == nil || op.Result.Revision != 4 {
+		t.Errorf("expected the succeeded operation, got %+v, %v", op, err)
+	}
+
+	if _, err := other.Get(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected unknown operation not to be found, got %v", err)
+	}
+}
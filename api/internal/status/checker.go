@@ -0,0 +1,244 @@
+// Package status reports the live status of the Kubernetes resources a Helm
+// release deployed.
+package status
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/helm-version-manager/api/internal/model"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
+)
+
+var (
+	podsResource        = schema.GroupVersionResource{Version: "v1", Resource: "pods"}
+	replicaSetsResource = schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "replicasets"}
+)
+
+// Checker fetches resources through a dynamic client and computes their
+// status with kstatus-style rules
+type Checker struct {
+	client dynamic.Interface
+	mapper meta.RESTMapper
+}
+
+func NewChecker(cfg *rest.Config) (*Checker, error) {
+	client, err := dynamic.NewForConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create dynamic client: %w", err)
+	}
+
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create discovery client: %w", err)
+	}
+	mapper := restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(discoveryClient))
+
+	return NewCheckerForClient(client, mapper), nil
+}
+
+// NewCheckerForClient creates a Checker from an existing client and mapper
+func NewCheckerForClient(client dynamic.Interface, mapper meta.RESTMapper) *Checker {
+	return &Checker{
+		client: client,
+		mapper: mapper,
+	}
+}
+
+// Check returns the status of each object in order. Namespaced objects
+// without a namespace are looked up in namespace. Failing to fetch an
+// object does not fail the check; the object is reported as Unknown.
+func (c *Checker) Check(ctx context.Context, namespace string, objects []model.ManifestObject) []model.ResourceStatus {
+	statuses := make([]model.ResourceStatus, 0, len(objects))
+	for _, obj := range objects {
+		statuses = append(statuses, c.checkObject(ctx, namespace, obj))
+	}
+	return statuses
+}
+
+func (c *Checker) checkObject(ctx context.Context, namespace string, obj model.ManifestObject) model.ResourceStatus {
+	result := model.ResourceStatus{
+		APIVersion: obj.APIVersion,
+		Kind:       obj.Kind,
+		Namespace:  obj.Namespace,
+		Name:       obj.Name,
+	}
+
+	gvk := schema.FromAPIVersionAndKind(obj.APIVersion, obj.Kind)
+	mapping, err := c.restMapping(gvk)
+	if err != nil {
+		result.Status = model.ResourceStatusUnknown
+		result.Message = fmt.Sprintf("Unknown resource type: %v", err)
+		return result
+	}
+
+	var resource dynamic.ResourceInterface = c.client.Resource(mapping.Resource)
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		if result.Namespace == "" {
+			result.Namespace = namespace
+		}
+		resource = c.client.Resource(mapping.Resource).Namespace(result.Namespace)
+	} else {
+		result.Namespace = ""
+	}
+
+	live, err := resource.Get(ctx, obj.Name, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			result.Status = model.ResourceStatusNotFound
+			result.Message = "Resource does not exist"
+			return result
+		}
+		result.Status = model.ResourceStatusUnknown
+		result.Message = fmt.Sprintf("Failed to get resource: %v", err)
+		return result
+	}
+
+	result.Status, result.Message = compute(live)
+	if result.Status == model.ResourceStatusInProgress || result.Status == model.ResourceStatusCurrent {
+		if message, failed := c.failedPod(ctx, live); failed {
+			result.Status, result.Message = model.ResourceStatusFailed, message
+		}
+	}
+	return result
+}
+
+// failedPod looks up the pods of a Deployment, StatefulSet or DaemonSet by
+// its selector and reports the first with a container that is waiting for
+// one of failedWaitingReasons, which the workload's own status does not
+// tell. Only pods of the revision being rolled out are considered where the
+// controller records it, so that crashing pods of the revision being
+// replaced do not fail the one replacing them. Pods that cannot be listed
+// are not reported.
+func (c *Checker) failedPod(ctx context.Context, workload *unstructured.Unstructured) (string, bool) {
+	kind := workload.GroupVersionKind().GroupKind()
+	if kind != deploymentKind && kind != statefulSetKind && kind != daemonSetKind {
+		return "", false
+	}
+
+	selector, err := podSelector(workload)
+	if err != nil {
+		return "", false
+	}
+
+	var revisionLabel, revision string
+	switch kind {
+	case deploymentKind:
+		hash, ok := c.currentPodTemplateHash(ctx, workload, selector)
+		if !ok {
+			return "", false
+		}
+		revisionLabel, revision = "pod-template-hash", hash
+	case statefulSetKind:
+		revisionLabel = "controller-revision-hash"
+		revision, _, _ = unstructured.NestedString(workload.Object, "status", "updateRevision")
+	}
+	if revision != "" {
+		req, err := labels.NewRequirement(revisionLabel, selection.Equals, []string{revision})
+		if err != nil {
+			return "", false
+		}
+		selector = selector.Add(*req)
+	}
+
+	pods, err := c.client.Resource(podsResource).Namespace(workload.GetNamespace()).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return "", false
+	}
+	for i := range pods.Items {
+		if name, reason, ok := failedContainer(&pods.Items[i]); ok {
+			return fmt.Sprintf("Pod %s: container %s: %s", pods.Items[i].GetName(), name, reason), true
+		}
+	}
+	return "", false
+}
+
+// currentPodTemplateHash returns the pod-template-hash of the ReplicaSet of
+// a Deployment's current revision
+func (c *Checker) currentPodTemplateHash(ctx context.Context, deployment *unstructured.Unstructured, selector labels.Selector) (string, bool) {
+	revision := deployment.GetAnnotations()[deploymentRevisionAnnotation]
+	if revision == "" {
+		return "", false
+	}
+
+	replicaSets, err := c.client.Resource(replicaSetsResource).Namespace(deployment.GetNamespace()).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return "", false
+	}
+	for _, rs := range replicaSets.Items {
+		if rs.GetAnnotations()[deploymentRevisionAnnotation] != revision {
+			continue
+		}
+		hash, ok := rs.GetLabels()["pod-template-hash"]
+		return hash, ok
+	}
+	return "", false
+}
+
+// deploymentRevisionAnnotation is set by the Deployment controller on a
+// Deployment and its ReplicaSets to the revision they belong to
+const deploymentRevisionAnnotation = "deployment.kubernetes.io/revision"
+
+func podSelector(workload *unstructured.Unstructured) (labels.Selector, error) {
+	raw, found, err := unstructured.NestedMap(workload.Object, "spec", "selector")
+	if err != nil || !found {
+		return nil, fmt.Errorf("workload has no selector")
+	}
+
+	var selector metav1.LabelSelector
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(raw, &selector); err != nil {
+		return nil, fmt.Errorf("invalid selector: %w", err)
+	}
+	result, err := metav1.LabelSelectorAsSelector(&selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid selector: %w", err)
+	}
+	if result.Empty() {
+		return nil, fmt.Errorf("workload selects every pod")
+	}
+	return result, nil
+}
+
+// restMapping resolves gvk, refreshing a discovery based mapper once when
+// the kind is not known yet, e.g. for CRDs installed by the release itself
+func (c *Checker) restMapping(gvk schema.GroupVersionKind) (*meta.RESTMapping, error) {
+	mapping, err := c.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err == nil || !meta.IsNoMatchError(err) {
+		return mapping, err
+	}
+
+	resettable, ok := c.mapper.(meta.ResettableRESTMapper)
+	if !ok {
+		return nil, err
+	}
+	resettable.Reset()
+	return c.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+}
+
+// Aggregate summarizes resource statuses: Failed if any resource failed,
+// Current if all are current, and InProgress otherwise.
+func Aggregate(statuses []model.ResourceStatus) string {
+	result := model.ResourceStatusCurrent
+	for _, s := range statuses {
+		switch s.Status {
+		case model.ResourceStatusFailed:
+			return model.ResourceStatusFailed
+		case model.ResourceStatusCurrent:
+		default:
+			result = model.ResourceStatusInProgress
+		}
+	}
+	return result
+}
@@ -0,0 +1,289 @@
+package status
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/helm-version-manager/api/internal/model"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+var (
+	deploymentGVR  = schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+	statefulSetGVR = schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "statefulsets"}
+	daemonSetGVR   = schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "daemonsets"}
+	serviceGVR     = schema.GroupVersionResource{Version: "v1", Resource: "services"}
+	namespaceGVR   = schema.GroupVersionResource{Version: "v1", Resource: "namespaces"}
+	configMapGVR   = schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	deploymentGVK  = schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+	statefulSetGVK = schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "StatefulSet"}
+	daemonSetGVK   = schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "DaemonSet"}
+	replicaSetGVK  = schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "ReplicaSet"}
+	serviceGVK     = schema.GroupVersionKind{Version: "v1", Kind: "Service"}
+	namespaceGVK   = schema.GroupVersionKind{Version: "v1", Kind: "Namespace"}
+	configMapGVK   = schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}
+	podGVK         = schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	testMapperGVKs = []schema.GroupVersionKind{deploymentGVK, statefulSetGVK, daemonSetGVK, serviceGVK, configMapGVK}
+)
+
+func newTestMapper() meta.RESTMapper {
+	mapper := meta.NewDefaultRESTMapper(nil)
+	for _, gvk := range testMapperGVKs {
+		mapper.Add(gvk, meta.RESTScopeNamespace)
+	}
+	mapper.Add(namespaceGVK, meta.RESTScopeRoot)
+	return mapper
+}
+
+func newLiveObject(gvk schema.GroupVersionKind, namespace, name string, spec, status map[string]any) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: map[string]any{}}
+	obj.SetGroupVersionKind(gvk)
+	obj.SetNamespace(namespace)
+	obj.SetName(name)
+	obj.SetGeneration(1)
+	if spec != nil {
+		obj.Object["spec"] = spec
+	}
+	if status != nil {
+		obj.Object["status"] = status
+	}
+	return obj
+}
+
+func newFakeDynamicClient(objects ...runtime.Object) *dynamicfake.FakeDynamicClient {
+	return dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		deploymentGVR:       "DeploymentList",
+		statefulSetGVR:      "StatefulSetList",
+		daemonSetGVR:        "DaemonSetList",
+		replicaSetsResource: "ReplicaSetList",
+		serviceGVR:          "ServiceList",
+		namespaceGVR:        "NamespaceList",
+		configMapGVR:        "ConfigMapList",
+		podsResource:        "PodList",
+	}, objects...)
+}
+
+func TestCheck(t *testing.T) {
+	client := newFakeDynamicClient(
+		newLiveObject(deploymentGVK, "apps", "web", map[string]any{"replicas": int64(2)}, map[string]any{
+			"observedGeneration": int64(1), "replicas": int64(2), "updatedReplicas": int64(2),
+			"readyReplicas": int64(1), "availableReplicas": int64(1),
+		}),
+		newLiveObject(serviceGVK, "apps", "web", map[string]any{"type": "ClusterIP"}, nil),
+		newLiveObject(serviceGVK, "other", "api", map[string]any{"type": "ClusterIP"}, nil),
+		newLiveObject(namespaceGVK, "", "apps", nil, nil),
+	)
+	checker := NewCheckerForClient(client, newTestMapper())
+
+	statuses := checker.Check(context.Background(), "apps", []model.ManifestObject{
+		{APIVersion: "apps/v1", Kind: "Deployment", Name: "web"},
+		{APIVersion: "v1", Kind: "Service", Name: "web"},
+		{APIVersion: "v1", Kind: "Service", Namespace: "other", Name: "api"},
+		{APIVersion: "v1", Kind: "Namespace", Namespace: "ignored", Name: "apps"},
+		{APIVersion: "v1", Kind: "ConfigMap", Name: "settings"},
+		{APIVersion: "example.com/v1", Kind: "Widget", Name: "w"},
+	})
+
+	want := []model.ResourceStatus{
+		{APIVersion: "apps/v1", Kind: "Deployment", Namespace: "apps", Name: "web", Status: model.ResourceStatusInProgress},
+		{APIVersion: "v1", Kind: "Service", Namespace: "apps", Name: "web", Status: model.ResourceStatusCurrent},
+		{APIVersion: "v1", Kind: "Service", Namespace: "other", Name: "api", Status: model.ResourceStatusCurrent},
+		{APIVersion: "v1", Kind: "Namespace", Name: "apps", Status: model.ResourceStatusCurrent},
+		{APIVersion: "v1", Kind: "ConfigMap", Namespace: "apps", Name: "settings", Status: model.ResourceStatusNotFound},
+		{APIVersion: "example.com/v1", Kind: "Widget", Name: "w", Status: model.ResourceStatusUnknown},
+	}
+
+	if len(statuses) != len(want) {
+		t.Fatalf("expected %d statuses, got %d: %+v", len(want), len(statuses), statuses)
+	}
+	for i, w := range want {
+		got := statuses[i]
+		got.Message = ""
+		if got != w {
+			t.Errorf("resource %d: expected %+v, got %+v (%s)", i, w, got, statuses[i].Message)
+		}
+	}
+
+	if statuses[0].Message != "Available: 1/2" {
+		t.Errorf("expected deployment message %q, got %q", "Available: 1/2", statuses[0].Message)
+	}
+	if got := Aggregate(statuses); got != model.ResourceStatusInProgress {
+		t.Errorf("expected aggregate InProgress, got %s", got)
+	}
+}
+
+func TestCheckGetError(t *testing.T) {
+	client := newFakeDynamicClient()
+	client.PrependReactor("get", "services", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.New("connection refused")
+	})
+	checker := NewCheckerForClient(client, newTestMapper())
+
+	statuses := checker.Check(context.Background(), "apps", []model.ManifestObject{
+		{APIVersion: "v1", Kind: "Service", Name: "web"},
+	})
+
+	if len(statuses) != 1 {
+		t.Fatalf("expected 1 status, got %d", len(statuses))
+	}
+	if statuses[0].Status != model.ResourceStatusUnknown {
+		t.Errorf("expected Unknown, got %s", statuses[0].Status)
+	}
+	if !strings.Contains(statuses[0].Message, "connection refused") {
+		t.Errorf("expected message to carry the error, got %q", statuses[0].Message)
+	}
+}
+
+// resettableMapper learns its kinds only after Reset, like a discovery
+// mapper that has not seen a freshly installed CRD yet
+type resettableMapper struct {
+	meta.RESTMapper
+	resets int
+}
+
+func (m *resettableMapper) Reset() {
+	m.resets++
+	m.RESTMapper = newTestMapper()
+}
+
+func TestCheckResetsMapper(t *testing.T) {
+	client := newFakeDynamicClient(newLiveObject(serviceGVK, "apps", "web", nil, nil))
+	mapper := &resettableMapper{RESTMapper: meta.NewDefaultRESTMapper(nil)}
+	checker := NewCheckerForClient(client, mapper)
+
+	statuses := checker.Check(context.Background(), "apps", []model.ManifestObject{
+		{APIVersion: "v1", Kind: "Service", Name: "web"},
+	})
+
+	if mapper.resets != 1 {
+		t.Errorf("expected the mapper to be reset once, got %d", mapper.resets)
+	}
+	if statuses[0].Status != model.ResourceStatusCurrent {
+		t.Errorf("expected Current, got %s (%s)", statuses[0].Status, statuses[0].Message)
+	}
+}
+
+// newWorkloadPod returns a pod labeled app=web and with the given revision
+// label, whose container app waits for reason
+func newWorkloadPod(name string, revisionLabels map[string]string, reason string) *unstructured.Unstructured {
+	pod := newLiveObject(podGVK, "apps", name, nil, map[string]any{
+		"phase": "Running",
+		"containerStatuses": []any{map[string]any{
+			"name":  "app",
+			"state": map[string]any{"waiting": map[string]any{"reason": reason}},
+		}},
+	})
+	podLabels := map[string]string{"app": "web"}
+	for k, v := range revisionLabels {
+		podLabels[k] = v
+	}
+	pod.SetLabels(podLabels)
+	return pod
+}
+
+func TestCheckFailedPods(t *testing.T) {
+	selector := map[string]any{"matchLabels": map[string]any{"app": "web"}}
+
+	deployment := newLiveObject(deploymentGVK, "apps", "web", map[string]any{"replicas": int64(1), "selector": selector}, map[string]any{
+		"observedGeneration": int64(1), "replicas": int64(2), "updatedReplicas": int64(1),
+		"readyReplicas": int64(1), "availableReplicas": int64(1),
+	})
+	deployment.SetAnnotations(map[string]string{deploymentRevisionAnnotation: "2"})
+	replicaSet := func(name, revision, hash string) *unstructured.Unstructured {
+		rs := newLiveObject(replicaSetGVK, "apps", name, nil, nil)
+		rs.SetLabels(map[string]string{"app": "web", "pod-template-hash": hash})
+		rs.SetAnnotations(map[string]string{deploymentRevisionAnnotation: revision})
+		return rs
+	}
+
+	statefulSet := newLiveObject(statefulSetGVK, "apps", "web", map[string]any{"replicas": int64(1), "selector": selector}, map[string]any{
+		"observedGeneration": int64(1), "readyReplicas": int64(0), "updatedReplicas": int64(1),
+		"currentRevision": "web-1", "updateRevision": "web-2",
+	})
+	daemonSet := newLiveObject(daemonSetGVK, "apps", "web", map[string]any{"selector": selector}, map[string]any{
+		"observedGeneration": int64(1), "desiredNumberScheduled": int64(1), "currentNumberScheduled": int64(1),
+		"updatedNumberScheduled": int64(1), "numberAvailable": int64(0), "numberReady": int64(0),
+	})
+
+	testCases := []struct {
+		name        string
+		objects     []runtime.Object
+		kind        string
+		wantStatus  string
+		wantMessage string
+	}{
+		{
+			name: "deployment with crashing pod",
+			objects: []runtime.Object{
+				deployment, replicaSet("web-old", "1", "old"), replicaSet("web-new", "2", "new"),
+				newWorkloadPod("web-new-x", map[string]string{"pod-template-hash": "new"}, "CrashLoopBackOff"),
+			},
+			kind:        "Deployment",
+			wantStatus:  model.ResourceStatusFailed,
+			wantMessage: "Pod web-new-x: container app: CrashLoopBackOff",
+		},
+		{
+			name: "deployment replacing a crashing revision",
+			objects: []runtime.Object{
+				deployment, replicaSet("web-old", "1", "old"), replicaSet("web-new", "2", "new"),
+				newWorkloadPod("web-old-x", map[string]string{"pod-template-hash": "old"}, "CrashLoopBackOff"),
+				newWorkloadPod("web-new-x", map[string]string{"pod-template-hash": "new"}, "ContainerCreating"),
+			},
+			kind:        "Deployment",
+			wantStatus:  model.ResourceStatusInProgress,
+			wantMessage: "Pending termination: 1",
+		},
+		{
+			name: "statefulset with image pull failure",
+			objects: []runtime.Object{
+				statefulSet,
+				newWorkloadPod("web-0", map[string]string{"controller-revision-hash": "web-2"}, "ImagePullBackOff"),
+			},
+			kind:        "StatefulSet",
+			wantStatus:  model.ResourceStatusFailed,
+			wantMessage: "Pod web-0: container app: ImagePullBackOff",
+		},
+		{
+			name: "daemonset with crashing pod",
+			objects: []runtime.Object{
+				daemonSet,
+				newWorkloadPod("web-abcde", nil, "CrashLoopBackOff"),
+			},
+			kind:        "DaemonSet",
+			wantStatus:  model.ResourceStatusFailed,
+			wantMessage: "Pod web-abcde: container app: CrashLoopBackOff",
+		},
+		{
+			name:        "daemonset with starting pod",
+			objects:     []runtime.Object{daemonSet, newWorkloadPod("web-abcde", nil, "ContainerCreating")},
+			kind:        "DaemonSet",
+			wantStatus:  model.ResourceStatusInProgress,
+			wantMessage: "Available: 0/1",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			objects := make([]runtime.Object, 0, len(tc.objects))
+			for _, obj := range tc.objects {
+				objects = append(objects, obj.DeepCopyObject())
+			}
+			checker := NewCheckerForClient(newFakeDynamicClient(objects...), newTestMapper())
+
+			statuses := checker.Check(context.Background(), "apps", []model.ManifestObject{
+				{APIVersion: "apps/v1", Kind: tc.kind, Name: "web"},
+			})
+
+			if statuses[0].Status != tc.wantStatus || statuses[0].Message != tc.wantMessage {
+				t.Errorf("expected %s (%s), got %s (%s)", tc.wantStatus, tc.wantMessage, statuses[0].Status, statuses[0].Message)
+			}
+		})
+	}
+}
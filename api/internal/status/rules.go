@@ -0,0 +1,289 @@
+package status
+
+import (
+	"fmt"
+
+	"github.com/helm-version-manager/api/internal/model"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var (
+	deploymentKind  = schema.GroupKind{Group: "apps", Kind: "Deployment"}
+	statefulSetKind = schema.GroupKind{Group: "apps", Kind: "StatefulSet"}
+	daemonSetKind   = schema.GroupKind{Group: "apps", Kind: "DaemonSet"}
+	jobKind         = schema.GroupKind{Group: "batch", Kind: "Job"}
+	pvcKind         = schema.GroupKind{Kind: "PersistentVolumeClaim"}
+	serviceKind     = schema.GroupKind{Kind: "Service"}
+	podKind         = schema.GroupKind{Kind: "Pod"}
+)
+
+// failedWaitingReasons are container waiting reasons that will not resolve
+// without a change to the workload
+var failedWaitingReasons = map[string]bool{
+	"CrashLoopBackOff":           true,
+	"ImagePullBackOff":           true,
+	"ErrImagePull":               true,
+	"InvalidImageName":           true,
+	"CreateContainerConfigError": true,
+	"CreateContainerError":       true,
+}
+
+// compute derives the status of a live object and a human readable message
+// explaining it. Resources that are being deleted or whose latest spec has
+// not been observed by their controller yet are never Current.
+func compute(obj *unstructured.Unstructured) (string, string) {
+	if obj.GetDeletionTimestamp() != nil {
+		return model.ResourceStatusTerminating, "Resource is being deleted"
+	}
+
+	if observed, found, _ := unstructured.NestedInt64(obj.Object, "status", "observedGeneration"); found && observed < obj.GetGeneration() {
+		return model.ResourceStatusInProgress, fmt.Sprintf("Generation %d is not observed yet, latest observed is %d", obj.GetGeneration(), observed)
+	}
+
+	switch obj.GroupVersionKind().GroupKind() {
+	case deploymentKind:
+		return deploymentStatus(obj)
+	case statefulSetKind:
+		return statefulSetStatus(obj)
+	case daemonSetKind:
+		return daemonSetStatus(obj)
+	case jobKind:
+		return jobStatus(obj)
+	case pvcKind:
+		return pvcStatus(obj)
+	case serviceKind:
+		return serviceStatus(obj)
+	case podKind:
+		return podStatus(obj)
+	default:
+		return genericStatus(obj)
+	}
+}
+
+func deploymentStatus(obj *unstructured.Unstructured) (string, string) {
+	if cond, ok := getCondition(obj, "Progressing"); ok && cond.reason == "ProgressDeadlineExceeded" {
+		return model.ResourceStatusFailed, fmt.Sprintf("Progress deadline exceeded: %s", cond.message)
+	}
+
+	specReplicas := specReplicas(obj)
+	statusReplicas := statusInt(obj, "replicas")
+	updated := statusInt(obj, "updatedReplicas")
+	ready := statusInt(obj, "readyReplicas")
+	available := statusInt(obj, "availableReplicas")
+
+	switch {
+	case updated < specReplicas:
+		return model.ResourceStatusInProgress, fmt.Sprintf("Updated: %d/%d", updated, specReplicas)
+	case statusReplicas > updated:
+		return model.ResourceStatusInProgress, fmt.Sprintf("Pending termination: %d", statusReplicas-updated)
+	case available < specReplicas:
+		return model.ResourceStatusInProgress, fmt.Sprintf("Available: %d/%d", available, specReplicas)
+	case ready < specReplicas:
+		return model.ResourceStatusInProgress, fmt.Sprintf("Ready: %d/%d", ready, specReplicas)
+	}
+
+	if cond, ok := getCondition(obj, "Available"); ok && cond.status == "False" {
+		return model.ResourceStatusInProgress, fmt.Sprintf("Deployment is not available: %s", cond.message)
+	}
+
+	return model.ResourceStatusCurrent, fmt.Sprintf("Deployment is available. Replicas: %d", specReplicas)
+}
+
+func statefulSetStatus(obj *unstructured.Unstructured) (string, string) {
+	specReplicas := specReplicas(obj)
+	ready := statusInt(obj, "readyReplicas")
+	updated := statusInt(obj, "updatedReplicas")
+
+	if ready < specReplicas {
+		return model.ResourceStatusInProgress, fmt.Sprintf("Ready: %d/%d", ready, specReplicas)
+	}
+
+	strategy, _, _ := unstructured.NestedString(obj.Object, "spec", "updateStrategy", "type")
+	if strategy == "OnDelete" {
+		return model.ResourceStatusCurrent, fmt.Sprintf("StatefulSet uses OnDelete updates. Replicas: %d", specReplicas)
+	}
+
+	partition, _, _ := unstructured.NestedInt64(obj.Object, "spec", "updateStrategy", "rollingUpdate", "partition")
+	if partition > 0 {
+		want := max(specReplicas-partition, 0)
+		if updated < want {
+			return model.ResourceStatusInProgress, fmt.Sprintf("Partitioned rollout, updated: %d/%d", updated, want)
+		}
+		return model.ResourceStatusCurrent, fmt.Sprintf("Partitioned rollout complete, updated: %d/%d", updated, want)
+	}
+
+	if updated < specReplicas {
+		return model.ResourceStatusInProgress, fmt.Sprintf("Updated: %d/%d", updated, specReplicas)
+	}
+
+	currentRevision, _, _ := unstructured.NestedString(obj.Object, "status", "currentRevision")
+	updateRevision, _, _ := unstructured.NestedString(obj.Object, "status", "updateRevision")
+	if currentRevision != updateRevision {
+		return model.ResourceStatusInProgress, fmt.Sprintf("Waiting for revision %s to replace %s", updateRevision, currentRevision)
+	}
+
+	return model.ResourceStatusCurrent, fmt.Sprintf("All replicas are ready. Replicas: %d", specReplicas)
+}
+
+func daemonSetStatus(obj *unstructured.Unstructured) (string, string) {
+	desired, found, _ := unstructured.NestedInt64(obj.Object, "status", "desiredNumberScheduled")
+	if !found {
+		return model.ResourceStatusInProgress, "Waiting for the DaemonSet controller"
+	}
+
+	scheduled := statusInt(obj, "currentNumberScheduled")
+	updated := statusInt(obj, "updatedNumberScheduled")
+	available := statusInt(obj, "numberAvailable")
+	ready := statusInt(obj, "numberReady")
+
+	switch {
+	case scheduled < desired:
+		return model.ResourceStatusInProgress, fmt.Sprintf("Scheduled: %d/%d", scheduled, desired)
+	case updated < desired:
+		return model.ResourceStatusInProgress, fmt.Sprintf("Updated: %d/%d", updated, desired)
+	case available < desired:
+		return model.ResourceStatusInProgress, fmt.Sprintf("Available: %d/%d", available, desired)
+	case ready < desired:
+		return model.ResourceStatusInProgress, fmt.Sprintf("Ready: %d/%d", ready, desired)
+	}
+
+	return model.ResourceStatusCurrent, fmt.Sprintf("All pods are available. Pods: %d", desired)
+}
+
+func jobStatus(obj *unstructured.Unstructured) (string, string) {
+	if cond, ok := getCondition(obj, "Failed"); ok && cond.status == "True" {
+		return model.ResourceStatusFailed, fmt.Sprintf("Job failed: %s", cond.message)
+	}
+	if cond, ok := getCondition(obj, "Complete"); ok && cond.status == "True" {
+		return model.ResourceStatusCurrent, "Job completed"
+	}
+
+	return model.ResourceStatusInProgress, fmt.Sprintf("Job in progress. Active: %d, succeeded: %d, failed: %d",
+		statusInt(obj, "active"), statusInt(obj, "succeeded"), statusInt(obj, "failed"))
+}
+
+func pvcStatus(obj *unstructured.Unstructured) (string, string) {
+	phase, _, _ := unstructured.NestedString(obj.Object, "status", "phase")
+	switch phase {
+	case "Bound":
+		return model.ResourceStatusCurrent, "PVC is bound"
+	case "Lost":
+		return model.ResourceStatusFailed, "PVC lost its volume"
+	default:
+		return model.ResourceStatusInProgress, fmt.Sprintf("PVC is %s", phaseOrPending(phase))
+	}
+}
+
+func serviceStatus(obj *unstructured.Unstructured) (string, string) {
+	serviceType, _, _ := unstructured.NestedString(obj.Object, "spec", "type")
+	if serviceType == "LoadBalancer" {
+		ingress, _, _ := unstructured.NestedSlice(obj.Object, "status", "loadBalancer", "ingress")
+		if len(ingress) == 0 {
+			return model.ResourceStatusInProgress, "Waiting for a load balancer address"
+		}
+	}
+
+	return model.ResourceStatusCurrent, "Service is ready"
+}
+
+func podStatus(obj *unstructured.Unstructured) (string, string) {
+	phase, _, _ := unstructured.NestedString(obj.Object, "status", "phase")
+	switch phase {
+	case "Succeeded":
+		return model.ResourceStatusCurrent, "Pod has completed"
+	case "Failed":
+		return model.ResourceStatusFailed, "Pod has failed"
+	}
+
+	if name, reason, ok := failedContainer(obj); ok {
+		return model.ResourceStatusFailed, fmt.Sprintf("Container %s: %s", name, reason)
+	}
+
+	if cond, ok := getCondition(obj, "Ready"); ok && cond.status == "True" {
+		return model.ResourceStatusCurrent, "Pod is ready"
+	}
+
+	return model.ResourceStatusInProgress, fmt.Sprintf("Pod is %s", phaseOrPending(phase))
+}
+
+// failedContainer returns the first container of a pod that is waiting for
+// one of failedWaitingReasons
+func failedContainer(pod *unstructured.Unstructured) (string, string, bool) {
+	for _, field := range []string{"initContainerStatuses", "containerStatuses"} {
+		containers, _, _ := unstructured.NestedSlice(pod.Object, "status", field)
+		for _, c := range containers {
+			container, ok := c.(map[string]any)
+			if !ok {
+				continue
+			}
+			reason, _, _ := unstructured.NestedString(container, "state", "waiting", "reason")
+			if failedWaitingReasons[reason] {
+				name, _, _ := unstructured.NestedString(container, "name")
+				return name, reason, true
+			}
+		}
+	}
+	return "", "", false
+}
+
+// genericStatus applies the standard Stalled, Reconciling and Ready
+// conditions. Objects without them are Current as soon as they exist.
+func genericStatus(obj *unstructured.Unstructured) (string, string) {
+	if cond, ok := getCondition(obj, "Stalled"); ok && cond.status == "True" {
+		return model.ResourceStatusFailed, cond.message
+	}
+	if cond, ok := getCondition(obj, "Reconciling"); ok && cond.status == "True" {
+		return model.ResourceStatusInProgress, cond.message
+	}
+	if cond, ok := getCondition(obj, "Ready"); ok && cond.status == "False" {
+		return model.ResourceStatusInProgress, cond.message
+	}
+
+	return model.ResourceStatusCurrent, "Resource is current"
+}
+
+type condition struct {
+	status  string
+	reason  string
+	message string
+}
+
+func getCondition(obj *unstructured.Unstructured, conditionType string) (condition, bool) {
+	conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	for _, c := range conditions {
+		cond, ok := c.(map[string]any)
+		if !ok {
+			continue
+		}
+		if t, _, _ := unstructured.NestedString(cond, "type"); t != conditionType {
+			continue
+		}
+		status, _, _ := unstructured.NestedString(cond, "status")
+		reason, _, _ := unstructured.NestedString(cond, "reason")
+		message, _, _ := unstructured.NestedString(cond, "message")
+		return condition{status: status, reason: reason, message: message}, true
+	}
+	return condition{}, false
+}
+
+// specReplicas returns spec.replicas, which defaults to 1 when unset
+func specReplicas(obj *unstructured.Unstructured) int64 {
+	replicas, found, _ := unstructured.NestedInt64(obj.Object, "spec", "replicas")
+	if !found {
+		return 1
+	}
+	return replicas
+}
+
+func statusInt(obj *unstructured.Unstructured, field string) int64 {
+	v, _, _ := unstructured.NestedInt64(obj.Object, "status", field)
+	return v
+}
+
+func phaseOrPending(phase string) string {
+	if phase == "" {
+		return "Pending"
+	}
+	return phase
+}
@@ -0,0 +1,305 @@
+package status
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/helm-version-manager/api/internal/model"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func newObject(apiVersion, kind string, spec, status map[string]any) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": apiVersion,
+		"kind":       kind,
+		"metadata":   map[string]any{"name": "test", "namespace": "default", "generation": int64(1)},
+	}}
+	if spec != nil {
+		obj.Object["spec"] = spec
+	}
+	if status != nil {
+		obj.Object["status"] = status
+	}
+	return obj
+}
+
+func conditions(conds ...map[string]any) []any {
+	result := make([]any, 0, len(conds))
+	for _, c := range conds {
+		result = append(result, c)
+	}
+	return result
+}
+
+func TestCompute(t *testing.T) {
+	testCases := []struct {
+		name        string
+		obj         *unstructured.Unstructured
+		wantStatus  string
+		wantMessage string
+	}{
+		{
+			name: "deployment available",
+			obj: newObject("apps/v1", "Deployment", map[string]any{"replicas": int64(2)}, map[string]any{
+				"observedGeneration": int64(1), "replicas": int64(2), "updatedReplicas": int64(2),
+				"readyReplicas": int64(2), "availableReplicas": int64(2),
+			}),
+			wantStatus: model.ResourceStatusCurrent,
+		},
+		{
+			name: "deployment with crash-looping pods",
+			obj: newObject("apps/v1", "Deployment", map[string]any{"replicas": int64(2)}, map[string]any{
+				"observedGeneration": int64(1), "replicas": int64(2), "updatedReplicas": int64(2),
+				"readyReplicas": int64(0), "availableReplicas": int64(0),
+			}),
+			wantStatus:  model.ResourceStatusInProgress,
+			wantMessage: "Available: 0/2",
+		},
+		{
+			name: "deployment rolling out",
+			obj: newObject("apps/v1", "Deployment", map[string]any{"replicas": int64(3)}, map[string]any{
+				"observedGeneration": int64(1), "replicas": int64(3), "updatedReplicas": int64(1),
+			}),
+			wantStatus:  model.ResourceStatusInProgress,
+			wantMessage: "Updated: 1/3",
+		},
+		{
+			name: "deployment with old replicas terminating",
+			obj: newObject("apps/v1", "Deployment", nil, map[string]any{
+				"observedGeneration": int64(1), "replicas": int64(2), "updatedReplicas": int64(1),
+				"readyReplicas": int64(1), "availableReplicas": int64(1),
+			}),
+			wantStatus:  model.ResourceStatusInProgress,
+			wantMessage: "Pending termination: 1",
+		},
+		{
+			name: "deployment past its progress deadline",
+			obj: newObject("apps/v1", "Deployment", map[string]any{"replicas": int64(1)}, map[string]any{
+				"observedGeneration": int64(1),
+				"conditions": conditions(map[string]any{
+					"type": "Progressing", "status": "False", "reason": "ProgressDeadlineExceeded",
+					"message": "ReplicaSet web-123 has timed out progressing.",
+				}),
+			}),
+			wantStatus:  model.ResourceStatusFailed,
+			wantMessage: "Progress deadline exceeded",
+		},
+		{
+			name: "deployment generation not observed",
+			obj: func() *unstructured.Unstructured {
+				obj := newObject("apps/v1", "Deployment", nil, map[string]any{"observedGeneration": int64(1)})
+				obj.SetGeneration(2)
+				return obj
+			}(),
+			wantStatus:  model.ResourceStatusInProgress,
+			wantMessage: "Generation 2",
+		},
+		{
+			name: "terminating",
+			obj: func() *unstructured.Unstructured {
+				obj := newObject("v1", "ConfigMap", nil, nil)
+				now := metav1.Now()
+				obj.SetDeletionTimestamp(&now)
+				return obj
+			}(),
+			wantStatus: model.ResourceStatusTerminating,
+		},
+		{
+			name: "statefulset ready",
+			obj: newObject("apps/v1", "StatefulSet", map[string]any{"replicas": int64(3)}, map[string]any{
+				"observedGeneration": int64(1), "readyReplicas": int64(3), "updatedReplicas": int64(3),
+				"currentRevision": "db-1", "updateRevision": "db-1",
+			}),
+			wantStatus: model.ResourceStatusCurrent,
+		},
+		{
+			name: "statefulset not ready",
+			obj: newObject("apps/v1", "StatefulSet", map[string]any{"replicas": int64(3)}, map[string]any{
+				"observedGeneration": int64(1), "readyReplicas": int64(1),
+			}),
+			wantStatus:  model.ResourceStatusInProgress,
+			wantMessage: "Ready: 1/3",
+		},
+		{
+			name: "statefulset rolling update",
+			obj: newObject("apps/v1", "StatefulSet", map[string]any{"replicas": int64(2)}, map[string]any{
+				"observedGeneration": int64(1), "readyReplicas": int64(2), "updatedReplicas": int64(2),
+				"currentRevision": "db-1", "updateRevision": "db-2",
+			}),
+			wantStatus:  model.ResourceStatusInProgress,
+			wantMessage: "Waiting for revision db-2",
+		},
+		{
+			name: "statefulset partitioned rollout complete",
+			obj: newObject("apps/v1", "StatefulSet", map[string]any{
+				"replicas":       int64(4),
+				"updateStrategy": map[string]any{"type": "RollingUpdate", "rollingUpdate": map[string]any{"partition": int64(2)}},
+			}, map[string]any{
+				"observedGeneration": int64(1), "readyReplicas": int64(4), "updatedReplicas": int64(2),
+				"currentRevision": "db-1", "updateRevision": "db-2",
+			}),
+			wantStatus: model.ResourceStatusCurrent,
+		},
+		{
+			name: "daemonset available",
+			obj: newObject("apps/v1", "DaemonSet", nil, map[string]any{
+				"observedGeneration": int64(1), "desiredNumberScheduled": int64(3), "currentNumberScheduled": int64(3),
+				"updatedNumberScheduled": int64(3), "numberAvailable": int64(3), "numberReady": int64(3),
+			}),
+			wantStatus: model.ResourceStatusCurrent,
+		},
+		{
+			name: "daemonset updating",
+			obj: newObject("apps/v1", "DaemonSet", nil, map[string]any{
+				"observedGeneration": int64(1), "desiredNumberScheduled": int64(3), "currentNumberScheduled": int64(3),
+				"updatedNumberScheduled": int64(1),
+			}),
+			wantStatus:  model.ResourceStatusInProgress,
+			wantMessage: "Updated: 1/3",
+		},
+		{
+			name:       "daemonset without status",
+			obj:        newObject("apps/v1", "DaemonSet", nil, nil),
+			wantStatus: model.ResourceStatusInProgress,
+		},
+		{
+			name: "job complete",
+			obj: newObject("batch/v1", "Job", nil, map[string]any{
+				"succeeded":  int64(1),
+				"conditions": conditions(map[string]any{"type": "Complete", "status": "True"}),
+			}),
+			wantStatus: model.ResourceStatusCurrent,
+		},
+		{
+			name: "job failed",
+			obj: newObject("batch/v1", "Job", nil, map[string]any{
+				"failed": int64(6),
+				"conditions": conditions(map[string]any{
+					"type": "Failed", "status": "True", "reason": "BackoffLimitExceeded",
+					"message": "Job has reached the specified backoff limit",
+				}),
+			}),
+			wantStatus:  model.ResourceStatusFailed,
+			wantMessage: "backoff limit",
+		},
+		{
+			name:        "job running",
+			obj:         newObject("batch/v1", "Job", nil, map[string]any{"active": int64(1)}),
+			wantStatus:  model.ResourceStatusInProgress,
+			wantMessage: "Active: 1",
+		},
+		{
+			name:       "pvc bound",
+			obj:        newObject("v1", "PersistentVolumeClaim", nil, map[string]any{"phase": "Bound"}),
+			wantStatus: model.ResourceStatusCurrent,
+		},
+		{
+			name:        "pvc pending",
+			obj:         newObject("v1", "PersistentVolumeClaim", nil, map[string]any{"phase": "Pending"}),
+			wantStatus:  model.ResourceStatusInProgress,
+			wantMessage: "PVC is Pending",
+		},
+		{
+			name:       "pvc lost",
+			obj:        newObject("v1", "PersistentVolumeClaim", nil, map[string]any{"phase": "Lost"}),
+			wantStatus: model.ResourceStatusFailed,
+		},
+		{
+			name:       "cluster ip service",
+			obj:        newObject("v1", "Service", map[string]any{"type": "ClusterIP"}, nil),
+			wantStatus: model.ResourceStatusCurrent,
+		},
+		{
+			name:        "load balancer without address",
+			obj:         newObject("v1", "Service", map[string]any{"type": "LoadBalancer"}, map[string]any{"loadBalancer": map[string]any{}}),
+			wantStatus:  model.ResourceStatusInProgress,
+			wantMessage: "load balancer",
+		},
+		{
+			name: "load balancer with address",
+			obj: newObject("v1", "Service", map[string]any{"type": "LoadBalancer"}, map[string]any{
+				"loadBalancer": map[string]any{"ingress": []any{map[string]any{"ip": "10.0.0.1"}}},
+			}),
+			wantStatus: model.ResourceStatusCurrent,
+		},
+		{
+			name: "crash-looping pod",
+			obj: newObject("v1", "Pod", nil, map[string]any{
+				"phase": "Running",
+				"containerStatuses": []any{map[string]any{
+					"name":  "app",
+					"state": map[string]any{"waiting": map[string]any{"reason": "CrashLoopBackOff"}},
+				}},
+			}),
+			wantStatus:  model.ResourceStatusFailed,
+			wantMessage: "Container app: CrashLoopBackOff",
+		},
+		{
+			name: "ready pod",
+			obj: newObject("v1", "Pod", nil, map[string]any{
+				"phase":      "Running",
+				"conditions": conditions(map[string]any{"type": "Ready", "status": "True"}),
+			}),
+			wantStatus: model.ResourceStatusCurrent,
+		},
+		{
+			name:       "configmap",
+			obj:        newObject("v1", "ConfigMap", nil, nil),
+			wantStatus: model.ResourceStatusCurrent,
+		},
+		{
+			name: "custom resource not ready",
+			obj: newObject("cert-manager.io/v1", "Certificate", nil, map[string]any{
+				"conditions": conditions(map[string]any{"type": "Ready", "status": "False", "message": "Issuing certificate"}),
+			}),
+			wantStatus:  model.ResourceStatusInProgress,
+			wantMessage: "Issuing certificate",
+		},
+		{
+			name: "custom resource stalled",
+			obj: newObject("example.com/v1", "Widget", nil, map[string]any{
+				"conditions": conditions(map[string]any{"type": "Stalled", "status": "True", "message": "invalid spec"}),
+			}),
+			wantStatus: model.ResourceStatusFailed,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			status, message := compute(tc.obj)
+			if status != tc.wantStatus {
+				t.Errorf("expected status %s, got %s (%s)", tc.wantStatus, status, message)
+			}
+			if !strings.Contains(message, tc.wantMessage) {
+				t.Errorf("expected message to contain %q, got %q", tc.wantMessage, message)
+			}
+		})
+	}
+}
+
+func TestAggregate(t *testing.T) {
+	testCases := []struct {
+		name     string
+		statuses []string
+		want     string
+	}{
+		{name: "empty", want: model.ResourceStatusCurrent},
+		{name: "all current", statuses: []string{model.ResourceStatusCurrent, model.ResourceStatusCurrent}, want: model.ResourceStatusCurrent},
+		{name: "in progress", statuses: []string{model.ResourceStatusCurrent, model.ResourceStatusInProgress}, want: model.ResourceStatusInProgress},
+		{name: "missing", statuses: []string{model.ResourceStatusNotFound, model.ResourceStatusCurrent}, want: model.ResourceStatusInProgress},
+		{name: "failed wins", statuses: []string{model.ResourceStatusInProgress, model.ResourceStatusFailed, model.ResourceStatusUnknown}, want: model.ResourceStatusFailed},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var statuses []model.ResourceStatus
+			for _, s := range tc.statuses {
+				statuses = append(statuses, model.ResourceStatus{Status: s})
+			}
+			if got := Aggregate(statuses); got != tc.want {
+				t.Errorf("expected %s, got %s", tc.want, got)
+			}
+		})
+	}
+}
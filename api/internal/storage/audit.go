@@ -0,0 +1,191 @@
+package storage
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/helm-version-manager/api/internal/model"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client/config"
+)
+
+const (
+	auditConfigMapName = "helm-version-manager-audit"
+	auditDataKey       = "records"
+	// maxAuditRecords keeps the ConfigMap well below the 1MiB object limit;
+	// older records are dropped first
+	maxAuditRecords = 200
+)
+
+// AuditStore keeps a bounded log of automated actions taken on releases in
+// a ConfigMap
+type AuditStore struct {
+	clientset kubernetes.Interface
+	namespace string
+	mu        sync.Mutex
+}
+
+func NewAuditStore() (*AuditStore, error) {
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get kubernetes config: %w", err)
+	}
+
+	clientset, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create kubernetes client: %w", err)
+	}
+
+	namespace := os.Getenv("NAMESPACE")
+	if namespace == "" {
+		namespace = defaultNamespace
+	}
+
+	return newAuditStore(clientset, namespace), nil
+}
+
+func newAuditStore(clientset kubernetes.Interface, namespace string) *AuditStore {
+	return &AuditStore{
+		clientset: clientset,
+		namespace: namespace,
+	}
+}
+
+// Record appends record to the audit log, filling in its ID and time when
+// unset. Every replica records to the same ConfigMap, so the write is
+// retried from a fresh read when another replica wrote it in between.
+func (s *AuditStore) Record(ctx context.Context, record model.AuditRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if record.ID == "" {
+		id, err := newAuditID()
+		if err != nil {
+			return err
+		}
+		record.ID = id
+	}
+	if record.Time.IsZero() {
+		record.Time = time.Now().UTC()
+	}
+
+	return retry.OnError(retry.DefaultRetry, isWriteConflict, func() error {
+		records, cm, err := s.loadRecords(ctx)
+		if err != nil {
+			return err
+		}
+
+		records = append(records, record)
+		if len(records) > maxAuditRecords {
+			records = records[len(records)-maxAuditRecords:]
+		}
+
+		return s.saveRecords(ctx, cm, records)
+	})
+}
+
+// ListRecords returns the audit records of a release, newest first. Empty
+// namespace and releaseName match every release.
+func (s *AuditStore) ListRecords(ctx context.Context, namespace, releaseName string) ([]model.AuditRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	records, _, err := s.loadRecords(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]model.AuditRecord, 0, len(records))
+	for i := len(records) - 1; i >= 0; i-- {
+		r := records[i]
+		if namespace != "" && r.Namespace != namespace {
+			continue
+		}
+		if releaseName != "" && r.Name != releaseName {
+			continue
+		}
+		result = append(result, r)
+	}
+
+	return result, nil
+}
+
+// loadRecords returns the stored records, oldest first, and the ConfigMap
+// holding them, which is nil if it does not exist yet
+func (s *AuditStore) loadRecords(ctx context.Context) ([]model.AuditRecord, *corev1.ConfigMap, error) {
+	cm, err := s.clientset.CoreV1().ConfigMaps(s.namespace).Get(ctx, auditConfigMapName, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil, nil
+		}
+		return nil, nil, fmt.Errorf("failed to get configmap: %w", err)
+	}
+
+	data, ok := cm.Data[auditDataKey]
+	if !ok || data == "" {
+		return nil, cm, nil
+	}
+
+	var records []model.AuditRecord
+	if err := json.Unmarshal([]byte(data), &records); err != nil {
+		return nil, nil, fmt.Errorf("failed to unmarshal audit records: %w", err)
+	}
+
+	return records, cm, nil
+}
+
+// saveRecords writes records to cm, which carries the resourceVersion it was
+// loaded at, or creates the ConfigMap when cm is nil
+func (s *AuditStore) saveRecords(ctx context.Context, cm *corev1.ConfigMap, records []model.AuditRecord) error {
+	data, err := json.Marshal(records)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit records: %w", err)
+	}
+
+	if cm == nil {
+		cm = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      auditConfigMapName,
+				Namespace: s.namespace,
+			},
+			Data: map[string]string{
+				auditDataKey: string(data),
+			},
+		}
+		_, err = s.clientset.CoreV1().ConfigMaps(s.namespace).Create(ctx, cm, metav1.CreateOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to create configmap: %w", err)
+		}
+		return nil
+	}
+
+	if cm.Data == nil {
+		cm.Data = make(map[string]string)
+	}
+	cm.Data[auditDataKey] = string(data)
+
+	_, err = s.clientset.CoreV1().ConfigMaps(s.namespace).Update(ctx, cm, metav1.UpdateOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to update configmap: %w", err)
+	}
+
+	return nil
+}
+
+func newAuditID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate audit record id: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,103 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/helm-version-manager/api/internal/model"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// writeAuditBehind appends a record directly in the tracker, as another
+// replica would, bypassing the reactors. It must only be called from a
+// reactor.
+func (c *versionedClientset) writeAuditBehind(t *testing.T, record model.AuditRecord) {
+	t.Helper()
+
+	var records []model.AuditRecord
+	obj, err := c.Tracker().Get(configMapsResource, testNamespace, auditConfigMapName)
+	exists := err == nil
+	if exists {
+		if err := json.Unmarshal([]byte(obj.(*corev1.ConfigMap).Data[auditDataKey]), &records); err != nil {
+			t.Fatalf("invalid audit records: %v", err)
+		}
+	}
+	records = append(records, record)
+	data, err := json.Marshal(records)
+	if err != nil {
+		t.Fatalf("failed to marshal audit records: %v", err)
+	}
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: auditConfigMapName, Namespace: testNamespace, ResourceVersion: c.nextVersion()},
+		Data:       map[string]string{auditDataKey: string(data)},
+	}
+	if exists {
+		err = c.Tracker().Update(configMapsResource, cm, testNamespace)
+	} else {
+		err = c.Tracker().Create(configMapsResource, cm, testNamespace)
+	}
+	if err != nil {
+		t.Fatalf("failed to write configmap: %v", err)
+	}
+}
+
+func auditRecord(name string) model.AuditRecord {
+	return model.AuditRecord{ID: name, Namespace: "apps", Name: name}
+}
+
+func auditIDs(t *testing.T, store *AuditStore) []string {
+	t.Helper()
+	records, err := store.ListRecords(context.Background(), "", "")
+	if err != nil {
+		t.Fatalf("failed to list audit records: %v", err)
+	}
+	ids := make([]string, 0, len(records))
+	for _, r := range records {
+		ids = append(ids, r.ID)
+	}
+	return ids
+}
+
+func TestAuditStoreRetriesOnConflict(t *testing.T) {
+	clientset := newVersionedClientset()
+	store := newAuditStore(clientset, testNamespace)
+	ctx := context.Background()
+
+	if err := store.Record(ctx, auditRecord("web")); err != nil {
+		t.Fatalf("failed to record: %v", err)
+	}
+	clientset.updates = 0
+
+	// Another replica records between this store's read and its update
+	clientset.onFirst("update", func() { clientset.writeAuditBehind(t, auditRecord("api")) })
+
+	if err := store.Record(ctx, auditRecord("worker")); err != nil {
+		t.Fatalf("failed to record: %v", err)
+	}
+	if clientset.updates != 2 {
+		t.Errorf("expected the conflicting update to be retried once, got %d updates", clientset.updates)
+	}
+	if ids := auditIDs(t, store); fmt.Sprint(ids) != "[worker api web]" {
+		t.Errorf("expected no record to be lost, got %v", ids)
+	}
+}
+
+func TestAuditStoreCreateRace(t *testing.T) {
+	clientset := newVersionedClientset()
+	store := newAuditStore(clientset, testNamespace)
+
+	// Another replica creates the configmap after this store found it
+	// missing
+	clientset.onFirst("create", func() { clientset.writeAuditBehind(t, auditRecord("api")) })
+
+	if err := store.Record(context.Background(), auditRecord("web")); err != nil {
+		t.Fatalf("failed to record: %v", err)
+	}
+	if ids := auditIDs(t, store); fmt.Sprint(ids) != "[web api]" {
+		t.Errorf("expected both records after the create race, got %v", ids)
+	}
+}
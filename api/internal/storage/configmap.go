@@ -12,17 +12,19 @@ import (
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
 	"sigs.k8s.io/controller-runtime/pkg/client/config"
 )
 
 const (
 	configMapName     = "helm-version-manager-registry-mappings"
 	configMapDataKey  = "mappings"
+	configMapRulesKey = "rules"
 	defaultNamespace  = "default"
 )
 
 type RegistryStore struct {
-	clientset *kubernetes.Clientset
+	clientset kubernetes.Interface
 	namespace string
 	mu        sync.RWMutex
 }
@@ -43,17 +45,21 @@ func NewRegistryStore() (*RegistryStore, error) {
 		namespace = defaultNamespace
 	}
 
+	return newRegistryStore(clientset, namespace), nil
+}
+
+func newRegistryStore(clientset kubernetes.Interface, namespace string) *RegistryStore {
 	return &RegistryStore{
 		clientset: clientset,
 		namespace: namespace,
-	}, nil
+	}
 }
 
 func (s *RegistryStore) GetMapping(ctx context.Context, namespace, releaseName string) (*model.RegistryMapping, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	mappings, err := s.loadMappings(ctx)
+	mappings, _, err := s.loadMappings(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -70,102 +76,203 @@ func (s *RegistryStore) SetMapping(ctx context.Context, mapping model.RegistryMa
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	mappings, err := s.loadMappings(ctx)
+	return s.updateMappings(ctx, func(mappings map[string]model.RegistryMapping) {
+		key := fmt.Sprintf("%s/%s", mapping.Namespace, mapping.ReleaseName)
+		mappings[key] = mapping
+	})
+}
+
+func (s *RegistryStore) DeleteMapping(ctx context.Context, namespace, releaseName string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.updateMappings(ctx, func(mappings map[string]model.RegistryMapping) {
+		key := fmt.Sprintf("%s/%s", namespace, releaseName)
+		delete(mappings, key)
+	})
+}
+
+func (s *RegistryStore) ListMappings(ctx context.Context) ([]model.RegistryMapping, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	mappings, _, err := s.loadMappings(ctx)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	key := fmt.Sprintf("%s/%s", mapping.Namespace, mapping.ReleaseName)
-	mappings[key] = mapping
+	result := make([]model.RegistryMapping, 0, len(mappings))
+	for _, m := range mappings {
+		result = append(result, m)
+	}
 
-	return s.saveMappings(ctx, mappings)
+	return result, nil
 }
 
-func (s *RegistryStore) DeleteMapping(ctx context.Context, namespace, releaseName string) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+func (s *RegistryStore) GetRule(ctx context.Context, id string) (*model.RegistryRule, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 
-	mappings, err := s.loadMappings(ctx)
+	rules, _, err := s.loadRules(ctx)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	key := fmt.Sprintf("%s/%s", namespace, releaseName)
-	delete(mappings, key)
+	if rule, ok := rules[id]; ok {
+		return &rule, nil
+	}
 
-	return s.saveMappings(ctx, mappings)
+	return nil, nil
 }
 
-func (s *RegistryStore) ListMappings(ctx context.Context) ([]model.RegistryMapping, error) {
+func (s *RegistryStore) SetRule(ctx context.Context, rule model.RegistryRule) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.updateRules(ctx, func(rules map[string]model.RegistryRule) {
+		rules[rule.ID] = rule
+	})
+}
+
+func (s *RegistryStore) DeleteRule(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.updateRules(ctx, func(rules map[string]model.RegistryRule) {
+		delete(rules, id)
+	})
+}
+
+func (s *RegistryStore) ListRules(ctx context.Context) ([]model.RegistryRule, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	mappings, err := s.loadMappings(ctx)
+	rules, _, err := s.loadRules(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	result := make([]model.RegistryMapping, 0, len(mappings))
-	for _, m := range mappings {
-		result = append(result, m)
+	result := make([]model.RegistryRule, 0, len(rules))
+	for _, r := range rules {
+		result = append(result, r)
 	}
 
 	return result, nil
 }
 
-func (s *RegistryStore) loadMappings(ctx context.Context) (map[string]model.RegistryMapping, error) {
+// loadMappings returns the stored mappings and the ConfigMap holding them,
+// which is nil if it does not exist yet
+func (s *RegistryStore) loadMappings(ctx context.Context) (map[string]model.RegistryMapping, *corev1.ConfigMap, error) {
+	var mappings map[string]model.RegistryMapping
+	cm, err := s.load(ctx, configMapDataKey, &mappings)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load mappings: %w", err)
+	}
+	if mappings == nil {
+		mappings = make(map[string]model.RegistryMapping)
+	}
+
+	return mappings, cm, nil
+}
+
+// loadRules returns the stored rules by ID and the ConfigMap holding them,
+// which is nil if it does not exist yet
+func (s *RegistryStore) loadRules(ctx context.Context) (map[string]model.RegistryRule, *corev1.ConfigMap, error) {
+	var rules map[string]model.RegistryRule
+	cm, err := s.load(ctx, configMapRulesKey, &rules)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load rules: %w", err)
+	}
+	if rules == nil {
+		rules = make(map[string]model.RegistryRule)
+	}
+
+	return rules, cm, nil
+}
+
+// updateMappings applies mutate to the stored mappings and writes them back.
+// s.mu only covers this process, so the write is conditional on the
+// ConfigMap's resourceVersion; when another replica wrote it in between, or
+// created it first, the mappings are reloaded and mutate applied again.
+func (s *RegistryStore) updateMappings(ctx context.Context, mutate func(map[string]model.RegistryMapping)) error {
+	return retry.OnError(retry.DefaultRetry, isWriteConflict, func() error {
+		mappings, cm, err := s.loadMappings(ctx)
+		if err != nil {
+			return err
+		}
+
+		mutate(mappings)
+		return s.save(ctx, cm, configMapDataKey, mappings)
+	})
+}
+
+// updateRules is updateMappings for the rules
+func (s *RegistryStore) updateRules(ctx context.Context, mutate func(map[string]model.RegistryRule)) error {
+	return retry.OnError(retry.DefaultRetry, isWriteConflict, func() error {
+		rules, cm, err := s.loadRules(ctx)
+		if err != nil {
+			return err
+		}
+
+		mutate(rules)
+		return s.save(ctx, cm, configMapRulesKey, rules)
+	})
+}
+
+// load unmarshals the JSON under key into v and returns the ConfigMap,
+// which is nil if it does not exist yet. v is left unchanged when the key
+// is missing.
+func (s *RegistryStore) load(ctx context.Context, key string, v any) (*corev1.ConfigMap, error) {
 	cm, err := s.clientset.CoreV1().ConfigMaps(s.namespace).Get(ctx, configMapName, metav1.GetOptions{})
 	if err != nil {
 		if errors.IsNotFound(err) {
-			return make(map[string]model.RegistryMapping), nil
+			return nil, nil
 		}
 		return nil, fmt.Errorf("failed to get configmap: %w", err)
 	}
 
-	data, ok := cm.Data[configMapDataKey]
+	data, ok := cm.Data[key]
 	if !ok || data == "" {
-		return make(map[string]model.RegistryMapping), nil
+		return cm, nil
 	}
 
-	var mappings map[string]model.RegistryMapping
-	if err := json.Unmarshal([]byte(data), &mappings); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal mappings: %w", err)
+	if err := json.Unmarshal([]byte(data), v); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal %s: %w", key, err)
 	}
 
-	return mappings, nil
+	return cm, nil
 }
 
-func (s *RegistryStore) saveMappings(ctx context.Context, mappings map[string]model.RegistryMapping) error {
-	data, err := json.Marshal(mappings)
+// save writes v as JSON under key to cm, which carries the resourceVersion
+// it was loaded at, or creates the ConfigMap when cm is nil. Other keys are
+// kept as they were loaded.
+func (s *RegistryStore) save(ctx context.Context, cm *corev1.ConfigMap, key string, v any) error {
+	data, err := json.Marshal(v)
 	if err != nil {
-		return fmt.Errorf("failed to marshal mappings: %w", err)
+		return fmt.Errorf("failed to marshal %s: %w", key, err)
 	}
 
-	cm, err := s.clientset.CoreV1().ConfigMaps(s.namespace).Get(ctx, configMapName, metav1.GetOptions{})
-	if err != nil {
-		if errors.IsNotFound(err) {
-			cm = &corev1.ConfigMap{
-				ObjectMeta: metav1.ObjectMeta{
-					Name:      configMapName,
-					Namespace: s.namespace,
-				},
-				Data: map[string]string{
-					configMapDataKey: string(data),
-				},
-			}
-			_, err = s.clientset.CoreV1().ConfigMaps(s.namespace).Create(ctx, cm, metav1.CreateOptions{})
-			if err != nil {
-				return fmt.Errorf("failed to create configmap: %w", err)
-			}
-			return nil
+	if cm == nil {
+		cm = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      configMapName,
+				Namespace: s.namespace,
+			},
+			Data: map[string]string{
+				key: string(data),
+			},
+		}
+		_, err = s.clientset.CoreV1().ConfigMaps(s.namespace).Create(ctx, cm, metav1.CreateOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to create configmap: %w", err)
 		}
-		return fmt.Errorf("failed to get configmap: %w", err)
+		return nil
 	}
 
 	if cm.Data == nil {
 		cm.Data = make(map[string]string)
 	}
-	cm.Data[configMapDataKey] = string(data)
+	cm.Data[key] = string(data)
 
 	_, err = s.clientset.CoreV1().ConfigMaps(s.namespace).Update(ctx, cm, metav1.UpdateOptions{})
 	if err != nil {
@@ -174,3 +281,9 @@ func (s *RegistryStore) saveMappings(ctx context.Context, mappings map[string]mo
 
 	return nil
 }
+
+// isWriteConflict reports whether a write failed because another writer
+// changed or created the ConfigMap first
+func isWriteConflict(err error) bool {
+	return errors.IsConflict(err) || errors.IsAlreadyExists(err)
+}
@@ -0,0 +1,272 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/helm-version-manager/api/internal/model"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+const testNamespace = "helm-ui"
+
+var configMapsResource = corev1.SchemeGroupVersion.WithResource("configmaps")
+
+// versionedClientset is a fake clientset that, like the API server, sets a
+// new resourceVersion on every ConfigMap write and rejects updates made at a
+// stale one. The fake serializes reactors, so checks and writes are atomic.
+type versionedClientset struct {
+	*fake.Clientset
+	version int
+	updates int
+}
+
+func newVersionedClientset() *versionedClientset {
+	c := &versionedClientset{Clientset: fake.NewClientset()}
+
+	c.PrependReactor("create", "configmaps", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		cm := action.(k8stesting.CreateAction).GetObject().(*corev1.ConfigMap)
+		cm.ResourceVersion = c.nextVersion()
+		return false, nil, nil
+	})
+	c.PrependReactor("update", "configmaps", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		c.updates++
+		cm := action.(k8stesting.UpdateAction).GetObject().(*corev1.ConfigMap)
+		current, err := c.Tracker().Get(configMapsResource, cm.Namespace, cm.Name)
+		if err != nil {
+			return true, nil, err
+		}
+		if cm.ResourceVersion != current.(*corev1.ConfigMap).ResourceVersion {
+			return true, nil, apierrors.NewConflict(configMapsResource.GroupResource(), cm.Name, fmt.Errorf("the object has been modified"))
+		}
+		cm.ResourceVersion = c.nextVersion()
+		return false, nil, nil
+	})
+
+	return c
+}
+
+func (c *versionedClientset) nextVersion() string {
+	c.version++
+	return strconv.Itoa(c.version)
+}
+
+// writeBehind stores mapping directly in the tracker, as another replica
+// would, bypassing the reactors. It must only be called from a reactor.
+func (c *versionedClientset) writeBehind(t *testing.T, mapping model.RegistryMapping) {
+	t.Helper()
+
+	mappings := map[string]model.RegistryMapping{}
+	obj, err := c.Tracker().Get(configMapsResource, testNamespace, configMapName)
+	exists := err == nil
+	if exists {
+		if err := json.Unmarshal([]byte(obj.(*corev1.ConfigMap).Data[configMapDataKey]), &mappings); err != nil {
+			t.Fatalf("invalid mappings: %v", err)
+		}
+	}
+	mappings[mapping.Namespace+"/"+mapping.ReleaseName] = mapping
+	data, err := json.Marshal(mappings)
+	if err != nil {
+		t.Fatalf("failed to marshal mappings: %v", err)
+	}
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: configMapName, Namespace: testNamespace, ResourceVersion: c.nextVersion()},
+		Data:       map[string]string{configMapDataKey: string(data)},
+	}
+	if exists {
+		err = c.Tracker().Update(configMapsResource, cm, testNamespace)
+	} else {
+		err = c.Tracker().Create(configMapsResource, cm, testNamespace)
+	}
+	if err != nil {
+		t.Fatalf("failed to write configmap: %v", err)
+	}
+}
+
+// onFirst runs fn before the first action with the given verb on
+// ConfigMaps reaches the other reactors
+func (c *versionedClientset) onFirst(verb string, fn func()) {
+	var once sync.Once
+	c.PrependReactor(verb, "configmaps", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		once.Do(fn)
+		return false, nil, nil
+	})
+}
+
+func mapping(name string) model.RegistryMapping {
+	return model.RegistryMapping{Namespace: "apps", ReleaseName: name, Registry: "oci://ghcr.io/example/charts"}
+}
+
+func mappingNames(t *testing.T, store *RegistryStore) []string {
+	t.Helper()
+	mappings, err := store.ListMappings(context.Background())
+	if err != nil {
+		t.Fatalf("failed to list mappings: %v", err)
+	}
+	names := make([]string, 0, len(mappings))
+	for _, m := range mappings {
+		names = append(names, m.ReleaseName)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestRegistryStore(t *testing.T) {
+	store := newRegistryStore(newVersionedClientset(), testNamespace)
+	ctx := context.Background()
+
+	got, err := store.GetMapping(ctx, "apps", "web")
+	if err != nil || got != nil {
+		t.Fatalf("expected no mapping before the configmap exists, got %+v (%v)", got, err)
+	}
+
+	for _, name := range []string{"web", "api"} {
+		if err := store.SetMapping(ctx, mapping(name)); err != nil {
+			t.Fatalf("failed to set mapping: %v", err)
+		}
+	}
+
+	got, err = store.GetMapping(ctx, "apps", "web")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got == nil || *got != mapping("web") {
+		t.Errorf("expected %+v, got %+v", mapping("web"), got)
+	}
+
+	if err := store.DeleteMapping(ctx, "apps", "web"); err != nil {
+		t.Fatalf("failed to delete mapping: %v", err)
+	}
+	if names := mappingNames(t, store); fmt.Sprint(names) != "[api]" {
+		t.Errorf("expected only api to remain, got %v", names)
+	}
+}
+
+func TestRegistryStoreRules(t *testing.T) {
+	store := newRegistryStore(newVersionedClientset(), testNamespace)
+	ctx := context.Background()
+
+	if err := store.SetMapping(ctx, mapping("web")); err != nil {
+		t.Fatalf("failed to set mapping: %v", err)
+	}
+
+	rule := model.RegistryRule{ID: "ourorg", Chart: "ourorg-*", Priority: 10, Registry: "oci://ghcr.io/ourorg/charts"}
+	if err := store.SetRule(ctx, rule); err != nil {
+		t.Fatalf("failed to set rule: %v", err)
+	}
+
+	got, err := store.GetRule(ctx, "ourorg")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got == nil || *got != rule {
+		t.Errorf("expected %+v, got %+v", rule, got)
+	}
+
+	// Rules are stored beside the mappings without replacing them
+	if names := mappingNames(t, store); fmt.Sprint(names) != "[web]" {
+		t.Errorf("expected mappings to be kept, got %v", names)
+	}
+
+	if err := store.DeleteRule(ctx, "ourorg"); err != nil {
+		t.Fatalf("failed to delete rule: %v", err)
+	}
+	rules, err := store.ListRules(ctx)
+	if err != nil || len(rules) != 0 {
+		t.Errorf("expected no rules, got %+v (%v)", rules, err)
+	}
+	if names := mappingNames(t, store); fmt.Sprint(names) != "[web]" {
+		t.Errorf("expected mappings to be kept, got %v", names)
+	}
+}
+
+func TestRegistryStoreRetriesOnConflict(t *testing.T) {
+	clientset := newVersionedClientset()
+	store := newRegistryStore(clientset, testNamespace)
+	ctx := context.Background()
+
+	if err := store.SetMapping(ctx, mapping("web")); err != nil {
+		t.Fatalf("failed to set mapping: %v", err)
+	}
+	clientset.updates = 0
+
+	// Another replica writes between this store's read and its update
+	clientset.onFirst("update", func() { clientset.writeBehind(t, mapping("api")) })
+
+	if err := store.SetMapping(ctx, mapping("worker")); err != nil {
+		t.Fatalf("failed to set mapping: %v", err)
+	}
+	if clientset.updates != 2 {
+		t.Errorf("expected the conflicting update to be retried once, got %d updates", clientset.updates)
+	}
+	if names := mappingNames(t, store); fmt.Sprint(names) != "[api web worker]" {
+		t.Errorf("expected no write to be lost, got %v", names)
+	}
+
+	// Deletes are retried the same way
+	clientset.onFirst("update", func() { clientset.writeBehind(t, mapping("batch")) })
+	if err := store.DeleteMapping(ctx, "apps", "web"); err != nil {
+		t.Fatalf("failed to delete mapping: %v", err)
+	}
+	if names := mappingNames(t, store); fmt.Sprint(names) != "[api batch worker]" {
+		t.Errorf("expected web to be deleted without losing batch, got %v", names)
+	}
+}
+
+func TestRegistryStoreCreateRace(t *testing.T) {
+	clientset := newVersionedClientset()
+	store := newRegistryStore(clientset, testNamespace)
+
+	// Another replica creates the configmap after this store found it
+	// missing
+	clientset.onFirst("create", func() { clientset.writeBehind(t, mapping("api")) })
+
+	if err := store.SetMapping(context.Background(), mapping("web")); err != nil {
+		t.Fatalf("failed to set mapping: %v", err)
+	}
+	if names := mappingNames(t, store); fmt.Sprint(names) != "[api web]" {
+		t.Errorf("expected both mappings after the create race, got %v", names)
+	}
+}
+
+func TestRegistryStoreConcurrentReplicas(t *testing.T) {
+	clientset := newVersionedClientset()
+	ctx := context.Background()
+
+	// Each replica has its own store, so only the resourceVersion checks
+	// keep them from overwriting each other. Every conflict means another
+	// writer succeeded, so five writers fit in the retry budget.
+	names := []string{"a", "b", "c", "d", "e"}
+	var wg sync.WaitGroup
+	errs := make(chan error, len(names))
+	for _, name := range names {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			store := newRegistryStore(clientset, testNamespace)
+			errs <- store.SetMapping(ctx, mapping(name))
+		}(name)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("failed to set mapping: %v", err)
+		}
+	}
+	if got := mappingNames(t, newRegistryStore(clientset, testNamespace)); fmt.Sprint(got) != fmt.Sprint(names) {
+		t.Errorf("expected all mappings to be stored, got %v", got)
+	}
+}
@@ -0,0 +1,344 @@
+package storage
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/helm-version-manager/api/internal/model"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client/config"
+)
+
+const (
+	credentialsConfigMapName = "helm-version-manager-registry-credentials"
+	credentialsDataKey       = "credentials"
+
+	// CredentialRegistriesAnnotation lists, comma-separated, the registries
+	// a Secret may be used for. Secrets without it cannot be referenced by
+	// a credential.
+	CredentialRegistriesAnnotation = "helm-ui/registries"
+)
+
+// Credential is a username and password for a registry
+type Credential struct {
+	Username string
+	Password string
+}
+
+// CredentialStore keeps the registry credentials, which reference Secrets
+// holding the actual username and password, in a ConfigMap. Only Secrets in
+// helm-ui's own namespace whose CredentialRegistriesAnnotation covers the
+// credential's registry can be referenced, so API users cannot send a
+// Secret's username and password to a registry of their choosing.
+type CredentialStore struct {
+	clientset kubernetes.Interface
+	namespace string
+	mu        sync.RWMutex
+}
+
+func NewCredentialStore() (*CredentialStore, error) {
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get kubernetes config: %w", err)
+	}
+
+	clientset, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create kubernetes client: %w", err)
+	}
+
+	namespace := os.Getenv("NAMESPACE")
+	if namespace == "" {
+		namespace = defaultNamespace
+	}
+
+	return newCredentialStore(clientset, namespace), nil
+}
+
+func newCredentialStore(clientset kubernetes.Interface, namespace string) *CredentialStore {
+	return &CredentialStore{
+		clientset: clientset,
+		namespace: namespace,
+	}
+}
+
+func (s *CredentialStore) GetCredential(ctx context.Context, id string) (*model.RegistryCredential, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	credentials, _, err := s.loadCredentials(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if credential, ok := credentials[id]; ok {
+		return &credential, nil
+	}
+
+	return nil, nil
+}
+
+func (s *CredentialStore) SetCredential(ctx context.Context, credential model.RegistryCredential) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.updateCredentials(ctx, func(credentials map[string]model.RegistryCredential) {
+		credentials[credential.ID] = credential
+	})
+}
+
+func (s *CredentialStore) DeleteCredential(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.updateCredentials(ctx, func(credentials map[string]model.RegistryCredential) {
+		delete(credentials, id)
+	})
+}
+
+// ListCredentials returns the credentials ordered by ID
+func (s *CredentialStore) ListCredentials(ctx context.Context) ([]model.RegistryCredential, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	credentials, _, err := s.loadCredentials(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]model.RegistryCredential, 0, len(credentials))
+	for _, c := range credentials {
+		result = append(result, c)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+
+	return result, nil
+}
+
+// ValidateCredential checks that credential can be stored under its ID and
+// that its Secret holds a username and password for its registry
+func (s *CredentialStore) ValidateCredential(ctx context.Context, credential model.RegistryCredential) error {
+	if errs := validation.IsDNS1123Subdomain(credential.ID); len(errs) > 0 {
+		return fmt.Errorf("invalid credential id %q: %s", credential.ID, strings.Join(errs, "; "))
+	}
+	if normalizeRegistry(credential.Registry) == "" {
+		return fmt.Errorf("registry is required")
+	}
+	if credential.SecretName == "" {
+		return fmt.Errorf("secretName is required")
+	}
+
+	_, err := s.secretCredential(ctx, credential, credential.Registry)
+	return err
+}
+
+// Lookup returns the username and password for the registry or repository
+// reference ref from the credential with the longest matching Registry, or
+// nil when no credential matches
+func (s *CredentialStore) Lookup(ctx context.Context, ref string) (*Credential, error) {
+	s.mu.RLock()
+	credentials, _, err := s.loadCredentials(ctx)
+	s.mu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+
+	ref = normalizeRegistry(ref)
+	var best *model.RegistryCredential
+	for id := range credentials {
+		credential := credentials[id]
+		registry := normalizeRegistry(credential.Registry)
+		if ref != registry && !strings.HasPrefix(ref, registry+"/") {
+			continue
+		}
+		if best == nil || len(registry) > len(normalizeRegistry(best.Registry)) {
+			best = &credential
+		}
+	}
+	if best == nil {
+		return nil, nil
+	}
+
+	credential, err := s.secretCredential(ctx, *best, ref)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read registry credential %s: %w", best.ID, err)
+	}
+	return credential, nil
+}
+
+// secretCredential reads the username and password for ref from the Secret
+// of credential, provided the Secret may be used for credential's registry
+func (s *CredentialStore) secretCredential(ctx context.Context, credential model.RegistryCredential, ref string) (*Credential, error) {
+	name := credential.SecretName
+	secret, err := s.clientset.CoreV1().Secrets(s.namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil, fmt.Errorf("secret %s not found in namespace %s", name, s.namespace)
+		}
+		return nil, fmt.Errorf("failed to get secret %s: %w", name, err)
+	}
+
+	if !secretAllowsRegistry(secret, credential.Registry) {
+		return nil, fmt.Errorf("secret %s may not be used for %s; list the registry in its %s annotation", name, credential.Registry, CredentialRegistriesAnnotation)
+	}
+
+	return credentialFromSecret(secret, ref)
+}
+
+// secretAllowsRegistry reports whether registry is one of, or under one of,
+// the registries listed in the Secret's CredentialRegistriesAnnotation
+func secretAllowsRegistry(secret *corev1.Secret, registry string) bool {
+	registry = normalizeRegistry(registry)
+	for _, allowed := range strings.Split(secret.Annotations[CredentialRegistriesAnnotation], ",") {
+		allowed = normalizeRegistry(strings.TrimSpace(allowed))
+		if allowed == "" {
+			continue
+		}
+		if registry == allowed || strings.HasPrefix(registry, allowed+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// credentialFromSecret extracts the username and password for registry
+// from a basic-auth Secret, or from the entry for registry's host in a
+// dockerconfigjson Secret
+func credentialFromSecret(secret *corev1.Secret, registry string) (*Credential, error) {
+	switch secret.Type {
+	case corev1.SecretTypeBasicAuth:
+		credential := &Credential{
+			Username: string(secret.Data[corev1.BasicAuthUsernameKey]),
+			Password: string(secret.Data[corev1.BasicAuthPasswordKey]),
+		}
+		if credential.Username == "" && credential.Password == "" {
+			return nil, fmt.Errorf("secret %s has no username or password", secret.Name)
+		}
+		return credential, nil
+
+	case corev1.SecretTypeDockerConfigJson:
+		var dockerConfig struct {
+			Auths map[string]struct {
+				Username string `json:"username"`
+				Password string `json:"password"`
+				Auth     string `json:"auth"`
+			} `json:"auths"`
+		}
+		if err := json.Unmarshal(secret.Data[corev1.DockerConfigJsonKey], &dockerConfig); err != nil {
+			return nil, fmt.Errorf("secret %s has an invalid %s: %w", secret.Name, corev1.DockerConfigJsonKey, err)
+		}
+
+		host := registryHost(registry)
+		for server, entry := range dockerConfig.Auths {
+			if registryHost(server) != host {
+				continue
+			}
+			credential := &Credential{Username: entry.Username, Password: entry.Password}
+			if entry.Auth != "" {
+				decoded, err := base64.StdEncoding.DecodeString(entry.Auth)
+				if err != nil {
+					return nil, fmt.Errorf("secret %s has an invalid auth for %s: %w", secret.Name, server, err)
+				}
+				credential.Username, credential.Password, _ = strings.Cut(string(decoded), ":")
+			}
+			return credential, nil
+		}
+		return nil, fmt.Errorf("secret %s has no credentials for %s", secret.Name, host)
+
+	default:
+		return nil, fmt.Errorf("secret %s has type %s, expected %s or %s", secret.Name, secret.Type, corev1.SecretTypeDockerConfigJson, corev1.SecretTypeBasicAuth)
+	}
+}
+
+// normalizeRegistry strips the scheme and trailing slashes from a registry
+// or repository reference, so that oci://ghcr.io/org and ghcr.io/org/
+// compare equal
+func normalizeRegistry(ref string) string {
+	for _, scheme := range []string{"oci://", "https://", "http://"} {
+		ref = strings.TrimPrefix(ref, scheme)
+	}
+	return strings.TrimRight(ref, "/")
+}
+
+// registryHost returns the host of a registry reference or docker config
+// server address
+func registryHost(ref string) string {
+	host, _, _ := strings.Cut(normalizeRegistry(ref), "/")
+	return host
+}
+
+// loadCredentials returns the stored credentials by ID and the ConfigMap
+// holding them, which is nil if it does not exist yet
+func (s *CredentialStore) loadCredentials(ctx context.Context) (map[string]model.RegistryCredential, *corev1.ConfigMap, error) {
+	cm, err := s.clientset.CoreV1().ConfigMaps(s.namespace).Get(ctx, credentialsConfigMapName, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return make(map[string]model.RegistryCredential), nil, nil
+		}
+		return nil, nil, fmt.Errorf("failed to get configmap: %w", err)
+	}
+
+	credentials := make(map[string]model.RegistryCredential)
+	if data := cm.Data[credentialsDataKey]; data != "" {
+		if err := json.Unmarshal([]byte(data), &credentials); err != nil {
+			return nil, nil, fmt.Errorf("failed to unmarshal credentials: %w", err)
+		}
+	}
+
+	return credentials, cm, nil
+}
+
+// updateCredentials applies mutate to the stored credentials and writes
+// them back, retrying when another replica wrote the ConfigMap in between
+func (s *CredentialStore) updateCredentials(ctx context.Context, mutate func(map[string]model.RegistryCredential)) error {
+	return retry.OnError(retry.DefaultRetry, isWriteConflict, func() error {
+		credentials, cm, err := s.loadCredentials(ctx)
+		if err != nil {
+			return err
+		}
+
+		mutate(credentials)
+
+		data, err := json.Marshal(credentials)
+		if err != nil {
+			return fmt.Errorf("failed to marshal credentials: %w", err)
+		}
+
+		if cm == nil {
+			cm = &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      credentialsConfigMapName,
+					Namespace: s.namespace,
+				},
+				Data: map[string]string{
+					credentialsDataKey: string(data),
+				},
+			}
+			if _, err := s.clientset.CoreV1().ConfigMaps(s.namespace).Create(ctx, cm, metav1.CreateOptions{}); err != nil {
+				return fmt.Errorf("failed to create configmap: %w", err)
+			}
+			return nil
+		}
+
+		if cm.Data == nil {
+			cm.Data = make(map[string]string)
+		}
+		cm.Data[credentialsDataKey] = string(data)
+
+		if _, err := s.clientset.CoreV1().ConfigMaps(s.namespace).Update(ctx, cm, metav1.UpdateOptions{}); err != nil {
+			return fmt.Errorf("failed to update configmap: %w", err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,167 @@
+package storage
+
+import (
+	"context"
+	"encoding/base64"
+	"reflect"
+	"testing"
+
+	"github.com/helm-version-manager/api/internal/model"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newCredentialFixture(t *testing.T) *CredentialStore {
+	t.Helper()
+
+	dockerConfig := `{"auths": {
+		"https://ghcr.io": {"auth": "` + base64.StdEncoding.EncodeToString([]byte("org-bot:org-token")) + `"},
+		"harbor.example.com": {"username": "harbor-bot", "password": "harbor-token"}
+	}}`
+
+	clientset := fake.NewClientset(
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "pull-secret",
+				Namespace:   testNamespace,
+				Annotations: map[string]string{CredentialRegistriesAnnotation: "oci://ghcr.io, harbor.example.com/chartrepo"},
+			},
+			Type: corev1.SecretTypeDockerConfigJson,
+			Data: map[string][]byte{corev1.DockerConfigJsonKey: []byte(dockerConfig)},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "team-chart",
+				Namespace:   testNamespace,
+				Annotations: map[string]string{CredentialRegistriesAnnotation: "ghcr.io/example/team, https://charts.example.com"},
+			},
+			Type: corev1.SecretTypeBasicAuth,
+			Data: map[string][]byte{"username": []byte("team"), "password": []byte("team-token")},
+		},
+		// Secrets that do not opt in cannot be referenced
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "app-db", Namespace: testNamespace},
+			Type:       corev1.SecretTypeBasicAuth,
+			Data:       map[string][]byte{"username": []byte("team"), "password": []byte("team-token")},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "opaque",
+				Namespace:   testNamespace,
+				Annotations: map[string]string{CredentialRegistriesAnnotation: "oci://ghcr.io"},
+			},
+			Type: corev1.SecretTypeOpaque,
+			Data: map[string][]byte{"token": []byte("x")},
+		},
+		// Secrets outside helm-ui's namespace cannot be referenced
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "elsewhere",
+				Namespace:   "apps",
+				Annotations: map[string]string{CredentialRegistriesAnnotation: "oci://ghcr.io"},
+			},
+			Type: corev1.SecretTypeBasicAuth,
+			Data: map[string][]byte{"username": []byte("apps"), "password": []byte("apps-token")},
+		},
+	)
+
+	return newCredentialStore(clientset, testNamespace)
+}
+
+func TestCredentialStore(t *testing.T) {
+	ctx := context.Background()
+	store := newCredentialFixture(t)
+
+	for _, credential := range []model.RegistryCredential{
+		{ID: "ghcr", Registry: "oci://ghcr.io", SecretName: "pull-secret"},
+		{ID: "ghcr-team", Registry: "ghcr.io/example/team/", SecretName: "team-chart"},
+		{ID: "harbor", Registry: "https://harbor.example.com/chartrepo", SecretName: "pull-secret"},
+	} {
+		if err := store.SetCredential(ctx, credential); err != nil {
+			t.Fatalf("SetCredential failed: %v", err)
+		}
+	}
+
+	credentials, err := store.ListCredentials(ctx)
+	if err != nil {
+		t.Fatalf("ListCredentials failed: %v", err)
+	}
+	var ids []string
+	for _, credential := range credentials {
+		ids = append(ids, credential.ID)
+	}
+	if !reflect.DeepEqual(ids, []string{"ghcr", "ghcr-team", "harbor"}) {
+		t.Errorf("expected credentials ordered by ID, got %v", ids)
+	}
+
+	testCases := []struct {
+		ref  string
+		want *Credential
+	}{
+		{"oci://ghcr.io/example/charts/web", &Credential{Username: "org-bot", Password: "org-token"}},
+		// The longest matching registry wins
+		{"ghcr.io/example/team/api", &Credential{Username: "team", Password: "team-token"}},
+		{"oci://ghcr.io/example/teams/api", &Credential{Username: "org-bot", Password: "org-token"}},
+		{"https://harbor.example.com/chartrepo", &Credential{Username: "harbor-bot", Password: "harbor-token"}},
+		{"https://harbor.example.com/other", nil},
+		{"oci://ghcr.io.example.com/charts", nil},
+		{"oci://docker.io/library", nil},
+	}
+	for _, tc := range testCases {
+		got, err := store.Lookup(ctx, tc.ref)
+		if err != nil {
+			t.Fatalf("Lookup(%q) failed: %v", tc.ref, err)
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("Lookup(%q) = %+v, want %+v", tc.ref, got, tc.want)
+		}
+	}
+
+	if err := store.DeleteCredential(ctx, "ghcr-team"); err != nil {
+		t.Fatalf("DeleteCredential failed: %v", err)
+	}
+	if got, _ := store.GetCredential(ctx, "ghcr-team"); got != nil {
+		t.Errorf("expected deleted credential to be gone, got %+v", got)
+	}
+	got, err := store.Lookup(ctx, "ghcr.io/example/team/api")
+	if err != nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+	if got == nil || got.Username != "org-bot" {
+		t.Errorf("expected the ghcr credential after deleting the team one, got %+v", got)
+	}
+}
+
+func TestValidateCredential(t *testing.T) {
+	store := newCredentialFixture(t)
+
+	testCases := map[string]struct {
+		credential model.RegistryCredential
+		wantErr    bool
+	}{
+		"dockerconfigjson":    {model.RegistryCredential{ID: "ghcr", Registry: "oci://ghcr.io/example", SecretName: "pull-secret"}, false},
+		"basic auth":          {model.RegistryCredential{ID: "charts", Registry: "https://charts.example.com", SecretName: "team-chart"}, false},
+		"invalid id":          {model.RegistryCredential{ID: "GHCR", Registry: "oci://ghcr.io", SecretName: "pull-secret"}, true},
+		"missing registry":    {model.RegistryCredential{ID: "ghcr", SecretName: "pull-secret"}, true},
+		"missing secret name": {model.RegistryCredential{ID: "ghcr", Registry: "oci://ghcr.io"}, true},
+		"unknown host":        {model.RegistryCredential{ID: "quay", Registry: "oci://quay.io/example", SecretName: "pull-secret"}, true},
+		"unsupported type":    {model.RegistryCredential{ID: "ghcr", Registry: "oci://ghcr.io", SecretName: "opaque"}, true},
+		"missing secret":      {model.RegistryCredential{ID: "ghcr", Registry: "oci://ghcr.io", SecretName: "missing"}, true},
+		"other namespace":     {model.RegistryCredential{ID: "ghcr", Registry: "oci://ghcr.io", SecretName: "elsewhere"}, true},
+		"not opted in":        {model.RegistryCredential{ID: "ghcr", Registry: "oci://ghcr.io", SecretName: "app-db"}, true},
+		"other registry":      {model.RegistryCredential{ID: "evil", Registry: "https://attacker.example", SecretName: "team-chart"}, true},
+		"registry prefix":     {model.RegistryCredential{ID: "evil", Registry: "oci://ghcr.io.attacker.example", SecretName: "pull-secret"}, true},
+		"path under allowed":  {model.RegistryCredential{ID: "team", Registry: "oci://ghcr.io/example/team/charts", SecretName: "team-chart"}, false},
+		"parent of allowed":   {model.RegistryCredential{ID: "team", Registry: "oci://ghcr.io/example", SecretName: "team-chart"}, true},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			err := store.ValidateCredential(context.Background(), tc.credential)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("ValidateCredential() error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,367 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/helm-version-manager/api/internal/model"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage/driver"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/controller-runtime/pkg/client/config"
+)
+
+// RegistryAnnotation names the registry of a release when set as a
+// Chart.yaml annotation, a label on the Helm release secret or an
+// annotation on the release's namespace. Label values cannot contain "/"
+// or ":", so a release label can only name an OCI registry host.
+const RegistryAnnotation = "helm-ui/registry"
+
+// discoveryTTL is how long ListMappings reuses the releases and namespaces it
+// read, so that listing releases over and over does not decode every Helm
+// release in the cluster each time
+const discoveryTTL = 10 * time.Second
+
+// DiscoveringStore resolves the registry of a release without an explicit
+// mapping from, in order, the registry rules, its chart's annotations, its
+// release labels and its namespace's annotations. Writes only affect the
+// explicit mappings and rules of the wrapped store.
+type DiscoveringStore struct {
+	store     MappingStore
+	clientset kubernetes.Interface
+	// helmDriver is the Helm storage driver releases are read from
+	helmDriver string
+	now        func() time.Time
+
+	// mu guards discovery, which is shared by ListMappings calls for
+	// discoveryTTL; explicit mappings and rules are always read afresh
+	mu        sync.Mutex
+	discovery *discovery
+}
+
+// discovery holds the latest revision of every release, keyed by
+// namespace/name, and every namespace, keyed by name
+type discovery struct {
+	releases   map[string]*release.Release
+	namespaces map[string]*corev1.Namespace
+	loadedAt   time.Time
+}
+
+func NewDiscoveringStore(store MappingStore) (*DiscoveringStore, error) {
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get kubernetes config: %w", err)
+	}
+
+	clientset, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create kubernetes client: %w", err)
+	}
+
+	return newDiscoveringStore(store, clientset, os.Getenv("HELM_DRIVER")), nil
+}
+
+func newDiscoveringStore(store MappingStore, clientset kubernetes.Interface, helmDriver string) *DiscoveringStore {
+	return &DiscoveringStore{
+		store:      store,
+		clientset:  clientset,
+		helmDriver: helmDriver,
+		now:        time.Now,
+	}
+}
+
+func (s *DiscoveringStore) GetMapping(ctx context.Context, namespace, releaseName string) (*model.RegistryMapping, error) {
+	resolution, err := s.ResolveMapping(ctx, namespace, releaseName)
+	if err != nil {
+		return nil, err
+	}
+	return resolution.Mapping, nil
+}
+
+// ResolveMapping resolves the registry of a release and explains which
+// sources were tried
+func (s *DiscoveringStore) ResolveMapping(ctx context.Context, namespace, releaseName string) (*model.RegistryResolution, error) {
+	explicit, err := s.store.GetMapping(ctx, namespace, releaseName)
+	if err != nil {
+		return nil, err
+	}
+
+	var rel *release.Release
+	var rules []model.RegistryRule
+	var ns *corev1.Namespace
+	if explicit == nil {
+		releases, err := s.latestReleases(namespace, map[string]string{"name": releaseName})
+		if err != nil {
+			return nil, err
+		}
+		rel = releases[namespace+"/"+releaseName]
+
+		rules, err = s.store.ListRules(ctx)
+		if err != nil {
+			return nil, err
+		}
+		sortRules(rules)
+
+		ns, err = s.clientset.CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{})
+		if err != nil {
+			if !errors.IsNotFound(err) {
+				return nil, fmt.Errorf("failed to get namespace: %w", err)
+			}
+			ns = nil
+		}
+	}
+
+	return resolve(namespace, releaseName, explicit, rel, rules, ns), nil
+}
+
+func (s *DiscoveringStore) SetMapping(ctx context.Context, mapping model.RegistryMapping) error {
+	mapping.Source = ""
+	mapping.RuleID = ""
+	return s.store.SetMapping(ctx, mapping)
+}
+
+func (s *DiscoveringStore) DeleteMapping(ctx context.Context, namespace, releaseName string) error {
+	return s.store.DeleteMapping(ctx, namespace, releaseName)
+}
+
+// ListMappings returns the explicit mappings and the resolved mappings of
+// all other releases. Releases and namespaces are read at most once per
+// discoveryTTL, so a release installed or annotated since may be missing for
+// that long.
+func (s *DiscoveringStore) ListMappings(ctx context.Context) ([]model.RegistryMapping, error) {
+	mappings, err := s.store.ListMappings(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	explicit := make(map[string]bool, len(mappings))
+	for i := range mappings {
+		mappings[i].Source = model.RegistrySourceExplicit
+		explicit[mappings[i].Namespace+"/"+mappings[i].ReleaseName] = true
+	}
+
+	discovered, err := s.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rules, err := s.store.ListRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sortRules(rules)
+
+	for key, rel := range discovered.releases {
+		if explicit[key] {
+			continue
+		}
+		resolution := resolve(rel.Namespace, rel.Name, nil, rel, rules, discovered.namespaces[rel.Namespace])
+		if resolution.Mapping != nil {
+			mappings = append(mappings, *resolution.Mapping)
+		}
+	}
+
+	return mappings, nil
+}
+
+// discover returns the releases and namespaces registries are resolved
+// from, reading them again once they are older than discoveryTTL
+func (s *DiscoveringStore) discover(ctx context.Context) (*discovery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if s.discovery != nil && now.Sub(s.discovery.loadedAt) < discoveryTTL {
+		return s.discovery, nil
+	}
+
+	releases, err := s.latestReleases("", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	namespaceList, err := s.clientset.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list namespaces: %w", err)
+	}
+	namespaces := make(map[string]*corev1.Namespace, len(namespaceList.Items))
+	for i := range namespaceList.Items {
+		namespaces[namespaceList.Items[i].Name] = &namespaceList.Items[i]
+	}
+
+	s.discovery = &discovery{releases: releases, namespaces: namespaces, loadedAt: now}
+	return s.discovery, nil
+}
+
+// ExplicitMappings returns the mappings in mappings that were set
+// explicitly, as they are stored, dropping resolved ones
+func ExplicitMappings(mappings []model.RegistryMapping) []model.RegistryMapping {
+	result := make([]model.RegistryMapping, 0, len(mappings))
+	for _, mapping := range mappings {
+		if mapping.Source != "" && mapping.Source != model.RegistrySourceExplicit {
+			continue
+		}
+		mapping.Source = ""
+		result = append(result, mapping)
+	}
+	return result
+}
+
+func (s *DiscoveringStore) GetRule(ctx context.Context, id string) (*model.RegistryRule, error) {
+	return s.store.GetRule(ctx, id)
+}
+
+func (s *DiscoveringStore) SetRule(ctx context.Context, rule model.RegistryRule) error {
+	return s.store.SetRule(ctx, rule)
+}
+
+func (s *DiscoveringStore) DeleteRule(ctx context.Context, id string) error {
+	return s.store.DeleteRule(ctx, id)
+}
+
+// ListRules returns the rules in the order they are tried
+func (s *DiscoveringStore) ListRules(ctx context.Context) ([]model.RegistryRule, error) {
+	rules, err := s.store.ListRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sortRules(rules)
+	return rules, nil
+}
+
+// latestReleases returns the latest revision of the Helm releases in
+// namespace, or all namespaces when it is empty, matching labels, keyed by
+// namespace/name
+func (s *DiscoveringStore) latestReleases(namespace string, labels map[string]string) (map[string]*release.Release, error) {
+	var d driver.Driver
+	switch s.helmDriver {
+	case "", "secret", "secrets":
+		d = driver.NewSecrets(s.clientset.CoreV1().Secrets(namespace))
+	case "configmap", "configmaps":
+		d = driver.NewConfigMaps(s.clientset.CoreV1().ConfigMaps(namespace))
+	default:
+		// Releases in other drivers cannot be read from here
+		return nil, nil
+	}
+
+	query := map[string]string{"owner": "helm"}
+	for k, v := range labels {
+		query[k] = v
+	}
+
+	releases, err := d.Query(query)
+	if err != nil {
+		if err == driver.ErrReleaseNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to query releases: %w", err)
+	}
+
+	latest := make(map[string]*release.Release)
+	for _, rel := range releases {
+		key := rel.Namespace + "/" + rel.Name
+		if current, ok := latest[key]; !ok || rel.Version > current.Version {
+			latest[key] = rel
+		}
+	}
+
+	return latest, nil
+}
+
+// resolve tries the explicit mapping, the sorted rules, the chart
+// annotations, the release labels and the namespace annotations of a
+// release in turn. rel and ns are nil when they do not exist.
+func resolve(namespace, releaseName string, explicit *model.RegistryMapping, rel *release.Release, rules []model.RegistryRule, ns *corev1.Namespace) *model.RegistryResolution {
+	resolution := &model.RegistryResolution{
+		Namespace:   namespace,
+		ReleaseName: releaseName,
+		Steps:       []model.RegistryResolutionStep{},
+	}
+	step := func(source, ruleID string, matched bool, reason string) {
+		resolution.Steps = append(resolution.Steps, model.RegistryResolutionStep{
+			Source:  source,
+			RuleID:  ruleID,
+			Matched: matched,
+			Reason:  reason,
+		})
+	}
+	found := func(mapping *model.RegistryMapping, message string) *model.RegistryResolution {
+		resolution.Mapping = mapping
+		resolution.Message = message
+		return resolution
+	}
+
+	if explicit != nil {
+		explicit.Source = model.RegistrySourceExplicit
+		step(model.RegistrySourceExplicit, "", true, "Registry set for the release")
+		return found(explicit, "Resolved by the explicit mapping")
+	}
+	step(model.RegistrySourceExplicit, "", false, "No registry set for the release")
+
+	if rel == nil {
+		resolution.Message = "Release not found; only explicit mappings apply to releases that are not installed"
+		return resolution
+	}
+
+	chartName := ""
+	var chartAnnotations map[string]string
+	if rel.Chart != nil && rel.Chart.Metadata != nil {
+		chartName = rel.Chart.Metadata.Name
+		chartAnnotations = rel.Chart.Metadata.Annotations
+	}
+	var namespaceLabels, namespaceAnnotations map[string]string
+	if ns != nil {
+		namespaceLabels = ns.Labels
+		namespaceAnnotations = ns.Annotations
+	}
+
+	for _, rule := range rules {
+		matched, reason := matchRule(rule, chartName, namespace, namespaceLabels)
+		step(model.RegistrySourceRule, rule.ID, matched, reason)
+		if matched {
+			mapping := discoveredMapping(rel, rule.Registry, model.RegistrySourceRule)
+			mapping.RuleID = rule.ID
+			return found(mapping, fmt.Sprintf("Resolved by rule %s", rule.ID))
+		}
+	}
+
+	if registry := chartAnnotations[RegistryAnnotation]; registry != "" {
+		step(model.RegistrySourceChartAnnotation, "", true, fmt.Sprintf("Chart %s is annotated with %s", chartName, RegistryAnnotation))
+		return found(discoveredMapping(rel, registry, model.RegistrySourceChartAnnotation), "Resolved by the chart annotation")
+	}
+	step(model.RegistrySourceChartAnnotation, "", false, fmt.Sprintf("Chart %s has no %s annotation", chartName, RegistryAnnotation))
+
+	if registry := rel.Labels[RegistryAnnotation]; registry != "" {
+		step(model.RegistrySourceReleaseLabel, "", true, fmt.Sprintf("Release is labeled with %s", RegistryAnnotation))
+		return found(discoveredMapping(rel, registry, model.RegistrySourceReleaseLabel), "Resolved by the release label")
+	}
+	step(model.RegistrySourceReleaseLabel, "", false, fmt.Sprintf("Release has no %s label", RegistryAnnotation))
+
+	if registry := namespaceAnnotations[RegistryAnnotation]; registry != "" {
+		step(model.RegistrySourceNamespaceDefault, "", true, fmt.Sprintf("Namespace %s is annotated with %s", namespace, RegistryAnnotation))
+		return found(discoveredMapping(rel, registry, model.RegistrySourceNamespaceDefault), "Resolved by the namespace default")
+	}
+	step(model.RegistrySourceNamespaceDefault, "", false, fmt.Sprintf("Namespace %s has no %s annotation", namespace, RegistryAnnotation))
+
+	resolution.Message = "No registry found"
+	return resolution
+}
+
+func discoveredMapping(rel *release.Release, registry, source string) *model.RegistryMapping {
+	mapping := &model.RegistryMapping{
+		Namespace:   rel.Namespace,
+		ReleaseName: rel.Name,
+		Registry:    registry,
+		Source:      source,
+	}
+	if rel.Chart != nil && rel.Chart.Metadata != nil {
+		mapping.ChartName = rel.Chart.Metadata.Name
+	}
+	return mapping
+}
@@ -0,0 +1,331 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/helm-version-manager/api/internal/model"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage/driver"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// newDiscoveryFixture returns a discovering store over releases stored by
+// the Helm secrets driver:
+//   - apps/web has an explicit mapping and a chart annotation
+//   - apps/api has a chart annotation
+//   - apps/worker has a release label on its latest revision only
+//   - apps/batch and other/cron have nothing, but apps has a default
+func newDiscoveryFixture(t *testing.T) *DiscoveringStore {
+	t.Helper()
+	clientset := newVersionedClientset()
+	ctx := context.Background()
+
+	for _, ns := range []*corev1.Namespace{
+		{ObjectMeta: metav1.ObjectMeta{
+			Name:        "apps",
+			Labels:      map[string]string{"team": "apps"},
+			Annotations: map[string]string{RegistryAnnotation: "oci://registry.example.com/apps"},
+		}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "other"}},
+	} {
+		if _, err := clientset.CoreV1().Namespaces().Create(ctx, ns, metav1.CreateOptions{}); err != nil {
+			t.Fatalf("failed to create namespace: %v", err)
+		}
+	}
+
+	releases := []struct {
+		namespace, name  string
+		version          int
+		chartAnnotations map[string]string
+		labels           map[string]string
+	}{
+		{namespace: "apps", name: "web", version: 1, chartAnnotations: map[string]string{RegistryAnnotation: "oci://ghcr.io/example/annotated"}},
+		{namespace: "apps", name: "api", version: 1, chartAnnotations: map[string]string{RegistryAnnotation: "oci://ghcr.io/example/charts"}},
+		{namespace: "apps", name: "worker", version: 1},
+		{namespace: "apps", name: "worker", version: 2, labels: map[string]string{RegistryAnnotation: "registry.example.com"}},
+		{namespace: "apps", name: "batch", version: 1},
+		{namespace: "other", name: "cron", version: 1},
+	}
+	for _, r := range releases {
+		rel := &release.Release{
+			Name:      r.name,
+			Namespace: r.namespace,
+			Version:   r.version,
+			Info:      &release.Info{Status: release.StatusDeployed},
+			Chart:     &chart.Chart{Metadata: &chart.Metadata{Name: r.name + "-chart", Version: "1.0.0", Annotations: r.chartAnnotations}},
+			Labels:    r.labels,
+		}
+		secrets := driver.NewSecrets(clientset.CoreV1().Secrets(r.namespace))
+		if err := secrets.Create(fmt.Sprintf("sh.helm.release.v1.%s.v%d", r.name, r.version), rel); err != nil {
+			t.Fatalf("failed to store release: %v", err)
+		}
+	}
+
+	explicit := newRegistryStore(clientset, testNamespace)
+	if err := explicit.SetMapping(ctx, model.RegistryMapping{Namespace: "apps", ReleaseName: "web", ChartName: "web-chart", Registry: "oci://ghcr.io/example/explicit"}); err != nil {
+		t.Fatalf("failed to set mapping: %v", err)
+	}
+
+	return newDiscoveringStore(explicit, clientset, "")
+}
+
+func TestDiscoveringStoreGetMapping(t *testing.T) {
+	store := newDiscoveryFixture(t)
+
+	testCases := []struct {
+		namespace, name string
+		want            *model.RegistryMapping
+	}{
+		{
+			namespace: "apps", name: "web",
+			want: &model.RegistryMapping{Namespace: "apps", ReleaseName: "web", ChartName: "web-chart", Registry: "oci://ghcr.io/example/explicit", Source: model.RegistrySourceExplicit},
+		},
+		{
+			namespace: "apps", name: "api",
+			want: &model.RegistryMapping{Namespace: "apps", ReleaseName: "api", ChartName: "api-chart", Registry: "oci://ghcr.io/example/charts", Source: model.RegistrySourceChartAnnotation},
+		},
+		{
+			namespace: "apps", name: "worker",
+			want: &model.RegistryMapping{Namespace: "apps", ReleaseName: "worker", ChartName: "worker-chart", Registry: "registry.example.com", Source: model.RegistrySourceReleaseLabel},
+		},
+		{
+			namespace: "apps", name: "batch",
+			want: &model.RegistryMapping{Namespace: "apps", ReleaseName: "batch", ChartName: "batch-chart", Registry: "oci://registry.example.com/apps", Source: model.RegistrySourceNamespaceDefault},
+		},
+		{namespace: "other", name: "cron"},
+		{namespace: "apps", name: "missing"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.namespace+"/"+tc.name, func(t *testing.T) {
+			got, err := store.GetMapping(context.Background(), tc.namespace, tc.name)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tc.want == nil {
+				if got != nil {
+					t.Errorf("expected no mapping, got %+v", got)
+				}
+				return
+			}
+			if got == nil || *got != *tc.want {
+				t.Errorf("expected %+v, got %+v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestDiscoveringStoreListMappings(t *testing.T) {
+	store := newDiscoveryFixture(t)
+
+	got, err := store.ListMappings(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sortMappings(got)
+
+	want := []model.RegistryMapping{
+		{Namespace: "apps", ReleaseName: "api", ChartName: "api-chart", Registry: "oci://ghcr.io/example/charts", Source: model.RegistrySourceChartAnnotation},
+		{Namespace: "apps", ReleaseName: "batch", ChartName: "batch-chart", Registry: "oci://registry.example.com/apps", Source: model.RegistrySourceNamespaceDefault},
+		{Namespace: "apps", ReleaseName: "web", ChartName: "web-chart", Registry: "oci://ghcr.io/example/explicit", Source: model.RegistrySourceExplicit},
+		{Namespace: "apps", ReleaseName: "worker", ChartName: "worker-chart", Registry: "registry.example.com", Source: model.RegistrySourceReleaseLabel},
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+}
+
+func TestDiscoveringStoreListMappingsReusesDiscovery(t *testing.T) {
+	store := newDiscoveryFixture(t)
+	now := time.Now()
+	store.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	registryOf := func(namespace, name string) string {
+		t.Helper()
+		mappings, err := store.ListMappings(ctx)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		for _, m := range mappings {
+			if m.Namespace == namespace && m.ReleaseName == name {
+				return m.Registry
+			}
+		}
+		return ""
+	}
+
+	if got := registryOf("other", "cron"); got != "" {
+		t.Fatalf("expected other/cron to have no registry, got %q", got)
+	}
+
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name:        "other",
+		Annotations: map[string]string{RegistryAnnotation: "oci://registry.example.com/other"},
+	}}
+	if _, err := store.clientset.CoreV1().Namespaces().Update(ctx, ns, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("failed to update namespace: %v", err)
+	}
+
+	// Explicit mappings are not cached
+	if err := store.SetMapping(ctx, model.RegistryMapping{Namespace: "other", ReleaseName: "job", ChartName: "job", Registry: "oci://ghcr.io/example/job"}); err != nil {
+		t.Fatalf("failed to set mapping: %v", err)
+	}
+	if got := registryOf("other", "job"); got != "oci://ghcr.io/example/job" {
+		t.Errorf("expected the new explicit mapping, got %q", got)
+	}
+	if got := registryOf("other", "cron"); got != "" {
+		t.Errorf("expected the namespace to be reused within the TTL, got %q", got)
+	}
+
+	now = now.Add(discoveryTTL)
+	if got := registryOf("other", "cron"); got != "oci://registry.example.com/other" {
+		t.Errorf("expected the namespace default to be read again after the TTL, got %q", got)
+	}
+}
+
+func TestDiscoveringStoreWritesExplicitMappings(t *testing.T) {
+	store := newDiscoveryFixture(t)
+	ctx := context.Background()
+
+	// Saving a discovered mapping makes it explicit
+	mapping, err := store.GetMapping(ctx, "apps", "batch")
+	if err != nil || mapping == nil {
+		t.Fatalf("expected discovered mapping, got %+v (%v)", mapping, err)
+	}
+	mapping.Registry = "oci://ghcr.io/example/batch"
+	if err := store.SetMapping(ctx, *mapping); err != nil {
+		t.Fatalf("failed to set mapping: %v", err)
+	}
+
+	stored, err := store.store.GetMapping(ctx, "apps", "batch")
+	if err != nil || stored == nil {
+		t.Fatalf("expected stored mapping, got %+v (%v)", stored, err)
+	}
+	if stored.Source != "" || stored.Registry != "oci://ghcr.io/example/batch" {
+		t.Errorf("expected the mapping to be stored without a source, got %+v", stored)
+	}
+
+	// Deleting an explicit mapping reveals the discovered one again
+	if err := store.DeleteMapping(ctx, "apps", "web"); err != nil {
+		t.Fatalf("failed to delete mapping: %v", err)
+	}
+	mapping, err = store.GetMapping(ctx, "apps", "web")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if mapping == nil || mapping.Source != model.RegistrySourceChartAnnotation {
+		t.Errorf("expected the chart annotation to be used after deleting the explicit mapping, got %+v", mapping)
+	}
+}
+
+func TestDiscoveringStoreResolveMapping(t *testing.T) {
+	store := newDiscoveryFixture(t)
+	ctx := context.Background()
+
+	for _, rule := range []model.RegistryRule{
+		{ID: "fallback", Registry: "oci://ghcr.io/example/fallback"},
+		{ID: "team-apps", NamespaceSelector: "team=apps", Priority: 5, Registry: "oci://ghcr.io/example/team"},
+		{ID: "workers", Chart: "worker-*", Priority: 10, Registry: "oci://ghcr.io/example/workers"},
+	} {
+		if err := store.SetRule(ctx, rule); err != nil {
+			t.Fatalf("failed to set rule: %v", err)
+		}
+	}
+
+	testCases := []struct {
+		namespace, name string
+		wantMapping     *model.RegistryMapping
+		wantMessage     string
+		wantSteps       []model.RegistryResolutionStep
+	}{
+		{
+			namespace:   "apps",
+			name:        "web",
+			wantMapping: &model.RegistryMapping{Namespace: "apps", ReleaseName: "web", ChartName: "web-chart", Registry: "oci://ghcr.io/example/explicit", Source: model.RegistrySourceExplicit},
+			wantMessage: "Resolved by the explicit mapping",
+			wantSteps: []model.RegistryResolutionStep{
+				{Source: model.RegistrySourceExplicit, Matched: true, Reason: "Registry set for the release"},
+			},
+		},
+		{
+			namespace:   "apps",
+			name:        "worker",
+			wantMapping: &model.RegistryMapping{Namespace: "apps", ReleaseName: "worker", ChartName: "worker-chart", Registry: "oci://ghcr.io/example/workers", Source: model.RegistrySourceRule, RuleID: "workers"},
+			wantMessage: "Resolved by rule workers",
+			wantSteps: []model.RegistryResolutionStep{
+				{Source: model.RegistrySourceExplicit, Reason: "No registry set for the release"},
+				{Source: model.RegistrySourceRule, RuleID: "workers", Matched: true, Reason: `Chart "worker-chart" matches "worker-*"`},
+			},
+		},
+		{
+			// Rules take precedence over the chart annotation
+			namespace:   "apps",
+			name:        "api",
+			wantMapping: &model.RegistryMapping{Namespace: "apps", ReleaseName: "api", ChartName: "api-chart", Registry: "oci://ghcr.io/example/team", Source: model.RegistrySourceRule, RuleID: "team-apps"},
+			wantMessage: "Resolved by rule team-apps",
+			wantSteps: []model.RegistryResolutionStep{
+				{Source: model.RegistrySourceExplicit, Reason: "No registry set for the release"},
+				{Source: model.RegistrySourceRule, RuleID: "workers", Reason: `Chart "api-chart" does not match "worker-*"`},
+				{Source: model.RegistrySourceRule, RuleID: "team-apps", Matched: true, Reason: `Labels of namespace apps match "team=apps"`},
+			},
+		},
+		{
+			namespace:   "other",
+			name:        "cron",
+			wantMapping: &model.RegistryMapping{Namespace: "other", ReleaseName: "cron", ChartName: "cron-chart", Registry: "oci://ghcr.io/example/fallback", Source: model.RegistrySourceRule, RuleID: "fallback"},
+			wantMessage: "Resolved by rule fallback",
+			wantSteps: []model.RegistryResolutionStep{
+				{Source: model.RegistrySourceExplicit, Reason: "No registry set for the release"},
+				{Source: model.RegistrySourceRule, RuleID: "workers", Reason: `Chart "cron-chart" does not match "worker-*"`},
+				{Source: model.RegistrySourceRule, RuleID: "team-apps", Reason: `Labels of namespace other do not match "team=apps"`},
+				{Source: model.RegistrySourceRule, RuleID: "fallback", Matched: true, Reason: "Rule matches every release"},
+			},
+		},
+		{
+			namespace:   "apps",
+			name:        "missing",
+			wantMessage: "Release not found; only explicit mappings apply to releases that are not installed",
+			wantSteps: []model.RegistryResolutionStep{
+				{Source: model.RegistrySourceExplicit, Reason: "No registry set for the release"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.namespace+"/"+tc.name, func(t *testing.T) {
+			got, err := store.ResolveMapping(ctx, tc.namespace, tc.name)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if fmt.Sprint(got.Mapping) != fmt.Sprint(tc.wantMapping) {
+				t.Errorf("expected mapping %+v, got %+v", tc.wantMapping, got.Mapping)
+			}
+			if got.Message != tc.wantMessage {
+				t.Errorf("expected message %q, got %q", tc.wantMessage, got.Message)
+			}
+			if fmt.Sprint(got.Steps) != fmt.Sprint(tc.wantSteps) {
+				t.Errorf("expected steps:\n%+v\ngot:\n%+v", tc.wantSteps, got.Steps)
+			}
+		})
+	}
+
+	// Deleting the rules falls back to discovery
+	for _, id := range []string{"fallback", "team-apps", "workers"} {
+		if err := store.DeleteRule(ctx, id); err != nil {
+			t.Fatalf("failed to delete rule: %v", err)
+		}
+	}
+	got, err := store.ResolveMapping(ctx, "apps", "api")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Mapping == nil || got.Mapping.Source != model.RegistrySourceChartAnnotation {
+		t.Errorf("expected the chart annotation without rules, got %+v", got.Mapping)
+	}
+}
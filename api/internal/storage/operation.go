@@ -0,0 +1,139 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/helm-version-manager/api/internal/model"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/controller-runtime/pkg/client/config"
+)
+
+const (
+	operationConfigMapPrefix = "helm-version-manager-operation-"
+	operationDataKey         = "operation"
+	// operationLabel marks the ConfigMaps holding operations so they can be
+	// listed for pruning
+	operationLabel = "helm-version-manager/operation"
+	// operationCompletedAtKey is the annotation holding when the operation
+	// completed, so pruning does not have to decode every operation
+	operationCompletedAtKey = "helm-version-manager/completed-at"
+	// maxOperationSize keeps an operation within the 1MiB object limit
+	maxOperationSize = 900 * 1024
+)
+
+// OperationStore keeps each background operation in a ConfigMap of its own,
+// so that any replica can serve an operation another replica is running
+type OperationStore struct {
+	clientset kubernetes.Interface
+	namespace string
+}
+
+func NewOperationStore() (*OperationStore, error) {
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get kubernetes config: %w", err)
+	}
+
+	clientset, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create kubernetes client: %w", err)
+	}
+
+	namespace := os.Getenv("NAMESPACE")
+	if namespace == "" {
+		namespace = defaultNamespace
+	}
+
+	return newOperationStore(clientset, namespace), nil
+}
+
+func newOperationStore(clientset kubernetes.Interface, namespace string) *OperationStore {
+	return &OperationStore{
+		clientset: clientset,
+		namespace: namespace,
+	}
+}
+
+// SaveOperation writes op as it stands, creating its ConfigMap the first
+// time. Only the replica running an operation writes it, so the write is
+// unconditional. A result too large for a ConfigMap, such as one with long
+// test logs, is left out; the release itself can still be fetched.
+func (s *OperationStore) SaveOperation(ctx context.Context, op model.Operation) error {
+	data, err := json.Marshal(op)
+	if err == nil && len(data) > maxOperationSize && op.Result != nil {
+		op.Result = nil
+		data, err = json.Marshal(op)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to marshal operation %s: %w", op.ID, err)
+	}
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      operationConfigMapPrefix + op.ID,
+			Namespace: s.namespace,
+			Labels:    map[string]string{operationLabel: "true"},
+		},
+		Data: map[string]string{operationDataKey: string(data)},
+	}
+	if op.CompletedAt != nil {
+		cm.Annotations = map[string]string{operationCompletedAtKey: op.CompletedAt.Format(time.RFC3339Nano)}
+	}
+
+	configMaps := s.clientset.CoreV1().ConfigMaps(s.namespace)
+	_, err = configMaps.Update(ctx, cm, metav1.UpdateOptions{})
+	if errors.IsNotFound(err) {
+		_, err = configMaps.Create(ctx, cm, metav1.CreateOptions{})
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save operation %s: %w", op.ID, err)
+	}
+	return nil
+}
+
+// GetOperation returns the operation with the given ID, or nil if there is
+// none
+func (s *OperationStore) GetOperation(ctx context.Context, id string) (*model.Operation, error) {
+	cm, err := s.clientset.CoreV1().ConfigMaps(s.namespace).Get(ctx, operationConfigMapPrefix+id, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get operation %s: %w", id, err)
+	}
+
+	var op model.Operation
+	if err := json.Unmarshal([]byte(cm.Data[operationDataKey]), &op); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal operation %s: %w", id, err)
+	}
+	return &op, nil
+}
+
+// PruneOperations deletes the operations that completed before
+// completedBefore. Operations still running are kept.
+func (s *OperationStore) PruneOperations(ctx context.Context, completedBefore time.Time) error {
+	configMaps := s.clientset.CoreV1().ConfigMaps(s.namespace)
+	list, err := configMaps.List(ctx, metav1.ListOptions{LabelSelector: operationLabel + "=true"})
+	if err != nil {
+		return fmt.Errorf("failed to list operations: %w", err)
+	}
+
+	for _, cm := range list.Items {
+		completedAt, err := time.Parse(time.RFC3339Nano, cm.Annotations[operationCompletedAtKey])
+		if err != nil || !completedAt.Before(completedBefore) {
+			continue
+		}
+		err = configMaps.Delete(ctx, cm.Name, metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete operation configmap %s: %w", cm.Name, err)
+		}
+	}
+	return nil
+}
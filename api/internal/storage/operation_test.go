@@ -0,0 +1,115 @@
+package storage
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/helm-version-manager/api/internal/model"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestOperationStore(t *testing.T) {
+	ctx := context.Background()
+	store := newOperationStore(fake.NewClientset(), testNamespace)
+
+	op := model.Operation{
+		ID:        "0123456789abcdef",
+		Type:      model.OperationUpgrade,
+		Namespace: "apps",
+		Name:      "web",
+		Status:    model.OperationRunning,
+		Events:    []model.OperationEvent{{Sequence: 1, Phase: model.PhaseApply, Message: "Applying"}},
+	}
+	if err := store.SaveOperation(ctx, op); err != nil {
+		t.Fatalf("failed to save operation: %v", err)
+	}
+
+	completedAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	op.Status = model.OperationSucceeded
+	op.Events = append(op.Events, model.OperationEvent{Sequence: 2, Phase: model.PhaseDone, Message: "Operation completed"})
+	op.CompletedAt = &completedAt
+	if err := store.SaveOperation(ctx, op); err != nil {
+		t.Fatalf("failed to save completed operation: %v", err)
+	}
+
+	got, err := store.GetOperation(ctx, op.ID)
+	if err != nil {
+		t.Fatalf("failed to get operation: %v", err)
+	}
+	if got == nil || got.Status != model.OperationSucceeded || len(got.Events) != 2 || !got.CompletedAt.Equal(completedAt) {
+		t.Errorf("expected the completed operation, got %+v", got)
+	}
+
+	missing, err := store.GetOperation(ctx, "missing")
+	if err != nil || missing != nil {
+		t.Errorf("expected no operation, got %+v, %v", missing, err)
+	}
+}
+
+func TestOperationStoreDropsLargeResult(t *testing.T) {
+	ctx := context.Background()
+	store := newOperationStore(fake.NewClientset(), testNamespace)
+
+	completedAt := time.Now().UTC()
+	op := model.Operation{
+		ID:          "large",
+		Status:      model.OperationSucceeded,
+		CompletedAt: &completedAt,
+		Result: &model.Release{
+			Name:  "web",
+			Tests: &model.TestResult{Tests: []model.TestHookResult{{Name: "web-test", Logs: strings.Repeat("x", maxOperationSize)}}},
+		},
+	}
+	if err := store.SaveOperation(ctx, op); err != nil {
+		t.Fatalf("failed to save operation: %v", err)
+	}
+
+	got, err := store.GetOperation(ctx, op.ID)
+	if err != nil {
+		t.Fatalf("failed to get operation: %v", err)
+	}
+	if got == nil || got.Status != model.OperationSucceeded || got.Result != nil {
+		t.Errorf("expected the completed operation without its result, got %+v", got)
+	}
+}
+
+func TestOperationStorePrune(t *testing.T) {
+	ctx := context.Background()
+	clientset := fake.NewClientset()
+	store := newOperationStore(clientset, testNamespace)
+
+	now := time.Date(2026, 1, 2, 12, 0, 0, 0, time.UTC)
+	old := now.Add(-2 * time.Hour)
+	recent := now.Add(-time.Minute)
+	for _, op := range []model.Operation{
+		{ID: "old", Status: model.OperationSucceeded, CompletedAt: &old},
+		{ID: "recent", Status: model.OperationFailed, CompletedAt: &recent},
+		{ID: "running", Status: model.OperationRunning},
+	} {
+		if err := store.SaveOperation(ctx, op); err != nil {
+			t.Fatalf("failed to save operation %s: %v", op.ID, err)
+		}
+	}
+
+	if err := store.PruneOperations(ctx, now.Add(-time.Hour)); err != nil {
+		t.Fatalf("failed to prune operations: %v", err)
+	}
+
+	list, err := clientset.CoreV1().ConfigMaps(testNamespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("failed to list configmaps: %v", err)
+	}
+	var names []string
+	for _, cm := range list.Items {
+		names = append(names, cm.Name)
+	}
+	sort.Strings(names)
+	want := []string{operationConfigMapPrefix + "recent", operationConfigMapPrefix + "running"}
+	if len(names) != len(want) || names[0] != want[0] || names[1] != want[1] {
+		t.Errorf("expected %v to be kept, got %v", want, names)
+	}
+}
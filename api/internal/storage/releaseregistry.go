@@ -0,0 +1,220 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/helm-version-manager/api/internal/model"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client/config"
+)
+
+const (
+	releaseRegistryKind = "ReleaseRegistry"
+	registryRuleKind    = "RegistryRule"
+)
+
+// releaseRegistryResource and registryRuleResource are the custom
+// resources installed by the chart's CRDs
+var (
+	releaseRegistryResource = schema.GroupVersionResource{
+		Group:    "helm-version-manager.io",
+		Version:  "v1alpha1",
+		Resource: "releaseregistries",
+	}
+	registryRuleResource = schema.GroupVersionResource{
+		Group:    "helm-version-manager.io",
+		Version:  "v1alpha1",
+		Resource: "registryrules",
+	}
+)
+
+// ReleaseRegistryStore keeps each registry mapping in a ReleaseRegistry
+// resource named after the release, in the release's namespace, and each
+// rule in a cluster-scoped RegistryRule named after its ID, so that they
+// can be inspected with kubectl and managed with GitOps
+type ReleaseRegistryStore struct {
+	client dynamic.Interface
+}
+
+func NewReleaseRegistryStore() (*ReleaseRegistryStore, error) {
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get kubernetes config: %w", err)
+	}
+
+	client, err := dynamic.NewForConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create dynamic client: %w", err)
+	}
+
+	return newReleaseRegistryStore(client), nil
+}
+
+func newReleaseRegistryStore(client dynamic.Interface) *ReleaseRegistryStore {
+	return &ReleaseRegistryStore{client: client}
+}
+
+func (s *ReleaseRegistryStore) GetMapping(ctx context.Context, namespace, releaseName string) (*model.RegistryMapping, error) {
+	obj, err := s.client.Resource(releaseRegistryResource).Namespace(namespace).Get(ctx, releaseName, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get release registry: %w", err)
+	}
+
+	mapping := mappingFromReleaseRegistry(obj)
+	return &mapping, nil
+}
+
+func (s *ReleaseRegistryStore) SetMapping(ctx context.Context, mapping model.RegistryMapping) error {
+	spec := map[string]any{"registry": mapping.Registry}
+	if mapping.ChartName != "" {
+		spec["chartName"] = mapping.ChartName
+	}
+
+	resource := s.client.Resource(releaseRegistryResource).Namespace(mapping.Namespace)
+	return upsert(ctx, resource, releaseRegistryResource.GroupVersion().WithKind(releaseRegistryKind), mapping.Namespace, mapping.ReleaseName, spec)
+}
+
+func (s *ReleaseRegistryStore) DeleteMapping(ctx context.Context, namespace, releaseName string) error {
+	err := s.client.Resource(releaseRegistryResource).Namespace(namespace).Delete(ctx, releaseName, metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete release registry: %w", err)
+	}
+
+	return nil
+}
+
+// ListMappings returns the mappings of all namespaces
+func (s *ReleaseRegistryStore) ListMappings(ctx context.Context) ([]model.RegistryMapping, error) {
+	list, err := s.client.Resource(releaseRegistryResource).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list release registries: %w", err)
+	}
+
+	result := make([]model.RegistryMapping, 0, len(list.Items))
+	for i := range list.Items {
+		result = append(result, mappingFromReleaseRegistry(&list.Items[i]))
+	}
+
+	return result, nil
+}
+
+func mappingFromReleaseRegistry(obj *unstructured.Unstructured) model.RegistryMapping {
+	chartName, _, _ := unstructured.NestedString(obj.Object, "spec", "chartName")
+	registry, _, _ := unstructured.NestedString(obj.Object, "spec", "registry")
+
+	return model.RegistryMapping{
+		Namespace:   obj.GetNamespace(),
+		ReleaseName: obj.GetName(),
+		ChartName:   chartName,
+		Registry:    registry,
+	}
+}
+
+func (s *ReleaseRegistryStore) GetRule(ctx context.Context, id string) (*model.RegistryRule, error) {
+	obj, err := s.client.Resource(registryRuleResource).Get(ctx, id, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get registry rule: %w", err)
+	}
+
+	rule := ruleFromRegistryRule(obj)
+	return &rule, nil
+}
+
+func (s *ReleaseRegistryStore) SetRule(ctx context.Context, rule model.RegistryRule) error {
+	spec := map[string]any{
+		"priority": int64(rule.Priority),
+		"registry": rule.Registry,
+	}
+	if rule.Chart != "" {
+		spec["chart"] = rule.Chart
+	}
+	if rule.NamespaceSelector != "" {
+		spec["namespaceSelector"] = rule.NamespaceSelector
+	}
+
+	resource := s.client.Resource(registryRuleResource)
+	return upsert(ctx, resource, registryRuleResource.GroupVersion().WithKind(registryRuleKind), "", rule.ID, spec)
+}
+
+func (s *ReleaseRegistryStore) DeleteRule(ctx context.Context, id string) error {
+	err := s.client.Resource(registryRuleResource).Delete(ctx, id, metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete registry rule: %w", err)
+	}
+
+	return nil
+}
+
+func (s *ReleaseRegistryStore) ListRules(ctx context.Context) ([]model.RegistryRule, error) {
+	list, err := s.client.Resource(registryRuleResource).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list registry rules: %w", err)
+	}
+
+	result := make([]model.RegistryRule, 0, len(list.Items))
+	for i := range list.Items {
+		result = append(result, ruleFromRegistryRule(&list.Items[i]))
+	}
+
+	return result, nil
+}
+
+func ruleFromRegistryRule(obj *unstructured.Unstructured) model.RegistryRule {
+	chart, _, _ := unstructured.NestedString(obj.Object, "spec", "chart")
+	namespaceSelector, _, _ := unstructured.NestedString(obj.Object, "spec", "namespaceSelector")
+	priority, _, _ := unstructured.NestedInt64(obj.Object, "spec", "priority")
+	registry, _, _ := unstructured.NestedString(obj.Object, "spec", "registry")
+
+	return model.RegistryRule{
+		ID:                obj.GetName(),
+		Chart:             chart,
+		NamespaceSelector: namespaceSelector,
+		Priority:          int(priority),
+		Registry:          registry,
+	}
+}
+
+// upsert creates or updates the named resource with spec. Updates are
+// conditional on the resourceVersion that was read and retried when
+// another writer changed or created the resource first.
+func upsert(ctx context.Context, resource dynamic.ResourceInterface, gvk schema.GroupVersionKind, namespace, name string, spec map[string]any) error {
+	kind := strings.ToLower(gvk.Kind)
+
+	return retry.OnError(retry.DefaultRetry, isWriteConflict, func() error {
+		obj, err := resource.Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			if !errors.IsNotFound(err) {
+				return fmt.Errorf("failed to get %s: %w", kind, err)
+			}
+
+			obj = &unstructured.Unstructured{}
+			obj.SetGroupVersionKind(gvk)
+			obj.SetNamespace(namespace)
+			obj.SetName(name)
+			obj.Object["spec"] = spec
+			if _, err := resource.Create(ctx, obj, metav1.CreateOptions{}); err != nil {
+				return fmt.Errorf("failed to create %s: %w", kind, err)
+			}
+			return nil
+		}
+
+		obj.Object["spec"] = spec
+		if _, err := resource.Update(ctx, obj, metav1.UpdateOptions{}); err != nil {
+			return fmt.Errorf("failed to update %s: %w", kind, err)
+		}
+		return nil
+	})
+}
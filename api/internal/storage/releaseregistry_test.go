@@ -0,0 +1,143 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/helm-version-manager/api/internal/model"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func newFakeDynamicClient(objects ...runtime.Object) *dynamicfake.FakeDynamicClient {
+	return dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		releaseRegistryResource: releaseRegistryKind + "List",
+		registryRuleResource:    registryRuleKind + "List",
+	}, objects...)
+}
+
+// newReleaseRegistry returns a ReleaseRegistry as it would be applied with
+// kubectl
+func newReleaseRegistry(namespace, name string, spec map[string]any) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: map[string]any{"spec": spec}}
+	obj.SetGroupVersionKind(releaseRegistryResource.GroupVersion().WithKind(releaseRegistryKind))
+	obj.SetNamespace(namespace)
+	obj.SetName(name)
+	return obj
+}
+
+func sortMappings(mappings []model.RegistryMapping) {
+	sort.Slice(mappings, func(i, j int) bool {
+		return mappings[i].Namespace+"/"+mappings[i].ReleaseName < mappings[j].Namespace+"/"+mappings[j].ReleaseName
+	})
+}
+
+func TestReleaseRegistryStore(t *testing.T) {
+	client := newFakeDynamicClient(
+		newReleaseRegistry("infra", "ingress", map[string]any{"registry": "oci://registry.example.com/charts"}),
+	)
+	store := newReleaseRegistryStore(client)
+	ctx := context.Background()
+
+	got, err := store.GetMapping(ctx, "apps", "web")
+	if err != nil || got != nil {
+		t.Fatalf("expected no mapping, got %+v (%v)", got, err)
+	}
+
+	web := model.RegistryMapping{Namespace: "apps", ReleaseName: "web", ChartName: "web", Registry: "oci://ghcr.io/example/charts"}
+	if err := store.SetMapping(ctx, web); err != nil {
+		t.Fatalf("failed to set mapping: %v", err)
+	}
+
+	obj, err := client.Resource(releaseRegistryResource).Namespace("apps").Get(ctx, "web", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("expected release registry to be created: %v", err)
+	}
+	if registry, _, _ := unstructured.NestedString(obj.Object, "spec", "registry"); registry != web.Registry {
+		t.Errorf("expected spec.registry %q, got %q", web.Registry, registry)
+	}
+
+	// Setting it again updates the existing resource
+	web.Registry = "oci://ghcr.io/example/stable"
+	if err := store.SetMapping(ctx, web); err != nil {
+		t.Fatalf("failed to update mapping: %v", err)
+	}
+	got, err = store.GetMapping(ctx, "apps", "web")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got == nil || *got != web {
+		t.Errorf("expected %+v, got %+v", web, got)
+	}
+
+	mappings, err := store.ListMappings(ctx)
+	if err != nil {
+		t.Fatalf("failed to list mappings: %v", err)
+	}
+	sortMappings(mappings)
+	want := []model.RegistryMapping{
+		{Namespace: "apps", ReleaseName: "web", ChartName: "web", Registry: "oci://ghcr.io/example/stable"},
+		{Namespace: "infra", ReleaseName: "ingress", Registry: "oci://registry.example.com/charts"},
+	}
+	if fmt.Sprint(mappings) != fmt.Sprint(want) {
+		t.Errorf("expected mappings of all namespaces %+v, got %+v", want, mappings)
+	}
+
+	if err := store.DeleteMapping(ctx, "apps", "web"); err != nil {
+		t.Fatalf("failed to delete mapping: %v", err)
+	}
+	if err := store.DeleteMapping(ctx, "apps", "web"); err != nil {
+		t.Errorf("expected deleting a missing mapping to succeed, got %v", err)
+	}
+	if got, _ := store.GetMapping(ctx, "apps", "web"); got != nil {
+		t.Errorf("expected mapping to be deleted, got %+v", got)
+	}
+}
+
+func TestReleaseRegistryStoreRules(t *testing.T) {
+	client := newFakeDynamicClient()
+	store := newReleaseRegistryStore(client)
+	ctx := context.Background()
+
+	rule := model.RegistryRule{ID: "ourorg", Chart: "ourorg-*", NamespaceSelector: "team=payments", Priority: 10, Registry: "oci://ghcr.io/ourorg/charts"}
+	if err := store.SetRule(ctx, rule); err != nil {
+		t.Fatalf("failed to set rule: %v", err)
+	}
+
+	obj, err := client.Resource(registryRuleResource).Get(ctx, "ourorg", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("expected registry rule to be created: %v", err)
+	}
+	if obj.GetNamespace() != "" {
+		t.Errorf("expected a cluster-scoped rule, got namespace %q", obj.GetNamespace())
+	}
+
+	rule.Priority = 20
+	if err := store.SetRule(ctx, rule); err != nil {
+		t.Fatalf("failed to update rule: %v", err)
+	}
+	got, err := store.GetRule(ctx, "ourorg")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got == nil || *got != rule {
+		t.Errorf("expected %+v, got %+v", rule, got)
+	}
+
+	rules, err := store.ListRules(ctx)
+	if err != nil || len(rules) != 1 {
+		t.Fatalf("expected one rule, got %+v (%v)", rules, err)
+	}
+
+	if err := store.DeleteRule(ctx, "ourorg"); err != nil {
+		t.Fatalf("failed to delete rule: %v", err)
+	}
+	if got, _ := store.GetRule(ctx, "ourorg"); got != nil {
+		t.Errorf("expected rule to be deleted, got %+v", got)
+	}
+}
@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/helm-version-manager/api/internal/model"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// ValidateRule checks that rule can be stored under its ID and matched
+func ValidateRule(rule model.RegistryRule) error {
+	if errs := validation.IsDNS1123Subdomain(rule.ID); len(errs) > 0 {
+		return fmt.Errorf("invalid rule id %q: %s", rule.ID, strings.Join(errs, "; "))
+	}
+	if rule.Registry == "" {
+		return fmt.Errorf("registry is required")
+	}
+	if _, err := path.Match(rule.Chart, ""); err != nil {
+		return fmt.Errorf("invalid chart pattern %q: %w", rule.Chart, err)
+	}
+	if _, err := labels.Parse(rule.NamespaceSelector); err != nil {
+		return fmt.Errorf("invalid namespace selector %q: %w", rule.NamespaceSelector, err)
+	}
+	return nil
+}
+
+// sortRules orders rules the way they are tried: highest priority first,
+// then by ID
+func sortRules(rules []model.RegistryRule) {
+	sort.Slice(rules, func(i, j int) bool {
+		if rules[i].Priority != rules[j].Priority {
+			return rules[i].Priority > rules[j].Priority
+		}
+		return rules[i].ID < rules[j].ID
+	})
+}
+
+// matchRule reports whether rule applies to a release of chartName in
+// namespace, whose labels are namespaceLabels, and explains why
+func matchRule(rule model.RegistryRule, chartName, namespace string, namespaceLabels map[string]string) (bool, string) {
+	var reasons []string
+
+	if rule.Chart != "" {
+		ok, err := path.Match(rule.Chart, chartName)
+		if err != nil {
+			return false, fmt.Sprintf("Invalid chart pattern %q: %v", rule.Chart, err)
+		}
+		if !ok {
+			return false, fmt.Sprintf("Chart %q does not match %q", chartName, rule.Chart)
+		}
+		reasons = append(reasons, fmt.Sprintf("chart %q matches %q", chartName, rule.Chart))
+	}
+
+	if rule.NamespaceSelector != "" {
+		selector, err := labels.Parse(rule.NamespaceSelector)
+		if err != nil {
+			return false, fmt.Sprintf("Invalid namespace selector %q: %v", rule.NamespaceSelector, err)
+		}
+		if !selector.Matches(labels.Set(namespaceLabels)) {
+			return false, fmt.Sprintf("Labels of namespace %s do not match %q", namespace, rule.NamespaceSelector)
+		}
+		reasons = append(reasons, fmt.Sprintf("labels of namespace %s match %q", namespace, rule.NamespaceSelector))
+	}
+
+	if len(reasons) == 0 {
+		return true, "Rule matches every release"
+	}
+	reason := strings.Join(reasons, " and ")
+	return true, strings.ToUpper(reason[:1]) + reason[1:]
+}
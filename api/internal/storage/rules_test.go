@@ -0,0 +1,106 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/helm-version-manager/api/internal/model"
+)
+
+func TestValidateRule(t *testing.T) {
+	testCases := []struct {
+		name    string
+		rule    model.RegistryRule
+		wantErr string
+	}{
+		{name: "valid", rule: model.RegistryRule{ID: "ourorg-charts", Chart: "ourorg-*", NamespaceSelector: "team in (a,b)", Registry: "oci://ghcr.io/ourorg/charts"}},
+		{name: "default", rule: model.RegistryRule{ID: "default", Registry: "oci://ghcr.io/ourorg/charts"}},
+		{name: "missing id", rule: model.RegistryRule{Registry: "oci://ghcr.io/ourorg/charts"}, wantErr: "invalid rule id"},
+		{name: "invalid id", rule: model.RegistryRule{ID: "Our_Org", Registry: "oci://ghcr.io/ourorg/charts"}, wantErr: "invalid rule id"},
+		{name: "missing registry", rule: model.RegistryRule{ID: "ourorg"}, wantErr: "registry is required"},
+		{name: "invalid glob", rule: model.RegistryRule{ID: "ourorg", Chart: "ourorg-[", Registry: "oci://ghcr.io/ourorg/charts"}, wantErr: "invalid chart pattern"},
+		{name: "invalid selector", rule: model.RegistryRule{ID: "ourorg", NamespaceSelector: "team in", Registry: "oci://ghcr.io/ourorg/charts"}, wantErr: "invalid namespace selector"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateRule(tc.rule)
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestMatchRule(t *testing.T) {
+	labels := map[string]string{"team": "payments", "env": "prod"}
+
+	testCases := []struct {
+		name       string
+		rule       model.RegistryRule
+		wantMatch  bool
+		wantReason string
+	}{
+		{
+			name:       "chart and selector",
+			rule:       model.RegistryRule{Chart: "ourorg-*", NamespaceSelector: "team=payments,env!=dev"},
+			wantMatch:  true,
+			wantReason: `Chart "ourorg-api" matches "ourorg-*" and labels of namespace payments match "team=payments,env!=dev"`,
+		},
+		{
+			name:       "chart mismatch",
+			rule:       model.RegistryRule{Chart: "nginx*", NamespaceSelector: "team=payments"},
+			wantReason: `Chart "ourorg-api" does not match "nginx*"`,
+		},
+		{
+			name:       "selector mismatch",
+			rule:       model.RegistryRule{NamespaceSelector: "env=dev"},
+			wantReason: `Labels of namespace payments do not match "env=dev"`,
+		},
+		{
+			name:       "default",
+			rule:       model.RegistryRule{},
+			wantMatch:  true,
+			wantReason: "Rule matches every release",
+		},
+		{
+			// Rules stored through the CRD are not validated by helm-ui
+			name:       "invalid glob",
+			rule:       model.RegistryRule{Chart: "["},
+			wantReason: `Invalid chart pattern "[": syntax error in pattern`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			matched, reason := matchRule(tc.rule, "ourorg-api", "payments", labels)
+			if matched != tc.wantMatch || reason != tc.wantReason {
+				t.Errorf("expected (%v, %q), got (%v, %q)", tc.wantMatch, tc.wantReason, matched, reason)
+			}
+		})
+	}
+}
+
+func TestSortRules(t *testing.T) {
+	rules := []model.RegistryRule{
+		{ID: "b", Priority: 0},
+		{ID: "c", Priority: 10},
+		{ID: "a", Priority: 0},
+		{ID: "d", Priority: -5},
+	}
+	sortRules(rules)
+
+	var ids []string
+	for _, r := range rules {
+		ids = append(ids, r.ID)
+	}
+	if got := strings.Join(ids, ","); got != "c,a,b,d" {
+		t.Errorf("expected c,a,b,d, got %s", got)
+	}
+}
@@ -0,0 +1,101 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/helm-version-manager/api/internal/model"
+)
+
+const (
+	// registryStorageEnv selects the registry mapping backend
+	registryStorageEnv = "HELM_UI_REGISTRY_STORAGE"
+
+	BackendConfigMap = "configmap"
+	BackendCRD       = "crd"
+)
+
+// MappingStore is implemented by the registry mapping backends, which keep
+// registry rules beside the per-release mappings
+type MappingStore interface {
+	GetMapping(ctx context.Context, namespace, releaseName string) (*model.RegistryMapping, error)
+	SetMapping(ctx context.Context, mapping model.RegistryMapping) error
+	DeleteMapping(ctx context.Context, namespace, releaseName string) error
+	ListMappings(ctx context.Context) ([]model.RegistryMapping, error)
+
+	GetRule(ctx context.Context, id string) (*model.RegistryRule, error)
+	SetRule(ctx context.Context, rule model.RegistryRule) error
+	DeleteRule(ctx context.Context, id string) error
+	ListRules(ctx context.Context) ([]model.RegistryRule, error)
+}
+
+// NewMappingStore creates the backend named by HELM_UI_REGISTRY_STORAGE,
+// the ConfigMap backend when it is unset
+func NewMappingStore() (MappingStore, error) {
+	return NewMappingStoreForBackend(os.Getenv(registryStorageEnv))
+}
+
+// NewMappingStoreForBackend creates the named registry mapping backend
+func NewMappingStoreForBackend(backend string) (MappingStore, error) {
+	switch backend {
+	case "", BackendConfigMap:
+		store, err := NewRegistryStore()
+		if err != nil {
+			return nil, err
+		}
+		return store, nil
+	case BackendCRD:
+		store, err := NewReleaseRegistryStore()
+		if err != nil {
+			return nil, err
+		}
+		return store, nil
+	default:
+		return nil, fmt.Errorf("unknown registry storage backend %q, expected %q or %q", backend, BackendConfigMap, BackendCRD)
+	}
+}
+
+// MigrateMappings copies every mapping in from to to, overwriting mappings
+// to already has for the same release. Mappings that fail to copy do not
+// stop the migration; their errors are returned together with the
+// mappings that were copied.
+func MigrateMappings(ctx context.Context, from, to MappingStore) ([]model.RegistryMapping, error) {
+	mappings, err := from.ListMappings(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list mappings: %w", err)
+	}
+
+	var migrated []model.RegistryMapping
+	var errs []error
+	for _, mapping := range mappings {
+		if err := to.SetMapping(ctx, mapping); err != nil {
+			errs = append(errs, fmt.Errorf("failed to migrate mapping of %s/%s: %w", mapping.Namespace, mapping.ReleaseName, err))
+			continue
+		}
+		migrated = append(migrated, mapping)
+	}
+
+	return migrated, errors.Join(errs...)
+}
+
+// MigrateRules copies every rule in from to to, like MigrateMappings
+func MigrateRules(ctx context.Context, from, to MappingStore) ([]model.RegistryRule, error) {
+	rules, err := from.ListRules(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list rules: %w", err)
+	}
+
+	var migrated []model.RegistryRule
+	var errs []error
+	for _, rule := range rules {
+		if err := to.SetRule(ctx, rule); err != nil {
+			errs = append(errs, fmt.Errorf("failed to migrate rule %s: %w", rule.ID, err))
+			continue
+		}
+		migrated = append(migrated, rule)
+	}
+
+	return migrated, errors.Join(errs...)
+}
@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/helm-version-manager/api/internal/model"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestMigrateMappings(t *testing.T) {
+	ctx := context.Background()
+	from := newRegistryStore(newVersionedClientset(), testNamespace)
+	for _, m := range []model.RegistryMapping{
+		{Namespace: "apps", ReleaseName: "web", ChartName: "web", Registry: "oci://ghcr.io/example/charts"},
+		{Namespace: "infra", ReleaseName: "ingress", ChartName: "ingress-nginx", Registry: "oci://registry.example.com/charts"},
+		{Namespace: "broken", ReleaseName: "api", Registry: "oci://ghcr.io/example/charts"},
+	} {
+		if err := from.SetMapping(ctx, m); err != nil {
+			t.Fatalf("failed to set mapping: %v", err)
+		}
+	}
+
+	// An existing resource is overwritten by the ConfigMap's mapping
+	client := newFakeDynamicClient(
+		newReleaseRegistry("apps", "web", map[string]any{"registry": "oci://ghcr.io/example/old"}),
+	)
+	client.PrependReactor("create", "releaseregistries", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetNamespace() == "broken" {
+			return true, nil, errors.New("namespace is terminating")
+		}
+		return false, nil, nil
+	})
+	to := newReleaseRegistryStore(client)
+
+	migrated, err := MigrateMappings(ctx, from, to)
+	if err == nil || !strings.Contains(err.Error(), "failed to migrate mapping of broken/api") {
+		t.Errorf("expected the failed mapping to be reported, got %v", err)
+	}
+	if len(migrated) != 2 {
+		t.Errorf("expected 2 migrated mappings, got %+v", migrated)
+	}
+
+	got, err := to.ListMappings(ctx)
+	if err != nil {
+		t.Fatalf("failed to list mappings: %v", err)
+	}
+	want := []model.RegistryMapping{
+		{Namespace: "apps", ReleaseName: "web", ChartName: "web", Registry: "oci://ghcr.io/example/charts"},
+		{Namespace: "infra", ReleaseName: "ingress", ChartName: "ingress-nginx", Registry: "oci://registry.example.com/charts"},
+	}
+	sortMappings(got)
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+}
+
+func TestMigrateRules(t *testing.T) {
+	ctx := context.Background()
+	from := newRegistryStore(newVersionedClientset(), testNamespace)
+	rule := model.RegistryRule{ID: "ourorg", Chart: "ourorg-*", Priority: 10, Registry: "oci://ghcr.io/ourorg/charts"}
+	if err := from.SetRule(ctx, rule); err != nil {
+		t.Fatalf("failed to set rule: %v", err)
+	}
+
+	to := newReleaseRegistryStore(newFakeDynamicClient())
+	migrated, err := MigrateRules(ctx, from, to)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(migrated) != 1 {
+		t.Errorf("expected 1 migrated rule, got %+v", migrated)
+	}
+	got, err := to.GetRule(ctx, "ourorg")
+	if err != nil || got == nil || *got != rule {
+		t.Errorf("expected %+v, got %+v (%v)", rule, got, err)
+	}
+}
+
+func TestNewMappingStoreForBackend(t *testing.T) {
+	_, err := NewMappingStoreForBackend("etcd")
+	if err == nil || !strings.Contains(err.Error(), `unknown registry storage backend "etcd"`) {
+		t.Errorf("expected unknown backend error, got %v", err)
+	}
+}
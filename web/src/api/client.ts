@@ -3,11 +3,33 @@ import type {
   Release,
   ReleaseFilter,
   ChartVersion,
-  ReleaseHistory,
+  HistoryPage,
   RegistryMapping,
+  RegistryRule,
+  RegistryResolution,
+  RegistryMappings,
+  RegistryImportMode,
+  RegistryImportReport,
+  RegistryCredential,
   SetRegistryRequest,
   VersionUpgradeRequest,
   ValuesUpdateRequest,
+  VersionQuery,
+  UpgradePreview,
+  RevisionDiff,
+  HistoryQuery,
+  InstallRequest,
+  UninstallRequest,
+  UninstallResult,
+  RollbackRequest,
+  ReleaseManifest,
+  ReleaseNotes,
+  ReleaseHooks,
+  ReleaseResources,
+  AuditRecord,
+  TestRequest,
+  TestResult,
+  Operation,
 } from '../types';
 
 const API_BASE_URL = import.meta.env.VITE_API_URL || '/api';
@@ -19,6 +41,41 @@ const client = axios.create({
   },
 });
 
+// OperationError is thrown when an operation started by the API fails
+export class OperationError extends Error {
+  constructor(public operation: Operation) {
+    super(operation.error || 'Operation failed');
+    this.name = 'OperationError';
+  }
+}
+
+// Operation APIs
+export const getOperation = async (id: string): Promise<Operation> => {
+  const { data } = await client.get<Operation>(`/operations/${id}`);
+  return data;
+};
+
+const OPERATION_POLL_INTERVAL_MS = 1000;
+
+// waitForOperation polls an operation until it completes and resolves to the
+// resulting release
+export const waitForOperation = async (
+  id: string,
+  onProgress?: (operation: Operation) => void
+): Promise<Release> => {
+  for (;;) {
+    const operation = await getOperation(id);
+    onProgress?.(operation);
+    if (operation.status === 'failed') {
+      throw new OperationError(operation);
+    }
+    if (operation.status === 'succeeded') {
+      return operation.result as Release;
+    }
+    await new Promise((resolve) => setTimeout(resolve, OPERATION_POLL_INTERVAL_MS));
+  }
+};
+
 // Release APIs
 export const getReleases = async (filter?: ReleaseFilter): Promise<Release[]> => {
   const params = new URLSearchParams();
@@ -39,8 +96,52 @@ export const getRelease = async (namespace: string, name: string): Promise<Relea
   return data;
 };
 
-export const getAvailableVersions = async (namespace: string, name: string): Promise<ChartVersion[]> => {
-  const { data } = await client.get<ChartVersion[]>(`/releases/${namespace}/${name}/versions`);
+export const installRelease = async (namespace: string, request: InstallRequest): Promise<Release> => {
+  const { data } = await client.post<Release>(`/releases/${namespace}`, request);
+  return data;
+};
+
+export const uninstallRelease = async (
+  namespace: string,
+  name: string,
+  request?: UninstallRequest
+): Promise<UninstallResult> => {
+  const params = new URLSearchParams();
+  if (request?.keepHistory !== undefined) {
+    params.append('keepHistory', String(request.keepHistory));
+  }
+  if (request?.wait !== undefined) {
+    params.append('wait', String(request.wait));
+  }
+  if (request?.timeout) {
+    params.append('timeout', request.timeout);
+  }
+  const queryString = params.toString();
+  const url = queryString ? `/releases/${namespace}/${name}?${queryString}` : `/releases/${namespace}/${name}`;
+  const { data } = await client.delete<UninstallResult>(url);
+  return data;
+};
+
+export const getAvailableVersions = async (
+  namespace: string,
+  name: string,
+  query?: VersionQuery
+): Promise<ChartVersion[]> => {
+  const params = new URLSearchParams();
+  if (query?.limit !== undefined) {
+    params.append('limit', String(query.limit));
+  }
+  if (query?.includePrerelease !== undefined) {
+    params.append('includePrerelease', String(query.includePrerelease));
+  }
+  if (query?.constraint) {
+    params.append('constraint', query.constraint);
+  }
+  const queryString = params.toString();
+  const url = queryString
+    ? `/releases/${namespace}/${name}/versions?${queryString}`
+    : `/releases/${namespace}/${name}/versions`;
+  const { data } = await client.get<ChartVersion[]>(url);
   return data;
 };
 
@@ -49,12 +150,57 @@ export const upgradeRelease = async (
   name: string,
   request: VersionUpgradeRequest
 ): Promise<Release> => {
-  const { data } = await client.put<Release>(`/releases/${namespace}/${name}`, request);
+  const { data } = await client.put<Operation>(`/releases/${namespace}/${name}`, request);
+  return waitForOperation(data.id);
+};
+
+export const previewUpgrade = async (
+  namespace: string,
+  name: string,
+  request: VersionUpgradeRequest
+): Promise<UpgradePreview> => {
+  const { data } = await client.post<UpgradePreview>(`/releases/${namespace}/${name}/upgrade/preview`, request);
+  return data;
+};
+
+export const getReleaseHistory = async (
+  namespace: string,
+  name: string,
+  query?: HistoryQuery
+): Promise<HistoryPage> => {
+  const params = new URLSearchParams();
+  if (query?.max !== undefined) {
+    params.append('max', String(query.max));
+  }
+  if (query?.before !== undefined) {
+    params.append('before', String(query.before));
+  }
+  const queryString = params.toString();
+  const url = queryString
+    ? `/releases/${namespace}/${name}/history?${queryString}`
+    : `/releases/${namespace}/${name}/history`;
+  const { data } = await client.get<HistoryPage>(url);
+  return data;
+};
+
+export const getRevisionValues = async (
+  namespace: string,
+  name: string,
+  revision: number
+): Promise<Record<string, unknown>> => {
+  const { data } = await client.get<Record<string, unknown>>(
+    `/releases/${namespace}/${name}/history/${revision}/values`
+  );
   return data;
 };
 
-export const getReleaseHistory = async (namespace: string, name: string): Promise<ReleaseHistory[]> => {
-  const { data } = await client.get<ReleaseHistory[]>(`/releases/${namespace}/${name}/history`);
+export const diffRevisions = async (
+  namespace: string,
+  name: string,
+  from: number,
+  to: number
+): Promise<RevisionDiff> => {
+  const { data } = await client.get<RevisionDiff>(`/releases/${namespace}/${name}/diff?from=${from}&to=${to}`);
   return data;
 };
 
@@ -77,27 +223,201 @@ export const deleteRegistry = async (namespace: string, name: string): Promise<v
   await client.delete(`/releases/${namespace}/${name}/registry`);
 };
 
+export const resolveRegistry = async (namespace: string, name: string): Promise<RegistryResolution> => {
+  const { data } = await client.get<RegistryResolution>(`/releases/${namespace}/${name}/registry/resolve`);
+  return data;
+};
+
+// Registry rule APIs; rules are listed in the order they are tried
+export const getRegistryRules = async (): Promise<RegistryRule[]> => {
+  const { data } = await client.get<RegistryRule[]>('/registry-rules');
+  return data;
+};
+
+export const createRegistryRule = async (rule: RegistryRule): Promise<RegistryRule> => {
+  const { data } = await client.post<RegistryRule>('/registry-rules', rule);
+  return data;
+};
+
+export const updateRegistryRule = async (rule: RegistryRule): Promise<RegistryRule> => {
+  const { data } = await client.put<RegistryRule>(`/registry-rules/${rule.id}`, rule);
+  return data;
+};
+
+export const deleteRegistryRule = async (id: string): Promise<void> => {
+  await client.delete(`/registry-rules/${id}`);
+};
+
+// Registry credential APIs; the Secret contents are never returned
+export const getRegistryCredentials = async (): Promise<RegistryCredential[]> => {
+  const { data } = await client.get<RegistryCredential[]>('/registry-credentials');
+  return data;
+};
+
+export const createRegistryCredential = async (credential: RegistryCredential): Promise<RegistryCredential> => {
+  const { data } = await client.post<RegistryCredential>('/registry-credentials', credential);
+  return data;
+};
+
+export const updateRegistryCredential = async (credential: RegistryCredential): Promise<RegistryCredential> => {
+  const { data } = await client.put<RegistryCredential>(`/registry-credentials/${credential.id}`, credential);
+  return data;
+};
+
+export const deleteRegistryCredential = async (id: string): Promise<void> => {
+  await client.delete(`/registry-credentials/${id}`);
+};
+
+// Registry mapping export and import, to move explicit mappings between
+// clusters
+export const exportRegistryMappings = async (): Promise<RegistryMappings> => {
+  const { data } = await client.get<RegistryMappings>('/registry-mappings/export');
+  return data;
+};
+
+export const exportRegistryMappingsYaml = async (): Promise<string> => {
+  const { data } = await client.get<string>('/registry-mappings/export', {
+    params: { format: 'yaml' },
+    responseType: 'text',
+  });
+  return data;
+};
+
+// importRegistryMappings accepts an exported JSON or YAML document. The
+// report is also returned when entries are invalid and nothing was applied
+// (422), and when some writes failed and the import was partly applied (500).
+export const importRegistryMappings = async (
+  document: string,
+  mode: RegistryImportMode = 'merge',
+  dryRun = false
+): Promise<RegistryImportReport> => {
+  const { data } = await client.post<RegistryImportReport>('/registry-mappings/import', document, {
+    params: { mode, dryRun },
+    headers: { 'Content-Type': 'application/yaml', Accept: 'application/json' },
+    validateStatus: (status) => (status >= 200 && status < 300) || status === 422 || status === 500,
+  });
+  // Other server errors carry a message instead of a report
+  if (!Array.isArray(data.results)) {
+    throw new Error((data as unknown as { message?: string }).message ?? 'Failed to import registry mappings');
+  }
+  return data;
+};
+
+// Release content APIs; revision defaults to the latest revision
+export const getReleaseManifest = async (
+  namespace: string,
+  name: string,
+  revision?: number
+): Promise<ReleaseManifest> => {
+  const { data } = await client.get<ReleaseManifest>(`/releases/${namespace}/${name}/manifest`, {
+    params: { revision },
+  });
+  return data;
+};
+
+export const getReleaseNotes = async (namespace: string, name: string, revision?: number): Promise<ReleaseNotes> => {
+  const { data } = await client.get<ReleaseNotes>(`/releases/${namespace}/${name}/notes`, { params: { revision } });
+  return data;
+};
+
+export const getReleaseHooks = async (namespace: string, name: string, revision?: number): Promise<ReleaseHooks> => {
+  const { data } = await client.get<ReleaseHooks>(`/releases/${namespace}/${name}/hooks`, { params: { revision } });
+  return data;
+};
+
+export const getReleaseResources = async (namespace: string, name: string): Promise<ReleaseResources> => {
+  const { data } = await client.get<ReleaseResources>(`/releases/${namespace}/${name}/resources`);
+  return data;
+};
+
+export const getAuditRecords = async (namespace: string, name: string): Promise<AuditRecord[]> => {
+  const { data } = await client.get<AuditRecord[]>(`/releases/${namespace}/${name}/audit`);
+  return data;
+};
+
+export const runReleaseTests = async (
+  namespace: string,
+  name: string,
+  request: TestRequest = {}
+): Promise<TestResult> => {
+  const { data } = await client.post<Operation>(`/releases/${namespace}/${name}/test`, request);
+  const release = await waitForOperation(data.id);
+  return release.tests as TestResult;
+};
+
 // Values APIs
 export const getValues = async (namespace: string, name: string): Promise<Record<string, unknown>> => {
   const { data } = await client.get<Record<string, unknown>>(`/releases/${namespace}/${name}/values`);
   return data;
 };
 
+// YAML values are returned with sorted keys so they can be kept in git
+export const getValuesYaml = async (namespace: string, name: string): Promise<string> => {
+  const { data } = await client.get<string>(`/releases/${namespace}/${name}/values`, {
+    params: { format: 'yaml' },
+    responseType: 'text',
+  });
+  return data;
+};
+
+// updateValuesYaml applies a YAML values file; keys set to null are removed
+// in merge mode
+export const updateValuesYaml = async (
+  namespace: string,
+  name: string,
+  yaml: string,
+  mode: 'merge' | 'replace' = 'merge'
+): Promise<Release> => {
+  const { data } = await client.put<Operation>(`/releases/${namespace}/${name}/values`, yaml, {
+    params: { mode },
+    headers: { 'Content-Type': 'application/yaml', Accept: 'application/json' },
+  });
+  return waitForOperation(data.id);
+};
+
+export const getChartValues = async (namespace: string, name: string): Promise<Record<string, unknown>> => {
+  const { data } = await client.get<Record<string, unknown>>(`/releases/${namespace}/${name}/values/defaults`);
+  return data;
+};
+
+export const getComputedValues = async (namespace: string, name: string): Promise<Record<string, unknown>> => {
+  const { data } = await client.get<Record<string, unknown>>(`/releases/${namespace}/${name}/values/computed`);
+  return data;
+};
+
+// getValuesSchema resolves to null when the chart ships no values.schema.json
+export const getValuesSchema = async (
+  namespace: string,
+  name: string
+): Promise<Record<string, unknown> | null> => {
+  try {
+    const { data } = await client.get<Record<string, unknown>>(`/releases/${namespace}/${name}/values/schema`);
+    return data;
+  } catch (err) {
+    if (axios.isAxiosError(err) && err.response?.status === 404) {
+      return null;
+    }
+    throw err;
+  }
+};
+
 export const updateValues = async (
   namespace: string,
   name: string,
   request: ValuesUpdateRequest
 ): Promise<Release> => {
-  const { data } = await client.put<Release>(`/releases/${namespace}/${name}/values`, request);
-  return data;
+  const { data } = await client.put<Operation>(`/releases/${namespace}/${name}/values`, request);
+  return waitForOperation(data.id);
 };
 
 // Rollback API
 export const rollbackRelease = async (
   namespace: string,
   name: string,
-  revision: number
+  revision: number,
+  options?: Omit<RollbackRequest, 'revision'>
 ): Promise<Release> => {
-  const { data } = await client.post<Release>(`/releases/${namespace}/${name}/rollback`, { revision });
-  return data;
+  const request: RollbackRequest = { ...options, revision };
+  const { data } = await client.post<Operation>(`/releases/${namespace}/${name}/rollback`, request);
+  return waitForOperation(data.id);
 };
@@ -33,10 +33,11 @@ interface HistoryDialogProps {
 }
 
 export default function HistoryDialog({ open, onClose, release, onRollbackSuccess }: HistoryDialogProps) {
-  const { data: history, isLoading, error } = useReleaseHistory(
+  const { data, isLoading, error, hasNextPage, fetchNextPage, isFetchingNextPage } = useReleaseHistory(
     release.namespace,
     release.name
   );
+  const history = data?.pages.flatMap((page) => page.history);
   const [rollbackConfirm, setRollbackConfirm] = useState<{
     open: boolean;
     revision: number;
@@ -57,7 +58,9 @@ export default function HistoryDialog({ open, onClose, release, onRollbackSucces
     setRollbackError(null);
 
     try {
-      await rollbackRelease(release.namespace, release.name, rollbackConfirm.revision);
+      await rollbackRelease(release.namespace, release.name, rollbackConfirm.revision, {
+        expectedRevision: release.revision,
+      });
       setRollbackConfirm(null);
       onClose();
       onRollbackSuccess?.();
@@ -149,6 +152,14 @@ export default function HistoryDialog({ open, onClose, release, onRollbackSucces
             </Table>
           </TableContainer>
         )}
+
+        {hasNextPage && (
+          <Box display="flex" justifyContent="center" mt={2}>
+            <Button onClick={() => fetchNextPage()} disabled={isFetchingNextPage}>
+              {isFetchingNextPage ? 'Loading...' : 'Load older revisions'}
+            </Button>
+          </Box>
+        )}
       </DialogContent>
       <DialogActions>
         <Button onClick={onClose}>Close</Button>
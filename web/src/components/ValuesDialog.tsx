@@ -138,7 +138,7 @@ export default function ValuesDialog({ open, onClose, release }: ValuesDialogPro
         {
           namespace: release.namespace,
           name: release.name,
-          request: { values: parsed },
+          request: { values: parsed, expectedRevision: release.revision },
         },
         {
           onSuccess: () => {
@@ -46,7 +46,7 @@ export default function VersionDialog({ open, onClose, release }: VersionDialogP
       {
         namespace: release.namespace,
         name: release.name,
-        request: { chartVersion: selectedVersion },
+        request: { chartVersion: selectedVersion, expectedRevision: release.revision },
       },
       {
         onSuccess: () => {
@@ -1,4 +1,4 @@
-import { useQuery, useMutation, useQueryClient } from '@tanstack/react-query';
+import { useQuery, useInfiniteQuery, useMutation, useQueryClient } from '@tanstack/react-query';
 import {
   getReleases,
   getRelease,
@@ -35,10 +35,14 @@ export const useAvailableVersions = (namespace: string, name: string) => {
   });
 };
 
+// useReleaseHistory loads history a page at a time, newest first; older
+// pages are fetched with fetchNextPage
 export const useReleaseHistory = (namespace: string, name: string) => {
-  return useQuery({
+  return useInfiniteQuery({
     queryKey: ['history', namespace, name],
-    queryFn: () => getReleaseHistory(namespace, name),
+    queryFn: ({ pageParam }) => getReleaseHistory(namespace, name, { before: pageParam }),
+    initialPageParam: undefined as number | undefined,
+    getNextPageParam: (lastPage) => lastPage.nextBefore,
     enabled: !!namespace && !!name,
   });
 };
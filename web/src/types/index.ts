@@ -8,6 +8,64 @@ export interface Release {
   updated: string;
   revision: number;
   hasRegistry: boolean;
+  verification?: Verification;
+  tests?: TestResult;
+}
+
+export type VerificationOutcome = 'verified' | 'rolled-back' | 'timed-out' | 'rollback-failed' | 'aborted';
+
+export interface Verification {
+  outcome: VerificationOutcome;
+  revision: number;
+  rolledBackTo?: number;
+  message: string;
+  startedAt: string;
+  completedAt: string;
+  resources: ResourceStatus[];
+}
+
+export type TestOutcome = 'tests-passed' | 'tests-failed';
+
+export interface TestRequest {
+  // Test names to run; a name prefixed with "!" is skipped instead
+  filter?: string[];
+  timeout?: string;
+  logs?: boolean;
+}
+
+export interface TestHookResult {
+  name: string;
+  kind: string;
+  phase: 'Succeeded' | 'Failed' | 'Running' | 'Unknown' | 'NotRun';
+  startedAt?: string;
+  completedAt?: string;
+  logs?: string;
+  logsError?: string;
+}
+
+export interface TestResult {
+  namespace: string;
+  name: string;
+  revision: number;
+  passed: boolean;
+  message?: string;
+  startedAt: string;
+  completedAt: string;
+  tests: TestHookResult[];
+  rolledBackTo?: number;
+}
+
+export interface AuditRecord {
+  id: string;
+  time: string;
+  namespace: string;
+  name: string;
+  action: 'upgrade' | 'update-values' | 'test';
+  chartVersion: string;
+  revision: number;
+  outcome: VerificationOutcome | TestOutcome;
+  rolledBackTo?: number;
+  message: string;
 }
 
 export interface ReleaseFilter {
@@ -15,6 +73,12 @@ export interface ReleaseFilter {
   hasRegistry?: boolean;
 }
 
+export interface VersionQuery {
+  limit?: number;
+  includePrerelease?: boolean;
+  constraint?: string;
+}
+
 export interface ChartVersion {
   version: string;
   appVersion: string;
@@ -26,26 +90,342 @@ export interface ReleaseHistory {
   updated: string;
   status: string;
   chart: string;
+  chartName: string;
   appVersion: string;
   description: string;
+  deployedBy?: string;
+  current: boolean;
+}
+
+export interface HistoryQuery {
+  max?: number;
+  before?: number;
+}
+
+// HistoryPage is a page of history, newest first; nextBefore is the cursor
+// for the next page and is absent on the last one
+export interface HistoryPage {
+  history: ReleaseHistory[];
+  nextBefore?: number;
 }
 
+export type RegistrySource = 'explicit' | 'rule' | 'chart-annotation' | 'release-label' | 'namespace-default';
+
 export interface RegistryMapping {
   namespace: string;
   releaseName: string;
   chartName: string;
   registry: string;
+  source?: RegistrySource;
+  ruleId?: string;
+}
+
+export interface RegistryRule {
+  id: string;
+  chart?: string;
+  namespaceSelector?: string;
+  priority: number;
+  registry: string;
+}
+
+export interface RegistryResolutionStep {
+  source: RegistrySource;
+  ruleId?: string;
+  matched: boolean;
+  reason: string;
+}
+
+export interface RegistryResolution {
+  namespace: string;
+  releaseName: string;
+  mapping?: RegistryMapping;
+  message: string;
+  steps: RegistryResolutionStep[];
+}
+
+export interface RegistryMappings {
+  mappings: RegistryMapping[];
+}
+
+export type RegistryImportMode = 'merge' | 'replace';
+
+export type RegistryImportAction = 'create' | 'update' | 'unchanged' | 'delete' | 'invalid' | 'failed';
+
+export interface RegistryImportResult {
+  namespace: string;
+  releaseName: string;
+  chartName?: string;
+  registry: string;
+  action: RegistryImportAction;
+  error?: string;
+}
+
+// Nothing is applied for dry runs or when any entry is invalid; applied is
+// also false when some writes failed, and only the failed entries were not
+// written
+export interface RegistryImportReport {
+  mode: RegistryImportMode;
+  dryRun: boolean;
+  applied: boolean;
+  results: RegistryImportResult[];
+  counts: Partial<Record<RegistryImportAction, number>>;
+}
+
+// RegistryCredential references a kubernetes.io/dockerconfigjson or
+// kubernetes.io/basic-auth Secret in helm-ui's namespace used for the
+// registries whose references start with registry. The Secret must list the
+// registry in its helm-ui/registries annotation.
+export interface RegistryCredential {
+  id: string;
+  registry: string;
+  secretName: string;
 }
 
 export interface SetRegistryRequest {
   registry: string;
 }
 
-export interface VersionUpgradeRequest {
+export interface UpgradeOptions {
+  atomic?: boolean;
+  wait?: boolean;
+  waitForJobs?: boolean;
+  timeout?: string;
+  force?: boolean;
+  disableHooks?: boolean;
+  cleanupOnFail?: boolean;
+  maxHistory?: number;
+  verify?: boolean;
+  verifyTimeout?: string;
+}
+
+export interface VersionUpgradeRequest extends UpgradeOptions {
   chartVersion: string;
   values?: Record<string, unknown>;
+  // Runs the release tests after the upgrade and rolls back if any fails
+  test?: TestRequest;
+  // Fails with 412 unless the release is still at this revision
+  expectedRevision?: number;
+}
+
+// The upgrade options without atomic and verify, which only apply to upgrades
+export type RollbackOptions = Omit<UpgradeOptions, 'atomic' | 'verify' | 'verifyTimeout'>;
+
+export interface RollbackRequest extends RollbackOptions {
+  revision: number;
+  expectedRevision?: number;
+}
+
+export interface InstallRequest {
+  name: string;
+  chart: string;
+  registry: string;
+  chartVersion?: string;
+  values?: Record<string, unknown>;
+}
+
+export interface UninstallRequest {
+  keepHistory?: boolean;
+  wait?: boolean;
+  timeout?: string;
+}
+
+export interface UninstallResult {
+  namespace: string;
+  name: string;
+  info?: string;
+  keptHistory: boolean;
+  registryMappingDeleted: boolean;
+}
+
+export type ValuesMode = 'merge' | 'replace' | 'json-patch';
+
+export interface ValuesUpdateRequest extends UpgradeOptions {
+  mode?: ValuesMode;
+  values?: Record<string, unknown>;
+  patch?: Record<string, unknown>[];
+  expectedRevision?: number;
+}
+
+// Returned with 412 Precondition Failed when the release changed since the
+// revision a request expected
+export interface RevisionConflictResponse {
+  message: string;
+  expectedRevision: number;
+  currentRevision: number;
+}
+
+export interface FieldError {
+  field: string;
+  message: string;
+}
+
+export interface ValidationErrorResponse {
+  message: string;
+  errors: FieldError[];
+}
+
+export type OperationType = 'upgrade' | 'update-values' | 'rollback' | 'test';
+
+// Operations that run synchronously but can hold a release's lock
+export type LockingOperationType = OperationType | 'install' | 'uninstall';
+
+export type OperationStatus = 'running' | 'succeeded' | 'failed';
+
+export type OperationPhase =
+  | 'pull-chart'
+  | 'render'
+  | 'apply'
+  | 'wait'
+  | 'verify'
+  | 'test'
+  | 'rollback'
+  | 'lock-lost'
+  | 'done'
+  | 'failed';
+
+export interface OperationEvent {
+  sequence: number;
+  time: string;
+  phase: OperationPhase;
+  message: string;
+}
+
+export interface Operation {
+  id: string;
+  type: OperationType;
+  namespace: string;
+  name: string;
+  status: OperationStatus;
+  phase: OperationPhase | '';
+  createdAt: string;
+  updatedAt: string;
+  completedAt?: string;
+  error?: string;
+  errors?: FieldError[];
+  result?: Release;
+  events: OperationEvent[];
+}
+
+export interface LockHolder {
+  namespace: string;
+  name: string;
+  operationId: string;
+  operation: LockingOperationType;
+  replica: string;
+  acquiredAt: string;
+  renewedAt: string;
+}
+
+// Returned with 409 Conflict while another operation holds the release
+export interface LockConflictResponse {
+  message: string;
+  holder: LockHolder;
+}
+
+export interface ManifestObject {
+  apiVersion: string;
+  kind: string;
+  namespace?: string;
+  name: string;
+  manifest: string;
+}
+
+export interface ReleaseManifest {
+  namespace: string;
+  name: string;
+  revision: number;
+  manifest: string;
+  objects: ManifestObject[];
+}
+
+export interface ReleaseNotes {
+  namespace: string;
+  name: string;
+  revision: number;
+  notes: string;
+}
+
+export interface HookRun {
+  phase?: string;
+  startedAt?: string;
+  completedAt?: string;
+}
+
+export interface ReleaseHook {
+  name: string;
+  kind: string;
+  path: string;
+  events: string[];
+  weight: number;
+  deletePolicies?: string[];
+  lastRun: HookRun;
+  manifest: string;
+}
+
+export interface ReleaseHooks {
+  namespace: string;
+  name: string;
+  revision: number;
+  hooks: ReleaseHook[];
+}
+
+export type ResourceStatusValue = 'Current' | 'InProgress' | 'Failed' | 'Terminating' | 'NotFound' | 'Unknown';
+
+export interface ResourceStatus {
+  apiVersion: string;
+  kind: string;
+  namespace?: string;
+  name: string;
+  status: ResourceStatusValue;
+  message?: string;
+}
+
+export interface ReleaseResources {
+  namespace: string;
+  name: string;
+  revision: number;
+  releaseStatus: string;
+  status: ResourceStatusValue;
+  ready: boolean;
+  resources: ResourceStatus[];
+}
+
+export interface ResourceDiff {
+  kind: string;
+  namespace?: string;
+  name: string;
+  change: 'added' | 'removed' | 'changed';
+  diff: string;
+}
+
+export interface ManifestDiff {
+  added: number;
+  removed: number;
+  changed: number;
+  resources: ResourceDiff[];
+}
+
+export interface UpgradePreview {
+  namespace: string;
+  name: string;
+  currentRevision: number;
+  currentChartVersion: string;
+  targetChartVersion: string;
+  diff: ManifestDiff;
 }
 
-export interface ValuesUpdateRequest {
-  values: Record<string, unknown>;
+export interface ValueChange {
+  path: string;
+  change: 'added' | 'removed' | 'changed';
+  from?: unknown;
+  to?: unknown;
+}
+
+export interface RevisionDiff {
+  namespace: string;
+  name: string;
+  from: number;
+  to: number;
+  values: ValueChange[];
+  manifest: ManifestDiff;
 }